@@ -16,6 +16,8 @@ type (
 	Grpc Grpc
 	Kafka    Kafka
 	Jwt Jwt
+	Storage Storage
+	Payment Payment
 }
 
 Kafka struct {
@@ -43,6 +45,36 @@ Jwt struct {
 	ApiDuration int64
 }
 
+// Storage selects the blob store for uploaded files
+Storage struct {
+	Driver     string
+	LocalPath  string
+	PublicUrl  string
+	SigningKey string // Signs short-lived URLs to private blobs
+}
+
+// Payment holds how long a pending payment's QR code can be paid and the
+// public base URL its QR image is served from. SlipVerifyUrl, when set, is
+// the bank API slips are checked against. The card gateway is enabled by
+// CardGatewayUrl and the fake gateway for tests by FakeGateway. The Receipt
+// fields describe us as the seller on receipts and credit notes.
+Payment struct {
+	TtlSeconds           int64
+	PublicUrl            string
+	SlipVerifyUrl        string
+	SlipVerifyApiKey     string
+	CardGatewayUrl       string
+	CardSecretKey        string
+	CardWebhookSecret    string
+	CardReturnUrl        string // Page the gateway sends the user back to after paying
+	FakeGateway          bool
+	FakeWebhookSecret    string // Signs the fake gateway's webhooks
+	ReceiptSellerName    string // Seller printed on receipts; the merchant account's display name when empty
+	ReceiptSellerTaxId   string // Our tax ID; the merchant account's when empty
+	ReceiptSellerAddress string
+	ReceiptFontPath      string // TTF with Thai glyphs, e.g. Sarabun; the core fonts are used when empty
+}
+
 Grpc struct {
 	AuthUrl string
 	UserUrl string
@@ -100,6 +132,44 @@ func LoadConfig(path string) Config {
 			ApiKey: os.Getenv("KAFKA_API_KEY"),
 			Secret: os.Getenv("KAFKA_SECRET"),
 		},
+		Storage: Storage{
+			Driver:    getEnvDefault("STORAGE_DRIVER", "local"),
+			LocalPath: getEnvDefault("STORAGE_LOCAL_PATH", "./storage"),
+			PublicUrl: getEnvDefault("STORAGE_PUBLIC_URL", "/facility_v1/media"),
+			SigningKey: os.Getenv("STORAGE_SIGNING_KEY"),
+		},
+		Payment: Payment{
+			TtlSeconds: func() int64 {
+				result, err := strconv.ParseInt(getEnvDefault("PAYMENT_TTL_SECONDS", "900"), 10, 64)
+				if err != nil {
+					log.Fatalf("Error loading payment ttl failed: %v", err)
+				}
+				if result <= 0 {
+					log.Fatalf("Error loading payment ttl failed: must be positive, got %d", result)
+				}
+				return result
+			}(),
+			PublicUrl: getEnvDefault("PAYMENT_PUBLIC_URL", "/payment_v1"),
+			SlipVerifyUrl: os.Getenv("SLIP_VERIFY_URL"),
+			SlipVerifyApiKey: os.Getenv("SLIP_VERIFY_API_KEY"),
+			CardGatewayUrl: os.Getenv("CARD_GATEWAY_URL"),
+			CardSecretKey: os.Getenv("CARD_GATEWAY_SECRET_KEY"),
+			CardWebhookSecret: os.Getenv("CARD_WEBHOOK_SECRET"),
+			CardReturnUrl: os.Getenv("CARD_RETURN_URL"),
+			FakeGateway: os.Getenv("PAYMENT_FAKE_GATEWAY") == "true",
+			FakeWebhookSecret: os.Getenv("PAYMENT_FAKE_WEBHOOK_SECRET"),
+			ReceiptSellerName: os.Getenv("RECEIPT_SELLER_NAME"),
+			ReceiptSellerTaxId: os.Getenv("RECEIPT_SELLER_TAX_ID"),
+			ReceiptSellerAddress: os.Getenv("RECEIPT_SELLER_ADDRESS"),
+			ReceiptFontPath: os.Getenv("RECEIPT_FONT_PATH"),
+		},
+	}
+}
+
+func getEnvDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
 }
 
@@ -60,6 +60,7 @@ type (
         PeakHours             []PeakHourMetric        `json:"peak_hours"`
         PopularFacilities     []FacilityUsageMetric   `json:"popular_facilities"`
         MaintenanceSchedule   []MaintenanceMetric     `json:"maintenance_schedule"`
+        OpenDays              map[string]int          `json:"open_days"` // Days each facility opens in the period, after holidays
     }
 
     TimeSeriesData struct {
@@ -125,7 +126,10 @@ type (
 
     MaintenanceMetric struct {
         FacilityName string `json:"facility_name"`
+        ResourceId   string `json:"resource_id,omitempty"`
+        Title        string `json:"title"`
         NextDate     string `json:"next_date"`
+        EndDate      string `json:"end_date"`
         Status       string `json:"status"`
     }
 
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"log"
 	"main/modules/analytics"
+	"main/modules/facility"
+	"main/modules/payment/ledger"
+	"main/pkg/utils"
 	"sort"
 	"strconv"
 	"strings"
@@ -514,7 +517,7 @@ func (r *analyticsRepository) GetFacilityMetrics(ctx context.Context, startDate,
 	bookingCol := bookingDb.Collection("booking_transaction")
 
 	// Get total facilities
-	facilityDb := r.db.Database("facility_db")
+	facilityDb := r.db.Database(facility.RegistryDb)
 	facilityCol := facilityDb.Collection("facilities")
 	totalFacilities, err := facilityCol.CountDocuments(ctx, bson.M{})
 	if err != nil {
@@ -584,11 +587,23 @@ func (r *analyticsRepository) GetFacilityMetrics(ctx context.Context, startDate,
 		{Hour: 17, Utilization: 0.9},
 	}
 
+	maintenance, err := r.getMaintenanceSchedule(ctx, "", time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	openDays, err := r.getOpenDays(ctx, "", startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
 	return &analytics.FacilityMetrics{
 		TotalFacilities:     int(totalFacilities),
 		FacilityUtilization: facilityUtilization,
 		PeakHours:          peakHours,
 		PopularFacilities:  popularFacilities,
+		MaintenanceSchedule: maintenance,
+		OpenDays:            openDays,
 	}, nil
 }
 
@@ -800,12 +815,15 @@ func (r *analyticsRepository) getFacilityDb(facilityName string) (*mongo.Databas
 		return nil, fmt.Errorf("facility name cannot be empty")
 	}
 	
-	db := r.db.Database(fmt.Sprintf("%s_facility", facilityName))
+	db := r.db.Database(facility.RegistryDb)
 	
-	// Verify database exists
-	err := db.RunCommand(context.Background(), bson.D{{"ping", 1}}).Err()
+	// Verify the facility is registered
+	count, err := db.Collection("facilities").CountDocuments(context.Background(), bson.M{"name": facilityName})
 	if err != nil {
-		return nil, fmt.Errorf("facility database not accessible: %w", err)
+		return nil, fmt.Errorf("facility registry not accessible: %w", err)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("facility %s is not registered", facilityName)
 	}
 	
 	return db, nil
@@ -831,6 +849,7 @@ func (r *analyticsRepository) getFacilityMetrics(ctx context.Context, facilityNa
 	pipeline := []bson.M{
 		{
 			"$match": bson.M{
+				"facility_name": facilityName,
 				"created_at": bson.M{
 					"$gte": startDate,
 					"$lte": endDate,
@@ -872,6 +891,7 @@ func (r *analyticsRepository) getFacilityMetrics(ctx context.Context, facilityNa
 	peakHoursPipeline := []bson.M{
 		{
 			"$match": bson.M{
+				"facility_name":    facilityName,
 				"current_bookings": bson.M{"$gt": 0},
 			},
 		},
@@ -917,11 +937,23 @@ func (r *analyticsRepository) getFacilityMetrics(ctx context.Context, facilityNa
 		})
 	}
 
+	maintenance, err := r.getMaintenanceSchedule(ctx, facilityName, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	openDays, err := r.getOpenDays(ctx, facilityName, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
 	return &analytics.FacilityMetrics{
 		FacilityUtilization: map[string]float64{
 			facilityName: utilizationRate,
 		},
 		PeakHours: peakHours,
+		MaintenanceSchedule: maintenance,
+		OpenDays: openDays,
 	}, nil
 }
 
@@ -1026,6 +1058,7 @@ func (r *analyticsRepository) getFacilitySlotStats(ctx context.Context, facility
 	pipeline := []bson.M{
 		{
 			"$match": bson.M{
+				"facility_name": facilityName,
 				"created_at": bson.M{
 					"$gte": startDate,
 					"$lte": endDate,
@@ -1082,7 +1115,16 @@ func (r *analyticsRepository) getFacilitySlotStats(ctx context.Context, facility
 
 // Add helper function to aggregate data from all facilities
 func (r *analyticsRepository) getAllFacilitiesStats(ctx context.Context, startDate, endDate time.Time) (*analytics.FacilityMetrics, error) {
-	facilities := []string{"fitness", "swimming", "badminton", "football"}
+	names, err := r.db.Database(facility.RegistryDb).Collection("facilities").Distinct(ctx, "name", bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing facilities: %w", err)
+	}
+	facilities := make([]string, 0, len(names))
+	for _, name := range names {
+		if n, ok := name.(string); ok {
+			facilities = append(facilities, n)
+		}
+	}
 	
 	var totalFacilities int
 	facilityUtilization := make(map[string]float64)
@@ -1109,13 +1151,142 @@ func (r *analyticsRepository) getAllFacilitiesStats(ctx context.Context, startDa
 		return popularFacilities[i].UsageRate > popularFacilities[j].UsageRate
 	})
 
+	maintenance, err := r.getMaintenanceSchedule(ctx, "", time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	openDays, err := r.getOpenDays(ctx, "", startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
 	return &analytics.FacilityMetrics{
 		TotalFacilities:     totalFacilities,
 		FacilityUtilization: facilityUtilization,
 		PopularFacilities:   popularFacilities,
+		MaintenanceSchedule: maintenance,
+		OpenDays:            openDays,
 	}, nil
 }
 
+// getMaintenanceSchedule lists the next window of every unfinished maintenance
+// task, soonest first; an empty facilityName covers all facilities
+func (r *analyticsRepository) getMaintenanceSchedule(ctx context.Context, facilityName string, from time.Time) ([]analytics.MaintenanceMetric, error) {
+	col := r.db.Database(facility.RegistryDb).Collection("maintenance")
+
+	filter := bson.M{"status": bson.M{"$ne": facility.MaintenanceDone}}
+	if facilityName != "" {
+		filter["facility_name"] = facilityName
+	}
+
+	cursor, err := col.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error getting maintenance schedule: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tasks []facility.MaintenanceTask
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, fmt.Errorf("error decoding maintenance schedule: %w", err)
+	}
+
+	type scheduled struct {
+		start  time.Time
+		metric analytics.MaintenanceMetric
+	}
+	var upcoming []scheduled
+	for i := range tasks {
+		start, end, ok := facility.NextMaintenanceWindow(&tasks[i], from)
+		if !ok {
+			continue
+		}
+		metric := analytics.MaintenanceMetric{
+			FacilityName: tasks[i].FacilityName,
+			Title:        tasks[i].Title,
+			NextDate:     start.Format(time.RFC3339),
+			EndDate:      end.Format(time.RFC3339),
+			Status:       tasks[i].Status,
+		}
+		if !tasks[i].ResourceId.IsZero() {
+			metric.ResourceId = tasks[i].ResourceId.Hex()
+		}
+		upcoming = append(upcoming, scheduled{start: start, metric: metric})
+	}
+
+	sort.Slice(upcoming, func(i, j int) bool {
+		return upcoming[i].start.Before(upcoming[j].start)
+	})
+
+	schedule := make([]analytics.MaintenanceMetric, 0, len(upcoming))
+	for _, u := range upcoming {
+		schedule = append(schedule, u.metric)
+	}
+	return schedule, nil
+}
+
+// maxOpenDaysRange bounds how far back open days are counted
+const maxOpenDaysRange = 3 * 366
+
+// getOpenDays counts the days between startDate and endDate each facility is
+// open, applying its weekly hours, public holidays and special days; an empty
+// facilityName covers all facilities
+func (r *analyticsRepository) getOpenDays(ctx context.Context, facilityName string, startDate, endDate time.Time) (map[string]int, error) {
+	db := r.db.Database(facility.RegistryDb)
+
+	loc := utils.Location()
+	start, end := startDate.In(loc), endDate.In(loc)
+	if earliest := end.AddDate(0, 0, -maxOpenDaysRange); start.Before(earliest) {
+		start = earliest
+	}
+
+	filter := bson.M{}
+	if facilityName != "" {
+		filter["name"] = facilityName
+	}
+	cursor, err := db.Collection("facilities").Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error getting facility opening hours: %w", err)
+	}
+	var facilities []facility.FacilityBson
+	if err := cursor.All(ctx, &facilities); err != nil {
+		return nil, fmt.Errorf("error decoding facility opening hours: %w", err)
+	}
+
+	cursor, err = db.Collection("calendar_days").Find(ctx, bson.M{
+		"date": bson.M{
+			"$gte": start.Format(facility.DateLayout),
+			"$lte": end.Format(facility.DateLayout),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting calendar days: %w", err)
+	}
+	var days []facility.CalendarDay
+	if err := cursor.All(ctx, &days); err != nil {
+		return nil, fmt.Errorf("error decoding calendar days: %w", err)
+	}
+
+	openDays := make(map[string]int, len(facilities))
+	for _, f := range facilities {
+		var own []facility.CalendarDay
+		for _, day := range days {
+			if day.FacilityName == "" || day.FacilityName == f.Name {
+				own = append(own, day)
+			}
+		}
+
+		count := 0
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			if facility.ResolveDayHours(f.OpeningHours, own, day).Open {
+				count++
+			}
+		}
+		openDays[f.Name] = count
+	}
+	return openDays, nil
+}
+
 // Add validation helper
 func validateTimeRange(startDate, endDate time.Time) error {
 	if endDate.Before(startDate) {
@@ -1129,48 +1300,32 @@ func validateTimeRange(startDate, endDate time.Time) error {
 	return nil
 }
 
+// getTotalRevenue is the revenue posted to the ledger in the range, after
+// refunds and before VAT. Payment amounts are not added up any more; the
+// ledger is where finance reconciles.
 func (r *analyticsRepository) getTotalRevenue(ctx context.Context, startDate, endDate time.Time) (float64, error) {
-	bookingDb := r.db.Database("booking_db")
-	bookingCol := bookingDb.Collection("booking_transaction")
+	journal := r.db.Database("payment_db").Collection("journal_entries")
 
-
-	// Aggregate query to calculate total revenue from payments related to bookings
 	pipeline := []bson.M{
-		{
-			"$lookup": bson.M{
-				"from":         "payments",
-				"localField":   "booking_id",
-				"foreignField": "booking_id",
-				"as":           "payment_info",
-			},
-		},
-		{
-			"$unwind": "$payment_info",
-		},
-		{
-			"$match": bson.M{
-				"payment_info.created_at": bson.M{
-					"$gte": startDate,
-					"$lte": endDate,
-				},
-			},
-		},
+		{"$match": bson.M{"effective_at": bson.M{"$gte": startDate, "$lte": endDate}}},
+		{"$unwind": "$lines"},
+		{"$match": bson.M{"lines.account": bson.M{"$regex": "^" + ledger.Revenue + ":"}}},
 		{
 			"$group": bson.M{
-				"_id": nil,
-				"total_revenue": bson.M{"$sum": "$payment_info.amount"},
+				"_id":           nil,
+				"total_revenue": bson.M{"$sum": bson.M{"$subtract": bson.A{"$lines.credit", "$lines.debit"}}},
 			},
 		},
 	}
 
-	cursor, err := bookingCol.Aggregate(ctx, pipeline)
+	cursor, err := journal.Aggregate(ctx, pipeline)
 	if err != nil {
 		return 0, fmt.Errorf("failed to aggregate total revenue: %w", err)
 	}
 	defer cursor.Close(ctx)
 
 	var result struct {
-		TotalRevenue float64 `bson:"total_revenue"`
+		TotalRevenue int64 `bson:"total_revenue"` // Satang
 	}
 	if cursor.Next(ctx) {
 		if err := cursor.Decode(&result); err != nil {
@@ -1178,5 +1333,5 @@ func (r *analyticsRepository) getTotalRevenue(ctx context.Context, startDate, en
 		}
 	}
 
-	return result.TotalRevenue, nil
+	return float64(result.TotalRevenue) / 100, nil
 }
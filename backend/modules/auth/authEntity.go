@@ -44,4 +44,6 @@ const (
 	PermissionDeleteUser    = "delete:user"
 	PermissionAccessDashboard = "access:dashboard"
 	PermissionManageBookings  = "manage:bookings"
+	PermissionManageFacilities = "manage:facilities"
+	PermissionManagePayments   = "manage:payments"
 )
\ No newline at end of file
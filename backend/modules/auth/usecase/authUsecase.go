@@ -13,7 +13,6 @@ import (
 	"main/pkg/jwt"
 	"main/pkg/utils"
 	"strings"
-	"time"
 )
 
 type AuthUsecaseService interface {
@@ -65,7 +64,7 @@ func (u *authUsecase) Login(pctx context.Context, cfg *config.Config, req *auth.
 		return nil, err
 	}
 
-	loc, _ := time.LoadLocation("Asia/Bangkok")
+	loc := utils.Location()
 
 	return &auth.ProfileIntercepter{
 		UserProfile: &user.UserProfile{
@@ -134,7 +133,7 @@ func (u *authUsecase) RefreshToken(pctx context.Context, cfg *config.Config, req
     }
 
     // Set timezone for the credential timestamps
-    loc, _ := time.LoadLocation("Asia/Bangkok")
+    loc := utils.Location()
 
     // Return the profile interceptor with updated tokens and user profile
     return &auth.ProfileIntercepter{
@@ -6,17 +6,53 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Booking statuses; PAID is upper case as bookings paid before events existed were stored that way
+const (
+	StatusPending         = "pending"
+	StatusPaid            = "PAID"
+	StatusExpired         = "expired"           // Payment deadline passed; the slot was given back
+	StatusPaidAfterExpiry = "paid_after_expiry" // Paid once the slot had filled again; staff refund it
+	StatusUnderpaid       = "underpaid"         // Paid less than its amount or by another user; staff refund it
+)
+
 type (
 	Booking struct {
 		Id              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 		UserId          string             `bson:"user_id" json:"user_id"`
-		SlotId          *string            `bson:"slot_id,omitempty" json:"slot_id,omitempty"`                     // String type for normal slot ID
-		BadmintonSlotId *string            `bson:"badminton_slot_id,omitempty" json:"badminton_slot_id,omitempty"` // String type for badminton slot ID
-		SlotType        string             `bson:"slot_type" json:"slot_type"`       // "normal" or "badminton"
+		SlotId          *string            `bson:"slot_id,omitempty" json:"slot_id,omitempty"`
+		ResourceId      *string            `bson:"resource_id,omitempty" json:"resource_id,omitempty"`             // Court, lane or pitch the slot belongs to
+		BadmintonSlotId *string            `bson:"badminton_slot_id,omitempty" json:"badminton_slot_id,omitempty"` // Deprecated: legacy alias of SlotId
+		SlotType        string             `bson:"slot_type" json:"slot_type"`       // Deprecated: slots are no longer typed
 		Status          string             `bson:"status" json:"status"`
+		Amount          float64            `bson:"amount,omitempty" json:"amount,omitempty"` // Facility price plus rentals, fixed when booked
 		PaymentID       string             `bson:"payment_id"`
 		QRCodeURL       string             `bson:"qr_code_url"`
+		PaidAt          *time.Time         `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
 		CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
 		UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
 	}
 )
+
+type (
+	// ProcessedEvent records a queue event the booking service has applied
+	ProcessedEvent struct {
+		EventId     string    `bson:"event_id"`
+		Topic       string    `bson:"topic"`
+		ProcessedAt time.Time `bson:"processed_at"`
+	}
+
+	// PaymentDrift is a completed payment whose booking is not marked paid
+	PaymentDrift struct {
+		BookingId string  `bson:"booking_id"`
+		PaymentId string  `bson:"payment_id"`
+		UserId    string  `bson:"user_id"`
+		Amount    float64 `bson:"amount"`
+	}
+
+	// PaidBy is the payment a booking is marked paid with
+	PaidBy struct {
+		PaymentId string
+		UserId    string
+		Amount    float64
+	}
+)
@@ -0,0 +1,12 @@
+package booking
+
+import "errors"
+
+var (
+	ErrWaiverNotAccepted = errors.New("error: user has not accepted the current facility waiver")
+	ErrSlotFull          = errors.New("error: Slot is full")
+	ErrNoCourtAvailable  = errors.New("error: no court is free at the requested time")
+	ErrBookingNotFound   = errors.New("error: booking not found")
+	ErrPaidAfterExpiry   = errors.New("error: booking was paid after its slot was given back and filled; it needs a refund")
+	ErrUnderpaid         = errors.New("error: payment does not cover the booking; it needs a refund")
+)
@@ -1,6 +1,7 @@
 package booking
 
 import (
+	"main/modules/equipment"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -8,12 +9,16 @@ import (
 
 
 type (
-	// CreateBookingRequest supports booking either a normal slot or a badminton slot
+	// CreateBookingRequest books a slot of a facility, whether or not the slot belongs to a resource.
+	// Without a slot id, start_time books any free court starting then.
 	CreateBookingRequest struct {
-		UserId          string  `json:"user_id" validate:"required,max=64"`
-		SlotId          *string `json:"slot_id,omitempty"`             // Normal slot ID (optional if badminton)
-		BadmintonSlotId *string `json:"badminton_slot_id,omitempty"`   // Badminton slot ID (optional if normal)
-		SlotType        string  `json:"slot_type" validate:"required"` // "normal" or "badminton"
+		UserId          string  `json:"user_id" validate:"omitempty,max=64"` // Defaults to the caller; only staff may book for someone else
+		SlotId          *string `json:"slot_id,omitempty"`
+		BadmintonSlotId *string `json:"badminton_slot_id,omitempty"`   // Deprecated: accepted as an alias of slot_id
+		SlotType        string  `json:"slot_type,omitempty"`           // Deprecated: ignored
+		StartTime       string  `json:"start_time,omitempty"`          // HH:mm, used when no slot is picked
+		EndTime         string  `json:"end_time,omitempty"`            // HH:mm, optional with start_time
+		Equipment       []equipment.RentalItemRequest `json:"equipment,omitempty" validate:"omitempty,dive"`
 	}
 
 	// BookingSearchRequest for searching bookings by user, slot, or status
@@ -39,14 +44,17 @@ type (
 	BookingResponse struct {
 		Id              primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
 		UserId          string             `bson:"user_id" json:"user_id"`
-		SlotId          *string            `bson:"slot_id,omitempty"`             // Slot ID for normal facilities
-		BadmintonSlotId *string            `bson:"badminton_slot_id,omitempty"`   // Slot ID for badminton-specific bookings
-		SlotType        string             `json:"slot_type"`                     // "normal" or "badminton"
+		SlotId          *string            `bson:"slot_id,omitempty"`
+		ResourceId      *string            `bson:"resource_id,omitempty" json:"resource_id,omitempty"`
+		BadmintonSlotId *string            `bson:"badminton_slot_id,omitempty"`   // Deprecated: legacy alias of SlotId
+		SlotType        string             `json:"slot_type"`                     // Deprecated: slots are no longer typed
 		Status          string                `json:"status"`
 		CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
 		UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
 		PaymentID       string             `json:"payment_id"`
 		QRCodeURL       string             `json:"qr_code_url"`
+		Equipment       []equipment.Rental `bson:"-" json:"equipment,omitempty"`
+		RentalAmount    float64            `bson:"-" json:"rental_amount,omitempty"`
 	}
 
 	// EnableOrDisableBookingRequest is used to enable or disable a booking
@@ -58,9 +66,18 @@ type (
 		UserId          string    `json:"user_id" validate:"required"`
 		SlotId          *string   `json:"slot_id,omitempty"`
 		BadmintonSlotId *string   `json:"badminton_slot_id,omitempty"`
-		SlotType        string    `json:"slot_type" validate:"required"`
+		SlotType        string    `json:"slot_type,omitempty"`
 		FacilityName    string    `json:"facility_name" validate:"required"`
 		Amount          float64   `json:"amount"`
 		CreatedAt       time.Time `json:"created_at"`
 	}
+
+	// CourtSlot is one court's slot at the requested time. Taken courts are
+	// kept so the allocator can see which free courts are contiguous.
+	CourtSlot struct {
+		SlotId     string
+		ResourceId string
+		Number     int
+		Free       bool
+	}
 )
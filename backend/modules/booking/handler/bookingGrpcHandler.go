@@ -33,7 +33,8 @@ func (h *bookingGrpcHandler) CreateBooking(ctx context.Context, req *bookingPb.C
         bookingReq.BadmintonSlotId = &req.BadmintonSlotId
     }
 
-    result, err := h.bookingUsecase.InsertBooking(ctx, req.FacilityName, bookingReq)
+    // Bookings made here are not priced, so the payment service won't charge them
+    result, err := h.bookingUsecase.InsertBooking(ctx, req.FacilityName, bookingReq, 0)
     if err != nil {
         return &bookingPb.BookingResponse{
             ErrorMessage: fmt.Sprintf("Failed to create booking: %v", err),
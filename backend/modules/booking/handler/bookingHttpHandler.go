@@ -2,12 +2,18 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	client "main/client/payment"
 	"main/config"
+	"main/modules/auth"
 	"main/modules/booking"
 	"main/modules/booking/usecase"
+	"main/modules/equipment"
+	equipmentUsecase "main/modules/equipment/usecase"
+	"main/pkg/rbac"
 	"main/pkg/response"
+	"main/pkg/utils"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -25,13 +31,14 @@ type (
 
 	bookingHttpHandler struct {
 		cfg            *config.Config
-		bookingUsecase usecase.BookingUsecaseService
-		paymentClient  *client.PaymentClient
+		bookingUsecase   usecase.BookingUsecaseService
+		equipmentUsecase equipmentUsecase.EquipmentUsecaseService
+		paymentClient    *client.PaymentClient
 	}
 )
 
-func NewBookingHttpHandler(cfg *config.Config, bookingUsecase usecase.BookingUsecaseService, paymentClient *client.PaymentClient) NewBookingHttpHandlerService {
-	return &bookingHttpHandler{cfg: cfg, bookingUsecase: bookingUsecase, paymentClient: paymentClient} // ส่ง payment client
+func NewBookingHttpHandler(cfg *config.Config, bookingUsecase usecase.BookingUsecaseService, equipmentUsecase equipmentUsecase.EquipmentUsecaseService, paymentClient *client.PaymentClient) NewBookingHttpHandlerService {
+	return &bookingHttpHandler{cfg: cfg, bookingUsecase: bookingUsecase, equipmentUsecase: equipmentUsecase, paymentClient: paymentClient} // ส่ง payment client
 }
 
 func (h *bookingHttpHandler) CreateBooking(c echo.Context) error {
@@ -46,13 +53,50 @@ func (h *bookingHttpHandler) CreateBooking(c echo.Context) error {
         return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
     }
 
+    // The booking is the caller's; the waiver, court history and rentals all
+    // follow the user id, so only staff may name someone else
+    callerId, _ := c.Get("user_id").(string)
+    if createBookingReq.UserId == "" {
+        createBookingReq.UserId = callerId
+    }
+    if createBookingReq.UserId != callerId && !rbac.HasPermission(c.Get("role_code").(int), auth.PermissionManageBookings) {
+        return c.JSON(http.StatusForbidden, map[string]string{"error": "Cannot book for another user"})
+    }
+
     facilityName := c.Param("facilityName")
     log.Printf("Received request to create booking for facility: %s", facilityName)
 
-    bookingResponse, err := h.bookingUsecase.InsertBooking(c.Request().Context(), facilityName, &createBookingReq)
-    if err != nil {
-        log.Printf("Error inserting booking in database: %v", err)
-        return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to insert booking: " + err.Error()})
+    slotId := ""
+    if createBookingReq.SlotId != nil {
+        slotId = *createBookingReq.SlotId
+    } else if createBookingReq.BadmintonSlotId != nil {
+        slotId = *createBookingReq.BadmintonSlotId
+    } else if createBookingReq.StartTime != "" {
+        // No court picked: book any free court starting at start_time
+        allocated, err := h.bookingUsecase.AllocateCourt(c.Request().Context(), facilityName, createBookingReq.UserId, createBookingReq.StartTime, createBookingReq.EndTime)
+        if errors.Is(err, booking.ErrNoCourtAvailable) {
+            return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+        }
+        if errors.Is(err, utils.ErrInvalidTimeFormat) || errors.Is(err, utils.ErrInvalidTimeRange) {
+            return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+        }
+        if err != nil {
+            log.Printf("Error allocating a court: %v", err)
+            return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to allocate a court: " + err.Error()})
+        }
+        slotId = allocated
+        createBookingReq.SlotId = &allocated
+    }
+
+    // Check equipment stock before the slot is taken so a failed rental does not leave a booking behind
+    var rentalAmount float64
+    if len(createBookingReq.Equipment) > 0 {
+        quote, err := h.equipmentUsecase.QuoteRental(c.Request().Context(), facilityName, slotId, createBookingReq.Equipment)
+        if err != nil {
+            log.Printf("Error quoting equipment rental: %v", err)
+            return c.JSON(rentalErrStatus(err), map[string]string{"error": err.Error()})
+        }
+        rentalAmount = quote.Amount
     }
 
     const PaymentMethods = "PromptPay"
@@ -92,18 +136,45 @@ func (h *bookingHttpHandler) CreateBooking(c echo.Context) error {
         return c.JSON(http.StatusBadRequest, map[string]string{"error": "Facility not found"})
     }
 
+    // The payment service charges the amount stored on the booking
+    amount := priceInsider + rentalAmount
+
+    bookingResponse, err := h.bookingUsecase.InsertBooking(c.Request().Context(), facilityName, &createBookingReq, amount)
+    if errors.Is(err, booking.ErrWaiverNotAccepted) {
+        return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+    }
+    if errors.Is(err, booking.ErrSlotFull) {
+        return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+    }
+    if err != nil {
+        log.Printf("Error inserting booking in database: %v", err)
+        return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to insert booking: " + err.Error()})
+    }
+
+    if len(createBookingReq.Equipment) > 0 {
+        quote, err := h.equipmentUsecase.ReserveRental(c.Request().Context(), bookingResponse.Id.Hex(), bookingResponse.UserId, facilityName, slotId, createBookingReq.Equipment)
+        if err != nil {
+            log.Printf("Error reserving equipment for booking %s: %v", bookingResponse.Id.Hex(), err)
+            h.abandonBooking(c, bookingResponse.Id.Hex())
+            return c.JSON(rentalErrStatus(err), map[string]string{"error": "Failed to reserve equipment: " + err.Error()})
+        }
+        bookingResponse.Equipment = quote.Items
+        bookingResponse.RentalAmount = quote.Amount
+    }
+
     paymentRequest := client.CreatePaymentRequest{
-        Amount:        priceInsider,
+        Amount:        amount,
         UserID:        bookingResponse.UserId,
         BookingID:     bookingResponse.Id.Hex(),
         PaymentMethod: PaymentMethods,
         Currency:      "THB",
         FacilityName:  facilityName,
     }
-    log.Printf("Attempting to create payment with amount: %.2f", priceInsider)
+    log.Printf("Attempting to create payment with amount: %.2f", amount)
 
 	paymentResponse, err := h.paymentClient.CreatePayment(paymentRequest)
     if err != nil {
+        h.abandonBooking(c, bookingResponse.Id.Hex())
         return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
     }
 
@@ -118,11 +189,14 @@ func (h *bookingHttpHandler) CreateBooking(c echo.Context) error {
             "payment_id":  paymentResponse.ID,
             "qr_code_url": paymentResponse.QRCodeURL,
             "status":      "PENDING",
+            "amount":      amount,
+            "equipment":   bookingResponse.Equipment,
         })
     }
 
     // Check if payment was successful
     if paymentResponse.Status != "PAID" {
+        h.abandonBooking(c, bookingResponse.Id.Hex())
         return c.JSON(http.StatusPaymentRequired, map[string]string{"error": "Payment is not completed"})
     }
 
@@ -133,6 +207,32 @@ func (h *bookingHttpHandler) CreateBooking(c echo.Context) error {
     return c.JSON(http.StatusOK, bookingResponse)
 }
 
+// abandonBooking gives back the slot and equipment of a booking that failed
+// after it was inserted. No payment exists to expire it, so nothing else would.
+func (h *bookingHttpHandler) abandonBooking(c echo.Context, bookingId string) {
+	if err := h.equipmentUsecase.CancelBookingRentals(c.Request().Context(), bookingId); err != nil {
+		log.Printf("Error releasing equipment for booking %s: %v", bookingId, err)
+	}
+	if err := h.bookingUsecase.ReleaseBookingHold(c.Request().Context(), bookingId); err != nil {
+		log.Printf("Error releasing slot for booking %s: %v", bookingId, err)
+	}
+}
+
+// rentalErrStatus maps equipment errors raised while booking to an HTTP status
+func rentalErrStatus(err error) int {
+	switch {
+	case errors.Is(err, equipment.ErrItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, equipment.ErrInvalidRentalSlot),
+		errors.Is(err, equipment.ErrDuplicateRentalItem):
+		return http.StatusBadRequest
+	case errors.Is(err, equipment.ErrInsufficientStock),
+		errors.Is(err, equipment.ErrItemInactive):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
 
 func (h *bookingHttpHandler) FindBooking(c echo.Context) error {
 	bookingId := c.Param("booking_id") // Ensure the same parameter name as UpdateBooking
@@ -154,6 +254,8 @@ func (h *bookingHttpHandler) FindOneUserBooking(c echo.Context) error {
 	return response.SuccessResponse(c, http.StatusOK, bookings)
 }
 
+// UpdateBookingStatusToPaid lets staff mark a booking paid by hand, e.g. when
+// the payment event and the reconciler both missed it
 func (h *bookingHttpHandler) UpdateBookingStatusToPaid(c echo.Context) error {
 	bookingID := c.Param("booking_id")
 	if bookingID == "" {
@@ -161,6 +263,12 @@ func (h *bookingHttpHandler) UpdateBookingStatusToPaid(c echo.Context) error {
 	}
 
 	err := h.bookingUsecase.UpdateBookingStatusPaid(c.Request().Context(), bookingID)
+	if errors.Is(err, booking.ErrBookingNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	if errors.Is(err, booking.ErrPaidAfterExpiry) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
 	if err != nil {
 		log.Printf("Error in UpdateBookingStatusToPaid: %s", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update booking status to paid"})
@@ -10,11 +10,14 @@ import (
 	"main/modules/booking"
 	"main/modules/facility"
 	"main/modules/models"
+	"main/pkg/utils"
 	"time"
 
+	"github.com/IBM/sarama"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type (
@@ -23,13 +26,25 @@ type (
 		UpdateBooking (ctx context.Context, booking *booking.Booking) (*booking.Booking, error)
 		FindBooking(ctx context.Context, bookingId string) (*booking.Booking, error)
 		FindOneUserBooking (ctx context.Context, userId string) ([]booking.Booking, error)
-        updateBadmintonSlotBookings(ctx context.Context, slotId primitive.ObjectID, increment int) error
 		InsertBooking(pctx context.Context, facilityName string, req *booking.Booking) (*booking.Booking, error)
+
+		//Court allocation
+		FindCourtSlots(ctx context.Context, facilityName, startTime, endTime string, day time.Time) ([]booking.CourtSlot, error)
+		CountResourceBookings(ctx context.Context, facilityName, userId string) (map[string]int, error)
 		
 
 		//Kafka Interface
 		GetOffset(pctx context.Context) (int64, error)
 		UpOffset(pctx context.Context, newOffset int64) error
+		GetTopicOffset(ctx context.Context, topic string) (int64, error)
+		SetTopicOffset(ctx context.Context, topic string, offset int64) error
+		HasProcessedEvent(ctx context.Context, eventId string) (bool, error)
+		RecordProcessedEvent(ctx context.Context, eventId, topic string) error
+
+		//Payment reconciliation
+		MarkBookingPaid(ctx context.Context, bookingId string, paidBy *booking.PaidBy) (bool, error)
+		ReleaseBookingHold(ctx context.Context, bookingId string) (bool, error)
+		FindPaymentDrift(ctx context.Context, paymentStatus string) ([]booking.PaymentDrift, error)
 
 		//Clearing system
 		ClearingBookingAtMidnight(ctx context.Context) error
@@ -60,10 +75,24 @@ func (r *bookingRepository) bookingDbConn(ctx context.Context) *mongo.Database {
 	return r.db.Database("booking_db")
 }
 
-func (r *bookingRepository) facilityDbConn(ctx context.Context, facilityName string) *mongo.Database {
-	// Use the facility name to dynamically create the database name
-	databaseName := fmt.Sprintf("%s_facility", facilityName)
-	return r.client.Database(databaseName) // This will create the DB if it doesn't exist
+func (r *bookingRepository) facilityDbConn(ctx context.Context) *mongo.Database {
+	return r.client.Database(facility.RegistryDb)
+}
+
+// listFacilityNames resolves every registered facility from the registry
+func (r *bookingRepository) listFacilityNames(ctx context.Context) ([]string, error) {
+	names, err := r.facilityDbConn(ctx).Collection("facilities").Distinct(ctx, "name", bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("error: list facilities failed: %w", err)
+	}
+
+	facilities := make([]string, 0, len(names))
+	for _, name := range names {
+		if n, ok := name.(string); ok {
+			facilities = append(facilities, n)
+		}
+	}
+	return facilities, nil
 }
 
 
@@ -88,7 +117,11 @@ func (r *bookingRepository) ClearingBookingAtMidnight(ctx context.Context) error
 
     //Reset facilitty 
      // Step 3: Reset facility slots for each facility type
-     facilities := []string{"fitness", "swimming", "badminton", "football"}
+     facilities, err := r.listFacilityNames(ctx)
+     if err != nil {
+         log.Printf("Error: clearingBookingAtMidnight: %s", err.Error())
+         return err
+     }
 
      for _, facilityName := range facilities {
          if err := r.ResetFacilitySlots(ctx, facilityName); err != nil {
@@ -151,22 +184,11 @@ func (r *bookingRepository) ResetFacilitySlots(ctx context.Context, facilityName
     ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
     defer cancel()
 
-    // Connect to the correct facility database
-    db := r.facilityDbConn(ctx, facilityName)
+    db := r.facilityDbConn(ctx)
     col := db.Collection("slots") // Use the "slots" collection for the facility
 
-    var update bson.M
-    var filter bson.M
-
-    if facilityName == "badminton" {
-        // For badminton, reset the status field to "available" only if max bookings have been reached
-        filter = bson.M{"current_bookings": bson.M{"$gte": 10}} // Example max bookings threshold
-        update = bson.M{"$set": bson.M{"status": "available", "current_bookings": 0}} // Reset to available, clear bookings
-    } else {
-        // For other facilities, reset current_bookings to 0
-        filter = bson.M{}
-        update = bson.M{"$set": bson.M{"current_bookings": 0}}
-    }
+    filter := bson.M{"facility_name": facilityName}
+    update := bson.M{"$set": bson.M{"current_bookings": 0}}
 
     // Update the slots collection for the specific facility
     _, err := col.UpdateMany(ctx, filter, update)
@@ -226,27 +248,12 @@ func (r *bookingRepository) UpOffset(pctx context.Context, newOffset int64) erro
 	return nil
 }
 
-func (r *bookingRepository) checkSlotAvailability(pctx context.Context, facilityName string, req *booking.Booking) (*facility.Slot, error) {
-	// Connect to the facility DB (specific to each facility)
-	facilityDb := r.facilityDbConn(pctx, facilityName)
+func (r *bookingRepository) checkSlotAvailability(pctx context.Context, facilityName string, slotId primitive.ObjectID) (*facility.Slot, error) {
+	facilityDb := r.facilityDbConn(pctx)
 	slotCol := facilityDb.Collection("slots")
 
 	var slot facility.Slot
-	var err error
-
-	// Check for normal SlotId
-	if req.SlotId != nil {
-		id, _ := primitive.ObjectIDFromHex(*req.SlotId)
-		err = slotCol.FindOne(pctx, bson.M{"_id": id}).Decode(&slot)
-	} 
-	// Check for BadmintonSlotId
-	if req.BadmintonSlotId != nil {
-		id, _ := primitive.ObjectIDFromHex(*req.BadmintonSlotId)
-		err = slotCol.FindOne(pctx, bson.M{"_id": id}).Decode(&slot)
-	}
-
-	// If error occurred, return the appropriate message
-	if err != nil {
+	if err := slotCol.FindOne(pctx, bson.M{"_id": slotId, "facility_name": facilityName}).Decode(&slot); err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, fmt.Errorf("slot not found or invalid slot ID")
 		}
@@ -258,46 +265,33 @@ func (r *bookingRepository) checkSlotAvailability(pctx context.Context, facility
 }
 
 
-func (r *bookingRepository) updateSlotCurrentBooking(ctx context.Context, facilityName string, slotId primitive.ObjectID, increment int) error {
+// updateSlotCurrentBooking adjusts a slot's booking count, refusing to go past
+// capacity so concurrent bookings can't overfill a slot
+func (r *bookingRepository) updateSlotCurrentBooking(ctx context.Context, facilityName string, slotId primitive.ObjectID, increment, capacity int) error {
     log.Printf("Updating %s slot %s bookings by %d", facilityName, slotId.Hex(), increment)
-    
-    db := r.facilityDbConn(ctx, facilityName)
+
+    db := r.facilityDbConn(ctx)
     col := db.Collection("slots")
 
-    // First get current slot state
-    var currentSlot facility.Slot
-    err := col.FindOne(ctx, bson.M{"_id": slotId}).Decode(&currentSlot)
-    if err != nil {
-        return fmt.Errorf("failed to get current slot state: %w", err)
+    filter := bson.M{"_id": slotId, "facility_name": facilityName}
+    if increment > 0 {
+        filter["current_bookings"] = bson.M{"$lte": capacity - increment}
     }
 
-    log.Printf("Current bookings before update: %d", currentSlot.CurrentBookings)
-
-    // Update the slot
     update := bson.M{
-        "$set": bson.M{
-            "current_bookings": currentSlot.CurrentBookings + increment,
-            "updated_at": time.Now(),
-        },
+        "$inc": bson.M{"current_bookings": increment},
+        "$set": bson.M{"updated_at": time.Now()},
     }
 
-    result, err := col.UpdateOne(ctx, bson.M{"_id": slotId}, update)
+    result, err := col.UpdateOne(ctx, filter, update)
     if err != nil {
         return fmt.Errorf("failed to update slot bookings: %w", err)
     }
 
     if result.MatchedCount == 0 {
-        return errors.New("slot not found")
+        return booking.ErrSlotFull
     }
 
-    // Verify the update
-    var updatedSlot facility.Slot
-    err = col.FindOne(ctx, bson.M{"_id": slotId}).Decode(&updatedSlot)
-    if err != nil {
-        return fmt.Errorf("failed to verify update: %w", err)
-    }
-
-    log.Printf("Current bookings after update: %d", updatedSlot.CurrentBookings)
     return nil
 }
 
@@ -313,105 +307,98 @@ func (r *bookingRepository) InsertBooking(pctx context.Context, facilityName str
 
     log.Printf("Attempting to insert booking for userId: %s", req.UserId)
 
-    var slotIdObject *primitive.ObjectID
-    var badmintonSlotIdObject *primitive.ObjectID
-    isBadminton := false
-
-    if req.SlotId != nil {
-        slotId, err := primitive.ObjectIDFromHex(*req.SlotId)
-        if err != nil {
-            return nil, fmt.Errorf("invalid SlotId: %w", err)
-        }
-        slotIdObject = &slotId
-    } else if req.BadmintonSlotId != nil {
-        badmintonSlotId, err := primitive.ObjectIDFromHex(*req.BadmintonSlotId)
-        if err != nil {
-            return nil, fmt.Errorf("invalid BadmintonSlotId: %w", err)
-        }
-        badmintonSlotIdObject = &badmintonSlotId
-        isBadminton = true
+    slotIdObject, err := primitive.ObjectIDFromHex(*req.SlotId)
+    if err != nil {
+        return nil, fmt.Errorf("invalid SlotId: %w", err)
     }
 
     // Check for duplicate bookings
-    exists, err := r.checkDuplicateBooking(ctx, req.UserId, slotIdObject, badmintonSlotIdObject)
+    exists, err := r.checkDuplicateBooking(ctx, req.UserId, slotIdObject)
     if err != nil {
         log.Printf("Error while checking duplicate booking: %s", err)
         return nil, err
     }
     if exists {
-        log.Printf("User %s has already booked slot %v/%v", req.UserId, slotIdObject, badmintonSlotIdObject)
+        log.Printf("User %s has already booked slot %s", req.UserId, slotIdObject.Hex())
         return nil, errors.New("error: user has already booked this slot")
     }
 
-    var updatedSlot *facility.Slot
-    // Check slot availability and update bookings
-    if isBadminton {
-        // Check badminton slot availability
-        count, err := r.countUserBadmintonBookings(ctx, req.UserId)
-        if err != nil {
-            return nil, err
-        }
-        if count >= 2 {
-            return nil, errors.New("error: user has reached the maximum limit of 2 badminton slots")
-        }
-
-        // Get current slot state before update
-        updatedSlot, err = r.getBadmintonSlot(ctx, *badmintonSlotIdObject)
-        if err != nil {
-            return nil, err
-        }
+    accepted, err := r.hasAcceptedWaiver(ctx, facilityName, req.UserId)
+    if err != nil {
+        return nil, err
+    }
+    if !accepted {
+        return nil, booking.ErrWaiverNotAccepted
+    }
 
-        // Update badminton slot booking count
-        err = r.updateBadmintonSlotBookings(ctx, *badmintonSlotIdObject, 1)
-        if err != nil {
-            return nil, fmt.Errorf("failed to update badminton slot booking count: %w", err)
-        }
+    slot, err := r.checkSlotAvailability(ctx, facilityName, slotIdObject)
+    if err != nil {
+        return nil, err
+    }
+    capacity, err := r.slotCapacityOn(ctx, facilityName, slot, utils.LocalTime())
+    if err != nil {
+        return nil, err
+    }
+    if slot.CurrentBookings >= capacity {
+        return nil, booking.ErrSlotFull
+    }
+    if !isSlotOpenOn(slot, utils.LocalTime()) {
+        return nil, errors.New("error: slot is not available today")
+    }
+    underMaintenance, err := r.isSlotUnderMaintenance(ctx, facilityName, slot, utils.LocalTime())
+    if err != nil {
+        return nil, err
+    }
+    if underMaintenance {
+        return nil, errors.New("error: slot is under maintenance")
+    }
+    facilityOpen, err := r.isFacilityOpenFor(ctx, facilityName, slot, utils.LocalTime())
+    if err != nil {
+        return nil, err
+    }
+    if !facilityOpen {
+        return nil, errors.New("error: facility is closed at this time")
+    }
 
-        // Get updated slot state
-        updatedSlot, err = r.getBadmintonSlot(ctx, *badmintonSlotIdObject)
-        if err != nil {
-            return nil, err
-        }
-    } else {
-        // Handle normal slot booking
-        slot, err := r.checkSlotAvailability(ctx, facilityName, req)
+    // Slots on a resource (court, lane, pitch) are limited per user and facility
+    if !slot.ResourceId.IsZero() {
+        count, err := r.countUserResourceBookings(ctx, req.UserId, facilityName)
         if err != nil {
             return nil, err
         }
-        if slot.CurrentBookings >= slot.MaxBookings {
-            return nil, errors.New("error: Slot is full")
-        }
-        
-        // Update normal slot booking count
-        err = r.updateSlotCurrentBooking(ctx, facilityName, *slotIdObject, 1)
-        if err != nil {
-            return nil, err
+        if count >= maxResourceBookingsPerUser {
+            return nil, fmt.Errorf("error: user has reached the maximum limit of %d %s slots", maxResourceBookingsPerUser, facilityName)
         }
+    }
 
-        // Get updated slot state
-        updatedSlot, err = r.getSlot(ctx, facilityName, *slotIdObject)
-        if err != nil {
-            return nil, err
-        }
+    if err := r.updateSlotCurrentBooking(ctx, facilityName, slotIdObject, 1, capacity); err != nil {
+        return nil, err
+    }
+
+    // Get updated slot state
+    updatedSlot, err := r.getSlot(ctx, facilityName, slotIdObject)
+    if err != nil {
+        return nil, err
     }
 
     // Create booking document with all necessary fields
     bookingDoc := bson.M{
         "user_id":          req.UserId,
         "facility":         facilityName,
+        "slot_id":          slotIdObject,
         "status":          "pending",
         "created_at":      time.Now(),
         "updated_at":      time.Now(),
-        "slot_type":       isBadminton,
         "current_bookings": updatedSlot.CurrentBookings,
-        "max_bookings":    updatedSlot.MaxBookings,
+        "max_bookings":    capacity,
     }
-
-    if req.SlotId != nil {
-        bookingDoc["slot_id"] = slotIdObject
+    if req.Amount > 0 {
+        bookingDoc["amount"] = req.Amount
     }
-    if req.BadmintonSlotId != nil {
-        bookingDoc["badminton_slot_id"] = badmintonSlotIdObject
+    if !slot.ResourceId.IsZero() {
+        bookingDoc["resource_id"] = slot.ResourceId
+        resourceId := slot.ResourceId.Hex()
+        req.ResourceId = &resourceId
     }
 
     // Insert booking
@@ -497,7 +484,7 @@ func (r *bookingRepository) UpdateStatusPaid(ctx context.Context, bookingID stri
 	}
 
 	filter := bson.M{"_id": objID}
-	update := bson.M{"$set": bson.M{"status": "PAID"}}
+	update := bson.M{"$set": bson.M{"status": booking.StatusPaid}}
 
 	_, err = col.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -508,16 +495,10 @@ func (r *bookingRepository) UpdateStatusPaid(ctx context.Context, bookingID stri
 	return nil
 }
 
-func (r *bookingRepository) checkDuplicateBooking(ctx context.Context, userId string, slotId, badmintonSlotId *primitive.ObjectID) (bool, error) {
+func (r *bookingRepository) checkDuplicateBooking(ctx context.Context, userId string, slotId primitive.ObjectID) (bool, error) {
     filter := bson.M{
         "user_id": userId,
-    }
-
-    // Add slot filter depending on slot type
-    if slotId != nil {
-        filter["slot_id"] = slotId
-    } else if badmintonSlotId != nil {
-        filter["badminton_slot_id"] = badmintonSlotId
+        "slot_id": slotId,
     }
 
     // Count documents matching the filter
@@ -564,13 +545,259 @@ func (r *bookingRepository) checkUserBookingExists(pctx context.Context, userId
     return count > 0, nil
 }
 
+// isSlotOpenOn reports whether a slot runs on the weekday of t; slots
+// without weekdays run every day.
+func isSlotOpenOn(slot *facility.Slot, t time.Time) bool {
+    if len(slot.Weekdays) == 0 {
+        return true
+    }
+    for _, weekday := range slot.Weekdays {
+        if time.Weekday(weekday) == t.Weekday() {
+            return true
+        }
+    }
+    return false
+}
+
+// isSlotUnderMaintenance checks the facility's unfinished maintenance tasks
+// against the slot's window on day
+func (r *bookingRepository) isSlotUnderMaintenance(ctx context.Context, facilityName string, slot *facility.Slot, day time.Time) (bool, error) {
+    col := r.facilityDbConn(ctx).Collection("maintenance")
+
+    cursor, err := col.Find(ctx, bson.M{
+        "facility_name": facilityName,
+        "status":        bson.M{"$ne": facility.MaintenanceDone},
+    })
+    if err != nil {
+        log.Printf("Error: isSlotUnderMaintenance: %s", err.Error())
+        return false, fmt.Errorf("error: check maintenance failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    var tasks []facility.MaintenanceTask
+    if err := cursor.All(ctx, &tasks); err != nil {
+        log.Printf("Error: isSlotUnderMaintenance: %s", err.Error())
+        return false, fmt.Errorf("error: check maintenance failed: %w", err)
+    }
+
+    for i := range tasks {
+        if facility.MaintenanceBlocksSlot(&tasks[i], slot, day) {
+            return true, nil
+        }
+    }
+    return false, nil
+}
+
+// FindCourtSlots returns every court's slot starting at startTime on day, and
+// ending at endTime when given. A court is free when it is in service, runs
+// on day, is not under maintenance and still has capacity.
+func (r *bookingRepository) FindCourtSlots(ctx context.Context, facilityName, startTime, endTime string, day time.Time) ([]booking.CourtSlot, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    db := r.facilityDbConn(ctx)
+
+    filter := bson.M{
+        "facility_name": facilityName,
+        "start_time":    startTime,
+        "resource_id":   bson.M{"$exists": true},
+    }
+    if endTime != "" {
+        filter["end_time"] = endTime
+    }
+    cursor, err := db.Collection("slots").Find(ctx, filter)
+    if err != nil {
+        log.Printf("Error: FindCourtSlots: %s", err.Error())
+        return nil, fmt.Errorf("error: find court slots failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    var slots []facility.Slot
+    if err := cursor.All(ctx, &slots); err != nil {
+        log.Printf("Error: FindCourtSlots: %s", err.Error())
+        return nil, fmt.Errorf("error: find court slots failed: %w", err)
+    }
+
+    cursor, err = db.Collection("resources").Find(ctx, bson.M{"facility_name": facilityName})
+    if err != nil {
+        log.Printf("Error: FindCourtSlots: %s", err.Error())
+        return nil, fmt.Errorf("error: find courts failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    var resources []facility.Resource
+    if err := cursor.All(ctx, &resources); err != nil {
+        log.Printf("Error: FindCourtSlots: %s", err.Error())
+        return nil, fmt.Errorf("error: find courts failed: %w", err)
+    }
+    resourcesById := make(map[primitive.ObjectID]facility.Resource, len(resources))
+    for _, resource := range resources {
+        resourcesById[resource.Id] = resource
+    }
+
+    courts := make([]booking.CourtSlot, 0, len(slots))
+    for i := range slots {
+        slot := &slots[i]
+        resource, ok := resourcesById[slot.ResourceId]
+        if !ok || !isSlotOpenOn(slot, day) {
+            continue
+        }
+
+        free := resource.Status == 0
+        if free {
+            capacity, err := r.slotCapacityOn(ctx, facilityName, slot, day)
+            if err != nil {
+                return nil, err
+            }
+            free = slot.CurrentBookings < capacity
+        }
+        if free {
+            underMaintenance, err := r.isSlotUnderMaintenance(ctx, facilityName, slot, day)
+            if err != nil {
+                return nil, err
+            }
+            free = !underMaintenance
+        }
+
+        courts = append(courts, booking.CourtSlot{
+            SlotId:     slot.Id.Hex(),
+            ResourceId: slot.ResourceId.Hex(),
+            Number:     resource.Number,
+            Free:       free,
+        })
+    }
+
+    return courts, nil
+}
+
+// CountResourceBookings counts a facility's bookings per resource across open
+// and past transactions; an empty user id counts every user
+func (r *bookingRepository) CountResourceBookings(ctx context.Context, facilityName, userId string) (map[string]int, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    match := bson.M{"facility": facilityName, "resource_id": bson.M{"$exists": true}}
+    if userId != "" {
+        match["user_id"] = userId
+    }
+    pipeline := bson.A{
+        bson.M{"$match": match},
+        bson.M{"$group": bson.M{"_id": "$resource_id", "count": bson.M{"$sum": 1}}},
+    }
+
+    counts := make(map[string]int)
+    for _, name := range []string{"booking_transaction", "histories_transaction"} {
+        cursor, err := r.bookingDbConn(ctx).Collection(name).Aggregate(ctx, pipeline)
+        if err != nil {
+            log.Printf("Error: CountResourceBookings: %s", err.Error())
+            return nil, fmt.Errorf("error: count resource bookings failed: %w", err)
+        }
+
+        var rows []struct {
+            ResourceId primitive.ObjectID `bson:"_id"`
+            Count      int                `bson:"count"`
+        }
+        err = cursor.All(ctx, &rows)
+        cursor.Close(ctx)
+        if err != nil {
+            log.Printf("Error: CountResourceBookings: %s", err.Error())
+            return nil, fmt.Errorf("error: count resource bookings failed: %w", err)
+        }
+        for _, row := range rows {
+            counts[row.ResourceId.Hex()] += row.Count
+        }
+    }
+
+    return counts, nil
+}
+
+// hasAcceptedWaiver checks the user accepted the facility's current waiver
+// version; facilities without a waiver accept everyone
+func (r *bookingRepository) hasAcceptedWaiver(ctx context.Context, facilityName, userId string) (bool, error) {
+    db := r.facilityDbConn(ctx)
+
+    var waiver facility.Waiver
+    if err := db.Collection("waivers").FindOne(
+        ctx,
+        bson.M{"facility_name": facilityName},
+        options.FindOne().SetSort(bson.M{"version": -1}),
+    ).Decode(&waiver); err != nil {
+        if err == mongo.ErrNoDocuments {
+            return true, nil
+        }
+        log.Printf("Error: hasAcceptedWaiver: %s", err.Error())
+        return false, fmt.Errorf("error: check waiver failed: %w", err)
+    }
+
+    count, err := db.Collection("waiver_acceptances").CountDocuments(ctx, bson.M{
+        "facility_name": facilityName,
+        "user_id":       userId,
+        "version":       waiver.Version,
+    })
+    if err != nil {
+        log.Printf("Error: hasAcceptedWaiver: %s", err.Error())
+        return false, fmt.Errorf("error: check waiver failed: %w", err)
+    }
+
+    return count > 0, nil
+}
+
+// slotCapacityOn applies the facility's capacity overrides for day to the slot
+func (r *bookingRepository) slotCapacityOn(ctx context.Context, facilityName string, slot *facility.Slot, day time.Time) (int, error) {
+    cursor, err := r.facilityDbConn(ctx).Collection("capacity_overrides").Find(ctx, bson.M{
+        "facility_name": facilityName,
+        "date":          day.Format(facility.DateLayout),
+    })
+    if err != nil {
+        log.Printf("Error: slotCapacityOn: %s", err.Error())
+        return 0, fmt.Errorf("error: check capacity failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    var overrides []facility.CapacityOverride
+    if err := cursor.All(ctx, &overrides); err != nil {
+        log.Printf("Error: slotCapacityOn: %s", err.Error())
+        return 0, fmt.Errorf("error: check capacity failed: %w", err)
+    }
+
+    return facility.EffectiveCapacity(slot, overrides), nil
+}
+
+// isFacilityOpenFor resolves the facility's opening on day from its weekly
+// hours, public holidays and special days, and checks the slot fits it
+func (r *bookingRepository) isFacilityOpenFor(ctx context.Context, facilityName string, slot *facility.Slot, day time.Time) (bool, error) {
+    db := r.facilityDbConn(ctx)
+
+    var result facility.FacilityBson
+    if err := db.Collection("facilities").FindOne(ctx, bson.M{"name": facilityName}).Decode(&result); err != nil {
+        log.Printf("Error: isFacilityOpenFor: %s", err.Error())
+        return false, fmt.Errorf("error: find facility failed: %w", err)
+    }
+
+    date := day.Format(facility.DateLayout)
+    cursor, err := db.Collection("calendar_days").Find(ctx, bson.M{
+        "facility_name": bson.M{"$in": bson.A{"", facilityName}},
+        "date":          date,
+    })
+    if err != nil {
+        log.Printf("Error: isFacilityOpenFor: %s", err.Error())
+        return false, fmt.Errorf("error: check calendar failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    var days []facility.CalendarDay
+    if err := cursor.All(ctx, &days); err != nil {
+        log.Printf("Error: isFacilityOpenFor: %s", err.Error())
+        return false, fmt.Errorf("error: check calendar failed: %w", err)
+    }
+
+    return facility.ResolveDayHours(result.OpeningHours, days, day).WithinHours(slot.TimeRange), nil
+}
+
 // Validate the booking request
 func validateBookingRequest(req *booking.Booking) error {
-    if req.SlotId == nil && req.BadmintonSlotId == nil {
-        return errors.New("SlotId or BadmintonSlotId is required")
-    }
-    if req.SlotId != nil && req.BadmintonSlotId != nil {
-        return errors.New("only one of SlotId or BadmintonSlotId is required")
+    if req.SlotId == nil {
+        return errors.New("SlotId is required")
     }
     // Add additional validations as needed
     return nil
@@ -578,17 +805,21 @@ func validateBookingRequest(req *booking.Booking) error {
 
 
 
-func (r *bookingRepository) countUserBadmintonBookings(ctx context.Context, userId string) (int, error) {
+// maxResourceBookingsPerUser caps how many resource slots a user may hold per facility per day
+const maxResourceBookingsPerUser = 2
+
+func (r *bookingRepository) countUserResourceBookings(ctx context.Context, userId, facilityName string) (int, error) {
     col := r.bookingDbConn(ctx).Collection("booking_transaction")
 
     filter := bson.M{
-        "user_id":          userId,
-        "badminton_slot_id": bson.M{"$exists": true}, // Count only badminton slots
+        "user_id":     userId,
+        "facility":    facilityName,
+        "resource_id": bson.M{"$exists": true},
     }
 
     count, err := col.CountDocuments(ctx, filter)
     if err != nil {
-        return 0, fmt.Errorf("error counting badminton bookings: %w", err)
+        return 0, fmt.Errorf("error counting resource bookings: %w", err)
     }
 
     return int(count), nil
@@ -748,85 +979,356 @@ func (r *bookingRepository) countUserBadmintonBookings(ctx context.Context, user
 //     return nil
 // }
 
-func (r *bookingRepository) updateBadmintonSlotBookings(ctx context.Context, slotId primitive.ObjectID, increment int) error {
-    db := r.facilityDbConn(ctx, "badminton")
+func (r *bookingRepository) getSlot(ctx context.Context, facilityName string, slotId primitive.ObjectID) (*facility.Slot, error) {
+    db := r.facilityDbConn(ctx)
     col := db.Collection("slots")
 
-    // First get current slot state
-    var currentSlot facility.BadmintonSlot
-    err := col.FindOne(ctx, bson.M{"_id": slotId}).Decode(&currentSlot)
+    var slot facility.Slot
+    err := col.FindOne(ctx, bson.M{"_id": slotId, "facility_name": facilityName}).Decode(&slot)
     if err != nil {
-        return fmt.Errorf("failed to get current slot state: %w", err)
+        return nil, fmt.Errorf("failed to get slot: %w", err)
     }
 
-    // Check if the update would exceed max bookings
-    newBookingCount := currentSlot.CurrentBookings + increment
-    if newBookingCount > currentSlot.MaxBookings {
-        return errors.New("booking would exceed maximum allowed bookings")
-    }
+    return &slot, nil
+}
 
-    // Update the slot
-    update := bson.M{
-        "$set": bson.M{
-            "current_bookings": newBookingCount,
-            "updated_at": time.Now(),
-        },
-    }
+// GetTopicOffset returns the next offset to read from topic, or the oldest
+// offset when the topic has never been consumed
+func (r *bookingRepository) GetTopicOffset(ctx context.Context, topic string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    result, err := col.UpdateOne(ctx, bson.M{"_id": slotId}, update)
-    if err != nil {
-        return fmt.Errorf("failed to update badminton slot bookings: %w", err)
-    }
+	result := new(models.KafkaOffset)
+	err := r.bookingDbConn(ctx).Collection("event_offsets").FindOne(ctx, bson.M{"topic": topic}).Decode(result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return sarama.OffsetOldest, nil
+	}
+	if err != nil {
+		log.Printf("Error: GetTopicOffset: %s", err.Error())
+		return -1, fmt.Errorf("error: get topic offset failed: %w", err)
+	}
 
-    if result.MatchedCount == 0 {
-        return errors.New("badminton slot not found")
-    }
+	return result.Offset, nil
+}
 
-    return nil
+func (r *bookingRepository) SetTopicOffset(ctx context.Context, topic string, offset int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.bookingDbConn(ctx).Collection("event_offsets").UpdateOne(
+		ctx,
+		bson.M{"topic": topic},
+		bson.M{"$set": bson.M{"offset": offset}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("Error: SetTopicOffset: %s", err.Error())
+		return fmt.Errorf("error: set topic offset failed: %w", err)
+	}
+
+	return nil
 }
 
-// Add these helper functions to get slot information
-func (r *bookingRepository) getBadmintonSlot(ctx context.Context, slotId primitive.ObjectID) (*facility.Slot, error) {
-    db := r.facilityDbConn(ctx, "badminton")
-    col := db.Collection("slots")
+func (r *bookingRepository) HasProcessedEvent(ctx context.Context, eventId string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    var slot facility.Slot
-    err := col.FindOne(ctx, bson.M{"_id": slotId}).Decode(&slot)
-    if err != nil {
-        return nil, fmt.Errorf("failed to get badminton slot: %w", err)
-    }
+	count, err := r.bookingDbConn(ctx).Collection("processed_events").CountDocuments(ctx, bson.M{"event_id": eventId})
+	if err != nil {
+		log.Printf("Error: HasProcessedEvent: %s", err.Error())
+		return false, fmt.Errorf("error: find processed event failed: %w", err)
+	}
 
-    return &slot, nil
+	return count > 0, nil
 }
 
-func (r *bookingRepository) getSlot(ctx context.Context, facilityName string, slotId primitive.ObjectID) (*facility.Slot, error) {
-    db := r.facilityDbConn(ctx, facilityName)
-    col := db.Collection("slots")
+// RecordProcessedEvent stores the event id once; recording it again is a no-op
+func (r *bookingRepository) RecordProcessedEvent(ctx context.Context, eventId, topic string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    var slot facility.Slot
-    err := col.FindOne(ctx, bson.M{"_id": slotId}).Decode(&slot)
-    if err != nil {
-        return nil, fmt.Errorf("failed to get slot: %w", err)
-    }
+	_, err := r.bookingDbConn(ctx).Collection("processed_events").UpdateOne(
+		ctx,
+		bson.M{"event_id": eventId},
+		bson.M{"$setOnInsert": booking.ProcessedEvent{EventId: eventId, Topic: topic, ProcessedAt: utils.LocalTime()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("Error: RecordProcessedEvent: %s", err.Error())
+		return fmt.Errorf("error: record processed event failed: %w", err)
+	}
 
-    return &slot, nil
+	return nil
 }
 
-// Add this function to check badminton slot availability
-func (r *bookingRepository) checkBadmintonSlotAvailability(ctx context.Context, slotId primitive.ObjectID) error {
-    db := r.facilityDbConn(ctx, "badminton")
-    col := db.Collection("slots")
+// underpaidTolerance absorbs rounding below a satang when comparing amounts
+const underpaidTolerance = 0.005
 
-    var slot facility.BadmintonSlot
-    err := col.FindOne(ctx, bson.M{"_id": slotId}).Decode(&slot)
-    if err != nil {
-        return fmt.Errorf("failed to find badminton slot: %w", err)
-    }
+// MarkBookingPaid moves a pending booking to paid, looking in today's
+// transactions and then the history. It reports false when the booking was
+// already paid. A booking whose hold expired first is paid only if it can take
+// its place in the slot back; see payExpiredBooking. A payment that does not
+// cover the booking's amount, or was made by another user, pays nothing; see
+// flagUnderpaid. A nil paidBy is staff marking the booking paid by hand.
+func (r *bookingRepository) MarkBookingPaid(ctx context.Context, bookingId string, paidBy *booking.PaidBy) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    // Check if the slot is already fully booked
-    if slot.CurrentBookings >= slot.MaxBookings {
-        return errors.New("slot is already fully booked")
-    }
+	id, err := primitive.ObjectIDFromHex(bookingId)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", booking.ErrBookingNotFound, bookingId)
+	}
 
-    return nil
+	now := utils.LocalTime()
+	set := bson.M{"status": booking.StatusPaid, "paid_at": now, "updated_at": now}
+	if paidBy != nil && paidBy.PaymentId != "" {
+		set["payment_id"] = paidBy.PaymentId
+	}
+
+	db := r.bookingDbConn(ctx)
+	for _, name := range []string{"booking_transaction", "histories_transaction"} {
+		col := db.Collection(name)
+
+		current := struct {
+			UserId   string             `bson:"user_id"`
+			Status   string             `bson:"status"`
+			Amount   float64            `bson:"amount"`
+			Facility string             `bson:"facility"`
+			SlotId   primitive.ObjectID `bson:"slot_id"`
+		}{}
+		err = col.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{"user_id": 1, "status": 1, "amount": 1, "facility": 1, "slot_id": 1})).Decode(&current)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error: MarkBookingPaid: %s", err.Error())
+			return false, fmt.Errorf("error: mark booking paid failed: %w", err)
+		}
+		if current.Status != booking.StatusPending && current.Status != booking.StatusExpired {
+			return false, nil
+		}
+
+		// Only today's slots are held; a past day's booking has no place to give back
+		slotId := primitive.NilObjectID
+		if name == "booking_transaction" {
+			slotId = current.SlotId
+		}
+
+		// Bookings made before amounts were stored have nothing to compare against
+		if paidBy != nil && (current.UserId != paidBy.UserId || current.Amount-paidBy.Amount >= underpaidTolerance) {
+			log.Printf("Warning: MarkBookingPaid: booking %s of %.2f for user %s paid %.2f by user %s", bookingId, current.Amount, current.UserId, paidBy.Amount, paidBy.UserId)
+			return false, r.flagUnderpaid(ctx, col, id, current.Status, current.Facility, slotId, set)
+		}
+
+		if current.Status == booking.StatusExpired {
+			return r.payExpiredBooking(ctx, col, id, current.Facility, slotId, set)
+		}
+
+		result, err := col.UpdateOne(ctx, bson.M{"_id": id, "status": booking.StatusPending}, bson.M{"$set": set})
+		if err != nil {
+			log.Printf("Error: MarkBookingPaid: %s", err.Error())
+			return false, fmt.Errorf("error: mark booking paid failed: %w", err)
+		}
+		if result.ModifiedCount > 0 {
+			return true, nil
+		}
+
+		// Expired meanwhile; pay it the way a late payment is paid
+		expired, err := col.CountDocuments(ctx, bson.M{"_id": id, "status": booking.StatusExpired})
+		if err != nil {
+			log.Printf("Error: MarkBookingPaid: %s", err.Error())
+			return false, fmt.Errorf("error: mark booking paid failed: %w", err)
+		}
+		if expired > 0 {
+			return r.payExpiredBooking(ctx, col, id, current.Facility, slotId, set)
+		}
+		return false, nil
+	}
+
+	return false, fmt.Errorf("%w: %s", booking.ErrBookingNotFound, bookingId)
+}
+
+// payExpiredBooking pays a booking whose hold was released before its payment
+// arrived. Its place was given back, so it is taken again if the slot still
+// has room. Otherwise the booking is marked StatusPaidAfterExpiry and
+// ErrPaidAfterExpiry returned so staff refund it instead of overbooking.
+func (r *bookingRepository) payExpiredBooking(ctx context.Context, col *mongo.Collection, id primitive.ObjectID, facilityName string, slotId primitive.ObjectID, set bson.M) (bool, error) {
+	if !slotId.IsZero() {
+		slot, err := r.getSlot(ctx, facilityName, slotId)
+		if err != nil {
+			return false, err
+		}
+		capacity, err := r.slotCapacityOn(ctx, facilityName, slot, utils.LocalTime())
+		if err != nil {
+			return false, err
+		}
+
+		err = r.updateSlotCurrentBooking(ctx, facilityName, slotId, 1, capacity)
+		if err != nil && !errors.Is(err, booking.ErrSlotFull) {
+			return false, err
+		}
+		if err == nil {
+			result, err := col.UpdateOne(ctx, bson.M{"_id": id, "status": booking.StatusExpired}, bson.M{"$set": set})
+			if err == nil && result.ModifiedCount > 0 {
+				return true, nil
+			}
+
+			// Paid or flagged by someone else meanwhile; give the place back
+			if releaseErr := r.updateSlotCurrentBooking(ctx, facilityName, slotId, -1, capacity); releaseErr != nil {
+				log.Printf("Error: payExpiredBooking: %s", releaseErr.Error())
+			}
+			if err != nil {
+				log.Printf("Error: payExpiredBooking: %s", err.Error())
+				return false, fmt.Errorf("error: mark booking paid failed: %w", err)
+			}
+			return false, nil
+		}
+	}
+
+	flag := bson.M{"status": booking.StatusPaidAfterExpiry, "updated_at": set["updated_at"]}
+	if paymentId, ok := set["payment_id"]; ok {
+		flag["payment_id"] = paymentId
+	}
+	if _, err := col.UpdateOne(ctx, bson.M{"_id": id, "status": booking.StatusExpired}, bson.M{"$set": flag}); err != nil {
+		log.Printf("Error: payExpiredBooking: %s", err.Error())
+		return false, fmt.Errorf("error: mark booking paid failed: %w", err)
+	}
+	return false, fmt.Errorf("%w: %s", booking.ErrPaidAfterExpiry, id.Hex())
+}
+
+// flagUnderpaid marks a booking StatusUnderpaid and returns ErrUnderpaid so
+// staff refund the payment. A pending booking gives its place in the slot back
+// as an expired one would; the payment was not for it.
+func (r *bookingRepository) flagUnderpaid(ctx context.Context, col *mongo.Collection, id primitive.ObjectID, status, facilityName string, slotId primitive.ObjectID, set bson.M) error {
+	flag := bson.M{"status": booking.StatusUnderpaid, "updated_at": set["updated_at"]}
+	if paymentId, ok := set["payment_id"]; ok {
+		flag["payment_id"] = paymentId
+	}
+	result, err := col.UpdateOne(ctx, bson.M{"_id": id, "status": status}, bson.M{"$set": flag})
+	if err != nil {
+		log.Printf("Error: flagUnderpaid: %s", err.Error())
+		return fmt.Errorf("error: mark booking paid failed: %w", err)
+	}
+
+	if result.ModifiedCount > 0 && status == booking.StatusPending && !slotId.IsZero() {
+		_, err = r.facilityDbConn(ctx).Collection("slots").UpdateOne(
+			ctx,
+			bson.M{"_id": slotId, "facility_name": facilityName, "current_bookings": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"current_bookings": -1}, "$set": bson.M{"updated_at": time.Now()}},
+		)
+		if err != nil {
+			log.Printf("Error: flagUnderpaid: %s", err.Error())
+			return fmt.Errorf("error: release booking hold failed: %w", err)
+		}
+	}
+
+	return fmt.Errorf("%w: %s", booking.ErrUnderpaid, id.Hex())
+}
+
+// ReleaseBookingHold expires a pending booking and gives its place in the slot
+// back. It reports false when the booking was no longer pending, e.g. already
+// paid or released.
+func (r *bookingRepository) ReleaseBookingHold(ctx context.Context, bookingId string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	id, err := primitive.ObjectIDFromHex(bookingId)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", booking.ErrBookingNotFound, bookingId)
+	}
+
+	// Only today's bookings hold a place; the midnight reset frees the rest
+	held := struct {
+		Facility string             `bson:"facility"`
+		SlotId   primitive.ObjectID `bson:"slot_id"`
+	}{}
+	err = r.bookingDbConn(ctx).Collection("booking_transaction").FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "status": booking.StatusPending},
+		bson.M{"$set": bson.M{"status": booking.StatusExpired, "updated_at": utils.LocalTime()}},
+	).Decode(&held)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("Error: ReleaseBookingHold: %s", err.Error())
+		return false, fmt.Errorf("error: release booking hold failed: %w", err)
+	}
+
+	_, err = r.facilityDbConn(ctx).Collection("slots").UpdateOne(
+		ctx,
+		bson.M{"_id": held.SlotId, "facility_name": held.Facility, "current_bookings": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"current_bookings": -1}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Error: ReleaseBookingHold: %s", err.Error())
+		return false, fmt.Errorf("error: release booking hold failed: %w", err)
+	}
+
+	return true, nil
+}
+
+// FindPaymentDrift lists today's unpaid bookings whose payment the payment
+// service has already moved to paymentStatus, e.g. COMPLETED or EXPIRED
+func (r *bookingRepository) FindPaymentDrift(ctx context.Context, paymentStatus string) ([]booking.PaymentDrift, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.bookingDbConn(ctx).Collection("booking_transaction").Find(
+		ctx,
+		bson.M{"status": bson.M{"$in": bson.A{booking.StatusPending, booking.StatusExpired}}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		log.Printf("Error: FindPaymentDrift: %s", err.Error())
+		return nil, fmt.Errorf("error: find payment drift failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var unpaid []booking.Booking
+	if err := cursor.All(ctx, &unpaid); err != nil {
+		log.Printf("Error: FindPaymentDrift: %s", err.Error())
+		return nil, fmt.Errorf("error: find payment drift failed: %w", err)
+	}
+	if len(unpaid) == 0 {
+		return []booking.PaymentDrift{}, nil
+	}
+
+	bookingIds := make([]string, 0, len(unpaid))
+	for _, b := range unpaid {
+		bookingIds = append(bookingIds, b.Id.Hex())
+	}
+
+	// Payments are owned by the payment service; only read them here
+	paymentCursor, err := r.db.Database("payment_db").Collection("payments").Find(
+		ctx,
+		bson.M{"booking_id": bson.M{"$in": bookingIds}, "status": paymentStatus},
+		options.Find().SetProjection(bson.M{"_id": 1, "booking_id": 1, "user_id": 1, "amount": 1}),
+	)
+	if err != nil {
+		log.Printf("Error: FindPaymentDrift: %s", err.Error())
+		return nil, fmt.Errorf("error: find payment drift failed: %w", err)
+	}
+	defer paymentCursor.Close(ctx)
+
+	drift := make([]booking.PaymentDrift, 0)
+	for paymentCursor.Next(ctx) {
+		var p struct {
+			Id        primitive.ObjectID `bson:"_id"`
+			BookingId string             `bson:"booking_id"`
+			UserId    string             `bson:"user_id"`
+			Amount    float64            `bson:"amount"`
+		}
+		if err := paymentCursor.Decode(&p); err != nil {
+			log.Printf("Error: FindPaymentDrift: %s", err.Error())
+			return nil, fmt.Errorf("error: find payment drift failed: %w", err)
+		}
+		drift = append(drift, booking.PaymentDrift{BookingId: p.BookingId, PaymentId: p.Id.Hex(), UserId: p.UserId, Amount: p.Amount})
+	}
+	if err := paymentCursor.Err(); err != nil {
+		log.Printf("Error: FindPaymentDrift: %s", err.Error())
+		return nil, fmt.Errorf("error: find payment drift failed: %w", err)
+	}
+
+	return drift, nil
 }
@@ -1,67 +1,145 @@
 package service
 
-// import (
-// 	"context"
-// 	"encoding/json"
-// 	"log"
-// 	"main/config"
-// 	"main/modules/booking"
-// 	"main/modules/booking/repository"
-// 	"main/pkg/queue"
-
-// 	"github.com/IBM/sarama"
-// )
-
-// type BookingQueueService struct {
-//     cfg      *config.Config
-//     repo     repository.BookingRepositoryService
-//     consumer sarama.Consumer
-// }
-
-// func NewBookingQueueService(cfg *config.Config, repo repository.BookingRepositoryService) (*BookingQueueService, error) {
-//     consumer, err := queue.ConnectConsumer(
-//         []string{cfg.Kafka.Url},
-//         cfg.Kafka.ApiKey,
-//         cfg.Kafka.Secret,
-//     )
-//     if err != nil {
-//         return nil, err
-//     }
-
-//     return &BookingQueueService{
-//         cfg:      cfg,
-//         repo:     repo,
-//         consumer: consumer,
-//     }, nil
-// }
-
-// func (s *BookingQueueService) Start(ctx context.Context) {
-//     partitionConsumer, err := s.consumer.ConsumePartition("bookings", 0, sarama.OffsetNewest)
-//     if err != nil {
-//         log.Printf("Error creating partition consumer: %v", err)
-//         return
-//     }
-//     defer partitionConsumer.Close()
-
-//     for {
-//         select {
-//         case msg := <-partitionConsumer.Messages():
-//             var bookingMsg booking.BookingQueueMessage
-//             if err := json.Unmarshal(msg.Value, &bookingMsg); err != nil {
-//                 log.Printf("Error unmarshalling message: %v", err)
-//                 continue
-//             }
-
-// 			// if err := s.repo.ProcessBookingQueue(ctx, s.cfg, &bookingMsg); err != nil {
-//             //     log.Printf("Error processing booking: %v", err)
-//             //     continue
-//             // }
-
-//         case err := <-partitionConsumer.Errors():
-//             log.Printf("Error from consumer: %v", err)
-
-//         case <-ctx.Done():
-//             return
-//         }
-//     }
-// }
\ No newline at end of file
+import (
+	"context"
+	"log"
+	"main/config"
+	"main/modules/booking/repository"
+	"main/modules/booking/usecase"
+	"main/modules/payment"
+	"main/pkg/queue"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// An event that fails to apply, e.g. while the database is down, is retried
+// after retryBackoff, doubling up to maxRetryBackoff
+const (
+	retryBackoff    = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// BookingQueueService consumes payment events and applies them to bookings
+type BookingQueueService struct {
+	cfg            *config.Config
+	repo           repository.BookingRepositoryService
+	bookingUsecase usecase.BookingUsecaseService
+	consumer       sarama.Consumer
+}
+
+func NewBookingQueueService(cfg *config.Config, repo repository.BookingRepositoryService, bookingUsecase usecase.BookingUsecaseService) (*BookingQueueService, error) {
+	consumer, err := queue.ConnectConsumer(
+		[]string{cfg.Kafka.Url},
+		cfg.Kafka.ApiKey,
+		cfg.Kafka.Secret,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BookingQueueService{
+		cfg:            cfg,
+		repo:           repo,
+		bookingUsecase: bookingUsecase,
+		consumer:       consumer,
+	}, nil
+}
+
+// Start consumes the payment topics until ctx is done
+func (s *BookingQueueService) Start(ctx context.Context) {
+	defer s.consumer.Close()
+
+	done := make(chan struct{})
+	go func() {
+		s.consume(ctx, payment.TopicPaymentExpired, s.applyPaymentExpired)
+		close(done)
+	}()
+	s.consume(ctx, payment.TopicPaymentCompleted, s.applyPaymentCompleted)
+	<-done
+}
+
+// consume reads topic from the last stored offset. The offset only moves past
+// an event once it is applied: a failing event is retried, holding back the
+// events after it, and a crash replays it instead of losing it. The usecase
+// handlers make the replay harmless.
+func (s *BookingQueueService) consume(ctx context.Context, topic string, apply func(ctx context.Context, msg *sarama.ConsumerMessage) error) {
+	offset, err := s.repo.GetTopicOffset(ctx, topic)
+	if err != nil {
+		log.Printf("Error reading %s offset: %v", topic, err)
+		return
+	}
+
+	partitionConsumer, err := s.consumer.ConsumePartition(topic, 0, offset)
+	if err != nil {
+		log.Printf("Error creating %s partition consumer: %v", topic, err)
+		return
+	}
+	defer partitionConsumer.Close()
+
+	for {
+		select {
+		case msg, ok := <-partitionConsumer.Messages():
+			if !ok {
+				log.Printf("Warning: %s consumer closed, relying on reconciliation", topic)
+				return
+			}
+			if !s.applyWithRetry(ctx, msg, apply) {
+				return
+			}
+
+			if err := s.repo.SetTopicOffset(ctx, msg.Topic, msg.Offset+1); err != nil {
+				log.Printf("Error saving %s offset: %v", msg.Topic, err)
+			}
+
+		case err, ok := <-partitionConsumer.Errors():
+			if !ok {
+				log.Printf("Warning: %s consumer closed, relying on reconciliation", topic)
+				return
+			}
+			log.Printf("Error from %s consumer: %v", topic, err)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// applyWithRetry applies msg until it succeeds, backing off between tries. It
+// reports false if ctx ends first, leaving the event to be read again.
+func (s *BookingQueueService) applyWithRetry(ctx context.Context, msg *sarama.ConsumerMessage, apply func(ctx context.Context, msg *sarama.ConsumerMessage) error) bool {
+	backoff := retryBackoff
+	for {
+		err := apply(ctx, msg)
+		if err == nil {
+			return true
+		}
+		log.Printf("Error applying %s at offset %d, retrying in %s: %v", msg.Topic, msg.Offset, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return false
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+func (s *BookingQueueService) applyPaymentCompleted(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	event := new(payment.PaymentCompletedEvent)
+	if err := queue.DecodeMessage(event, msg.Value); err != nil {
+		// A malformed event will never apply; skip it
+		log.Printf("Error decoding %s at offset %d: %v", msg.Topic, msg.Offset, err)
+		return nil
+	}
+	return s.bookingUsecase.HandlePaymentCompleted(ctx, event)
+}
+
+func (s *BookingQueueService) applyPaymentExpired(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	event := new(payment.PaymentExpiredEvent)
+	if err := queue.DecodeMessage(event, msg.Value); err != nil {
+		log.Printf("Error decoding %s at offset %d: %v", msg.Topic, msg.Offset, err)
+		return nil
+	}
+	return s.bookingUsecase.HandlePaymentExpired(ctx, event)
+}
@@ -9,6 +9,7 @@ import (
 	"main/modules/booking"
 	bm "main/modules/booking"
 	"main/modules/booking/repository"
+	"main/modules/payment"
 	"main/pkg/utils"
 	"time"
 )
@@ -19,13 +20,21 @@ type(
 		UpdateBooking (ctx context.Context, bookingId string, status string) (*booking.Booking, error)
 		FindBooking (ctx context.Context, bookingId string) (*booking.Booking, error)
 		FindOneUserBooking(ctx context.Context, userId string) ([]booking.Booking, error)
-		InsertBooking(ctx context.Context, facilityName string, req *booking.CreateBookingRequest) (*booking.BookingResponse, error)
+		InsertBooking(ctx context.Context, facilityName string, req *booking.CreateBookingRequest, amount float64) (*booking.BookingResponse, error)
+		AllocateCourt(ctx context.Context, facilityName, userId, startTime, endTime string) (string, error)
+		ReleaseBookingHold(ctx context.Context, bookingId string) error
 
 		//Kafka Interface
 		GetOffSet(ctx context.Context) (int64, error)
 		UpOffSet(ctx context.Context, newOffset int64) error
 		UpdateBookingStatusPaid(ctx context.Context, bookingID string) error
 		ScheduleMidnightClearing()
+
+		//Payment events
+		HandlePaymentCompleted(ctx context.Context, event *payment.PaymentCompletedEvent) error
+		HandlePaymentExpired(ctx context.Context, event *payment.PaymentExpiredEvent) error
+		ReconcilePayments(ctx context.Context) (int, error)
+		ScheduleReconciliation()
 	}
 
 	bookingUsecase struct {
@@ -94,24 +103,27 @@ func (u *bookingUsecase) UpOffSet(ctx context.Context, newOffset int64) error {
     return u.bookingRepository.UpOffset(ctx, newOffset)
 }
 
-func (u *bookingUsecase) InsertBooking(ctx context.Context, facilityName string, req *booking.CreateBookingRequest) (*booking.BookingResponse, error) {
-    // Validate slot type and IDs
-    if req.SlotType == "normal" && req.SlotId == nil {
-        return nil, errors.New("error: SlotId is required for normal bookings")
+// InsertBooking holds a place in the slot for a pending booking. amount is
+// what its payment must cover; the payment service charges exactly that.
+func (u *bookingUsecase) InsertBooking(ctx context.Context, facilityName string, req *booking.CreateBookingRequest, amount float64) (*booking.BookingResponse, error) {
+    // Bookings from older clients still send the slot as badminton_slot_id
+    slotId := req.SlotId
+    if slotId == nil {
+        slotId = req.BadmintonSlotId
     }
-    if req.SlotType == "badminton" && req.BadmintonSlotId == nil {
-        return nil, errors.New("error: BadmintonSlotId is required for badminton bookings")
+    if slotId == nil {
+        return nil, errors.New("error: SlotId is required")
     }
-    if req.SlotId != nil && req.BadmintonSlotId != nil {
+    if req.SlotId != nil && req.BadmintonSlotId != nil && *req.SlotId != *req.BadmintonSlotId {
         return nil, errors.New("error: Only one of SlotId or BadmintonSlotId should be provided")
     }
 
     // Create the booking request struct for repository interaction
     bookingReq := &booking.Booking{
         UserId:          req.UserId,
-        SlotId:          req.SlotId,
-        BadmintonSlotId: req.BadmintonSlotId,
-        Status:          "pending",
+        SlotId:          slotId,
+        Status:          booking.StatusPending,
+        Amount:          amount,
         CreatedAt:       time.Now(),
         UpdatedAt:       time.Now(),
     }
@@ -128,7 +140,7 @@ func (u *bookingUsecase) InsertBooking(ctx context.Context, facilityName string,
 			Id:              booking.Id,
 			UserId:          booking.UserId,
 			SlotId:          booking.SlotId,
-			BadmintonSlotId: booking.BadmintonSlotId,
+			ResourceId:      booking.ResourceId,
 			SlotType:        req.SlotType,
 			Status:          booking.Status,
 			CreatedAt:       booking.CreatedAt,
@@ -141,6 +153,43 @@ func (u *bookingUsecase) InsertBooking(ctx context.Context, facilityName string,
 
 
 
+// AllocateCourt picks the best free court for a user at a time and returns
+// its slot id; see rankCourts for the order courts are tried in
+func (u *bookingUsecase) AllocateCourt(ctx context.Context, facilityName, userId, startTime, endTime string) (string, error) {
+    // Slot times are stored as "HH:mm", so normalise before matching
+    start, err := utils.ParseClock(startTime)
+    if err != nil {
+        return "", err
+    }
+    startTime = start.String()
+    if endTime != "" {
+        timeRange, err := utils.NewTimeRange(startTime, endTime)
+        if err != nil {
+            return "", err
+        }
+        endTime = timeRange.EndTime.String()
+    }
+
+    courts, err := u.bookingRepository.FindCourtSlots(ctx, facilityName, startTime, endTime, utils.LocalTime())
+    if err != nil {
+        return "", err
+    }
+    history, err := u.bookingRepository.CountResourceBookings(ctx, facilityName, userId)
+    if err != nil {
+        return "", err
+    }
+    wear, err := u.bookingRepository.CountResourceBookings(ctx, facilityName, "")
+    if err != nil {
+        return "", err
+    }
+
+    ranked := rankCourts(courts, history, wear)
+    if len(ranked) == 0 {
+        return "", booking.ErrNoCourtAvailable
+    }
+    return ranked[0].SlotId, nil
+}
+
 func (u *bookingUsecase) UpdateBooking (ctx context.Context, bookingId string, status string) (*booking.Booking, error) {
 	booking, err := u.bookingRepository.FindBooking(ctx, bookingId)
 	if err != nil {
@@ -166,6 +215,16 @@ func (u * bookingUsecase) FindOneUserBooking(ctx context.Context, userId string)
 	return u.bookingRepository.FindOneUserBooking(ctx, userId)
 }
 
+// ReleaseBookingHold gives back the slot of a pending booking that can't go
+// ahead, e.g. when its payment could not be created
+func (u *bookingUsecase) ReleaseBookingHold(ctx context.Context, bookingId string) error {
+	_, err := u.bookingRepository.ReleaseBookingHold(ctx, bookingId)
+	return err
+}
+
+// UpdateBookingStatusPaid marks a booking paid by hand. Payments normally do
+// this through the payment.completed event; calling it again is harmless.
 func (u *bookingUsecase) UpdateBookingStatusPaid(ctx context.Context, bookingID string) error {
-	return u.bookingRepository.UpdateStatusPaid(ctx, bookingID)
+	_, err := u.bookingRepository.MarkBookingPaid(ctx, bookingID, nil)
+	return err
 }
\ No newline at end of file
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"main/modules/booking"
+	"sort"
+)
+
+// courtRank is what the allocator knows about one free court
+type courtRank struct {
+	court     booking.CourtSlot
+	edge      bool // Taking it leaves the rest of its free block contiguous
+	blockSize int  // Free courts in the run of adjacent numbers it belongs to
+	history   int  // Times the user booked this court before
+	wear      int  // Times anyone booked this court
+}
+
+// rankCourts orders the free courts best first. Courts at the edge of a free
+// block come first so free courts stay contiguous, then the user's familiar
+// courts, then the smallest block (best fit) and the least worn court. Court
+// number and slot id break ties, so the same input always gives the same order.
+func rankCourts(courts []booking.CourtSlot, history, wear map[string]int) []booking.CourtSlot {
+	sorted := append([]booking.CourtSlot{}, courts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Number != sorted[j].Number {
+			return sorted[i].Number < sorted[j].Number
+		}
+		return sorted[i].SlotId < sorted[j].SlotId
+	})
+
+	ranks := make([]courtRank, 0, len(sorted))
+	for start := 0; start < len(sorted); {
+		if !sorted[start].Free {
+			start++
+			continue
+		}
+
+		// A block is a run of free courts whose numbers follow each other
+		end := start + 1
+		for end < len(sorted) && sorted[end].Free && sorted[end].Number == sorted[end-1].Number+1 {
+			end++
+		}
+		for i := start; i < end; i++ {
+			ranks = append(ranks, courtRank{
+				court:     sorted[i],
+				edge:      i == start || i == end-1,
+				blockSize: end - start,
+				history:   history[sorted[i].ResourceId],
+				wear:      wear[sorted[i].ResourceId],
+			})
+		}
+		start = end
+	}
+
+	sort.SliceStable(ranks, func(i, j int) bool {
+		a, b := ranks[i], ranks[j]
+		switch {
+		case a.edge != b.edge:
+			return a.edge
+		case a.history != b.history:
+			return a.history > b.history
+		case a.blockSize != b.blockSize:
+			return a.blockSize < b.blockSize
+		case a.wear != b.wear:
+			return a.wear < b.wear
+		case a.court.Number != b.court.Number:
+			return a.court.Number < b.court.Number
+		default:
+			return a.court.SlotId < b.court.SlotId
+		}
+	})
+
+	ranked := make([]booking.CourtSlot, 0, len(ranks))
+	for _, r := range ranks {
+		ranked = append(ranked, r.court)
+	}
+	return ranked
+}
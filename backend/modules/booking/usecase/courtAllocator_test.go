@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"main/modules/booking"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// court is a court numbered n whose slot and resource ids are derived from n
+func court(n int, free bool) booking.CourtSlot {
+	return booking.CourtSlot{
+		SlotId:     "slot-" + strconv.Itoa(n),
+		ResourceId: "court-" + strconv.Itoa(n),
+		Number:     n,
+		Free:       free,
+	}
+}
+
+func slotIds(courts []booking.CourtSlot) []string {
+	ids := make([]string, 0, len(courts))
+	for _, c := range courts {
+		ids = append(ids, c.SlotId)
+	}
+	return ids
+}
+
+func TestRankCourts(t *testing.T) {
+	tests := []struct {
+		name    string
+		courts  []booking.CourtSlot
+		history map[string]int
+		wear    map[string]int
+		want    []string
+	}{
+		{
+			name:   "no free court",
+			courts: []booking.CourtSlot{court(1, false), court(2, false)},
+			want:   []string{},
+		},
+		{
+			name:   "edges of a free block before its middle",
+			courts: []booking.CourtSlot{court(1, true), court(2, true), court(3, true), court(4, true)},
+			want:   []string{"slot-1", "slot-4", "slot-2", "slot-3"},
+		},
+		{
+			name:    "edge beats a familiar court that would split the block",
+			courts:  []booking.CourtSlot{court(1, true), court(2, true), court(3, true)},
+			history: map[string]int{"court-2": 5},
+			want:    []string{"slot-1", "slot-3", "slot-2"},
+		},
+		{
+			name:    "user's familiar court first",
+			courts:  []booking.CourtSlot{court(1, true), court(2, false), court(3, true), court(4, false), court(5, true)},
+			history: map[string]int{"court-5": 2, "court-3": 1},
+			want:    []string{"slot-5", "slot-3", "slot-1"},
+		},
+		{
+			name:   "smallest block fills first",
+			courts: []booking.CourtSlot{court(1, true), court(2, true), court(3, false), court(4, true)},
+			want:   []string{"slot-4", "slot-1", "slot-2"},
+		},
+		{
+			name:   "least worn court first",
+			courts: []booking.CourtSlot{court(1, true), court(2, false), court(3, true)},
+			wear:   map[string]int{"court-1": 10, "court-3": 2},
+			want:   []string{"slot-3", "slot-1"},
+		},
+		{
+			name:    "history beats wear",
+			courts:  []booking.CourtSlot{court(1, true), court(2, false), court(3, true)},
+			history: map[string]int{"court-3": 1},
+			wear:    map[string]int{"court-3": 9},
+			want:    []string{"slot-3", "slot-1"},
+		},
+		{
+			name:   "court number breaks ties whatever the input order",
+			courts: []booking.CourtSlot{court(5, true), court(3, true), court(4, false), court(1, true), court(2, false)},
+			want:   []string{"slot-1", "slot-3", "slot-5"},
+		},
+		{
+			name: "slot id breaks ties between slots of the same court number",
+			courts: []booking.CourtSlot{
+				{SlotId: "slot-b", ResourceId: "court-1", Number: 1, Free: true},
+				{SlotId: "slot-a", ResourceId: "court-1", Number: 1, Free: true},
+			},
+			want: []string{"slot-a", "slot-b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slotIds(rankCourts(tt.courts, tt.history, tt.wear))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rankCourts() = %v, want %v", got, tt.want)
+			}
+
+			// The order must not depend on the order courts were read in
+			reversed := make([]booking.CourtSlot, len(tt.courts))
+			for i, c := range tt.courts {
+				reversed[len(tt.courts)-1-i] = c
+			}
+			if got := slotIds(rankCourts(reversed, tt.history, tt.wear)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rankCourts(reversed) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
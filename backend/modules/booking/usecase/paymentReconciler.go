@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"main/modules/booking"
+	"main/modules/payment"
+	"time"
+)
+
+// reconcileInterval is how often bookings are checked against completed payments
+const reconcileInterval = 5 * time.Minute
+
+// HandlePaymentCompleted marks the event's booking paid. Events already applied
+// are skipped, and marking an already paid booking changes nothing, so the same
+// event can be delivered any number of times.
+func (u *bookingUsecase) HandlePaymentCompleted(ctx context.Context, event *payment.PaymentCompletedEvent) error {
+	processed, err := u.bookingRepository.HasProcessedEvent(ctx, event.EventId)
+	if err != nil {
+		return err
+	}
+	if processed {
+		return nil
+	}
+
+	changed, err := u.bookingRepository.MarkBookingPaid(ctx, event.BookingId, &booking.PaidBy{
+		PaymentId: event.PaymentId,
+		UserId:    event.UserId,
+		Amount:    event.Amount,
+	})
+	if errors.Is(err, booking.ErrBookingNotFound) {
+		// Nothing to pay for; record the event so it isn't retried forever
+		log.Printf("Warning: payment %s completed for unknown booking %s", event.PaymentId, event.BookingId)
+	} else if errors.Is(err, booking.ErrPaidAfterExpiry) {
+		// Flagged for a refund; retrying won't give the slot back
+		log.Printf("Warning: payment %s completed too late for booking %s: %s", event.PaymentId, event.BookingId, err.Error())
+	} else if errors.Is(err, booking.ErrUnderpaid) {
+		// Flagged for a refund; the same payment won't cover it on a retry
+		log.Printf("Warning: payment %s does not cover booking %s: %s", event.PaymentId, event.BookingId, err.Error())
+	} else if err != nil {
+		return err
+	}
+	if changed {
+		log.Printf("Booking %s paid by payment %s", event.BookingId, event.PaymentId)
+	}
+
+	return u.bookingRepository.RecordProcessedEvent(ctx, event.EventId, payment.TopicPaymentCompleted)
+}
+
+// HandlePaymentExpired gives back the slot held by the event's booking unless
+// it was paid in the meantime. Like HandlePaymentCompleted it is safe to replay.
+func (u *bookingUsecase) HandlePaymentExpired(ctx context.Context, event *payment.PaymentExpiredEvent) error {
+	processed, err := u.bookingRepository.HasProcessedEvent(ctx, event.EventId)
+	if err != nil {
+		return err
+	}
+	if processed {
+		return nil
+	}
+
+	released, err := u.bookingRepository.ReleaseBookingHold(ctx, event.BookingId)
+	if errors.Is(err, booking.ErrBookingNotFound) {
+		log.Printf("Warning: payment %s expired for unknown booking %s", event.PaymentId, event.BookingId)
+	} else if err != nil {
+		return err
+	}
+	if released {
+		log.Printf("Booking %s released, payment %s expired", event.BookingId, event.PaymentId)
+	}
+
+	return u.bookingRepository.RecordProcessedEvent(ctx, event.EventId, payment.TopicPaymentExpired)
+}
+
+// ReconcilePayments marks paid every booking whose payment completed without
+// the booking hearing about it, e.g. when the event was never published, and
+// releases the holds of bookings whose payment expired the same way
+func (u *bookingUsecase) ReconcilePayments(ctx context.Context) (int, error) {
+	drift, err := u.bookingRepository.FindPaymentDrift(ctx, string(payment.Completed))
+	if err != nil {
+		return 0, err
+	}
+
+	fixed := 0
+	for _, d := range drift {
+		changed, err := u.bookingRepository.MarkBookingPaid(ctx, d.BookingId, &booking.PaidBy{
+			PaymentId: d.PaymentId,
+			UserId:    d.UserId,
+			Amount:    d.Amount,
+		})
+		if err != nil {
+			log.Printf("Error: ReconcilePayments: booking %s: %s", d.BookingId, err.Error())
+			continue
+		}
+		if changed {
+			fixed++
+		}
+	}
+
+	expired, err := u.bookingRepository.FindPaymentDrift(ctx, string(payment.Expired))
+	if err != nil {
+		return fixed, err
+	}
+	for _, d := range expired {
+		released, err := u.bookingRepository.ReleaseBookingHold(ctx, d.BookingId)
+		if err != nil {
+			log.Printf("Error: ReconcilePayments: booking %s: %s", d.BookingId, err.Error())
+			continue
+		}
+		if released {
+			fixed++
+		}
+	}
+
+	return fixed, nil
+}
+
+// ScheduleReconciliation runs ReconcilePayments every reconcileInterval
+func (u *bookingUsecase) ScheduleReconciliation() {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		fixed, err := u.ReconcilePayments(context.Background())
+		if err != nil {
+			log.Printf("Error reconciling payments: %s", err.Error())
+			continue
+		}
+		if fixed > 0 {
+			log.Printf("Reconciled %d bookings with their payments", fixed)
+		}
+	}
+}
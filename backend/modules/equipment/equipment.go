@@ -0,0 +1,27 @@
+package equipment
+
+import "time"
+
+// windowsOverlap compares two HH:mm ranges on the same day
+func windowsOverlap(startA, endA, startB, endB string) bool {
+	a0, errA0 := time.Parse("15:04", startA)
+	a1, errA1 := time.Parse("15:04", endA)
+	b0, errB0 := time.Parse("15:04", startB)
+	b1, errB1 := time.Parse("15:04", endB)
+	if errA0 != nil || errA1 != nil || errB0 != nil || errB1 != nil {
+		// Unparseable ranges are treated as clashing so stock is never oversold
+		return true
+	}
+	return a0.Before(b1) && b0.Before(a1)
+}
+
+// ReservedDuring sums the quantity of open rentals overlapping the window
+func ReservedDuring(rentals []Rental, startTime, endTime string) int {
+	reserved := 0
+	for _, r := range rentals {
+		if r.Status == RentalReserved && windowsOverlap(r.StartTime, r.EndTime, startTime, endTime) {
+			reserved += r.Quantity
+		}
+	}
+	return reserved
+}
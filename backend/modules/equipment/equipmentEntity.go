@@ -0,0 +1,59 @@
+package equipment
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EquipmentDb holds the rentable items and their rentals
+const EquipmentDb = "equipment_db"
+
+const (
+	ItemActive   = 0
+	ItemInactive = 1
+
+	// RentalReserved is held against stock until the item comes back
+	RentalReserved = "reserved"
+	RentalReturned = "returned"
+	RentalDamaged  = "damaged"
+	RentalCanceled = "canceled"
+)
+
+type (
+	// Item is a rentable piece of equipment with its stock at one facility
+	Item struct {
+		Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		FacilityName string             `bson:"facility_name" json:"facility_name"`
+		Name         string             `bson:"name" json:"name"`
+		Category     string             `bson:"category" json:"category"` // e.g., "racket", "shuttlecock", "ball", "swim_gear"
+		RentalPrice  float64            `bson:"rental_price" json:"rental_price"`
+		Stock        int                `bson:"stock" json:"stock"`
+		Status       int                `bson:"status" json:"status"` // 0 = active, 1 = inactive
+		CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+		UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	}
+
+	// Rental is a quantity of one item attached to a booking for the slot's time
+	Rental struct {
+		Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		BookingId    string             `bson:"booking_id" json:"booking_id"`
+		UserId       string             `bson:"user_id" json:"user_id"`
+		ItemId       primitive.ObjectID `bson:"item_id" json:"item_id"`
+		ItemName     string             `bson:"item_name" json:"item_name"`
+		FacilityName string             `bson:"facility_name" json:"facility_name"`
+		SlotId       string             `bson:"slot_id" json:"slot_id"`
+		Date         string             `bson:"date" json:"date"` // YYYY-MM-DD the slot is used
+		StartTime    string             `bson:"start_time" json:"start_time"`
+		EndTime      string             `bson:"end_time" json:"end_time"`
+		Quantity     int                `bson:"quantity" json:"quantity"`
+		UnitPrice    float64            `bson:"unit_price" json:"unit_price"`
+		Amount       float64            `bson:"amount" json:"amount"`
+		Status       string             `bson:"status" json:"status"`
+		Note         string             `bson:"note,omitempty" json:"note,omitempty"`
+		CheckedOutBy string             `bson:"checked_out_by,omitempty" json:"checked_out_by,omitempty"`
+		CheckedOutAt *time.Time         `bson:"checked_out_at,omitempty" json:"checked_out_at,omitempty"`
+		CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+		UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	}
+)
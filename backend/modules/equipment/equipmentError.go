@@ -0,0 +1,14 @@
+package equipment
+
+import "errors"
+
+var (
+	ErrItemNotFound        = errors.New("error: equipment item not found")
+	ErrItemInactive        = errors.New("error: equipment item is not available for rent")
+	ErrInsufficientStock   = errors.New("error: not enough equipment in stock for this slot")
+	ErrItemHasRentals      = errors.New("error: equipment item still has open rentals")
+	ErrRentalNotFound      = errors.New("error: rental not found")
+	ErrRentalClosed        = errors.New("error: rental has already been checked out")
+	ErrInvalidRentalSlot   = errors.New("error: invalid slot for rental")
+	ErrDuplicateRentalItem = errors.New("error: each item can only be listed once per booking")
+)
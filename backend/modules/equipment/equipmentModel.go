@@ -0,0 +1,34 @@
+package equipment
+
+type (
+	ItemRequest struct {
+		Name        string  `json:"name" validate:"required"`
+		Category    string  `json:"category" validate:"required"`
+		RentalPrice float64 `json:"rental_price" validate:"min=0"`
+		Stock       int     `json:"stock" validate:"min=0"`
+		Status      int     `json:"status" validate:"oneof=0 1"`
+	}
+
+	// RentalItemRequest is one line of equipment added to a booking
+	RentalItemRequest struct {
+		ItemId   string `json:"item_id" validate:"required"`
+		Quantity int    `json:"quantity" validate:"required,gt=0"`
+	}
+
+	// RentalQuote prices the requested items for a slot after checking stock
+	RentalQuote struct {
+		Items  []Rental `json:"items"`
+		Amount float64  `json:"amount"`
+	}
+
+	// ItemAvailability is an item's stock left for one slot window
+	ItemAvailability struct {
+		Item      Item `json:"item"`
+		Available int  `json:"available"`
+	}
+
+	CheckOutRentalRequest struct {
+		Status string `json:"status" validate:"required,oneof=returned damaged"`
+		Note   string `json:"note"`
+	}
+)
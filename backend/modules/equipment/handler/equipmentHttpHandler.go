@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"errors"
+	"main/config"
+	"main/modules/equipment"
+	"main/modules/equipment/usecase"
+	"main/pkg/response"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	NewEquipmentHttpHandlerService interface {
+		//Item
+		InsertItem(c echo.Context) error
+		FindManyItem(c echo.Context) error
+		UpdateItem(c echo.Context) error
+		DeleteItem(c echo.Context) error
+		FindAvailability(c echo.Context) error
+
+		//Rental
+		FindBookingRentals(c echo.Context) error
+		CheckOutRental(c echo.Context) error
+	}
+
+	equipmentHttpHandler struct {
+		cfg              *config.Config
+		equipmentUsecase usecase.EquipmentUsecaseService
+	}
+)
+
+func NewEquipmentHttpHandler(cfg *config.Config, equipmentUsecase usecase.EquipmentUsecaseService) NewEquipmentHttpHandlerService {
+	return &equipmentHttpHandler{cfg: cfg, equipmentUsecase: equipmentUsecase}
+}
+
+func (h *equipmentHttpHandler) InsertItem(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(equipment.ItemRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.equipmentUsecase.InsertItem(ctx, c.Param("facilityName"), req)
+	if err != nil {
+		return equipmentErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusCreated, item)
+}
+
+func (h *equipmentHttpHandler) FindManyItem(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	items, err := h.equipmentUsecase.FindManyItem(ctx, c.Param("facilityName"))
+	if err != nil {
+		return equipmentErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, items)
+}
+
+func (h *equipmentHttpHandler) UpdateItem(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(equipment.ItemRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	item, err := h.equipmentUsecase.UpdateItem(ctx, c.Param("facilityName"), c.Param("item_id"), req)
+	if err != nil {
+		return equipmentErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, item)
+}
+
+func (h *equipmentHttpHandler) DeleteItem(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if err := h.equipmentUsecase.DeleteItem(ctx, c.Param("facilityName"), c.Param("item_id")); err != nil {
+		return equipmentErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, &response.MsgResponse{Message: "Equipment item deleted"})
+}
+
+// FindAvailability lists rentable items with the stock left for ?slot_id= today
+func (h *equipmentHttpHandler) FindAvailability(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	slotId := c.QueryParam("slot_id")
+	if slotId == "" {
+		return response.ErrResponse(c, http.StatusBadRequest, "slot_id is required")
+	}
+
+	items, err := h.equipmentUsecase.FindAvailability(ctx, c.Param("facilityName"), slotId)
+	if err != nil {
+		return equipmentErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, items)
+}
+
+func (h *equipmentHttpHandler) FindBookingRentals(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	rentals, err := h.equipmentUsecase.FindBookingRentals(ctx, c.Param("booking_id"))
+	if err != nil {
+		return equipmentErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, rentals)
+}
+
+// CheckOutRental marks a rented item returned or damaged at the counter
+func (h *equipmentHttpHandler) CheckOutRental(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(equipment.CheckOutRentalRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	checkedOutBy, _ := c.Get("user_id").(string)
+	rental, err := h.equipmentUsecase.CheckOutRental(ctx, c.Param("booking_id"), c.Param("rental_id"), checkedOutBy, req)
+	if err != nil {
+		return equipmentErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, rental)
+}
+
+func equipmentErrResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, equipment.ErrItemNotFound),
+		errors.Is(err, equipment.ErrRentalNotFound):
+		return response.ErrResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, equipment.ErrInvalidRentalSlot),
+		errors.Is(err, equipment.ErrDuplicateRentalItem):
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, equipment.ErrInsufficientStock),
+		errors.Is(err, equipment.ErrItemInactive),
+		errors.Is(err, equipment.ErrItemHasRentals),
+		errors.Is(err, equipment.ErrRentalClosed):
+		return response.ErrResponse(c, http.StatusConflict, err.Error())
+	default:
+		return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,362 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"main/modules/equipment"
+	"main/modules/facility"
+	"main/pkg/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type (
+	EquipmentRepositoryService interface {
+		//Item
+		InsertItem(ctx context.Context, item *equipment.Item) (primitive.ObjectID, error)
+		FindManyItem(ctx context.Context, facilityName string, activeOnly bool) ([]equipment.Item, error)
+		FindOneItem(ctx context.Context, facilityName, itemId string) (*equipment.Item, error)
+		UpdateItem(ctx context.Context, item *equipment.Item) error
+		DeleteItem(ctx context.Context, facilityName, itemId string) error
+		ReduceItemStock(ctx context.Context, itemId primitive.ObjectID, quantity int) error
+
+		//Rental
+		InsertRentals(ctx context.Context, rentals []equipment.Rental) error
+		FindOpenRentals(ctx context.Context, itemIds []primitive.ObjectID, date string) ([]equipment.Rental, error)
+		CountOpenRentalsByItem(ctx context.Context, itemId primitive.ObjectID) (int64, error)
+		FindManyRentalByBooking(ctx context.Context, bookingId string) ([]equipment.Rental, error)
+		FindOneRental(ctx context.Context, bookingId, rentalId string) (*equipment.Rental, error)
+		CloseRental(ctx context.Context, rentalId primitive.ObjectID, status, note, checkedOutBy string) error
+		CancelRentals(ctx context.Context, rentalIds []primitive.ObjectID) error
+
+		//Slot
+		FindSlot(ctx context.Context, facilityName, slotId string) (*facility.Slot, error)
+	}
+
+	equipmentRepository struct {
+		db *mongo.Client
+	}
+)
+
+func NewEquipmentRepository(db *mongo.Client) EquipmentRepositoryService {
+	return &equipmentRepository{db: db}
+}
+
+func (r *equipmentRepository) equipmentDbConn(ctx context.Context) *mongo.Database {
+	return r.db.Database(equipment.EquipmentDb)
+}
+
+func (r *equipmentRepository) facilityDbConn(ctx context.Context) *mongo.Database {
+	return r.db.Database(facility.RegistryDb)
+}
+
+func (r *equipmentRepository) InsertItem(ctx context.Context, item *equipment.Item) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("items")
+
+	result, err := col.InsertOne(ctx, item)
+	if err != nil {
+		log.Printf("Error: InsertItem: %s", err.Error())
+		return primitive.NilObjectID, fmt.Errorf("error: insert equipment item failed: %w", err)
+	}
+
+	itemId, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("error: insert equipment item failed")
+	}
+
+	return itemId, nil
+}
+
+func (r *equipmentRepository) FindManyItem(ctx context.Context, facilityName string, activeOnly bool) ([]equipment.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("items")
+
+	filter := bson.M{"facility_name": facilityName}
+	if activeOnly {
+		filter["status"] = equipment.ItemActive
+	}
+
+	cur, err := col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "category", Value: 1}, {Key: "name", Value: 1}}))
+	if err != nil {
+		log.Printf("Error: FindManyItem: %s", err.Error())
+		return nil, fmt.Errorf("error: find many equipment item failed: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	result := make([]equipment.Item, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindManyItem: %s", err.Error())
+		return nil, fmt.Errorf("error: find many equipment item failed: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *equipmentRepository) FindOneItem(ctx context.Context, facilityName, itemId string) (*equipment.Item, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("items")
+
+	item := new(equipment.Item)
+	if err := col.FindOne(ctx, bson.M{"_id": utils.ConvertToObjectId(itemId), "facility_name": facilityName}).Decode(item); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", equipment.ErrItemNotFound, itemId)
+		}
+		log.Printf("Error: FindOneItem: %s", err.Error())
+		return nil, fmt.Errorf("error: find one equipment item failed: %w", err)
+	}
+
+	return item, nil
+}
+
+func (r *equipmentRepository) UpdateItem(ctx context.Context, item *equipment.Item) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("items")
+
+	result, err := col.UpdateOne(
+		ctx,
+		bson.M{"_id": item.Id, "facility_name": item.FacilityName},
+		bson.M{"$set": bson.M{
+			"name":         item.Name,
+			"category":     item.Category,
+			"rental_price": item.RentalPrice,
+			"stock":        item.Stock,
+			"status":       item.Status,
+			"updated_at":   item.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		log.Printf("Error: UpdateItem: %s", err.Error())
+		return fmt.Errorf("error: update equipment item failed: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", equipment.ErrItemNotFound, item.Id.Hex())
+	}
+
+	return nil
+}
+
+func (r *equipmentRepository) DeleteItem(ctx context.Context, facilityName, itemId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("items")
+
+	result, err := col.DeleteOne(ctx, bson.M{"_id": utils.ConvertToObjectId(itemId), "facility_name": facilityName})
+	if err != nil {
+		log.Printf("Error: DeleteItem: %s", err.Error())
+		return fmt.Errorf("error: delete equipment item failed: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", equipment.ErrItemNotFound, itemId)
+	}
+
+	return nil
+}
+
+// ReduceItemStock takes damaged units out of stock, never going below zero
+func (r *equipmentRepository) ReduceItemStock(ctx context.Context, itemId primitive.ObjectID, quantity int) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("items")
+
+	if _, err := col.UpdateOne(
+		ctx,
+		bson.M{"_id": itemId},
+		bson.A{bson.M{"$set": bson.M{
+			"stock":      bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{"$stock", quantity}}}},
+			"updated_at": utils.LocalTime(),
+		}}},
+	); err != nil {
+		log.Printf("Error: ReduceItemStock: %s", err.Error())
+		return fmt.Errorf("error: reduce equipment stock failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *equipmentRepository) InsertRentals(ctx context.Context, rentals []equipment.Rental) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("rentals")
+
+	docs := make([]interface{}, len(rentals))
+	for i := range rentals {
+		docs[i] = rentals[i]
+	}
+
+	if _, err := col.InsertMany(ctx, docs); err != nil {
+		log.Printf("Error: InsertRentals: %s", err.Error())
+		return fmt.Errorf("error: insert rentals failed: %w", err)
+	}
+
+	return nil
+}
+
+// FindOpenRentals lists reserved rentals of the items on date
+func (r *equipmentRepository) FindOpenRentals(ctx context.Context, itemIds []primitive.ObjectID, date string) ([]equipment.Rental, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("rentals")
+
+	cur, err := col.Find(ctx, bson.M{
+		"item_id": bson.M{"$in": itemIds},
+		"date":    date,
+		"status":  equipment.RentalReserved,
+	})
+	if err != nil {
+		log.Printf("Error: FindOpenRentals: %s", err.Error())
+		return nil, fmt.Errorf("error: find open rentals failed: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	result := make([]equipment.Rental, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindOpenRentals: %s", err.Error())
+		return nil, fmt.Errorf("error: find open rentals failed: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *equipmentRepository) CountOpenRentalsByItem(ctx context.Context, itemId primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("rentals")
+
+	count, err := col.CountDocuments(ctx, bson.M{"item_id": itemId, "status": equipment.RentalReserved})
+	if err != nil {
+		log.Printf("Error: CountOpenRentalsByItem: %s", err.Error())
+		return 0, fmt.Errorf("error: count open rentals failed: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *equipmentRepository) FindManyRentalByBooking(ctx context.Context, bookingId string) ([]equipment.Rental, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("rentals")
+
+	cur, err := col.Find(ctx, bson.M{"booking_id": bookingId}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		log.Printf("Error: FindManyRentalByBooking: %s", err.Error())
+		return nil, fmt.Errorf("error: find booking rentals failed: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	result := make([]equipment.Rental, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindManyRentalByBooking: %s", err.Error())
+		return nil, fmt.Errorf("error: find booking rentals failed: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *equipmentRepository) FindOneRental(ctx context.Context, bookingId, rentalId string) (*equipment.Rental, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("rentals")
+
+	rental := new(equipment.Rental)
+	if err := col.FindOne(ctx, bson.M{"_id": utils.ConvertToObjectId(rentalId), "booking_id": bookingId}).Decode(rental); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", equipment.ErrRentalNotFound, rentalId)
+		}
+		log.Printf("Error: FindOneRental: %s", err.Error())
+		return nil, fmt.Errorf("error: find one rental failed: %w", err)
+	}
+
+	return rental, nil
+}
+
+// CloseRental moves a reserved rental to returned or damaged; a rental that
+// was already closed is left alone
+func (r *equipmentRepository) CloseRental(ctx context.Context, rentalId primitive.ObjectID, status, note, checkedOutBy string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("rentals")
+
+	now := utils.LocalTime()
+	result, err := col.UpdateOne(
+		ctx,
+		bson.M{"_id": rentalId, "status": equipment.RentalReserved},
+		bson.M{"$set": bson.M{
+			"status":         status,
+			"note":           note,
+			"checked_out_by": checkedOutBy,
+			"checked_out_at": now,
+			"updated_at":     now,
+		}},
+	)
+	if err != nil {
+		log.Printf("Error: CloseRental: %s", err.Error())
+		return fmt.Errorf("error: close rental failed: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return equipment.ErrRentalClosed
+	}
+
+	return nil
+}
+
+func (r *equipmentRepository) CancelRentals(ctx context.Context, rentalIds []primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.equipmentDbConn(ctx).Collection("rentals")
+
+	if _, err := col.UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": rentalIds}, "status": equipment.RentalReserved},
+		bson.M{"$set": bson.M{"status": equipment.RentalCanceled, "updated_at": utils.LocalTime()}},
+	); err != nil {
+		log.Printf("Error: CancelRentals: %s", err.Error())
+		return fmt.Errorf("error: cancel rentals failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *equipmentRepository) FindSlot(ctx context.Context, facilityName, slotId string) (*facility.Slot, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("slots")
+
+	slot := new(facility.Slot)
+	if err := col.FindOne(ctx, bson.M{"_id": utils.ConvertToObjectId(slotId), "facility_name": facilityName}).Decode(slot); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", equipment.ErrInvalidRentalSlot, slotId)
+		}
+		log.Printf("Error: FindSlot: %s", err.Error())
+		return nil, fmt.Errorf("error: find slot failed: %w", err)
+	}
+
+	return slot, nil
+}
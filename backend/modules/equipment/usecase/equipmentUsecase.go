@@ -0,0 +1,302 @@
+package usecase
+
+import (
+	"context"
+	"main/modules/equipment"
+	"main/modules/equipment/repository"
+	"main/modules/facility"
+	"main/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type (
+	EquipmentUsecaseService interface {
+		//Item - usecase
+		InsertItem(ctx context.Context, facilityName string, req *equipment.ItemRequest) (*equipment.Item, error)
+		FindManyItem(ctx context.Context, facilityName string) ([]equipment.Item, error)
+		UpdateItem(ctx context.Context, facilityName, itemId string, req *equipment.ItemRequest) (*equipment.Item, error)
+		DeleteItem(ctx context.Context, facilityName, itemId string) error
+		FindAvailability(ctx context.Context, facilityName, slotId string) ([]equipment.ItemAvailability, error)
+
+		//Rental - usecase
+		QuoteRental(ctx context.Context, facilityName, slotId string, items []equipment.RentalItemRequest) (*equipment.RentalQuote, error)
+		ReserveRental(ctx context.Context, bookingId, userId, facilityName, slotId string, items []equipment.RentalItemRequest) (*equipment.RentalQuote, error)
+		FindBookingRentals(ctx context.Context, bookingId string) ([]equipment.Rental, error)
+		CancelBookingRentals(ctx context.Context, bookingId string) error
+		CheckOutRental(ctx context.Context, bookingId, rentalId, checkedOutBy string, req *equipment.CheckOutRentalRequest) (*equipment.Rental, error)
+	}
+
+	equipmentUsecase struct {
+		equipmentRepository repository.EquipmentRepositoryService
+	}
+)
+
+func NewEquipmentUsecase(equipmentRepository repository.EquipmentRepositoryService) EquipmentUsecaseService {
+	return &equipmentUsecase{equipmentRepository: equipmentRepository}
+}
+
+func (u *equipmentUsecase) InsertItem(ctx context.Context, facilityName string, req *equipment.ItemRequest) (*equipment.Item, error) {
+	item := &equipment.Item{
+		FacilityName: facilityName,
+		Name:         req.Name,
+		Category:     req.Category,
+		RentalPrice:  req.RentalPrice,
+		Stock:        req.Stock,
+		Status:       req.Status,
+		CreatedAt:    utils.LocalTime(),
+		UpdatedAt:    utils.LocalTime(),
+	}
+
+	itemId, err := u.equipmentRepository.InsertItem(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	item.Id = itemId
+
+	return item, nil
+}
+
+func (u *equipmentUsecase) FindManyItem(ctx context.Context, facilityName string) ([]equipment.Item, error) {
+	return u.equipmentRepository.FindManyItem(ctx, facilityName, false)
+}
+
+func (u *equipmentUsecase) UpdateItem(ctx context.Context, facilityName, itemId string, req *equipment.ItemRequest) (*equipment.Item, error) {
+	item, err := u.equipmentRepository.FindOneItem(ctx, facilityName, itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Name = req.Name
+	item.Category = req.Category
+	item.RentalPrice = req.RentalPrice
+	item.Stock = req.Stock
+	item.Status = req.Status
+	item.UpdatedAt = utils.LocalTime()
+
+	if err := u.equipmentRepository.UpdateItem(ctx, item); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// DeleteItem refuses while any rental of the item is still out
+func (u *equipmentUsecase) DeleteItem(ctx context.Context, facilityName, itemId string) error {
+	item, err := u.equipmentRepository.FindOneItem(ctx, facilityName, itemId)
+	if err != nil {
+		return err
+	}
+
+	open, err := u.equipmentRepository.CountOpenRentalsByItem(ctx, item.Id)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return equipment.ErrItemHasRentals
+	}
+
+	return u.equipmentRepository.DeleteItem(ctx, facilityName, itemId)
+}
+
+// FindAvailability lists the facility's active items with the stock left
+// during the slot today
+func (u *equipmentUsecase) FindAvailability(ctx context.Context, facilityName, slotId string) ([]equipment.ItemAvailability, error) {
+	slot, err := u.equipmentRepository.FindSlot(ctx, facilityName, slotId)
+	if err != nil {
+		return nil, err
+	}
+	items, err := u.equipmentRepository.FindManyItem(ctx, facilityName, true)
+	if err != nil {
+		return nil, err
+	}
+
+	itemIds := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		itemIds = append(itemIds, item.Id)
+	}
+	rentals, err := u.equipmentRepository.FindOpenRentals(ctx, itemIds, rentalDate())
+	if err != nil {
+		return nil, err
+	}
+	byItem := groupRentalsByItem(rentals)
+
+	result := make([]equipment.ItemAvailability, 0, len(items))
+	for _, item := range items {
+		result = append(result, equipment.ItemAvailability{
+			Item:      item,
+			Available: max(item.Stock-equipment.ReservedDuring(byItem[item.Id], slot.StartTime.String(), slot.EndTime.String()), 0),
+		})
+	}
+	return result, nil
+}
+
+// QuoteRental checks every requested item has enough stock free during the
+// slot and prices it, without reserving anything
+func (u *equipmentUsecase) QuoteRental(ctx context.Context, facilityName, slotId string, items []equipment.RentalItemRequest) (*equipment.RentalQuote, error) {
+	quote, _, err := u.quote(ctx, facilityName, slotId, items)
+	return quote, err
+}
+
+// quote builds the unsaved rentals and returns each item's stock with them
+func (u *equipmentUsecase) quote(ctx context.Context, facilityName, slotId string, items []equipment.RentalItemRequest) (*equipment.RentalQuote, map[primitive.ObjectID]int, error) {
+	quote := &equipment.RentalQuote{Items: make([]equipment.Rental, 0, len(items))}
+	if len(items) == 0 {
+		return quote, nil, nil
+	}
+
+	slot, err := u.equipmentRepository.FindSlot(ctx, facilityName, slotId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stock := make(map[primitive.ObjectID]int, len(items))
+	for _, req := range items {
+		item, err := u.equipmentRepository.FindOneItem(ctx, facilityName, req.ItemId)
+		if err != nil {
+			return nil, nil, err
+		}
+		if item.Status != equipment.ItemActive {
+			return nil, nil, equipment.ErrItemInactive
+		}
+		if _, ok := stock[item.Id]; ok {
+			return nil, nil, equipment.ErrDuplicateRentalItem
+		}
+		stock[item.Id] = item.Stock
+
+		quote.Items = append(quote.Items, equipment.Rental{
+			ItemId:       item.Id,
+			ItemName:     item.Name,
+			FacilityName: facilityName,
+			SlotId:       slotId,
+			Date:         rentalDate(),
+			StartTime:    slot.StartTime.String(),
+			EndTime:      slot.EndTime.String(),
+			Quantity:     req.Quantity,
+			UnitPrice:    item.RentalPrice,
+			Amount:       item.RentalPrice * float64(req.Quantity),
+			Status:       equipment.RentalReserved,
+		})
+		quote.Amount += item.RentalPrice * float64(req.Quantity)
+	}
+
+	if err := u.checkStock(ctx, quote.Items, stock, 0); err != nil {
+		return nil, nil, err
+	}
+	return quote, stock, nil
+}
+
+// ReserveRental attaches the items to a booking. Stock is checked again after
+// inserting, so two bookings racing for the last item can't both keep it.
+func (u *equipmentUsecase) ReserveRental(ctx context.Context, bookingId, userId, facilityName, slotId string, items []equipment.RentalItemRequest) (*equipment.RentalQuote, error) {
+	quote, stock, err := u.quote(ctx, facilityName, slotId, items)
+	if err != nil || len(quote.Items) == 0 {
+		return quote, err
+	}
+
+	rentalIds := make([]primitive.ObjectID, 0, len(quote.Items))
+	for i := range quote.Items {
+		quote.Items[i].Id = primitive.NewObjectID()
+		quote.Items[i].BookingId = bookingId
+		quote.Items[i].UserId = userId
+		quote.Items[i].CreatedAt = utils.LocalTime()
+		quote.Items[i].UpdatedAt = utils.LocalTime()
+		rentalIds = append(rentalIds, quote.Items[i].Id)
+	}
+
+	if err := u.equipmentRepository.InsertRentals(ctx, quote.Items); err != nil {
+		return nil, err
+	}
+
+	// The new rentals are now part of the open set, so none may push it over stock
+	if err := u.checkStock(ctx, quote.Items, stock, 1); err != nil {
+		if cancelErr := u.equipmentRepository.CancelRentals(ctx, rentalIds); cancelErr != nil {
+			return nil, cancelErr
+		}
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+func (u *equipmentUsecase) FindBookingRentals(ctx context.Context, bookingId string) ([]equipment.Rental, error) {
+	return u.equipmentRepository.FindManyRentalByBooking(ctx, bookingId)
+}
+
+// CancelBookingRentals releases the stock held by a booking that won't go ahead
+func (u *equipmentUsecase) CancelBookingRentals(ctx context.Context, bookingId string) error {
+	rentals, err := u.equipmentRepository.FindManyRentalByBooking(ctx, bookingId)
+	if err != nil {
+		return err
+	}
+
+	rentalIds := make([]primitive.ObjectID, 0, len(rentals))
+	for _, rental := range rentals {
+		rentalIds = append(rentalIds, rental.Id)
+	}
+	if len(rentalIds) == 0 {
+		return nil
+	}
+	return u.equipmentRepository.CancelRentals(ctx, rentalIds)
+}
+
+// CheckOutRental records the item coming back; damaged units leave the stock
+func (u *equipmentUsecase) CheckOutRental(ctx context.Context, bookingId, rentalId, checkedOutBy string, req *equipment.CheckOutRentalRequest) (*equipment.Rental, error) {
+	rental, err := u.equipmentRepository.FindOneRental(ctx, bookingId, rentalId)
+	if err != nil {
+		return nil, err
+	}
+	if rental.Status != equipment.RentalReserved {
+		return nil, equipment.ErrRentalClosed
+	}
+
+	if err := u.equipmentRepository.CloseRental(ctx, rental.Id, req.Status, req.Note, checkedOutBy); err != nil {
+		return nil, err
+	}
+	if req.Status == equipment.RentalDamaged {
+		if err := u.equipmentRepository.ReduceItemStock(ctx, rental.ItemId, rental.Quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	return u.equipmentRepository.FindOneRental(ctx, bookingId, rentalId)
+}
+
+// checkStock compares each rental's item stock with the quantity already out
+// during its window. own is how many times the rentals themselves are counted
+// in the stored set: 0 for a quote, 1 once they have been inserted.
+func (u *equipmentUsecase) checkStock(ctx context.Context, rentals []equipment.Rental, stock map[primitive.ObjectID]int, own int) error {
+	itemIds := make([]primitive.ObjectID, 0, len(rentals))
+	for _, rental := range rentals {
+		itemIds = append(itemIds, rental.ItemId)
+	}
+	open, err := u.equipmentRepository.FindOpenRentals(ctx, itemIds, rentalDate())
+	if err != nil {
+		return err
+	}
+	byItem := groupRentalsByItem(open)
+
+	for _, rental := range rentals {
+		reserved := equipment.ReservedDuring(byItem[rental.ItemId], rental.StartTime, rental.EndTime)
+		if own == 0 {
+			reserved += rental.Quantity
+		}
+		if reserved > stock[rental.ItemId] {
+			return equipment.ErrInsufficientStock
+		}
+	}
+	return nil
+}
+
+func groupRentalsByItem(rentals []equipment.Rental) map[primitive.ObjectID][]equipment.Rental {
+	byItem := make(map[primitive.ObjectID][]equipment.Rental)
+	for _, rental := range rentals {
+		byItem[rental.ItemId] = append(byItem[rental.ItemId], rental)
+	}
+	return byItem
+}
+
+// rentalDate is the day rentals are taken for; slots are booked for today
+func rentalDate() string {
+	return utils.LocalTime().Format(facility.DateLayout)
+}
@@ -0,0 +1,258 @@
+package facility
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"main/pkg/utils"
+	"strings"
+	"time"
+)
+
+// maxICalEventDays bounds how many days one imported event may cover
+const maxICalEventDays = 31
+
+// ResolveDayHours works out a facility's opening on date. A special opening
+// day wins over a public holiday, which wins over the weekly hours. days must
+// only hold public holidays and the facility's own special days.
+func ResolveDayHours(hours []OpeningHour, days []CalendarDay, date time.Time) DayHours {
+	key := date.Format(DateLayout)
+
+	var holiday *CalendarDay
+	for i := range days {
+		if days[i].Date != key {
+			continue
+		}
+		switch days[i].Kind {
+		case CalendarSpecial:
+			return DayHours{
+				Date:      key,
+				Open:      true,
+				OpenTime:  days[i].OpenTime,
+				CloseTime: days[i].CloseTime,
+				Reason:    CalendarSpecial,
+				Name:      days[i].Name,
+			}
+		case CalendarHoliday:
+			holiday = &days[i]
+		}
+	}
+
+	if holiday != nil {
+		return DayHours{Date: key, Reason: CalendarHoliday, Name: holiday.Name}
+	}
+
+	if len(hours) == 0 {
+		return DayHours{Date: key, Open: true, Reason: DayRegular}
+	}
+	for _, h := range hours {
+		if h.Weekday == int(date.Weekday()) {
+			return DayHours{Date: key, Open: true, OpenTime: h.OpenTime, CloseTime: h.CloseTime, Reason: DayRegular}
+		}
+	}
+	return DayHours{Date: key, Reason: DayClosed}
+}
+
+// WithinHours reports whether the range fits the day's opening
+func (d DayHours) WithinHours(r utils.TimeRange) bool {
+	if !d.Open {
+		return false
+	}
+	if d.OpenTime == "" {
+		return true
+	}
+	return clockRangeWithin(r, d.OpenTime, d.CloseTime)
+}
+
+// ValidateOpeningHours checks each weekday appears once with open before close
+func ValidateOpeningHours(hours []OpeningHour) error {
+	seen := make(map[int]bool)
+	for _, h := range hours {
+		if h.Weekday < 0 || h.Weekday > 6 || seen[h.Weekday] {
+			return fmt.Errorf("%w: weekday %d is invalid or repeated", ErrInvalidOpeningHours, h.Weekday)
+		}
+		seen[h.Weekday] = true
+
+		open, err := clockMinutes(h.OpenTime)
+		if err != nil {
+			return err
+		}
+		close, err := clockMinutes(h.CloseTime)
+		if err != nil {
+			return err
+		}
+		if open >= close {
+			return ErrInvalidTimeRange
+		}
+	}
+	return nil
+}
+
+// SlotWithinOpeningHours checks a slot against the weekly hours on every
+// weekday it runs; weekdays the facility is closed are rejected.
+func SlotWithinOpeningHours(hours []OpeningHour, r utils.TimeRange, weekdays []int) bool {
+	if len(hours) == 0 {
+		return true
+	}
+
+	days := weekdays
+	if len(days) == 0 {
+		days = []int{0, 1, 2, 3, 4, 5, 6}
+	}
+	for _, day := range days {
+		fits := false
+		for _, h := range hours {
+			if h.Weekday == day {
+				fits = clockRangeWithin(r, h.OpenTime, h.CloseTime)
+				break
+			}
+		}
+		if !fits {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseDate reads a YYYY-MM-DD date in loc
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	date, err := time.ParseInLocation(DateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	return date, nil
+}
+
+// ParseICalHolidays reads the VEVENTs of an iCalendar file as public holidays,
+// one per day covered. Date-times in UTC are moved into loc before taking the day.
+func ParseICalHolidays(r io.Reader, loc *time.Location) ([]CalendarDay, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		holidays []CalendarDay
+		inEvent  bool
+		start    time.Time
+		end      time.Time
+		summary  string
+	)
+	for _, line := range lines {
+		name, value, ok := splitICalProperty(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent, start, end, summary = true, time.Time{}, time.Time{}, ""
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("%w: event %q has no DTSTART", ErrInvalidICal, summary)
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			if end.Sub(start) > maxICalEventDays*24*time.Hour {
+				return nil, fmt.Errorf("%w: event %q spans more than %d days", ErrInvalidICal, summary, maxICalEventDays)
+			}
+			// DTEND is exclusive for all-day events
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				holidays = append(holidays, CalendarDay{
+					Date:   day.Format(DateLayout),
+					Kind:   CalendarHoliday,
+					Name:   summary,
+					Source: CalendarSourceICal,
+				})
+			}
+		case !inEvent:
+			// Properties of the calendar itself or of other components
+		case name == "DTSTART":
+			if start, err = parseICalDate(value, loc); err != nil {
+				return nil, err
+			}
+		case name == "DTEND":
+			if end, err = parseICalDate(value, loc); err != nil {
+				return nil, err
+			}
+		case name == "SUMMARY":
+			summary = unescapeICalText(value)
+		}
+	}
+
+	if len(holidays) == 0 {
+		return nil, fmt.Errorf("%w: no events found", ErrInvalidICal)
+	}
+	return holidays, nil
+}
+
+// unfoldICalLines joins continuation lines, which start with a space or tab
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidICal, err)
+	}
+	return lines, nil
+}
+
+// splitICalProperty splits "NAME;PARAM=X:VALUE" into its name and value
+func splitICalProperty(line string) (name, value string, ok bool) {
+	colon := strings.Index(line, ":")
+	if colon <= 0 {
+		return "", "", false
+	}
+	name = line[:colon]
+	if semi := strings.Index(name, ";"); semi >= 0 {
+		name = name[:semi]
+	}
+	return strings.ToUpper(name), line[colon+1:], true
+}
+
+func parseICalDate(value string, loc *time.Location) (time.Time, error) {
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: bad date %q", ErrInvalidICal, value)
+		}
+		value = t.In(loc).Format("20060102")
+	}
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("%w: bad date %q", ErrInvalidICal, value)
+	}
+	t, err := time.ParseInLocation("20060102", value[:8], loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: bad date %q", ErrInvalidICal, value)
+	}
+	return t, nil
+}
+
+func unescapeICalText(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+func clockMinutes(value string) (int, error) {
+	t, err := utils.ParseClock(value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Minutes(), nil
+}
+
+func clockRangeWithin(r utils.TimeRange, openTime, closeTime string) bool {
+	opening, err := utils.NewTimeRange(openTime, closeTime)
+	if err != nil {
+		return false
+	}
+	return r.Within(opening)
+}
@@ -0,0 +1,52 @@
+package facility
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DateLayout is how calendar days are stored and accepted
+const DateLayout = "2006-01-02"
+
+const (
+	// CalendarHoliday closes every facility for the day
+	CalendarHoliday = "holiday"
+	// CalendarSpecial opens one facility with its own hours, even on a holiday
+	CalendarSpecial = "special"
+
+	// DayRegular and DayClosed describe days resolved from the weekly hours
+	DayRegular = "regular"
+	DayClosed  = "closed"
+
+	CalendarSourceManual = "manual"
+	CalendarSourceICal   = "ical"
+)
+
+type (
+	// CalendarDay is a public holiday (no facility_name) or a facility's
+	// special opening day, stored in the registry's calendar_days collection
+	CalendarDay struct {
+		Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		FacilityName string             `bson:"facility_name" json:"facility_name,omitempty"`
+		Date         string             `bson:"date" json:"date"`
+		Kind         string             `bson:"kind" json:"kind"`
+		Name         string             `bson:"name" json:"name"`
+		OpenTime     string             `bson:"open_time,omitempty" json:"open_time,omitempty"`
+		CloseTime    string             `bson:"close_time,omitempty" json:"close_time,omitempty"`
+		Source       string             `bson:"source" json:"source"`
+		CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+		UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	}
+
+	// DayHours is a facility's resolved opening for one date. An open day
+	// without times means the facility has no opening hours configured.
+	DayHours struct {
+		Date      string `json:"date"`
+		Open      bool   `json:"open"`
+		OpenTime  string `json:"open_time,omitempty"`
+		CloseTime string `json:"close_time,omitempty"`
+		Reason    string `json:"reason"`
+		Name      string `json:"name,omitempty"`
+	}
+)
@@ -0,0 +1,18 @@
+package facility
+
+// EffectiveCapacity returns the slot's max bookings on the date the overrides
+// were loaded for. A slot override wins over an override of its resource.
+func EffectiveCapacity(slot *Slot, overrides []CapacityOverride) int {
+	capacity := slot.MaxBookings
+	for i := range overrides {
+		switch {
+		case !overrides[i].SlotId.IsZero():
+			if overrides[i].SlotId == slot.Id {
+				return overrides[i].MaxBookings
+			}
+		case !overrides[i].ResourceId.IsZero() && overrides[i].ResourceId == slot.ResourceId:
+			capacity = overrides[i].MaxBookings
+		}
+	}
+	return capacity
+}
@@ -0,0 +1,33 @@
+package facility
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type (
+	// CapacityOverride replaces the max bookings of one slot, or of every slot
+	// on one resource, for a single date. A slot override wins over a resource one.
+	CapacityOverride struct {
+		Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		FacilityName string             `bson:"facility_name" json:"facility_name"`
+		Date         string             `bson:"date" json:"date"`
+		SlotId       primitive.ObjectID `bson:"slot_id,omitempty" json:"slot_id,omitempty"`
+		ResourceId   primitive.ObjectID `bson:"resource_id,omitempty" json:"resource_id,omitempty"`
+		MaxBookings  int                `bson:"max_bookings" json:"max_bookings"`
+		Reason       string             `bson:"reason" json:"reason"` // e.g., "Swim team training", "Extra trainers on duty"
+		CreatedBy    string             `bson:"created_by" json:"created_by"`
+		CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+		UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	}
+
+	// SlotBooking is the part of a booking transaction staff need to resolve an overbooked slot
+	SlotBooking struct {
+		Id        primitive.ObjectID `bson:"_id" json:"id"`
+		UserId    string             `bson:"user_id" json:"user_id"`
+		SlotId    primitive.ObjectID `bson:"slot_id" json:"slot_id"`
+		Status    string             `bson:"status" json:"status"`
+		CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	}
+)
@@ -6,6 +6,10 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// RegistryDb is the single database holding every facility with its slots,
+// resources, templates, calendar days and media, each keyed by facility_name
+const RegistryDb = "facility_db"
+
 type (
 	Facilitiy struct {
 		Id          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -13,6 +17,7 @@ type (
 		PriceInsider float64           `bson:"price_insider" json:"price_insider"`
 		PriceOutsider float64          `bson:"price_outsider" json:"price_outsider"`
 		Description string             `bson:"description" json:"description"`
+		OpeningHours []OpeningHour     `bson:"opening_hours,omitempty" json:"opening_hours,omitempty"` // Weekly hours; empty means always open
 		CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 		UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
 	}
@@ -23,6 +28,8 @@ type (
 		PriceInsider float64           `bson:"price_insider" json:"price_insider"`
 		PriceOutsider float64          `bson:"price_outsider" json:"price_outsider"`
 		Description string             `bson:"description" json:"description"`
+		OpeningHours []OpeningHour     `bson:"opening_hours,omitempty" json:"opening_hours,omitempty"`
+		Images      []FacilityImage    `bson:"-" json:"images,omitempty"`
 		CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 		UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
 	}
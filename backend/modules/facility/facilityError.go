@@ -0,0 +1,36 @@
+package facility
+
+import (
+	"errors"
+	"main/pkg/utils"
+)
+
+var (
+	ErrInvalidTimeRange      = utils.ErrInvalidTimeRange
+	ErrInvalidTimeFormat     = utils.ErrInvalidTimeFormat
+	ErrInvalidCapacity       = errors.New("error: max bookings must be greater than zero")
+	ErrSlotOverlap           = errors.New("error: slot overlaps an existing slot")
+	ErrSlotNotFound          = errors.New("error: slot not found")
+	ErrSlotHasBookings       = errors.New("error: slot still has bookings")
+	ErrCapacityBelowBookings = errors.New("error: max bookings cannot be lower than current bookings")
+	ErrSlotFull              = errors.New("error: booking count would exceed the slot's capacity")
+	ErrResourceNotFound      = errors.New("error: resource not found")
+	ErrResourceHasBookings   = errors.New("error: resource still has booked slots")
+	ErrTemplateNotFound      = errors.New("error: slot template not found")
+	ErrInvalidTemplate       = errors.New("error: invalid slot template")
+	ErrMaintenanceNotFound   = errors.New("error: maintenance task not found")
+	ErrInvalidMaintenance    = errors.New("error: invalid maintenance window")
+	ErrInvalidDate           = errors.New("error: invalid date, expected YYYY-MM-DD")
+	ErrInvalidOpeningHours   = errors.New("error: invalid opening hours")
+	ErrOutsideOpeningHours   = errors.New("error: slot is outside the facility opening hours")
+	ErrCalendarDayNotFound   = errors.New("error: calendar day not found")
+	ErrInvalidICal           = errors.New("error: invalid iCal file")
+	ErrMediaNotFound         = errors.New("error: media not found")
+	ErrMediaTooLarge         = errors.New("error: media file is too large")
+	ErrInvalidMediaType      = errors.New("error: media must be a JPEG, PNG or GIF image")
+	ErrInvalidMediaOrder     = errors.New("error: media order must list every media id of the facility once")
+	ErrOverrideNotFound      = errors.New("error: capacity override not found")
+	ErrInvalidOverride       = errors.New("error: capacity override must target either a slot or a resource")
+	ErrWaiverNotFound        = errors.New("error: waiver not found")
+	ErrWaiverOutdated        = errors.New("error: waiver version is not the current version")
+)
@@ -30,4 +30,182 @@ type (
 	FacilityRequest struct {
 		Name          string  `json:"name"`
 	}
-)
\ No newline at end of file
+
+	CreateSlotRequest struct {
+		StartTime       string `json:"start_time" validate:"required"`
+		EndTime         string `json:"end_time" validate:"required"`
+		MaxBookings     int    `json:"max_bookings"`
+		CurrentBookings int    `json:"current_bookings"`
+		FacilityType    string `json:"facility_type"`
+		ResourceId      string `json:"resource_id,omitempty"`
+	}
+
+	UpdateSlotRequest struct {
+		StartTime   string `json:"start_time,omitempty"`
+		EndTime     string `json:"end_time,omitempty"`
+		MaxBookings int    `json:"max_bookings,omitempty"`
+	}
+
+	UpdateSlotStatusRequest struct {
+		Status int `json:"status" validate:"oneof=0 1"`
+	}
+
+	SlotTemplateRequest struct {
+		OpeningHours        []OpeningHour `json:"opening_hours" validate:"dive"` // Defaults to the facility opening hours
+		SlotDurationMinutes int           `json:"slot_duration_minutes" validate:"required,gt=0"`
+		GapMinutes          int           `json:"gap_minutes" validate:"min=0"`
+		CapacityPerSlot     int           `json:"capacity_per_slot" validate:"required,gt=0"`
+		ResourceIds         []string      `json:"resource_ids,omitempty"`
+	}
+
+	// SlotPlan is one generated slot, optionally matched to an existing slot
+	SlotPlan struct {
+		ExistingId      string `json:"existing_id,omitempty"`
+		StartTime       string `json:"start_time"`
+		EndTime         string `json:"end_time"`
+		ResourceId      string `json:"resource_id,omitempty"`
+		Weekdays        []int  `json:"weekdays,omitempty"`
+		MaxBookings     int    `json:"max_bookings"`
+		CurrentBookings int    `json:"current_bookings"`
+	}
+
+	// SlotTemplateDiff is the change set between a template and the stored slots.
+	// Blocked lists slots the template would remove or shrink but that still have
+	// bookings; Conflicts lists generated slots skipped because they overlap those.
+	SlotTemplateDiff struct {
+		Create    []SlotPlan `json:"create"`
+		Update    []SlotPlan `json:"update"`
+		Delete    []SlotPlan `json:"delete"`
+		Blocked   []SlotPlan `json:"blocked"`
+		Conflicts []SlotPlan `json:"conflicts"`
+	}
+
+	ResourceRequest struct {
+		Name     string `json:"name" validate:"required"`
+		Kind     string `json:"kind" validate:"required"`
+		Number   int    `json:"number"`
+		Capacity int    `json:"capacity" validate:"min=0"`
+		Status   int    `json:"status" validate:"oneof=0 1"`
+	}
+
+	CreateMaintenanceRequest struct {
+		ResourceId  string     `json:"resource_id,omitempty"`
+		Title       string     `json:"title" validate:"required"`
+		Description string     `json:"description"`
+		StartAt     time.Time  `json:"start_at" validate:"required"`
+		EndAt       time.Time  `json:"end_at" validate:"required"`
+		Recurrence  string     `json:"recurrence,omitempty" validate:"omitempty,oneof=daily weekly monthly"`
+		RecurUntil  *time.Time `json:"recur_until,omitempty"`
+	}
+
+	UpdateMaintenanceStatusRequest struct {
+		Status string `json:"status" validate:"required,oneof=planned in_progress done"`
+	}
+
+	OpeningHoursRequest struct {
+		OpeningHours []OpeningHour `json:"opening_hours" validate:"dive"`
+	}
+
+	HolidayRequest struct {
+		Date string `json:"date" validate:"required"`
+		Name string `json:"name" validate:"required"`
+	}
+
+	SpecialDayRequest struct {
+		Date      string `json:"date" validate:"required"`
+		Name      string `json:"name"`
+		OpenTime  string `json:"open_time" validate:"required"`
+		CloseTime string `json:"close_time" validate:"required"`
+	}
+
+	HolidayImportResponse struct {
+		Imported int           `json:"imported"`
+		Holidays []CalendarDay `json:"holidays"`
+	}
+
+	UploadMediaRequest struct {
+		Kind    string `form:"kind" validate:"required,oneof=photo floor_map court_diagram"`
+		Caption string `form:"caption"`
+	}
+
+	ReorderMediaRequest struct {
+		MediaIds []string `json:"media_ids" validate:"required,min=1"`
+	}
+
+	// AvailabilitySearchRequest is read from the query string. An empty window
+	// covers the whole day and a zero max price means no ceiling.
+	AvailabilitySearchRequest struct {
+		Date      string   `query:"date" validate:"required"`
+		From      string   `query:"from"`
+		To        string   `query:"to"`
+		Types     []string `query:"type"`
+		MaxPrice  float64  `query:"max_price" validate:"min=0"`
+		PartySize int      `query:"party_size" validate:"min=0"`
+		UserType  string   `query:"user_type" validate:"omitempty,oneof=insider outsider"`
+	}
+
+	// AvailableSlot is a slot that can still take the whole party on the searched date
+	AvailableSlot struct {
+		FacilityName string  `json:"facility_name"`
+		FacilityType string  `json:"facility_type"`
+		SlotId       string  `json:"slot_id"`
+		ResourceId   string  `json:"resource_id,omitempty"`
+		ResourceName string  `json:"resource_name,omitempty"`
+		Date         string  `json:"date"`
+		StartTime    string  `json:"start_time"`
+		EndTime      string  `json:"end_time"`
+		Remaining    int     `json:"remaining"`
+		MaxBookings  int     `json:"max_bookings"`
+		Price        float64 `json:"price"`       // Per person for the requested user type
+		TotalPrice   float64 `json:"total_price"` // Price for the whole party
+		Currency     string  `json:"currency"`
+	}
+
+	CapacityOverrideRequest struct {
+		Date        string `json:"date" validate:"required"`
+		SlotId      string `json:"slot_id,omitempty"`
+		ResourceId  string `json:"resource_id,omitempty"`
+		MaxBookings int    `json:"max_bookings" validate:"gt=0"`
+		Reason      string `json:"reason" validate:"required"`
+	}
+
+	// OverbookedSlot is a slot whose capacity on the date is below its current
+	// bookings. Overflow holds the latest bookings past the new capacity.
+	OverbookedSlot struct {
+		SlotId          string        `json:"slot_id"`
+		ResourceId      string        `json:"resource_id,omitempty"`
+		StartTime       string        `json:"start_time"`
+		EndTime         string        `json:"end_time"`
+		MaxBookings     int           `json:"max_bookings"`
+		CurrentBookings int           `json:"current_bookings"`
+		Overflow        []SlotBooking `json:"overflow"`
+	}
+
+	CapacityReport struct {
+		Date  string           `json:"date"`
+		Slots []OverbookedSlot `json:"slots"`
+	}
+
+	CapacityOverrideResponse struct {
+		Override *CapacityOverride `json:"override"`
+		Report   *CapacityReport   `json:"report"`
+	}
+
+	PublishWaiverRequest struct {
+		Title string `json:"title" validate:"required"`
+		Body  string `json:"body" validate:"required"`
+	}
+
+	// AcceptWaiverRequest names the version the user read, so a waiver
+	// published in the meantime is not accepted unseen
+	AcceptWaiverRequest struct {
+		Version int `json:"version" validate:"required,gt=0"`
+	}
+
+	// WaiverStatus tells a user whether they still need to accept the current waiver
+	WaiverStatus struct {
+		Waiver     *Waiver           `json:"waiver"`
+		Accepted   bool              `json:"accepted"`
+		Acceptance *WaiverAcceptance `json:"acceptance,omitempty"`
+	}
+)
@@ -2,10 +2,12 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"main/modules/facility"
 	facilityPb "main/modules/facility/proto"
 	"main/modules/facility/usecase"
+	"main/pkg/utils"
 )
 
 type facilityGrpcHandler struct {
@@ -20,47 +22,58 @@ func NewFacilityGrpcHandler(facilityUsecase usecase.FacilityUsecaseService) faci
 }
 
 func (h *facilityGrpcHandler) CheckSlotAvailability(ctx context.Context, req *facilityPb.CheckSlotRequest) (*facilityPb.SlotAvailabilityResponse, error) {
-    var slot interface{}
-    var err error
+    slot, err := h.facilityUsecase.FindOneSlot(ctx, req.FacilityName, req.SlotId)
+    if err != nil {
+        return &facilityPb.SlotAvailabilityResponse{
+            IsAvailable:  false,
+            ErrorMessage: fmt.Sprintf("Failed to find slot: %v", err),
+        }, nil
+    }
 
-    if req.SlotType == "badminton" {
-        // Handle badminton slot check
-        slots, err := h.facilityUsecase.FindBadmintonSlot(ctx)
-        if err != nil {
-            return &facilityPb.SlotAvailabilityResponse{
-                IsAvailable:  false,
-                ErrorMessage: fmt.Sprintf("Failed to find badminton slot: %v", err),
-            }, nil
-        }
-        // Find the specific slot
-        for _, s := range slots {
-            if s.Id.Hex() == req.SlotId {
-                return &facilityPb.SlotAvailabilityResponse{
-                    IsAvailable: s.Status == 0,
-                }, nil
-            }
-        }
-    } else {
-        // Handle normal slot check
-        slot, err = h.facilityUsecase.FindOneSlot(ctx, req.FacilityName, req.SlotId)
-        if err != nil {
-            return &facilityPb.SlotAvailabilityResponse{
-                IsAvailable:  false,
-                ErrorMessage: fmt.Sprintf("Failed to find slot: %v", err),
-            }, nil
-        }
-        if s, ok := slot.(*facility.Slot); ok {
-            return &facilityPb.SlotAvailabilityResponse{
-                IsAvailable:     s.CurrentBookings < s.MaxBookings,
-                CurrentBookings: int32(s.CurrentBookings),
-                MaxBookings:     int32(s.MaxBookings),
-            }, nil
-        }
+    underMaintenance, err := h.facilityUsecase.IsSlotUnderMaintenance(ctx, req.FacilityName, slot, utils.LocalTime())
+    if err != nil {
+        return &facilityPb.SlotAvailabilityResponse{
+            IsAvailable:  false,
+            ErrorMessage: fmt.Sprintf("Failed to check maintenance: %v", err),
+        }, nil
+    }
+    if underMaintenance {
+        return &facilityPb.SlotAvailabilityResponse{
+            IsAvailable:     false,
+            CurrentBookings: int32(slot.CurrentBookings),
+            MaxBookings:     int32(slot.MaxBookings),
+            ErrorMessage:    "Slot is under maintenance",
+        }, nil
+    }
+
+    open, err := h.facilityUsecase.IsSlotOpenOn(ctx, req.FacilityName, slot, utils.LocalTime())
+    if err != nil {
+        return &facilityPb.SlotAvailabilityResponse{
+            IsAvailable:  false,
+            ErrorMessage: fmt.Sprintf("Failed to check opening hours: %v", err),
+        }, nil
+    }
+    if !open {
+        return &facilityPb.SlotAvailabilityResponse{
+            IsAvailable:     false,
+            CurrentBookings: int32(slot.CurrentBookings),
+            MaxBookings:     int32(slot.MaxBookings),
+            ErrorMessage:    "Facility is closed at this time",
+        }, nil
+    }
+
+    capacity, err := h.facilityUsecase.EffectiveCapacity(ctx, req.FacilityName, slot, utils.LocalTime())
+    if err != nil {
+        return &facilityPb.SlotAvailabilityResponse{
+            IsAvailable:  false,
+            ErrorMessage: fmt.Sprintf("Failed to check capacity: %v", err),
+        }, nil
     }
 
     return &facilityPb.SlotAvailabilityResponse{
-        IsAvailable:  false,
-        ErrorMessage: "Slot not found",
+        IsAvailable:     slot.CurrentBookings < capacity,
+        CurrentBookings: int32(slot.CurrentBookings),
+        MaxBookings:     int32(capacity),
     }, nil
 }
 
@@ -94,18 +107,13 @@ func (h *facilityGrpcHandler) UpdateSlotBookingCount(ctx context.Context, req *f
         }, nil
     }
 
-    slot.CurrentBookings += int(req.Increment)
-    
-    if slot.CurrentBookings < 0 {
-        slot.CurrentBookings = 0
-    } else if slot.CurrentBookings > slot.MaxBookings {
+    err = h.facilityUsecase.UpdateSlotBookings(ctx, req.FacilityName, slot, int(req.Increment))
+    if errors.Is(err, facility.ErrSlotFull) {
         return &facilityPb.UpdateSlotResponse{
             Success:      false,
             ErrorMessage: "Booking count would exceed maximum allowed",
         }, nil
     }
-
-    _, err = h.facilityUsecase.UpdateSlot(ctx, req.FacilityName, slot)
     if err != nil {
         return &facilityPb.UpdateSlotResponse{
             Success:      false,
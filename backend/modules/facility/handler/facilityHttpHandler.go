@@ -1,17 +1,29 @@
 package handler
 
 import (
+	"encoding/csv"
+	"errors"
+	"io"
 	"log"
 	"main/config"
 	"main/modules/facility"
 	"main/modules/facility/usecase"
 	"main/pkg/request"
 	"main/pkg/response"
+	"main/pkg/storage"
+	"main/pkg/utils"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
+// maxICalSize bounds an uploaded holiday calendar
+const maxICalSize = 1 << 20
+
 type (
 	NewFacilityHttpHandlerService interface {
 		CreateFacility(c echo.Context) error
@@ -22,13 +34,61 @@ type (
 		InsertSlot (c echo.Context) error
 		FindOneSlot (c echo.Context) error
 		FindAllSlots (c echo.Context) error
-
-		//Badminton
-		InsertBadCourt ( c echo.Context) error
-		FindCourt(c echo.Context) error
-		InsertBadmintonSlot ( c echo.Context) error
-		FindBadmintonSlot(c echo.Context) error
-		
+		UpdateSlot(c echo.Context) error
+		EnableOrDisableSlot(c echo.Context) error
+		DeleteSlot(c echo.Context) error
+
+		//Resource
+		InsertResource(c echo.Context) error
+		FindManyResource(c echo.Context) error
+		UpdateResource(c echo.Context) error
+		DeleteResource(c echo.Context) error
+
+		//Maintenance
+		CreateMaintenance(c echo.Context) error
+		FindManyMaintenance(c echo.Context) error
+		UpdateMaintenanceStatus(c echo.Context) error
+		DeleteMaintenance(c echo.Context) error
+
+		//Capacity override
+		SetCapacityOverride(c echo.Context) error
+		FindManyCapacityOverride(c echo.Context) error
+		DeleteCapacityOverride(c echo.Context) error
+		FindCapacityReport(c echo.Context) error
+
+		//Calendar
+		UpdateOpeningHours(c echo.Context) error
+		FindFacilityCalendar(c echo.Context) error
+		AddSpecialDay(c echo.Context) error
+		DeleteSpecialDay(c echo.Context) error
+		AddHoliday(c echo.Context) error
+		ImportHolidays(c echo.Context) error
+		FindManyHoliday(c echo.Context) error
+		DeleteHoliday(c echo.Context) error
+
+		//Search
+		SearchAvailability(c echo.Context) error
+
+		//Waiver
+		PublishWaiver(c echo.Context) error
+		FindCurrentWaiver(c echo.Context) error
+		FindManyWaiver(c echo.Context) error
+		FindWaiverStatus(c echo.Context) error
+		AcceptWaiver(c echo.Context) error
+		ExportWaiverAcceptances(c echo.Context) error
+
+		//Media
+		UploadMedia(c echo.Context) error
+		FindManyMedia(c echo.Context) error
+		ReorderMedia(c echo.Context) error
+		DeleteMedia(c echo.Context) error
+		ServeMedia(c echo.Context) error
+
+		//Slot template
+		SaveSlotTemplate(c echo.Context) error
+		FindSlotTemplate(c echo.Context) error
+		PreviewSlotTemplate(c echo.Context) error
+		ApplySlotTemplate(c echo.Context) error
 	}
 
 	facilityHttpHandler struct {
@@ -104,36 +164,78 @@ func (h *facilityHttpHandler) FindManyFacility(c echo.Context) error {
 func (h *facilityHttpHandler) InsertSlot(c echo.Context) error {
 	facilityName := c.Param("facilityName")
 
-	var slotRequest struct {
-		StartTime       string `json:"start_time"`
-		EndTime         string `json:"end_time"`
-		MaxBookings     int    `json:"max_bookings"`
-		CurrentBookings int    `json:"current_bookings"`
-		FacilityType    string `json:"facility_type"`
-	}
-
-	if err := c.Bind(&slotRequest); err != nil {
+	req := new(facility.CreateSlotRequest)
+	if err := c.Bind(req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid input: "+err.Error())
 	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
 
 	ctx := c.Request().Context()
 
-	slot, err := h.facilityUsecase.InsertSlot(
-		ctx,
-		slotRequest.StartTime,
-		slotRequest.EndTime,
-		facilityName, 
-		slotRequest.MaxBookings,
-		slotRequest.CurrentBookings,
-		slotRequest.FacilityType,
-	)
+	slot, err := h.facilityUsecase.InsertSlot(ctx, facilityName, req)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return slotErrResponse(c, err)
 	}
 
 	return c.JSON(http.StatusOK, slot)
 }
 
+func (h *facilityHttpHandler) UpdateSlot(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	facilityName := c.Param("facilityName")
+	slotId := c.Param("slot_id")
+
+	req := new(facility.UpdateSlotRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+
+	slot, err := h.facilityUsecase.UpdateSlotDetails(ctx, facilityName, slotId, req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, slot)
+}
+
+func (h *facilityHttpHandler) EnableOrDisableSlot(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	facilityName := c.Param("facilityName")
+	slotId := c.Param("slot_id")
+
+	req := new(facility.UpdateSlotStatusRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	slot, err := h.facilityUsecase.EnableOrDisableSlot(ctx, facilityName, slotId, req.Status)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, slot)
+}
+
+func (h *facilityHttpHandler) DeleteSlot(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	facilityName := c.Param("facilityName")
+	slotId := c.Param("slot_id")
+
+	if err := h.facilityUsecase.DeleteSlot(ctx, facilityName, slotId); err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, &response.MsgResponse{Message: "Slot deleted"})
+}
+
 
 func (h *facilityHttpHandler) FindOneSlot (c echo.Context) error {
 
@@ -175,59 +277,629 @@ func (h *facilityHttpHandler) FindAllSlots(c echo.Context) error {
 	return response.SuccessResponse(c, http.StatusOK, slots)
 }
 
-func (h *facilityHttpHandler) InsertBadCourt ( c echo.Context) error {
+func (h *facilityHttpHandler) InsertResource(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var court facility.BadmintonCourt
-	if err := c.Bind(&court); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request payload")
+	req := new(facility.ResourceRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
 	}
 
-	courtId, err := h.facilityUsecase.InsertBadCourt(ctx, &court)
+	resource, err := h.facilityUsecase.InsertResource(ctx, c.Param("facilityName"), req)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return slotErrResponse(c, err)
 	}
 
-	return c.JSON(http.StatusCreated, echo.Map{"court_id": courtId})
+	return response.SuccessResponse(c, http.StatusCreated, resource)
 }
 
-func (h *facilityHttpHandler) FindCourt(c echo.Context) error {
+func (h *facilityHttpHandler) FindManyResource(c echo.Context) error {
 	ctx := c.Request().Context()
 
-	var court []facility.BadmintonCourt // Change the variable type to a slice
+	resources, err := h.facilityUsecase.FindManyResource(ctx, c.Param("facilityName"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, resources)
+}
+
+func (h *facilityHttpHandler) UpdateResource(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.ResourceRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
 
-	court, err := h.facilityUsecase.FindBadCourt(ctx)
+	resource, err := h.facilityUsecase.UpdateResource(ctx, c.Param("facilityName"), c.Param("resource_id"), req)
 	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, resource)
+}
+
+func (h *facilityHttpHandler) DeleteResource(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if err := h.facilityUsecase.DeleteResource(ctx, c.Param("facilityName"), c.Param("resource_id")); err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, &response.MsgResponse{Message: "Resource deleted"})
+}
+
+func (h *facilityHttpHandler) CreateMaintenance(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.CreateMaintenanceRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
 		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
 	}
 
-	return response.SuccessResponse(c, http.StatusOK, court)
+	createdBy, _ := c.Get("user_id").(string)
+	task, err := h.facilityUsecase.CreateMaintenance(ctx, c.Param("facilityName"), createdBy, req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusCreated, task)
+}
+
+func (h *facilityHttpHandler) FindManyMaintenance(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	includeDone := c.QueryParam("include_done") == "true"
+	tasks, err := h.facilityUsecase.FindManyMaintenance(ctx, c.Param("facilityName"), includeDone)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, tasks)
 }
 
-func (h *facilityHttpHandler) InsertBadmintonSlot ( c echo.Context) error {
-    ctx := c.Request().Context()
+func (h *facilityHttpHandler) UpdateMaintenanceStatus(c echo.Context) error {
+	ctx := c.Request().Context()
 
-	var slot facility.BadmintonSlot
-	if err := c.Bind(&slot); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request payload")
+	req := new(facility.UpdateMaintenanceStatusRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
 	}
 
-	slotId, err := h.facilityUsecase.InsertBadmintonSlot(ctx, &slot)
+	task, err := h.facilityUsecase.UpdateMaintenanceStatus(ctx, c.Param("facilityName"), c.Param("maintenance_id"), req.Status)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return slotErrResponse(c, err)
 	}
 
-	return c.JSON(http.StatusCreated, echo.Map{"slot_id": slotId})
+	return response.SuccessResponse(c, http.StatusOK, task)
 }
 
-func (h *facilityHttpHandler) FindBadmintonSlot(c echo.Context) error {
+func (h *facilityHttpHandler) DeleteMaintenance(c echo.Context) error {
 	ctx := c.Request().Context()
 
+	if err := h.facilityUsecase.DeleteMaintenance(ctx, c.Param("facilityName"), c.Param("maintenance_id")); err != nil {
+		return slotErrResponse(c, err)
+	}
 
-	slot, err := h.facilityUsecase.FindBadmintonSlot(ctx)
+	return response.SuccessResponse(c, http.StatusOK, &response.MsgResponse{Message: "Maintenance task deleted"})
+}
+
+func (h *facilityHttpHandler) SetCapacityOverride(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.CapacityOverrideRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	createdBy, _ := c.Get("user_id").(string)
+	result, err := h.facilityUsecase.SetCapacityOverride(ctx, c.Param("facilityName"), createdBy, req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, result)
+}
+
+// FindManyCapacityOverride lists overrides, ?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *facilityHttpHandler) FindManyCapacityOverride(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	overrides, err := h.facilityUsecase.FindManyCapacityOverride(ctx, c.Param("facilityName"), c.QueryParam("from"), c.QueryParam("to"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, overrides)
+}
+
+func (h *facilityHttpHandler) DeleteCapacityOverride(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if err := h.facilityUsecase.DeleteCapacityOverride(ctx, c.Param("facilityName"), c.Param("override_id")); err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, &response.MsgResponse{Message: "Capacity override deleted"})
+}
+
+// FindCapacityReport lists slots booked past their capacity, ?date=YYYY-MM-DD defaulting to today
+func (h *facilityHttpHandler) FindCapacityReport(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	date := c.QueryParam("date")
+	if date == "" {
+		date = utils.LocalTime().Format(facility.DateLayout)
+	}
+
+	report, err := h.facilityUsecase.FindCapacityReport(ctx, c.Param("facilityName"), date)
 	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, report)
+}
+
+func (h *facilityHttpHandler) UpdateOpeningHours(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.OpeningHoursRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
 		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
 	}
 
-	return response.SuccessResponse(c, http.StatusOK, slot)
-}
\ No newline at end of file
+	result, err := h.facilityUsecase.UpdateOpeningHours(ctx, c.Param("facilityName"), req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, result)
+}
+
+// FindFacilityCalendar resolves opening per day, ?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *facilityHttpHandler) FindFacilityCalendar(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	days, err := h.facilityUsecase.FindFacilityCalendar(ctx, c.Param("facilityName"), c.QueryParam("from"), c.QueryParam("to"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, days)
+}
+
+func (h *facilityHttpHandler) AddSpecialDay(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.SpecialDayRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	day, err := h.facilityUsecase.AddSpecialDay(ctx, c.Param("facilityName"), req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusCreated, day)
+}
+
+func (h *facilityHttpHandler) DeleteSpecialDay(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if err := h.facilityUsecase.DeleteSpecialDay(ctx, c.Param("facilityName"), c.Param("date")); err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, &response.MsgResponse{Message: "Special day deleted"})
+}
+
+func (h *facilityHttpHandler) AddHoliday(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.HolidayRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	day, err := h.facilityUsecase.AddHoliday(ctx, req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusCreated, day)
+}
+
+// ImportHolidays accepts an .ics file as the multipart "file" field or as the raw body
+func (h *facilityHttpHandler) ImportHolidays(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	body := io.Reader(c.Request().Body)
+	if file, err := c.FormFile("file"); err == nil {
+		src, err := file.Open()
+		if err != nil {
+			return response.ErrResponse(c, http.StatusBadRequest, "Invalid iCal file")
+		}
+		defer src.Close()
+		body = src
+	}
+
+	result, err := h.facilityUsecase.ImportHolidays(ctx, io.LimitReader(body, maxICalSize))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusCreated, result)
+}
+
+// FindManyHoliday lists public holidays, ?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *facilityHttpHandler) FindManyHoliday(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	days, err := h.facilityUsecase.FindManyHoliday(ctx, c.QueryParam("from"), c.QueryParam("to"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, days)
+}
+
+func (h *facilityHttpHandler) DeleteHoliday(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if err := h.facilityUsecase.DeleteHoliday(ctx, c.Param("date")); err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, &response.MsgResponse{Message: "Holiday deleted"})
+}
+
+// SearchAvailability lists bookable slots across facilities,
+// ?date=YYYY-MM-DD&from=HH:mm&to=HH:mm&type=gym&max_price=100&party_size=2&user_type=insider
+func (h *facilityHttpHandler) SearchAvailability(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.AvailabilitySearchRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid search query")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	slots, err := h.facilityUsecase.SearchAvailability(ctx, req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, slots)
+}
+
+func (h *facilityHttpHandler) PublishWaiver(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.PublishWaiverRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	publishedBy, _ := c.Get("user_id").(string)
+	waiver, err := h.facilityUsecase.PublishWaiver(ctx, c.Param("facilityName"), publishedBy, req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusCreated, waiver)
+}
+
+func (h *facilityHttpHandler) FindCurrentWaiver(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	waiver, err := h.facilityUsecase.FindCurrentWaiver(ctx, c.Param("facilityName"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, waiver)
+}
+
+func (h *facilityHttpHandler) FindManyWaiver(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	waivers, err := h.facilityUsecase.FindManyWaiver(ctx, c.Param("facilityName"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, waivers)
+}
+
+func (h *facilityHttpHandler) FindWaiverStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	userId, _ := c.Get("user_id").(string)
+	status, err := h.facilityUsecase.FindWaiverStatus(ctx, c.Param("facilityName"), userId)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, status)
+}
+
+func (h *facilityHttpHandler) AcceptWaiver(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.AcceptWaiverRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	userId, _ := c.Get("user_id").(string)
+	acceptance, err := h.facilityUsecase.AcceptWaiver(ctx, c.Param("facilityName"), userId, c.RealIP(), req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, acceptance)
+}
+
+// ExportWaiverAcceptances lists who accepted which version, ?version=N&format=csv
+func (h *facilityHttpHandler) ExportWaiverAcceptances(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	version := 0
+	if v := c.QueryParam("version"); v != "" {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil || version < 0 {
+			return response.ErrResponse(c, http.StatusBadRequest, "Invalid waiver version")
+		}
+	}
+
+	facilityName := c.Param("facilityName")
+	acceptances, err := h.facilityUsecase.FindManyWaiverAcceptance(ctx, facilityName, version)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	if c.QueryParam("format") != "csv" {
+		return response.SuccessResponse(c, http.StatusOK, acceptances)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{
+		"filename": facilityName + "-waiver-acceptances.csv",
+	}))
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+	w.Write([]string{"user_id", "version", "waiver_id", "accepted_at", "ip_address"})
+	for _, a := range acceptances {
+		w.Write([]string{a.UserId, strconv.Itoa(a.Version), a.WaiverId.Hex(), a.AcceptedAt.Format(time.RFC3339), a.IpAddress})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// UploadMedia takes a multipart form with the image in "file", plus "kind" and "caption"
+func (h *facilityHttpHandler) UploadMedia(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.UploadMediaRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Missing media file")
+	}
+	if file.Size > facility.MaxMediaSize {
+		return response.ErrResponse(c, http.StatusRequestEntityTooLarge, facility.ErrMediaTooLarge.Error())
+	}
+	src, err := file.Open()
+	if err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid media file")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, facility.MaxMediaSize+1))
+	if err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid media file")
+	}
+
+	uploadedBy, _ := c.Get("user_id").(string)
+	image, err := h.facilityUsecase.UploadMedia(ctx, c.Param("facilityName"), uploadedBy, req, data)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusCreated, image)
+}
+
+func (h *facilityHttpHandler) FindManyMedia(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	images, err := h.facilityUsecase.FindManyMedia(ctx, c.Param("facilityName"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, images)
+}
+
+func (h *facilityHttpHandler) ReorderMedia(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.ReorderMediaRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	images, err := h.facilityUsecase.ReorderMedia(ctx, c.Param("facilityName"), req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, images)
+}
+
+func (h *facilityHttpHandler) DeleteMedia(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	if err := h.facilityUsecase.DeleteMedia(ctx, c.Param("facilityName"), c.Param("media_id")); err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, &response.MsgResponse{Message: "Media deleted"})
+}
+
+// ServeMedia streams a stored file by its blob key
+func (h *facilityHttpHandler) ServeMedia(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	key := c.Param("*")
+	blob, err := h.facilityUsecase.OpenMedia(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			return response.ErrResponse(c, http.StatusNotFound, facility.ErrMediaNotFound.Error())
+		}
+		return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+	}
+	defer blob.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = echo.MIMEOctetStream
+	}
+	// Keys embed the media id, so a stored file never changes
+	c.Response().Header().Set("Cache-Control", "public, max-age=86400")
+	c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	return c.Stream(http.StatusOK, contentType, blob)
+}
+
+func (h *facilityHttpHandler) SaveSlotTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	req := new(facility.SlotTemplateRequest)
+	if err := c.Bind(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, "Invalid request payload")
+	}
+	if err := c.Validate(req); err != nil {
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	}
+
+	template, err := h.facilityUsecase.SaveSlotTemplate(ctx, c.Param("facilityName"), req)
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, template)
+}
+
+func (h *facilityHttpHandler) FindSlotTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	template, err := h.facilityUsecase.FindSlotTemplate(ctx, c.Param("facilityName"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, template)
+}
+
+func (h *facilityHttpHandler) PreviewSlotTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	diff, err := h.facilityUsecase.PreviewSlotTemplate(ctx, c.Param("facilityName"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, diff)
+}
+
+func (h *facilityHttpHandler) ApplySlotTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	diff, err := h.facilityUsecase.ApplySlotTemplate(ctx, c.Param("facilityName"))
+	if err != nil {
+		return slotErrResponse(c, err)
+	}
+
+	return response.SuccessResponse(c, http.StatusOK, diff)
+}
+
+// slotErrResponse maps typed slot and resource errors to their HTTP status
+func slotErrResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, facility.ErrSlotNotFound),
+		errors.Is(err, facility.ErrResourceNotFound),
+		errors.Is(err, facility.ErrTemplateNotFound),
+		errors.Is(err, facility.ErrMaintenanceNotFound),
+		errors.Is(err, facility.ErrCalendarDayNotFound),
+		errors.Is(err, facility.ErrMediaNotFound),
+		errors.Is(err, facility.ErrOverrideNotFound),
+		errors.Is(err, facility.ErrWaiverNotFound):
+		return response.ErrResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, facility.ErrInvalidTimeRange),
+		errors.Is(err, facility.ErrInvalidTimeFormat),
+		errors.Is(err, facility.ErrInvalidCapacity),
+		errors.Is(err, facility.ErrInvalidTemplate),
+		errors.Is(err, facility.ErrInvalidMaintenance),
+		errors.Is(err, facility.ErrInvalidDate),
+		errors.Is(err, facility.ErrInvalidOpeningHours),
+		errors.Is(err, facility.ErrOutsideOpeningHours),
+		errors.Is(err, facility.ErrInvalidICal),
+		errors.Is(err, facility.ErrInvalidMediaType),
+		errors.Is(err, facility.ErrInvalidMediaOrder),
+		errors.Is(err, facility.ErrInvalidOverride):
+		return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, facility.ErrSlotOverlap),
+		errors.Is(err, facility.ErrSlotHasBookings),
+		errors.Is(err, facility.ErrCapacityBelowBookings),
+		errors.Is(err, facility.ErrResourceHasBookings),
+		errors.Is(err, facility.ErrWaiverOutdated):
+		return response.ErrResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, facility.ErrMediaTooLarge):
+		return response.ErrResponse(c, http.StatusRequestEntityTooLarge, err.Error())
+	default:
+		return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,82 @@
+package facility
+
+import "time"
+
+// NextMaintenanceWindow returns the first occurrence of the task that is still
+// running or upcoming at from. ok is false once a task has no more occurrences.
+func NextMaintenanceWindow(task *MaintenanceTask, from time.Time) (start, end time.Time, ok bool) {
+	start, end = task.StartAt, task.EndAt
+	if end.After(from) {
+		return start, end, true
+	}
+	if task.Recurrence == RecurrenceNone {
+		return time.Time{}, time.Time{}, false
+	}
+
+	// Jump close to from for fixed periods instead of stepping one by one
+	switch task.Recurrence {
+	case RecurrenceDaily, RecurrenceWeekly:
+		period := 24 * time.Hour
+		if task.Recurrence == RecurrenceWeekly {
+			period = 7 * 24 * time.Hour
+		}
+		days := int(from.Sub(end)/period) * int(period/(24*time.Hour))
+		start, end = start.AddDate(0, 0, days), end.AddDate(0, 0, days)
+	}
+
+	for i := 0; !end.After(from); i++ {
+		start, end = nextRecurrence(task.Recurrence, start), nextRecurrence(task.Recurrence, end)
+		if i > 1000 {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	if task.RecurUntil != nil && start.After(*task.RecurUntil) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// MaintenanceOverlaps reports whether any occurrence of an unfinished task
+// overlaps the [start, end) range
+func MaintenanceOverlaps(task *MaintenanceTask, start, end time.Time) bool {
+	if task.Status == MaintenanceDone {
+		return false
+	}
+	windowStart, _, ok := NextMaintenanceWindow(task, start)
+	return ok && windowStart.Before(end)
+}
+
+func nextRecurrence(recurrence string, t time.Time) time.Time {
+	switch recurrence {
+	case RecurrenceDaily:
+		return t.AddDate(0, 0, 1)
+	case RecurrenceWeekly:
+		return t.AddDate(0, 0, 7)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+// SlotWindowOn places a slot's time range on the calendar day of day in the
+// facilities' time zone
+func SlotWindowOn(slot *Slot, day time.Time) (start, end time.Time, err error) {
+	if err := slot.TimeRange.Validate(); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start, end = slot.TimeRange.On(day)
+	return start, end, nil
+}
+
+// MaintenanceBlocksSlot reports whether the task covers the slot's window on day.
+// Facility-wide tasks block every slot; resource tasks only that resource's slots.
+func MaintenanceBlocksSlot(task *MaintenanceTask, slot *Slot, day time.Time) bool {
+	if !task.ResourceId.IsZero() && task.ResourceId != slot.ResourceId {
+		return false
+	}
+	start, end, err := SlotWindowOn(slot, day)
+	if err != nil {
+		return false
+	}
+	return MaintenanceOverlaps(task, start, end)
+}
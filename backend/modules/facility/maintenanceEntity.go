@@ -0,0 +1,38 @@
+package facility
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MaintenancePlanned    = "planned"
+	MaintenanceInProgress = "in_progress"
+	MaintenanceDone       = "done"
+
+	RecurrenceNone    = ""
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+type (
+	// MaintenanceTask is a planned closure of a whole facility or of one resource.
+	// Recurring tasks repeat their StartAt-EndAt window until RecurUntil.
+	MaintenanceTask struct {
+		Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		FacilityName string             `bson:"facility_name" json:"facility_name"`
+		ResourceId   primitive.ObjectID `bson:"resource_id,omitempty" json:"resource_id,omitempty"` // Empty closes the whole facility
+		Title        string             `bson:"title" json:"title"`
+		Description  string             `bson:"description" json:"description"`
+		StartAt      time.Time          `bson:"start_at" json:"start_at"`
+		EndAt        time.Time          `bson:"end_at" json:"end_at"`
+		Recurrence   string             `bson:"recurrence,omitempty" json:"recurrence,omitempty"` // "", "daily", "weekly" or "monthly"
+		RecurUntil   *time.Time         `bson:"recur_until,omitempty" json:"recur_until,omitempty"`
+		Status       string             `bson:"status" json:"status"`
+		CreatedBy    string             `bson:"created_by" json:"created_by"`
+		CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+		UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	}
+)
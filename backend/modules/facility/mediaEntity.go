@@ -0,0 +1,56 @@
+package facility
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MediaPhoto        = "photo"
+	MediaFloorMap     = "floor_map"
+	MediaCourtDiagram = "court_diagram"
+
+	// MaxMediaSize is the largest accepted upload, below the server body limit
+	MaxMediaSize = 8 << 20
+)
+
+// ThumbnailSizes maps each generated thumbnail to its longest side in pixels
+var ThumbnailSizes = map[string]int{
+	"small":  160,
+	"medium": 480,
+	"large":  1024,
+}
+
+type (
+	// FacilityMedia is an uploaded image stored in the registry's media
+	// collection; Key and Thumbnails are blob store keys, not URLs
+	FacilityMedia struct {
+		Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		FacilityName string             `bson:"facility_name" json:"facility_name"`
+		Kind         string             `bson:"kind" json:"kind"`
+		Caption      string             `bson:"caption" json:"caption"`
+		ContentType  string             `bson:"content_type" json:"content_type"`
+		Size         int64              `bson:"size" json:"size"`
+		Width        int                `bson:"width" json:"width"`
+		Height       int                `bson:"height" json:"height"`
+		Key          string             `bson:"key" json:"key"`
+		Thumbnails   map[string]string  `bson:"thumbnails" json:"thumbnails"`
+		Position     int                `bson:"position" json:"position"`
+		UploadedBy   string             `bson:"uploaded_by" json:"uploaded_by"`
+		CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+		UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	}
+
+	// FacilityImage is a media item as returned to clients, with resolved URLs
+	FacilityImage struct {
+		Id         string            `json:"id"`
+		Kind       string            `json:"kind"`
+		Caption    string            `json:"caption"`
+		Url        string            `json:"url"`
+		Thumbnails map[string]string `json:"thumbnails"`
+		Width      int               `json:"width"`
+		Height     int               `json:"height"`
+		Position   int               `json:"position"`
+	}
+)
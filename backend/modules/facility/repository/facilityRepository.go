@@ -7,7 +7,6 @@ import (
 	"log"
 	"main/modules/facility"
 	"main/pkg/utils"
-	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -26,24 +25,61 @@ type (
 		FindOneFacility(pctx context.Context, facilityId,facilityName string) (*facility.FacilityBson, error)
 		FindManyFacility(ctx context.Context) ([]facility.FacilityBson, error)
 		DeleteOneFacility(pctx context.Context, facilityId, facilityName string) error
+		UpdateOpeningHours(ctx context.Context, facilityName string, hours []facility.OpeningHour) error
 
 		//Slot
 		InsertSlot (pctx context.Context, facilityName string, slot facility.Slot) (*facility.Slot, error)
 		FindOneSlot (ctx context.Context, facilityName,slotId string) (*facility.Slot, error)
 		FindManySlot (ctx context.Context, facilityName string) ([]facility.Slot, error)
+		FindOverlappingSlots(ctx context.Context, facilityName string, resourceId primitive.ObjectID, timeRange utils.TimeRange) ([]facility.Slot, error)
 		UpdateSlot (ctx context.Context, facilityName string, req *facility.Slot) (*facility.Slot, error)
+		UpdateSlotBookings(ctx context.Context, facilityName string, slotId primitive.ObjectID, increment, capacity int) error
 		EnableOrDisableSlot (ctx context.Context, facilityName, slotId string, status int) (*facility.Slot, error)
 		DeleteSlot(ctx context.Context, facilityName, slotId string) error
 
-		//Badminton
-		InsertBadCourt(ctx context.Context, court *facility.BadmintonCourt) (primitive.ObjectID, error)
-		FindBadmintonCourt (ctx context.Context) ([]facility.BadmintonCourt, error)
-		InsertBadmintonSlot(ctx context.Context, req *facility.BadmintonSlot) (primitive.ObjectID, error)
-		FindBadmintonSlot (ctx context.Context) ([]facility.BadmintonSlot, error)
-		UpdateBadmintonSlot(ctx context.Context, req *facility.BadmintonSlot) error
-		UpdateBadCourt(ctx context.Context, courtId string, updateFields bson.M) error
-		DeleteBadmintonCourt(ctx context.Context, courtId string) error
-		DeleteBadmintonSlot(ctx context.Context, slotId string) error
+		//Resource
+		InsertResource(ctx context.Context, resource *facility.Resource) (primitive.ObjectID, error)
+		FindManyResource(ctx context.Context, facilityName string) ([]facility.Resource, error)
+		FindOneResource(ctx context.Context, facilityName, resourceId string) (*facility.Resource, error)
+		UpdateResource(ctx context.Context, resource *facility.Resource) error
+		DeleteResource(ctx context.Context, facilityName, resourceId string) error
+		DeleteSlotsByResource(ctx context.Context, facilityName string, resourceId primitive.ObjectID) error
+
+		//Maintenance
+		InsertMaintenance(ctx context.Context, task *facility.MaintenanceTask) (primitive.ObjectID, error)
+		FindManyMaintenance(ctx context.Context, facilityName string, includeDone bool) ([]facility.MaintenanceTask, error)
+		FindOneMaintenance(ctx context.Context, facilityName, taskId string) (*facility.MaintenanceTask, error)
+		UpdateMaintenanceStatus(ctx context.Context, facilityName, taskId, status string) error
+		DeleteMaintenance(ctx context.Context, facilityName, taskId string) error
+
+		//Capacity override
+		UpsertCapacityOverride(ctx context.Context, override *facility.CapacityOverride) (*facility.CapacityOverride, error)
+		FindManyCapacityOverride(ctx context.Context, facilityName, from, to string) ([]facility.CapacityOverride, error)
+		DeleteCapacityOverride(ctx context.Context, facilityName, overrideId string) error
+		FindManySlotBooking(ctx context.Context, slotIds []primitive.ObjectID) ([]facility.SlotBooking, error)
+
+		//Waiver
+		InsertWaiver(ctx context.Context, waiver *facility.Waiver) (primitive.ObjectID, error)
+		FindLatestWaiver(ctx context.Context, facilityName string) (*facility.Waiver, error)
+		FindManyWaiver(ctx context.Context, facilityName string) ([]facility.Waiver, error)
+		UpsertWaiverAcceptance(ctx context.Context, acceptance *facility.WaiverAcceptance) (*facility.WaiverAcceptance, error)
+		FindManyWaiverAcceptance(ctx context.Context, facilityName, userId string, version int) ([]facility.WaiverAcceptance, error)
+
+		//Calendar
+		UpsertCalendarDay(ctx context.Context, day *facility.CalendarDay) (*facility.CalendarDay, error)
+		FindManyCalendarDay(ctx context.Context, facilityName, kind, from, to string) ([]facility.CalendarDay, error)
+		DeleteCalendarDay(ctx context.Context, facilityName, kind, date string) error
+
+		//Media
+		InsertMedia(ctx context.Context, media *facility.FacilityMedia) (primitive.ObjectID, error)
+		FindManyMedia(ctx context.Context, facilityName string) ([]facility.FacilityMedia, error)
+		FindOneMedia(ctx context.Context, facilityName, mediaId string) (*facility.FacilityMedia, error)
+		UpdateMediaPositions(ctx context.Context, facilityName string, mediaIds []primitive.ObjectID) error
+		DeleteMedia(ctx context.Context, facilityName, mediaId string) error
+
+		//Slot template
+		UpsertSlotTemplate(ctx context.Context, template *facility.SlotTemplate) (*facility.SlotTemplate, error)
+		FindSlotTemplate(ctx context.Context, facilityName string) (*facility.SlotTemplate, error)
 	}
 
 	facilitiyReposiory struct {
@@ -56,49 +92,36 @@ func NewFacilityRepository(client *mongo.Client) *facilitiyReposiory {
 	return &facilitiyReposiory{client: client}
 }
 
-func (r *facilitiyReposiory) facilityDbConn(pctx context.Context, facilityName string) *mongo.Database {
-	// Use the facility name to dynamically create the database name
-	databaseName := fmt.Sprintf("%s_facility", facilityName)
-	return r.client.Database(databaseName) // This will create the DB if it doesn't exist
-}
-
-func (r *facilitiyReposiory) slotDbConn(pctx context.Context,facilityName string) *mongo.Database {
-	// Use the existing client to connect to the facility database
-	databaseName := fmt.Sprintf("%s_facility", facilityName) // Consistent naming
-	return r.client.Database(databaseName) // Connect to the existing database
-}
-
-func (r *facilitiyReposiory) courtDbConn(pctx context.Context) *mongo.Database {
-	// Use the existing client to connect to the facility database
-	databaseName := fmt.Sprintf("badminton_facility") // Consistent naming
-	return r.client.Database(databaseName) // Connect to the existing database
+func (r *facilitiyReposiory) facilityDbConn(pctx context.Context) *mongo.Database {
+	// Every facility is resolved from the shared registry database
+	return r.client.Database(facility.RegistryDb)
 }
 
 func (r *facilitiyReposiory) ListAllFacilities(pctx context.Context) ([]string, error) {
 	ctx, cancel := context.WithTimeout(pctx, 10*time.Second)
     defer cancel()
 
-	dbs, err := r.client.ListDatabaseNames(ctx, bson.M{})
+	names, err := r.facilityDbConn(ctx).Collection("facilities").Distinct(ctx, "name", bson.M{})
 	if err != nil {
 		log.Printf("Error: ListAllFacilities: %s", err.Error())
 		return nil, fmt.Errorf("error: list all facilities failed: %w", err)
 	}
 
-	var facilityDbs []string
-    for _, dbName := range dbs {
-        if strings.HasSuffix(dbName, "_facility") {
-            facilityDbs = append(facilityDbs, dbName)
+	var facilityNames []string
+    for _, name := range names {
+        if n, ok := name.(string); ok {
+            facilityNames = append(facilityNames, n)
         }
     }
 
-    return facilityDbs, nil
+    return facilityNames, nil
 }
 
 func (r *facilitiyReposiory) InsertFacility (pctx context.Context, req * facility.Facilitiy) (primitive.ObjectID, error) {
 	ctx, cancel := context.WithTimeout(pctx, 10*time.Second)
 	defer cancel()
 
-	db := r.facilityDbConn(ctx, req.Name)
+	db := r.facilityDbConn(ctx)
 	col := db.Collection("facilities")
 	
 	result, err := col.InsertOne(ctx, req)
@@ -120,7 +143,7 @@ func (r *facilitiyReposiory) IsUniqueName(pctx context.Context, facilityName str
 	ctx, cancel := context.WithTimeout(pctx, 10*time.Second)
 	defer cancel()
 
-	db := r.facilityDbConn(ctx, facilityName) // Pass the facility name
+	db := r.facilityDbConn(ctx)
 	col := db.Collection("facilities")
 
 	nameCount, err := col.CountDocuments(ctx, bson.M{"name": facilityName})
@@ -139,7 +162,7 @@ func (r *facilitiyReposiory) UpdateOneFacility(pctx context.Context, facilityId,
     ctx, cancel := context.WithTimeout(pctx, 10*time.Second)
     defer cancel()
 
-    db := r.facilityDbConn(ctx, facilityName)
+    db := r.facilityDbConn(ctx)
     col := db.Collection("facilities")
 
     updateResult, err := col.UpdateOne(
@@ -164,7 +187,7 @@ func (r *facilitiyReposiory) DeleteOneFacility(pctx context.Context, facilityId,
 	ctx, cancel := context.WithTimeout(pctx, 10*time.Second)
 	defer cancel()
 
-	db := r.facilityDbConn(ctx,facilityName)
+	db := r.facilityDbConn(ctx)
 	col := db.Collection("facilities")
 
 	result, err := col.DeleteOne(ctx, bson.M{"_id": utils.ConvertToObjectId(facilityId)})
@@ -186,13 +209,18 @@ func (r *facilitiyReposiory) FindOneFacility(pctx context.Context, facilityId, f
 	ctx, cancel := context.WithTimeout(pctx, 10*time.Second)
 	defer cancel()
 
-	db := r.facilityDbConn(ctx, facilityName)
+	db := r.facilityDbConn(ctx)
 	col := db.Collection("facilities")
 
+	filter := bson.M{"name": facilityName}
+	if facilityId != "" {
+		filter["_id"] = utils.ConvertToObjectId(facilityId)
+	}
+
 	result := new(facility.FacilityBson)
 	if err := col.FindOne(
 		ctx,
-		bson.M{"_id": utils.ConvertToObjectId(facilityId)},
+		filter,
 		options.FindOne().SetProjection(
 			bson.M{
 				"_id": 1,
@@ -200,6 +228,7 @@ func (r *facilitiyReposiory) FindOneFacility(pctx context.Context, facilityId, f
 				"price_insider": 1,
 				"price_outsider": 1,
 				"description": 1,
+				"opening_hours": 1,
 				"created_at": 1,
 				"updated_at": 1,
 			},
@@ -216,33 +245,19 @@ func (r *facilitiyReposiory) FindManyFacility(ctx context.Context) ([]facility.F
     ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
     defer cancel()
 
-    // List all databases
-    dbs, err := r.client.ListDatabaseNames(ctx, bson.M{})
+    col := r.facilityDbConn(ctx).Collection("facilities")
+
+    cur, err := col.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
     if err != nil {
-        return nil, fmt.Errorf("error: failed to list databases: %w", err)
+        log.Printf("Error: FindManyFacility: %s", err.Error())
+        return nil, fmt.Errorf("error: find many facility failed: %w", err)
     }
+    defer cur.Close(ctx)
 
     var allFacilities []facility.FacilityBson
-
-    // Loop through all databases to find facilities
-    for _, dbName := range dbs {
-        if strings.HasSuffix(dbName, "_facility") {
-            db := r.client.Database(dbName)
-            col := db.Collection("facilities")
-
-            var facilities []facility.FacilityBson
-            cur, err := col.Find(ctx, bson.M{})
-            if err != nil {
-                log.Printf("Error: FindAllFacilities: %s", err.Error())
-                continue
-            }
-            if err = cur.All(ctx, &facilities); err != nil {
-                log.Printf("Error: FindAllFacilities: %s", err.Error())
-                continue
-            }
-
-            allFacilities = append(allFacilities, facilities...)
-        }
+    if err = cur.All(ctx, &allFacilities); err != nil {
+        log.Printf("Error: FindManyFacility: %s", err.Error())
+        return nil, fmt.Errorf("error: find many facility failed: %w", err)
     }
 
     return allFacilities, nil
@@ -252,23 +267,31 @@ func (r *facilitiyReposiory) InsertSlot(pctx context.Context, facilityName strin
 	ctx, cancel := context.WithTimeout(pctx, 10*time.Second)
 	defer cancel()
 
-	// Use the slotDbConn to ensure we're connecting to an existing facility DB
-	db := r.slotDbConn(ctx, facilityName)
+	// Slots of every facility share one collection, keyed by facility_name
+	db := r.facilityDbConn(ctx)
 	col := db.Collection("slots") // Get the "slots" collection
 
 	slot.CreatedAt = time.Now()
 	slot.UpdatedAt = time.Now()
 
-	result, err := col.InsertOne(ctx, bson.M{
+	slot.FacilityName = facilityName
+	doc := bson.M{
+		"facility_name":   facilityName,
 		"start_time":      slot.StartTime,
 		"end_time":        slot.EndTime,
 		"status":          slot.Status,
 		"max_bookings":    slot.MaxBookings,
 		"current_bookings": slot.CurrentBookings,
 		"facility_type": slot.FacilityType,
+		"weekdays":        slot.Weekdays,
 		"created_at":      slot.CreatedAt,
 		"updated_at":      slot.UpdatedAt,
-	})
+	}
+	if !slot.ResourceId.IsZero() {
+		doc["resource_id"] = slot.ResourceId
+	}
+
+	result, err := col.InsertOne(ctx, doc)
 	if err != nil {
 		log.Printf("Error: InsertSlot: %s", err.Error())
 		return nil, fmt.Errorf("error: insert slot failed: %w", err)
@@ -288,7 +311,7 @@ func (r *facilitiyReposiory) FindOneSlot (ctx context.Context, facilityName,slot
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	db := r.facilityDbConn(ctx, facilityName)
+	db := r.facilityDbConn(ctx)
 	col := db.Collection("slots")
 
 	id, err := primitive.ObjectIDFromHex(slotId)
@@ -297,37 +320,33 @@ func (r *facilitiyReposiory) FindOneSlot (ctx context.Context, facilityName,slot
         return nil, fmt.Errorf("error: find one slot failed: %w", err)
 	}
 
-	var result bson.M
-	err = col.FindOne(ctx, bson.M{"_id": id}).Decode(&result)
-	if err != nil {
+	slot := new(facility.Slot)
+	if err := col.FindOne(ctx, bson.M{"_id": id, "facility_name": facilityName}).Decode(slot); err != nil {
 	    if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("error: slot %s doesn't exist", slotId)
+			return nil, fmt.Errorf("%w: %s", facility.ErrSlotNotFound, slotId)
 		}
 		log.Printf("Error: FindOneSlot: %s", err.Error())
         return nil, fmt.Errorf("error: find one slot failed: %w", err)
 	}
 
-	slot := &facility.Slot{
-		Id: id,
-		StartTime: result["start_time"].(string),
-		EndTime: result["end_time"].(string),
-		Status: result["status"].(int),
-		MaxBookings: result["max_bookings"].(int),
-		CurrentBookings: result["current_bookings"].(int),
-		CreatedAt: result["created_at"].(time.Time),
-		UpdatedAt: result["updated_at"].(time.Time),
-	}
 	return slot, nil
 }
 
+// FindManySlot lists a facility's slots; an empty facility name lists the
+// slots of every facility
 func (r *facilitiyReposiory) FindManySlot (ctx context.Context, facilityName string) ([]facility.Slot, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	db := r.facilityDbConn(ctx, facilityName)
+	db := r.facilityDbConn(ctx)
 	col := db.Collection("slots")
 
-	cur, err := col.Find(ctx, bson.M{})
+	filter := bson.M{}
+	if facilityName != "" {
+		filter["facility_name"] = facilityName
+	}
+
+	cur, err := col.Find(ctx, filter)
 	if err != nil {
 		log.Printf("Error: FindManySlot: %s", err.Error())
         return nil, fmt.Errorf("error: find many slot failed: %w", err)
@@ -347,280 +366,961 @@ func (r *facilitiyReposiory) FindManySlot (ctx context.Context, facilityName str
 	return result, nil
 }
 
+// FindOverlappingSlots lists the facility's slots on the same resource whose
+// time range overlaps r. Stored times are normalised "HH:mm", so comparing them
+// as strings orders them like times; weekdays are left to the caller.
+func (r *facilitiyReposiory) FindOverlappingSlots(ctx context.Context, facilityName string, resourceId primitive.ObjectID, timeRange utils.TimeRange) ([]facility.Slot, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := r.facilityDbConn(ctx)
+	col := db.Collection("slots")
+
+	filter := bson.M{
+		"facility_name": facilityName,
+		"start_time":    bson.M{"$lt": timeRange.EndTime.String()},
+		"end_time":      bson.M{"$gt": timeRange.StartTime.String()},
+	}
+	if resourceId.IsZero() {
+		// Slots without a resource have no resource_id field
+		filter["resource_id"] = bson.M{"$in": bson.A{nil, primitive.NilObjectID}}
+	} else {
+		filter["resource_id"] = resourceId
+	}
+
+	cur, err := col.Find(ctx, filter)
+	if err != nil {
+		log.Printf("Error: FindOverlappingSlots: %s", err.Error())
+		return nil, fmt.Errorf("error: find overlapping slots failed: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	result := make([]facility.Slot, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindOverlappingSlots: %s", err.Error())
+		return nil, fmt.Errorf("error: find overlapping slots failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// UpdateSlot saves a slot's times and capacity. The booking count is left to
+// UpdateSlotBookings, and the capacity is only lowered if the bookings the
+// slot holds now still fit.
 func (r *facilitiyReposiory) UpdateSlot(ctx context.Context, facilityName string, slot *facility.Slot) (*facility.Slot, error) {
     ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
     defer cancel()
 
-    db := r.slotDbConn(ctx, facilityName)
+    db := r.facilityDbConn(ctx)
     col := db.Collection("slots")
 
     update := bson.M{
         "$set": bson.M{
-            "current_bookings": slot.CurrentBookings,
-            "updated_at":      slot.UpdatedAt,
+            "start_time":       slot.StartTime,
+            "end_time":         slot.EndTime,
+            "status":           slot.Status,
+            "max_bookings":     slot.MaxBookings,
+            "weekdays":         slot.Weekdays,
+            "updated_at":       slot.UpdatedAt,
         },
     }
 
-    _, err := col.UpdateOne(ctx, bson.M{"_id": slot.Id}, update)
+    filter := bson.M{"_id": slot.Id, "facility_name": facilityName, "current_bookings": bson.M{"$lte": slot.MaxBookings}}
+    result, err := col.UpdateOne(ctx, filter, update)
     if err != nil {
         log.Printf("Error updating slot: %v", err)
         return nil, fmt.Errorf("failed to update slot: %w", err)
     }
 
+    if result.MatchedCount == 0 {
+        count, err := col.CountDocuments(ctx, bson.M{"_id": slot.Id, "facility_name": facilityName})
+        if err != nil {
+            log.Printf("Error updating slot: %v", err)
+            return nil, fmt.Errorf("failed to update slot: %w", err)
+        }
+        if count > 0 {
+            return nil, facility.ErrCapacityBelowBookings
+        }
+        return nil, fmt.Errorf("%w: %s", facility.ErrSlotNotFound, slot.Id.Hex())
+    }
+
     return slot, nil
 }
 
+// UpdateSlotBookings adds increment to a slot's booking count in one update,
+// refusing to go past capacity and never going below zero
+func (r *facilitiyReposiory) UpdateSlotBookings(ctx context.Context, facilityName string, slotId primitive.ObjectID, increment, capacity int) error {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    col := r.facilityDbConn(ctx).Collection("slots")
+
+    filter := bson.M{"_id": slotId, "facility_name": facilityName}
+    if increment > 0 {
+        filter["current_bookings"] = bson.M{"$lte": capacity - increment}
+    }
+    update := bson.A{bson.M{"$set": bson.M{
+        "current_bookings": bson.M{"$max": bson.A{0, bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$current_bookings", 0}}, increment}}}},
+        "updated_at":       time.Now(),
+    }}}
+
+    result, err := col.UpdateOne(ctx, filter, update)
+    if err != nil {
+        log.Printf("Error: UpdateSlotBookings: %s", err.Error())
+        return fmt.Errorf("error: update slot bookings failed: %w", err)
+    }
+    if result.MatchedCount == 0 {
+        if increment > 0 {
+            return facility.ErrSlotFull
+        }
+        return fmt.Errorf("%w: %s", facility.ErrSlotNotFound, slotId.Hex())
+    }
+
+    return nil
+}
+
 
 func (r *facilitiyReposiory) EnableOrDisableSlot (ctx context.Context, facilityName, slotId string, status int) (*facility.Slot, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	db := r.facilityDbConn(ctx, facilityName)
+	db := r.facilityDbConn(ctx)
 	col := db.Collection("slots")
 
-	_, err := col.UpdateOne(ctx, bson.M{"_id": slotId}, bson.M{"$set": bson.M{"status": status}})
+	result, err := col.UpdateOne(
+		ctx,
+		bson.M{"_id": utils.ConvertToObjectId(slotId), "facility_name": facilityName},
+		bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}},
+	)
 	if err != nil {
 		log.Printf("Error: EnableOrDisableSlot: %s", err.Error())
         return nil, fmt.Errorf("error: update slot failed: %w", err)
 	}
+
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("%w: %s", facility.ErrSlotNotFound, slotId)
+	}
+
 	return r.FindOneSlot(ctx, facilityName, slotId)
 }
 
+// DeleteSlot deletes a slot only while it has no bookings; the check is part
+// of the delete so a booking made meanwhile is never orphaned
 func (r *facilitiyReposiory) DeleteSlot(ctx context.Context, facilityName, slotId string) error {
     ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
     defer cancel()
 
-    db := r.slotDbConn(ctx, facilityName)
+    db := r.facilityDbConn(ctx)
     col := db.Collection("slots")
 
-    result, err := col.DeleteOne(ctx, bson.M{"_id": utils.ConvertToObjectId(slotId)})
+    filter := bson.M{"_id": utils.ConvertToObjectId(slotId), "facility_name": facilityName}
+    result, err := col.DeleteOne(ctx, bson.M{"$and": bson.A{filter, unbookedSlot}})
     if err != nil {
         log.Printf("Error: DeleteSlot: %s", err.Error())
         return fmt.Errorf("error: delete slot failed: %w", err)
     }
 
     if result.DeletedCount == 0 {
-        return fmt.Errorf("error: slot %s not found", slotId)
+        count, err := col.CountDocuments(ctx, filter)
+        if err != nil {
+            log.Printf("Error: DeleteSlot: %s", err.Error())
+            return fmt.Errorf("error: delete slot failed: %w", err)
+        }
+        if count > 0 {
+            return facility.ErrSlotHasBookings
+        }
+        return fmt.Errorf("%w: %s", facility.ErrSlotNotFound, slotId)
     }
 
     return nil
 }
 
+// unbookedSlot matches slots holding no bookings, including slots stored
+// before the count existed
+var unbookedSlot = bson.M{"current_bookings": bson.M{"$in": bson.A{0, nil}}}
+
+
+func (r *facilitiyReposiory) InsertResource(ctx context.Context, resource *facility.Resource) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := r.facilityDbConn(ctx)
+	col := db.Collection("resources")
+
+	result, err := col.InsertOne(ctx, resource)
+	if err != nil {
+		log.Printf("Error: InsertResource: %s", err.Error())
+		return primitive.NilObjectID, fmt.Errorf("error: insert resource failed: %w", err)
+	}
+
+	resourceId, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("error: insert resource failed")
+	}
+
+	return resourceId, nil
+}
+
+// FindManyResource lists a facility's resources; an empty facility name lists
+// the resources of every facility
+func (r *facilitiyReposiory) FindManyResource(ctx context.Context, facilityName string) ([]facility.Resource, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := r.facilityDbConn(ctx)
+	col := db.Collection("resources")
+
+	filter := bson.M{}
+	if facilityName != "" {
+		filter["facility_name"] = facilityName
+	}
+
+	cur, err := col.Find(ctx, filter, options.Find().SetSort(bson.M{"number": 1}))
+	if err != nil {
+		log.Printf("Error: FindManyResource: %s", err.Error())
+		return nil, fmt.Errorf("error: find many resource failed: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	result := make([]facility.Resource, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindManyResource: %s", err.Error())
+		return nil, fmt.Errorf("error: find many resource failed: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *facilitiyReposiory) FindOneResource(ctx context.Context, facilityName, resourceId string) (*facility.Resource, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := r.facilityDbConn(ctx)
+	col := db.Collection("resources")
+
+	resource := new(facility.Resource)
+	if err := col.FindOne(ctx, bson.M{"_id": utils.ConvertToObjectId(resourceId), "facility_name": facilityName}).Decode(resource); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", facility.ErrResourceNotFound, resourceId)
+		}
+		log.Printf("Error: FindOneResource: %s", err.Error())
+		return nil, fmt.Errorf("error: find one resource failed: %w", err)
+	}
+
+	return resource, nil
+}
 
-func (r *facilitiyReposiory) InsertBadCourt(ctx context.Context, court *facility.BadmintonCourt) (primitive.ObjectID, error) {
+func (r *facilitiyReposiory) UpdateResource(ctx context.Context, resource *facility.Resource) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	// Connect to the badminton facility database
-	db := r.courtDbConn(ctx)
-	col := db.Collection("court")
+	db := r.facilityDbConn(ctx)
+	col := db.Collection("resources")
 
-	result, err := col.InsertOne(ctx, bson.M{
-		"court_number": court.CourtNumber,
-		"status": court.Status,
-	})
+	result, err := col.UpdateOne(
+		ctx,
+		bson.M{"_id": resource.Id},
+		bson.M{"$set": bson.M{
+			"name":       resource.Name,
+			"kind":       resource.Kind,
+			"number":     resource.Number,
+			"capacity":   resource.Capacity,
+			"status":     resource.Status,
+			"updated_at": resource.UpdatedAt,
+		}},
+	)
 	if err != nil {
-		log.Printf("Error: Insert Badminton Court: %s", err.Error())
-		return primitive.NilObjectID, fmt.Errorf("error: insert badminton court failed: %w", err)
+		log.Printf("Error: UpdateResource: %s", err.Error())
+		return fmt.Errorf("error: update resource failed: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", facility.ErrResourceNotFound, resource.Id.Hex())
 	}
 
-	// Insert the court into the collection
+	return nil
+}
+
+func (r *facilitiyReposiory) DeleteResource(ctx context.Context, facilityName, resourceId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := r.facilityDbConn(ctx)
+	col := db.Collection("resources")
 
-	courtID, ok := result.InsertedID.(primitive.ObjectID)
+	result, err := col.DeleteOne(ctx, bson.M{"_id": utils.ConvertToObjectId(resourceId), "facility_name": facilityName})
+	if err != nil {
+		log.Printf("Error: DeleteResource: %s", err.Error())
+		return fmt.Errorf("error: delete resource failed: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", facility.ErrResourceNotFound, resourceId)
+	}
+
+	return nil
+}
+
+// DeleteSlotsByResource deletes a resource's unbooked slots. It returns
+// ErrResourceHasBookings if booked slots are left, which are kept.
+func (r *facilitiyReposiory) DeleteSlotsByResource(ctx context.Context, facilityName string, resourceId primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := r.facilityDbConn(ctx)
+	col := db.Collection("slots")
+
+	filter := bson.M{"resource_id": resourceId, "facility_name": facilityName}
+	if _, err := col.DeleteMany(ctx, bson.M{"$and": bson.A{filter, unbookedSlot}}); err != nil {
+		log.Printf("Error: DeleteSlotsByResource: %s", err.Error())
+		return fmt.Errorf("error: delete slots by resource failed: %w", err)
+	}
+
+	left, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Printf("Error: DeleteSlotsByResource: %s", err.Error())
+		return fmt.Errorf("error: delete slots by resource failed: %w", err)
+	}
+	if left > 0 {
+		return facility.ErrResourceHasBookings
+	}
+
+	return nil
+}
+
+func (r *facilitiyReposiory) InsertMaintenance(ctx context.Context, task *facility.MaintenanceTask) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("maintenance")
+
+	result, err := col.InsertOne(ctx, task)
+	if err != nil {
+		log.Printf("Error: InsertMaintenance: %s", err.Error())
+		return primitive.NilObjectID, fmt.Errorf("error: insert maintenance failed: %w", err)
+	}
+
+	taskId, ok := result.InsertedID.(primitive.ObjectID)
 	if !ok {
-		return primitive.NilObjectID, fmt.Errorf("error: insert badminton court failed")
+		return primitive.NilObjectID, errors.New("error: insert maintenance failed")
 	}
 
-	return courtID, nil
+	return taskId, nil
 }
 
-func (r *facilitiyReposiory) UpdateBadCourt(ctx context.Context, courtId string, updateFields bson.M) error {
-    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-    defer cancel()
+func (r *facilitiyReposiory) FindManyMaintenance(ctx context.Context, facilityName string, includeDone bool) ([]facility.MaintenanceTask, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    db := r.courtDbConn(ctx)
-    col := db.Collection("court")
+	col := r.facilityDbConn(ctx).Collection("maintenance")
 
-    updateResult, err := col.UpdateOne(
-        ctx,
-        bson.M{"_id": utils.ConvertToObjectId(courtId)},
-        bson.M{"$set": updateFields},
-    )
-    if err != nil {
-        log.Printf("Error: UpdateBadCourt: %s", err.Error())
-        return fmt.Errorf("error: update badminton court failed: %w", err)
-    }
+	filter := bson.M{}
+	if facilityName != "" {
+		filter["facility_name"] = facilityName
+	}
+	if !includeDone {
+		filter["status"] = bson.M{"$ne": facility.MaintenanceDone}
+	}
 
-    if updateResult.MatchedCount == 0 {
-        return errors.New("error: court not found")
-    }
+	cur, err := col.Find(ctx, filter, options.Find().SetSort(bson.M{"start_at": 1}))
+	if err != nil {
+		log.Printf("Error: FindManyMaintenance: %s", err.Error())
+		return nil, fmt.Errorf("error: find many maintenance failed: %w", err)
+	}
+	defer cur.Close(ctx)
 
-    return nil
+	result := make([]facility.MaintenanceTask, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindManyMaintenance: %s", err.Error())
+		return nil, fmt.Errorf("error: find many maintenance failed: %w", err)
+	}
+
+	return result, nil
 }
 
-func (r *facilitiyReposiory) DeleteBadmintonCourt(ctx context.Context, courtId string) error {
-    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-    defer cancel()
+func (r *facilitiyReposiory) FindOneMaintenance(ctx context.Context, facilityName, taskId string) (*facility.MaintenanceTask, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    db := r.courtDbConn(ctx)
-    col := db.Collection("court")
+	col := r.facilityDbConn(ctx).Collection("maintenance")
 
-    result, err := col.DeleteOne(ctx, bson.M{"_id": utils.ConvertToObjectId(courtId)})
-    if err != nil {
-        log.Printf("Error: DeleteBadmintonCourt: %s", err.Error())
-        return fmt.Errorf("error: delete badminton court failed: %w", err)
-    }
+	task := new(facility.MaintenanceTask)
+	if err := col.FindOne(ctx, bson.M{"_id": utils.ConvertToObjectId(taskId), "facility_name": facilityName}).Decode(task); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", facility.ErrMaintenanceNotFound, taskId)
+		}
+		log.Printf("Error: FindOneMaintenance: %s", err.Error())
+		return nil, fmt.Errorf("error: find one maintenance failed: %w", err)
+	}
 
-    if result.DeletedCount == 0 {
-        return fmt.Errorf("error: badminton court %s not found", courtId)
-    }
+	return task, nil
+}
 
-    return nil
+func (r *facilitiyReposiory) UpdateMaintenanceStatus(ctx context.Context, facilityName, taskId, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("maintenance")
+
+	result, err := col.UpdateOne(
+		ctx,
+		bson.M{"_id": utils.ConvertToObjectId(taskId), "facility_name": facilityName},
+		bson.M{"$set": bson.M{"status": status, "updated_at": utils.LocalTime()}},
+	)
+	if err != nil {
+		log.Printf("Error: UpdateMaintenanceStatus: %s", err.Error())
+		return fmt.Errorf("error: update maintenance status failed: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: %s", facility.ErrMaintenanceNotFound, taskId)
+	}
+
+	return nil
 }
 
+func (r *facilitiyReposiory) DeleteMaintenance(ctx context.Context, facilityName, taskId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("maintenance")
+
+	result, err := col.DeleteOne(ctx, bson.M{"_id": utils.ConvertToObjectId(taskId), "facility_name": facilityName})
+	if err != nil {
+		log.Printf("Error: DeleteMaintenance: %s", err.Error())
+		return fmt.Errorf("error: delete maintenance failed: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", facility.ErrMaintenanceNotFound, taskId)
+	}
 
+	return nil
+}
 
-func (r *facilitiyReposiory) FindBadmintonCourt (ctx context.Context) ([]facility.BadmintonCourt, error) {
+// UpsertCapacityOverride keeps one override per slot or resource and date,
+// so setting a date again replaces the earlier capacity
+func (r *facilitiyReposiory) UpsertCapacityOverride(ctx context.Context, override *facility.CapacityOverride) (*facility.CapacityOverride, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	db := r.courtDbConn(ctx)
-	col := db.Collection("court")
+	col := r.facilityDbConn(ctx).Collection("capacity_overrides")
 
-	cur, err := col.Find(ctx, bson.M{})
+	filter := bson.M{"facility_name": override.FacilityName, "date": override.Date}
+	for field, id := range map[string]primitive.ObjectID{"slot_id": override.SlotId, "resource_id": override.ResourceId} {
+		if id.IsZero() {
+			filter[field] = bson.M{"$exists": false}
+		} else {
+			filter[field] = id
+		}
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"max_bookings": override.MaxBookings,
+			"reason":       override.Reason,
+			"created_by":   override.CreatedBy,
+			"updated_at":   override.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{"created_at": override.CreatedAt},
+	}
+
+	result := new(facility.CapacityOverride)
+	if err := col.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(result); err != nil {
+		log.Printf("Error: UpsertCapacityOverride: %s", err.Error())
+		return nil, fmt.Errorf("error: upsert capacity override failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// FindManyCapacityOverride lists a facility's overrides between from and to inclusive
+func (r *facilitiyReposiory) FindManyCapacityOverride(ctx context.Context, facilityName, from, to string) ([]facility.CapacityOverride, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("capacity_overrides")
+
+	filter := bson.M{}
+	if facilityName != "" {
+		filter["facility_name"] = facilityName
+	}
+	dateFilter := bson.M{}
+	if from != "" {
+		dateFilter["$gte"] = from
+	}
+	if to != "" {
+		dateFilter["$lte"] = to
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	cur, err := col.Find(ctx, filter, options.Find().SetSort(bson.M{"date": 1}))
 	if err != nil {
-		log.Printf("Error: Find Many Badminton Court: %s", err.Error())
-        return nil, fmt.Errorf("error: find many court failed: %w", err)
+		log.Printf("Error: FindManyCapacityOverride: %s", err.Error())
+		return nil, fmt.Errorf("error: find many capacity override failed: %w", err)
 	}
 	defer cur.Close(ctx)
 
-	var result []facility.BadmintonCourt
+	result := make([]facility.CapacityOverride, 0)
 	if err = cur.All(ctx, &result); err != nil {
-		log.Printf("Error: FindManySlot: %s", err.Error())
-        return nil, fmt.Errorf("error: find many slot failed: %w", err)
+		log.Printf("Error: FindManyCapacityOverride: %s", err.Error())
+		return nil, fmt.Errorf("error: find many capacity override failed: %w", err)
 	}
 
 	return result, nil
 }
 
-func (r *facilitiyReposiory) InsertBadmintonSlot(ctx context.Context, req *facility.BadmintonSlot) (primitive.ObjectID, error) {
+func (r *facilitiyReposiory) DeleteCapacityOverride(ctx context.Context, facilityName, overrideId string) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	db := r.courtDbConn(ctx)
-	col := db.Collection("slots")
+	col := r.facilityDbConn(ctx).Collection("capacity_overrides")
 
-	// Set default values for new fields
-	if req.MaxBookings == 0 {
-		req.MaxBookings = 1 // Default max bookings for badminton is 1
+	result, err := col.DeleteOne(ctx, bson.M{"_id": utils.ConvertToObjectId(overrideId), "facility_name": facilityName})
+	if err != nil {
+		log.Printf("Error: DeleteCapacityOverride: %s", err.Error())
+		return fmt.Errorf("error: delete capacity override failed: %w", err)
 	}
-	req.CurrentBookings = 0 // Initialize current bookings to 0
 
-	result, err := col.InsertOne(ctx, req)
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", facility.ErrOverrideNotFound, overrideId)
+	}
+
+	return nil
+}
+
+// FindManySlotBooking reads the open booking transactions of the given slots
+// from booking_db, oldest first
+func (r *facilitiyReposiory) FindManySlotBooking(ctx context.Context, slotIds []primitive.ObjectID) ([]facility.SlotBooking, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.client.Database("booking_db").Collection("booking_transaction")
+
+	cur, err := col.Find(ctx, bson.M{"slot_id": bson.M{"$in": slotIds}}, options.Find().SetSort(bson.M{"created_at": 1}))
 	if err != nil {
-		log.Printf("Error: InsertBadmintonSlot: %s", err.Error())
-		return primitive.NilObjectID, fmt.Errorf("error: insert badminton slot failed: %w", err)
+		log.Printf("Error: FindManySlotBooking: %s", err.Error())
+		return nil, fmt.Errorf("error: find many slot booking failed: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	result := make([]facility.SlotBooking, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindManySlotBooking: %s", err.Error())
+		return nil, fmt.Errorf("error: find many slot booking failed: %w", err)
 	}
 
-	return result.InsertedID.(primitive.ObjectID), nil
+	return result, nil
 }
 
-func (r *facilitiyReposiory) UpdateBadmintonSlot(ctx context.Context, req *facility.BadmintonSlot) error {
-    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-    defer cancel()
+func (r *facilitiyReposiory) InsertWaiver(ctx context.Context, waiver *facility.Waiver) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    db := r.courtDbConn(ctx)
-    col := db.Collection("slots")
+	col := r.facilityDbConn(ctx).Collection("waivers")
 
-    _, err := col.UpdateOne(ctx, bson.M{"_id": req.Id}, bson.M{"$set": req})
-    if err != nil {
-        log.Printf("Error: UpdateBadmintonSlot: %s", err.Error())
-        return fmt.Errorf("error: update badminton slot failed: %w", err)
-    }
-    return nil
+	result, err := col.InsertOne(ctx, waiver)
+	if err != nil {
+		log.Printf("Error: InsertWaiver: %s", err.Error())
+		return primitive.NilObjectID, fmt.Errorf("error: insert waiver failed: %w", err)
+	}
+
+	waiverId, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("error: insert waiver failed")
+	}
+
+	return waiverId, nil
 }
 
+// FindLatestWaiver returns the facility's current waiver, the highest version
+func (r *facilitiyReposiory) FindLatestWaiver(ctx context.Context, facilityName string) (*facility.Waiver, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-func (r *facilitiyReposiory) FindBadmintonSlot(ctx context.Context) ([]facility.BadmintonSlot, error) {
-    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-    defer cancel()
+	col := r.facilityDbConn(ctx).Collection("waivers")
 
-    db := r.courtDbConn(ctx)
-    col := db.Collection("slots")
+	waiver := new(facility.Waiver)
+	if err := col.FindOne(
+		ctx,
+		bson.M{"facility_name": facilityName},
+		options.FindOne().SetSort(bson.M{"version": -1}),
+	).Decode(waiver); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", facility.ErrWaiverNotFound, facilityName)
+		}
+		log.Printf("Error: FindLatestWaiver: %s", err.Error())
+		return nil, fmt.Errorf("error: find latest waiver failed: %w", err)
+	}
 
-    // Find all slots
-    cursor, err := col.Find(ctx, bson.M{})
-    if err != nil {
-        log.Printf("Error: Find Badminton Slot: %s", err.Error())
-        return nil, fmt.Errorf("error: find badminton slot failed: %w", err)
-    }
-    defer cursor.Close(ctx)
+	return waiver, nil
+}
 
-    var slots []facility.BadmintonSlot
-    if err = cursor.All(ctx, &slots); err != nil {
-        log.Printf("Error decoding slots: %s", err.Error())
-        return nil, fmt.Errorf("error decoding slots: %w", err)
-    }
+// FindManyWaiver lists every version of a facility's waiver, newest first
+func (r *facilitiyReposiory) FindManyWaiver(ctx context.Context, facilityName string) ([]facility.Waiver, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    // Set default max_bookings to 1 if it's 0
-    for i := range slots {
-        if slots[i].MaxBookings == 0 {
-            slots[i].MaxBookings = 1 // Set default max bookings to 1
-        }
-    }
+	col := r.facilityDbConn(ctx).Collection("waivers")
 
-    // Log for debugging
-    for _, slot := range slots {
-        log.Printf("Slot %s: max_bookings=%d, current_bookings=%d", 
-            slot.Id.Hex(), 
-            slot.MaxBookings,
-            slot.CurrentBookings)
-    }
+	cur, err := col.Find(ctx, bson.M{"facility_name": facilityName}, options.Find().SetSort(bson.M{"version": -1}))
+	if err != nil {
+		log.Printf("Error: FindManyWaiver: %s", err.Error())
+		return nil, fmt.Errorf("error: find many waiver failed: %w", err)
+	}
+	defer cur.Close(ctx)
 
-    return slots, nil
+	result := make([]facility.Waiver, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindManyWaiver: %s", err.Error())
+		return nil, fmt.Errorf("error: find many waiver failed: %w", err)
+	}
+
+	return result, nil
 }
 
-func (r *facilitiyReposiory) DeleteBadmintonSlot(ctx context.Context, slotId string) error {
-    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-    defer cancel()
+// UpsertWaiverAcceptance keeps one acceptance per user and waiver version;
+// accepting again only refreshes when and from where it was accepted
+func (r *facilitiyReposiory) UpsertWaiverAcceptance(ctx context.Context, acceptance *facility.WaiverAcceptance) (*facility.WaiverAcceptance, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    db := r.courtDbConn(ctx)
-    col := db.Collection("slots")
+	col := r.facilityDbConn(ctx).Collection("waiver_acceptances")
 
-    result, err := col.DeleteOne(ctx, bson.M{"_id": utils.ConvertToObjectId(slotId)})
-    if err != nil {
-        log.Printf("Error: DeleteBadmintonSlot: %s", err.Error())
-        return fmt.Errorf("error: delete badminton slot failed: %w", err)
-    }
+	filter := bson.M{
+		"facility_name": acceptance.FacilityName,
+		"user_id":       acceptance.UserId,
+		"version":       acceptance.Version,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"waiver_id":   acceptance.WaiverId,
+			"ip_address":  acceptance.IpAddress,
+			"accepted_at": acceptance.AcceptedAt,
+		},
+	}
 
-    if result.DeletedCount == 0 {
-        return fmt.Errorf("error: badminton slot %s not found", slotId)
-    }
+	result := new(facility.WaiverAcceptance)
+	if err := col.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(result); err != nil {
+		log.Printf("Error: UpsertWaiverAcceptance: %s", err.Error())
+		return nil, fmt.Errorf("error: upsert waiver acceptance failed: %w", err)
+	}
 
-    return nil
+	return result, nil
 }
 
-// Add a new method to update badminton slot bookings
-func (r *facilitiyReposiory) UpdateBadmintonSlotBookings(ctx context.Context, slotId primitive.ObjectID, increment int) error {
-    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-    defer cancel()
+// FindManyWaiverAcceptance lists a facility's acceptances, oldest first. An
+// empty user id matches every user and a zero version every version.
+func (r *facilitiyReposiory) FindManyWaiverAcceptance(ctx context.Context, facilityName, userId string, version int) ([]facility.WaiverAcceptance, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-    db := r.courtDbConn(ctx)
-    col := db.Collection("slots")
+	col := r.facilityDbConn(ctx).Collection("waiver_acceptances")
 
-    update := bson.M{
-        "$inc": bson.M{"current_bookings": increment},
-    }
+	filter := bson.M{"facility_name": facilityName}
+	if userId != "" {
+		filter["user_id"] = userId
+	}
+	if version > 0 {
+		filter["version"] = version
+	}
 
-    result, err := col.UpdateOne(ctx, bson.M{"_id": slotId}, update)
-    if err != nil {
-        return fmt.Errorf("failed to update badminton slot bookings: %w", err)
-    }
+	cur, err := col.Find(ctx, filter, options.Find().SetSort(bson.M{"accepted_at": 1}))
+	if err != nil {
+		log.Printf("Error: FindManyWaiverAcceptance: %s", err.Error())
+		return nil, fmt.Errorf("error: find many waiver acceptance failed: %w", err)
+	}
+	defer cur.Close(ctx)
 
-    if result.MatchedCount == 0 {
-        return errors.New("badminton slot not found")
-    }
+	result := make([]facility.WaiverAcceptance, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindManyWaiverAcceptance: %s", err.Error())
+		return nil, fmt.Errorf("error: find many waiver acceptance failed: %w", err)
+	}
 
-    return nil
+	return result, nil
+}
+
+func (r *facilitiyReposiory) UpdateOpeningHours(ctx context.Context, facilityName string, hours []facility.OpeningHour) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("facilities")
+
+	result, err := col.UpdateOne(
+		ctx,
+		bson.M{"name": facilityName},
+		bson.M{"$set": bson.M{"opening_hours": hours, "updated_at": utils.LocalTime()}},
+	)
+	if err != nil {
+		log.Printf("Error: UpdateOpeningHours: %s", err.Error())
+		return fmt.Errorf("error: update opening hours failed: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.New("error: facility not found")
+	}
+
+	return nil
+}
+
+// UpsertCalendarDay stores one day per facility, kind and date, so importing
+// the same holidays again only refreshes their names
+func (r *facilitiyReposiory) UpsertCalendarDay(ctx context.Context, day *facility.CalendarDay) (*facility.CalendarDay, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("calendar_days")
+
+	filter := bson.M{"facility_name": day.FacilityName, "kind": day.Kind, "date": day.Date}
+	update := bson.M{
+		"$set": bson.M{
+			"name":       day.Name,
+			"open_time":  day.OpenTime,
+			"close_time": day.CloseTime,
+			"source":     day.Source,
+			"updated_at": day.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{"created_at": day.CreatedAt},
+	}
+
+	result := new(facility.CalendarDay)
+	if err := col.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(result); err != nil {
+		log.Printf("Error: UpsertCalendarDay: %s", err.Error())
+		return nil, fmt.Errorf("error: upsert calendar day failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// FindManyCalendarDay lists days between from and to inclusive. A facility
+// name also returns the public holidays; an empty kind returns every kind.
+func (r *facilitiyReposiory) FindManyCalendarDay(ctx context.Context, facilityName, kind, from, to string) ([]facility.CalendarDay, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("calendar_days")
+
+	filter := bson.M{"facility_name": bson.M{"$in": bson.A{"", facilityName}}}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+	dateFilter := bson.M{}
+	if from != "" {
+		dateFilter["$gte"] = from
+	}
+	if to != "" {
+		dateFilter["$lte"] = to
+	}
+	if len(dateFilter) > 0 {
+		filter["date"] = dateFilter
+	}
+
+	cur, err := col.Find(ctx, filter, options.Find().SetSort(bson.M{"date": 1}))
+	if err != nil {
+		log.Printf("Error: FindManyCalendarDay: %s", err.Error())
+		return nil, fmt.Errorf("error: find many calendar day failed: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	result := make([]facility.CalendarDay, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindManyCalendarDay: %s", err.Error())
+		return nil, fmt.Errorf("error: find many calendar day failed: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *facilitiyReposiory) DeleteCalendarDay(ctx context.Context, facilityName, kind, date string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("calendar_days")
+
+	result, err := col.DeleteOne(ctx, bson.M{"facility_name": facilityName, "kind": kind, "date": date})
+	if err != nil {
+		log.Printf("Error: DeleteCalendarDay: %s", err.Error())
+		return fmt.Errorf("error: delete calendar day failed: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", facility.ErrCalendarDayNotFound, date)
+	}
+
+	return nil
+}
+
+func (r *facilitiyReposiory) InsertMedia(ctx context.Context, media *facility.FacilityMedia) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("media")
+
+	result, err := col.InsertOne(ctx, media)
+	if err != nil {
+		log.Printf("Error: InsertMedia: %s", err.Error())
+		return primitive.NilObjectID, fmt.Errorf("error: insert media failed: %w", err)
+	}
+
+	mediaId, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, errors.New("error: insert media failed")
+	}
+
+	return mediaId, nil
 }
 
+// FindManyMedia lists media in display order; an empty facilityName lists
+// the media of every facility
+func (r *facilitiyReposiory) FindManyMedia(ctx context.Context, facilityName string) ([]facility.FacilityMedia, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("media")
+
+	filter := bson.M{}
+	if facilityName != "" {
+		filter["facility_name"] = facilityName
+	}
+
+	cur, err := col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		log.Printf("Error: FindManyMedia: %s", err.Error())
+		return nil, fmt.Errorf("error: find many media failed: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	result := make([]facility.FacilityMedia, 0)
+	if err = cur.All(ctx, &result); err != nil {
+		log.Printf("Error: FindManyMedia: %s", err.Error())
+		return nil, fmt.Errorf("error: find many media failed: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *facilitiyReposiory) FindOneMedia(ctx context.Context, facilityName, mediaId string) (*facility.FacilityMedia, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("media")
+
+	media := new(facility.FacilityMedia)
+	if err := col.FindOne(ctx, bson.M{"_id": utils.ConvertToObjectId(mediaId), "facility_name": facilityName}).Decode(media); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", facility.ErrMediaNotFound, mediaId)
+		}
+		log.Printf("Error: FindOneMedia: %s", err.Error())
+		return nil, fmt.Errorf("error: find one media failed: %w", err)
+	}
+
+	return media, nil
+}
+
+// UpdateMediaPositions sets each media's position to its index in mediaIds
+func (r *facilitiyReposiory) UpdateMediaPositions(ctx context.Context, facilityName string, mediaIds []primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("media")
+
+	models := make([]mongo.WriteModel, 0, len(mediaIds))
+	for position, mediaId := range mediaIds {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": mediaId, "facility_name": facilityName}).
+			SetUpdate(bson.M{"$set": bson.M{"position": position, "updated_at": utils.LocalTime()}}))
+	}
+
+	if _, err := col.BulkWrite(ctx, models); err != nil {
+		log.Printf("Error: UpdateMediaPositions: %s", err.Error())
+		return fmt.Errorf("error: update media positions failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *facilitiyReposiory) DeleteMedia(ctx context.Context, facilityName, mediaId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	col := r.facilityDbConn(ctx).Collection("media")
+
+	result, err := col.DeleteOne(ctx, bson.M{"_id": utils.ConvertToObjectId(mediaId), "facility_name": facilityName})
+	if err != nil {
+		log.Printf("Error: DeleteMedia: %s", err.Error())
+		return fmt.Errorf("error: delete media failed: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s", facility.ErrMediaNotFound, mediaId)
+	}
+
+	return nil
+}
+
+func (r *facilitiyReposiory) UpsertSlotTemplate(ctx context.Context, template *facility.SlotTemplate) (*facility.SlotTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := r.facilityDbConn(ctx)
+	col := db.Collection("slot_templates")
+
+	_, err := col.UpdateOne(
+		ctx,
+		bson.M{"facility_name": template.FacilityName},
+		bson.M{
+			"$set": bson.M{
+				"opening_hours":         template.OpeningHours,
+				"slot_duration_minutes": template.SlotDurationMinutes,
+				"gap_minutes":           template.GapMinutes,
+				"capacity_per_slot":     template.CapacityPerSlot,
+				"resource_ids":          template.ResourceIds,
+				"updated_at":            template.UpdatedAt,
+			},
+			"$setOnInsert": bson.M{"created_at": template.CreatedAt},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("Error: UpsertSlotTemplate: %s", err.Error())
+		return nil, fmt.Errorf("error: upsert slot template failed: %w", err)
+	}
+
+	return r.FindSlotTemplate(ctx, template.FacilityName)
+}
+
+func (r *facilitiyReposiory) FindSlotTemplate(ctx context.Context, facilityName string) (*facility.SlotTemplate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := r.facilityDbConn(ctx)
+	col := db.Collection("slot_templates")
+
+	template := new(facility.SlotTemplate)
+	if err := col.FindOne(ctx, bson.M{"facility_name": facilityName}).Decode(template); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", facility.ErrTemplateNotFound, facilityName)
+		}
+		log.Printf("Error: FindSlotTemplate: %s", err.Error())
+		return nil, fmt.Errorf("error: find slot template failed: %w", err)
+	}
+
+	return template, nil
+}
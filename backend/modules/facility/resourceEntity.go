@@ -0,0 +1,22 @@
+package facility
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type (
+	// Resource is a bookable part of a facility, such as a court, lane or pitch
+	Resource struct {
+		Id           primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		FacilityName string             `bson:"facility_name" json:"facility_name"`
+		Name         string             `bson:"name" json:"name"`     // e.g., "Court 1", "Lane 4"
+		Kind         string             `bson:"kind" json:"kind"`     // e.g., "court", "lane", "pitch"
+		Number       int                `bson:"number" json:"number"` // Display order within the facility
+		Capacity     int                `bson:"capacity" json:"capacity"`
+		Status       int                `bson:"status" json:"status"` // 0 = active, 1 = out of service
+		CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+		UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	}
+)
@@ -1,6 +1,7 @@
 package facility
 
 import (
+	"main/pkg/utils"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,32 +10,34 @@ import (
 type (
 	Slot struct {
 		Id              primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-		StartTime       string             `bson:"start_time" json:"start_time"`
-		EndTime         string             `bson:"end_time" json:"end_time"`
+		FacilityName    string             `bson:"facility_name" json:"facility_name"`
+		utils.TimeRange `bson:",inline"`   // start_time and end_time as validated HH:mm
 		Status          int                `bson:"status" json:"status"`
 		MaxBookings     int                `bson:"max_bookings" json:"max_bookings"`
 		CurrentBookings int                `bson:"current_bookings" json:"current_bookings"`
 		FacilityType    string             `bson:"facility_type" json:"facility_type"` // e.g., "gym", "swimming", "football"
+		ResourceId      primitive.ObjectID `bson:"resource_id,omitempty" json:"resource_id,omitempty"` // Court, lane or pitch; empty for facility-wide slots
+		Weekdays        []int              `bson:"weekdays,omitempty" json:"weekdays,omitempty"`  // 0 = Sunday; empty means every day
 		CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
 		UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
 	}
 
-	BadmintonCourt struct {
-		Id          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-		CourtNumber int                `bson:"court_number" json:"court_number"` // Court number, e.g., 1, 2, 3, 4
-		Status      int                `bson:"status" json:"status"`             // e.g., 0 = available, 1 = booked
+	// SlotTemplate describes how a facility's slot set is generated
+	SlotTemplate struct {
+		Id                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		FacilityName        string             `bson:"facility_name" json:"facility_name"`
+		OpeningHours        []OpeningHour      `bson:"opening_hours" json:"opening_hours"`
+		SlotDurationMinutes int                `bson:"slot_duration_minutes" json:"slot_duration_minutes"`
+		GapMinutes          int                `bson:"gap_minutes" json:"gap_minutes"`
+		CapacityPerSlot     int                `bson:"capacity_per_slot" json:"capacity_per_slot"`
+		ResourceIds         []string           `bson:"resource_ids,omitempty" json:"resource_ids,omitempty"` // Generate one slot set per resource
+		CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
+		UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
 	}
 
-	BadmintonSlot struct {
-		Id              primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-		StartTime       string            `bson:"start_time" json:"start_time"`
-		EndTime         string            `bson:"end_time" json:"end_time"`
-		CourtId         primitive.ObjectID `bson:"court_id" json:"court_id"`
-		CourtNumber    int                `bson:"court_number"`
-		Status          int               `bson:"status" json:"status"`
-		MaxBookings     int               `bson:"max_bookings" json:"max_bookings"`
-		CurrentBookings int               `bson:"current_bookings" json:"current_bookings"`
-		CreatedAt       time.Time         `bson:"created_at" json:"created_at"`
-		UpdatedAt       time.Time         `bson:"updated_at" json:"updated_at"`
+	OpeningHour struct {
+		Weekday   int    `bson:"weekday" json:"weekday" validate:"min=0,max=6"` // 0 = Sunday
+		OpenTime  string `bson:"open_time" json:"open_time" validate:"required"`
+		CloseTime string `bson:"close_time" json:"close_time" validate:"required"`
 	}
 )
\ No newline at end of file
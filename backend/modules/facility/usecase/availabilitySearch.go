@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"context"
+	"main/modules/facility"
+	"main/pkg/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SearchAvailability finds the slots of every facility that fit inside the
+// requested window on one date and can still take the whole party
+func (u *facilityUsecase) SearchAvailability(ctx context.Context, req *facility.AvailabilitySearchRequest) ([]facility.AvailableSlot, error) {
+	loc := utils.Location()
+
+	date, err := facility.ParseDate(req.Date, loc)
+	if err != nil {
+		return nil, err
+	}
+	windowStart, windowEnd, err := searchWindow(req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	partySize := max(req.PartySize, 1)
+
+	facilities, err := u.facilityRepository.FindManyFacility(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slots, err := u.facilityRepository.FindManySlot(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	resources, err := u.facilityRepository.FindManyResource(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := u.facilityRepository.FindManyMaintenance(ctx, "", false)
+	if err != nil {
+		return nil, err
+	}
+	key := date.Format(facility.DateLayout)
+	overrides, err := u.facilityRepository.FindManyCapacityOverride(ctx, "", key, key)
+	if err != nil {
+		return nil, err
+	}
+
+	resourcesById := make(map[primitive.ObjectID]*facility.Resource, len(resources))
+	for i := range resources {
+		resourcesById[resources[i].Id] = &resources[i]
+	}
+	tasksByFacility := make(map[string][]*facility.MaintenanceTask)
+	for i := range tasks {
+		tasksByFacility[tasks[i].FacilityName] = append(tasksByFacility[tasks[i].FacilityName], &tasks[i])
+	}
+	overridesByFacility := make(map[string][]facility.CapacityOverride)
+	for _, o := range overrides {
+		overridesByFacility[o.FacilityName] = append(overridesByFacility[o.FacilityName], o)
+	}
+	slotsByFacility := make(map[string][]*facility.Slot)
+	for i := range slots {
+		slotsByFacility[slots[i].FacilityName] = append(slotsByFacility[slots[i].FacilityName], &slots[i])
+	}
+
+	results := make([]facility.AvailableSlot, 0)
+	for _, f := range facilities {
+		price := f.PriceOutsider
+		if req.UserType == "insider" {
+			price = f.PriceInsider
+		}
+		if req.MaxPrice > 0 && price > req.MaxPrice {
+			continue
+		}
+
+		candidates := make([]*facility.Slot, 0)
+		for _, slot := range slotsByFacility[f.Name] {
+			if matchesFacilityType(slot, req.Types) {
+				candidates = append(candidates, slot)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		days, err := u.facilityRepository.FindManyCalendarDay(ctx, f.Name, "", key, key)
+		if err != nil {
+			return nil, err
+		}
+		hours := facility.ResolveDayHours(f.OpeningHours, days, date)
+		if !hours.Open {
+			continue
+		}
+
+		for _, slot := range candidates {
+			capacity := facility.EffectiveCapacity(slot, overridesByFacility[f.Name])
+			remaining := capacity - slot.CurrentBookings
+			if slot.Status != 1 || remaining < partySize {
+				continue
+			}
+			if !runsOnWeekday(slot.Weekdays, int(date.Weekday())) || !hours.WithinHours(slot.TimeRange) {
+				continue
+			}
+			if !slotWithinWindow(slot, windowStart, windowEnd) {
+				continue
+			}
+
+			resource := resourcesById[slot.ResourceId]
+			if !slot.ResourceId.IsZero() && (resource == nil || resource.Status != 0) {
+				continue
+			}
+			if maintenanceBlocks(tasksByFacility[f.Name], slot, date) {
+				continue
+			}
+
+			available := facility.AvailableSlot{
+				FacilityName: f.Name,
+				FacilityType: slotFacilityType(slot),
+				SlotId:       slot.Id.Hex(),
+				ResourceId:   resourceHex(slot.ResourceId),
+				Date:         key,
+				StartTime:    slot.StartTime.String(),
+				EndTime:      slot.EndTime.String(),
+				Remaining:    remaining,
+				MaxBookings:  capacity,
+				Price:        price,
+				TotalPrice:   price * float64(partySize),
+				Currency:     "THB",
+			}
+			if resource != nil {
+				available.ResourceName = resource.Name
+			}
+			results = append(results, available)
+		}
+	}
+
+	sortByRelevance(results)
+	return results, nil
+}
+
+// searchWindow parses the HH:mm window, defaulting to the whole day
+func searchWindow(from, to string) (start, end int, err error) {
+	start, end = 0, 24*60
+	if from != "" {
+		if start, err = parseSlotMinutes(from); err != nil {
+			return 0, 0, err
+		}
+	}
+	if to != "" {
+		if end, err = parseSlotMinutes(to); err != nil {
+			return 0, 0, err
+		}
+	}
+	if start >= end {
+		return 0, 0, facility.ErrInvalidTimeRange
+	}
+	return start, end, nil
+}
+
+func slotWithinWindow(slot *facility.Slot, windowStart, windowEnd int) bool {
+	return slot.StartTime.Minutes() >= windowStart && slot.EndTime.Minutes() <= windowEnd
+}
+
+// slotFacilityType falls back to the facility name for slots created without a type
+func slotFacilityType(slot *facility.Slot) string {
+	if slot.FacilityType != "" {
+		return slot.FacilityType
+	}
+	return slot.FacilityName
+}
+
+// matchesFacilityType treats an empty type list as every type
+func matchesFacilityType(slot *facility.Slot, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	slotType := slotFacilityType(slot)
+	for _, t := range types {
+		if strings.EqualFold(strings.TrimSpace(t), slotType) {
+			return true
+		}
+	}
+	return false
+}
+
+func runsOnWeekday(weekdays []int, weekday int) bool {
+	return weekdaysIntersect(weekdays, []int{weekday})
+}
+
+func maintenanceBlocks(tasks []*facility.MaintenanceTask, slot *facility.Slot, day time.Time) bool {
+	for _, task := range tasks {
+		if facility.MaintenanceBlocksSlot(task, slot, day) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortByRelevance puts the cheapest slots first, then the earliest, then
+// those with the most places left so a party has room to grow
+func sortByRelevance(slots []facility.AvailableSlot) {
+	sort.SliceStable(slots, func(i, j int) bool {
+		a, b := slots[i], slots[j]
+		if a.Price != b.Price {
+			return a.Price < b.Price
+		}
+		if a.StartTime != b.StartTime {
+			return a.StartTime < b.StartTime
+		}
+		if a.Remaining != b.Remaining {
+			return a.Remaining > b.Remaining
+		}
+		return a.FacilityName < b.FacilityName
+	})
+}
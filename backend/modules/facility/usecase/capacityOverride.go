@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"main/modules/facility"
+	"main/pkg/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetCapacityOverride stores a dated capacity for a slot or resource and
+// reports the bookings that no longer fit
+func (u *facilityUsecase) SetCapacityOverride(ctx context.Context, facilityName, createdBy string, req *facility.CapacityOverrideRequest) (*facility.CapacityOverrideResponse, error) {
+	if _, err := facility.ParseDate(req.Date, utils.Location()); err != nil {
+		return nil, err
+	}
+	if (req.SlotId == "") == (req.ResourceId == "") {
+		return nil, facility.ErrInvalidOverride
+	}
+
+	override := &facility.CapacityOverride{
+		FacilityName: facilityName,
+		Date:         req.Date,
+		MaxBookings:  req.MaxBookings,
+		Reason:       req.Reason,
+		CreatedBy:    createdBy,
+		CreatedAt:    utils.LocalTime(),
+		UpdatedAt:    utils.LocalTime(),
+	}
+	if req.SlotId != "" {
+		slot, err := u.facilityRepository.FindOneSlot(ctx, facilityName, req.SlotId)
+		if err != nil {
+			return nil, err
+		}
+		override.SlotId = slot.Id
+	} else {
+		resource, err := u.facilityRepository.FindOneResource(ctx, facilityName, req.ResourceId)
+		if err != nil {
+			return nil, err
+		}
+		override.ResourceId = resource.Id
+	}
+
+	saved, err := u.facilityRepository.UpsertCapacityOverride(ctx, override)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := u.FindCapacityReport(ctx, facilityName, req.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	return &facility.CapacityOverrideResponse{Override: saved, Report: report}, nil
+}
+
+func (u *facilityUsecase) FindManyCapacityOverride(ctx context.Context, facilityName, from, to string) ([]facility.CapacityOverride, error) {
+	return u.facilityRepository.FindManyCapacityOverride(ctx, facilityName, from, to)
+}
+
+func (u *facilityUsecase) DeleteCapacityOverride(ctx context.Context, facilityName, overrideId string) error {
+	return u.facilityRepository.DeleteCapacityOverride(ctx, facilityName, overrideId)
+}
+
+// FindCapacityReport lists the slots booked past their capacity on date. Booking
+// transactions only cover the current day, so other dates report no slots.
+func (u *facilityUsecase) FindCapacityReport(ctx context.Context, facilityName, date string) (*facility.CapacityReport, error) {
+	if _, err := facility.ParseDate(date, utils.Location()); err != nil {
+		return nil, err
+	}
+
+	report := &facility.CapacityReport{Date: date, Slots: make([]facility.OverbookedSlot, 0)}
+	if date != utils.LocalTime().Format(facility.DateLayout) {
+		return report, nil
+	}
+
+	slots, err := u.facilityRepository.FindManySlot(ctx, facilityName)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := u.facilityRepository.FindManyCapacityOverride(ctx, facilityName, date, date)
+	if err != nil {
+		return nil, err
+	}
+
+	overbooked := make(map[primitive.ObjectID]*facility.OverbookedSlot)
+	slotIds := make([]primitive.ObjectID, 0)
+	for i := range slots {
+		capacity := facility.EffectiveCapacity(&slots[i], overrides)
+		if slots[i].CurrentBookings <= capacity {
+			continue
+		}
+		report.Slots = append(report.Slots, facility.OverbookedSlot{
+			SlotId:          slots[i].Id.Hex(),
+			ResourceId:      resourceHex(slots[i].ResourceId),
+			StartTime:       slots[i].StartTime.String(),
+			EndTime:         slots[i].EndTime.String(),
+			MaxBookings:     capacity,
+			CurrentBookings: slots[i].CurrentBookings,
+			Overflow:        make([]facility.SlotBooking, 0),
+		})
+		slotIds = append(slotIds, slots[i].Id)
+	}
+	if len(slotIds) == 0 {
+		return report, nil
+	}
+	for i := range report.Slots {
+		overbooked[slotIds[i]] = &report.Slots[i]
+	}
+
+	bookings, err := u.facilityRepository.FindManySlotBooking(ctx, slotIds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bookings come oldest first; the ones past capacity are the latest
+	kept := make(map[primitive.ObjectID]int)
+	for _, b := range bookings {
+		slot := overbooked[b.SlotId]
+		if kept[b.SlotId] < slot.MaxBookings {
+			kept[b.SlotId]++
+			continue
+		}
+		slot.Overflow = append(slot.Overflow, b)
+	}
+
+	return report, nil
+}
+
+// EffectiveCapacity resolves the slot's max bookings on day, taking the
+// facility's capacity overrides into account
+func (u *facilityUsecase) EffectiveCapacity(ctx context.Context, facilityName string, slot *facility.Slot, day time.Time) (int, error) {
+	date := day.In(utils.Location()).Format(facility.DateLayout)
+	overrides, err := u.facilityRepository.FindManyCapacityOverride(ctx, facilityName, date, date)
+	if err != nil {
+		return 0, err
+	}
+	return facility.EffectiveCapacity(slot, overrides), nil
+}
@@ -1,17 +1,33 @@
 package usecase
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"main/modules/facility"
 	"main/modules/facility/repository"
+	"main/pkg/imaging"
+	"main/pkg/storage"
 	"main/pkg/utils"
+	"sort"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	defaultCalendarDays = 30
+	maxCalendarDays     = 366
+)
+
+// mediaKeyPrefix is where facility media is stored. The blob store is shared,
+// e.g. with payment slips, so the public media route serves nothing else.
+const mediaKeyPrefix = "facilities/"
+
 type (
 	FacilityUsecaseService interface {
 		CreateFacility(pctx context.Context, req *facility.CreateFaciliityRequest) (facility.FacilityBson, error)
@@ -19,31 +35,83 @@ type (
 		FindManyFacility(pctx context.Context) ([]facility.FacilityBson, error)
 		UpdateOneFacility(pctx context.Context, facilityId, facilityName string, updateFields map[string]interface{}) error
 		DeleteOneFacility(pctx context.Context, facilityId, facilityName string) error
+		UpdateOpeningHours(ctx context.Context, facilityName string, req *facility.OpeningHoursRequest) (*facility.FacilityBson, error)
 
 		//Slot - usecase
-		InsertSlot(ctx context.Context, startTime, endTime, facilityName string, maxBookings, currentBookings int, facilityType string) (*facility.Slot, error)
+		InsertSlot(ctx context.Context, facilityName string, req *facility.CreateSlotRequest) (*facility.Slot, error)
 		FindOneSlot(ctx context.Context, facilityName, slotId string) (*facility.Slot, error)
 		FindManySlot(ctx context.Context,facilityName string) ([]facility.Slot, error)
 		EnableOrDisableSlot(ctx context.Context, facilityName, slotId string, status int) (*facility.Slot, error)
 
-		UpdateSlot(ctx context.Context, facilityName string, slot *facility.Slot) (*facility.Slot, error)
-
-		//Court - usecase
-		InsertBadCourt(ctx context.Context, court *facility.BadmintonCourt) (primitive.ObjectID, error)
-		FindBadCourt(ctx context.Context) ([]facility.BadmintonCourt, error)
-		InsertBadmintonSlot(ctx context.Context, slot *facility.BadmintonSlot) (primitive.ObjectID, error)
-		FindBadmintonSlot(ctx context.Context) ([]facility.BadmintonSlot, error)
-
+		UpdateSlotBookings(ctx context.Context, facilityName string, slot *facility.Slot, increment int) error
+		UpdateSlotDetails(ctx context.Context, facilityName, slotId string, req *facility.UpdateSlotRequest) (*facility.Slot, error)
+		DeleteSlot(ctx context.Context, facilityName, slotId string) error
+
+		//Resource - usecase
+		InsertResource(ctx context.Context, facilityName string, req *facility.ResourceRequest) (*facility.Resource, error)
+		FindManyResource(ctx context.Context, facilityName string) ([]facility.Resource, error)
+		UpdateResource(ctx context.Context, facilityName, resourceId string, req *facility.ResourceRequest) (*facility.Resource, error)
+		DeleteResource(ctx context.Context, facilityName, resourceId string) error
+
+		//Maintenance - usecase
+		CreateMaintenance(ctx context.Context, facilityName, createdBy string, req *facility.CreateMaintenanceRequest) (*facility.MaintenanceTask, error)
+		FindManyMaintenance(ctx context.Context, facilityName string, includeDone bool) ([]facility.MaintenanceTask, error)
+		UpdateMaintenanceStatus(ctx context.Context, facilityName, taskId, status string) (*facility.MaintenanceTask, error)
+		DeleteMaintenance(ctx context.Context, facilityName, taskId string) error
+		IsSlotUnderMaintenance(ctx context.Context, facilityName string, slot *facility.Slot, day time.Time) (bool, error)
+
+		//Capacity override - usecase
+		SetCapacityOverride(ctx context.Context, facilityName, createdBy string, req *facility.CapacityOverrideRequest) (*facility.CapacityOverrideResponse, error)
+		FindManyCapacityOverride(ctx context.Context, facilityName, from, to string) ([]facility.CapacityOverride, error)
+		DeleteCapacityOverride(ctx context.Context, facilityName, overrideId string) error
+		FindCapacityReport(ctx context.Context, facilityName, date string) (*facility.CapacityReport, error)
+		EffectiveCapacity(ctx context.Context, facilityName string, slot *facility.Slot, day time.Time) (int, error)
+
+		//Calendar - usecase
+		AddHoliday(ctx context.Context, req *facility.HolidayRequest) (*facility.CalendarDay, error)
+		ImportHolidays(ctx context.Context, r io.Reader) (*facility.HolidayImportResponse, error)
+		FindManyHoliday(ctx context.Context, from, to string) ([]facility.CalendarDay, error)
+		DeleteHoliday(ctx context.Context, date string) error
+		AddSpecialDay(ctx context.Context, facilityName string, req *facility.SpecialDayRequest) (*facility.CalendarDay, error)
+		DeleteSpecialDay(ctx context.Context, facilityName, date string) error
+		FindFacilityCalendar(ctx context.Context, facilityName, from, to string) ([]facility.DayHours, error)
+		IsSlotOpenOn(ctx context.Context, facilityName string, slot *facility.Slot, day time.Time) (bool, error)
+
+		//Search - usecase
+		SearchAvailability(ctx context.Context, req *facility.AvailabilitySearchRequest) ([]facility.AvailableSlot, error)
+
+		//Waiver - usecase
+		PublishWaiver(ctx context.Context, facilityName, publishedBy string, req *facility.PublishWaiverRequest) (*facility.Waiver, error)
+		FindCurrentWaiver(ctx context.Context, facilityName string) (*facility.Waiver, error)
+		FindManyWaiver(ctx context.Context, facilityName string) ([]facility.Waiver, error)
+		FindWaiverStatus(ctx context.Context, facilityName, userId string) (*facility.WaiverStatus, error)
+		AcceptWaiver(ctx context.Context, facilityName, userId, ipAddress string, req *facility.AcceptWaiverRequest) (*facility.WaiverAcceptance, error)
+		FindManyWaiverAcceptance(ctx context.Context, facilityName string, version int) ([]facility.WaiverAcceptance, error)
+
+		//Media - usecase
+		UploadMedia(ctx context.Context, facilityName, uploadedBy string, req *facility.UploadMediaRequest, data []byte) (*facility.FacilityImage, error)
+		FindManyMedia(ctx context.Context, facilityName string) ([]facility.FacilityImage, error)
+		ReorderMedia(ctx context.Context, facilityName string, req *facility.ReorderMediaRequest) ([]facility.FacilityImage, error)
+		DeleteMedia(ctx context.Context, facilityName, mediaId string) error
+		OpenMedia(ctx context.Context, key string) (io.ReadCloser, error)
+
+		//Slot template - usecase
+		SaveSlotTemplate(ctx context.Context, facilityName string, req *facility.SlotTemplateRequest) (*facility.SlotTemplate, error)
+		FindSlotTemplate(ctx context.Context, facilityName string) (*facility.SlotTemplate, error)
+		PreviewSlotTemplate(ctx context.Context, facilityName string) (*facility.SlotTemplateDiff, error)
+		ApplySlotTemplate(ctx context.Context, facilityName string) (*facility.SlotTemplateDiff, error)
 	}
 
 	facilityUsecase struct {
 		facilityRepository repository.FacilityRepositoryService
+		blobStore          storage.BlobStore
 	}
 )
 
-func NewFacilityUsecase(facilityRepository repository.FacilityRepositoryService) FacilityUsecaseService {
+func NewFacilityUsecase(facilityRepository repository.FacilityRepositoryService, blobStore storage.BlobStore) FacilityUsecaseService {
 	return &facilityUsecase{
 		facilityRepository: facilityRepository,
+		blobStore:          blobStore,
 	}
 }
 
@@ -81,11 +149,7 @@ func (u *facilityUsecase) FindOneFacility(pctx context.Context, facilityId, faci
 		return nil, err
 	}
 
-	// Set the location to Asia/Bangkok
-	loc, err := time.LoadLocation("Asia/Bangkok")
-	if err != nil {
-		return nil, fmt.Errorf("error: unable to load time location: %w", err)
-	}
+	loc := utils.Location()
 
 	// Return facility details with localized time
 	return &facility.FacilityBson{
@@ -94,6 +158,7 @@ func (u *facilityUsecase) FindOneFacility(pctx context.Context, facilityId, faci
 		PriceInsider:  result.PriceInsider,
 		PriceOutsider: result.PriceOutsider,
 		Description:   result.Description,
+		OpeningHours:  result.OpeningHours,
 		CreatedAt:     result.CreatedAt.In(loc),
 		UpdatedAt:     result.UpdatedAt.In(loc),
 	}, nil
@@ -105,6 +170,15 @@ func (u *facilityUsecase) FindManyFacility(pctx context.Context) ([]facility.Fac
 		return nil, err
 	}
 
+	media, err := u.facilityRepository.FindManyMedia(pctx, "")
+	if err != nil {
+		return nil, err
+	}
+	imagesByFacility := make(map[string][]facility.FacilityImage)
+	for i := range media {
+		imagesByFacility[media[i].FacilityName] = append(imagesByFacility[media[i].FacilityName], u.toFacilityImage(&media[i]))
+	}
+
 	var facilityProfile []facility.FacilityBson
 	for _, result := range results {
 		facilityProfile = append(facilityProfile, facility.FacilityBson{
@@ -113,6 +187,8 @@ func (u *facilityUsecase) FindManyFacility(pctx context.Context) ([]facility.Fac
 			PriceInsider:  result.PriceInsider,
 			PriceOutsider: result.PriceOutsider,
 			Description:   result.Description,
+			OpeningHours:  result.OpeningHours,
+			Images:        imagesByFacility[result.Name],
 			CreatedAt:     result.CreatedAt,
 			UpdatedAt:     result.UpdatedAt,
 		})
@@ -145,14 +221,48 @@ func (u *facilityUsecase) DeleteOneFacility(pctx context.Context, facilityId, fa
 	return u.facilityRepository.DeleteOneFacility(pctx, facilityId, facilityName)
 }
 
-func (u *facilityUsecase) InsertSlot(ctx context.Context, startTime, endTime, facilityName string, maxBookings, currentBookings int, facilityType string) (*facility.Slot, error) {
+func (u *facilityUsecase) InsertSlot(ctx context.Context, facilityName string, req *facility.CreateSlotRequest) (*facility.Slot, error) {
+	var resourceId primitive.ObjectID
+	if req.ResourceId != "" {
+		resource, err := u.facilityRepository.FindOneResource(ctx, facilityName, req.ResourceId)
+		if err != nil {
+			return nil, err
+		}
+		resourceId = resource.Id
+		if req.MaxBookings == 0 {
+			req.MaxBookings = max(resource.Capacity, 1)
+		}
+	}
+
+	if req.MaxBookings <= 0 {
+		return nil, facility.ErrInvalidCapacity
+	}
+	if req.CurrentBookings > req.MaxBookings {
+		return nil, facility.ErrCapacityBelowBookings
+	}
+
+	timeRange, err := utils.NewTimeRange(req.StartTime, req.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := u.resourceSlotRanges(ctx, facilityName, resourceId, timeRange)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSlotTimes(timeRange, nil, primitive.NilObjectID, ranges); err != nil {
+		return nil, err
+	}
+	if err := u.checkOpeningHours(ctx, facilityName, timeRange, nil); err != nil {
+		return nil, err
+	}
+
 	slot := facility.Slot{
-		StartTime:       startTime,
-		EndTime:         endTime,
+		TimeRange:       timeRange,
 		Status:          1,
-		MaxBookings:     maxBookings,
-		CurrentBookings: currentBookings,
-		FacilityType:    facilityType,
+		MaxBookings:     req.MaxBookings,
+		CurrentBookings: req.CurrentBookings,
+		FacilityType:    req.FacilityType,
+		ResourceId:      resourceId,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
@@ -174,69 +284,708 @@ func (u *facilityUsecase) EnableOrDisableSlot(ctx context.Context, facilityName,
 	return u.facilityRepository.EnableOrDisableSlot(ctx, facilityName, slotId, status)
 }
 
-func (u *facilityUsecase) InsertBadCourt(ctx context.Context, court *facility.BadmintonCourt) (primitive.ObjectID, error) {
-	return u.facilityRepository.InsertBadCourt(ctx, court)
+// UpdateSlotBookings moves a slot's booking count by increment, within the
+// slot's capacity for today
+func (u *facilityUsecase) UpdateSlotBookings(ctx context.Context, facilityName string, slot *facility.Slot, increment int) error {
+	capacity, err := u.EffectiveCapacity(ctx, facilityName, slot, utils.LocalTime())
+	if err != nil {
+		return err
+	}
+	return u.facilityRepository.UpdateSlotBookings(ctx, facilityName, slot.Id, increment, capacity)
 }
 
-func (u *facilityUsecase) FindBadCourt(ctx context.Context) ([]facility.BadmintonCourt, error) {
-	results, err := u.facilityRepository.FindBadmintonCourt(ctx)
+// UpdateSlotDetails changes a slot's time range or capacity
+func (u *facilityUsecase) UpdateSlotDetails(ctx context.Context, facilityName, slotId string, req *facility.UpdateSlotRequest) (*facility.Slot, error) {
+	slot, err := u.facilityRepository.FindOneSlot(ctx, facilityName, slotId)
 	if err != nil {
 		return nil, err
 	}
 
-	var badCourt []facility.BadmintonCourt
-	for _, result := range results {
-		badCourt = append(badCourt, facility.BadmintonCourt{
-			Id:          result.Id,
-			CourtNumber: result.CourtNumber,
-			Status: result.Status,
-		})
+	if err := applySlotUpdate(&slot.TimeRange, &slot.MaxBookings, req); err != nil {
+		return nil, err
+	}
+	if slot.MaxBookings <= 0 {
+		return nil, facility.ErrInvalidCapacity
+	}
+	if slot.MaxBookings < slot.CurrentBookings {
+		return nil, facility.ErrCapacityBelowBookings
+	}
+
+	ranges, err := u.resourceSlotRanges(ctx, facilityName, slot.ResourceId, slot.TimeRange)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSlotTimes(slot.TimeRange, slot.Weekdays, slot.Id, ranges); err != nil {
+		return nil, err
+	}
+	if err := u.checkOpeningHours(ctx, facilityName, slot.TimeRange, slot.Weekdays); err != nil {
+		return nil, err
+	}
+
+	slot.UpdatedAt = time.Now()
+	return u.facilityRepository.UpdateSlot(ctx, facilityName, slot)
+}
+
+// DeleteSlot deletes a slot by its ID, refusing while it has bookings
+func (u *facilityUsecase) DeleteSlot(ctx context.Context, facilityName, slotId string) error {
+	return u.facilityRepository.DeleteSlot(ctx, facilityName, slotId)
+}
+
+func (u *facilityUsecase) InsertResource(ctx context.Context, facilityName string, req *facility.ResourceRequest) (*facility.Resource, error) {
+	resource := &facility.Resource{
+		FacilityName: facilityName,
+		Name:         req.Name,
+		Kind:         req.Kind,
+		Number:       req.Number,
+		Capacity:     max(req.Capacity, 1),
+		Status:       req.Status,
+		CreatedAt:    utils.LocalTime(),
+		UpdatedAt:    utils.LocalTime(),
+	}
+
+	resourceId, err := u.facilityRepository.InsertResource(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	resource.Id = resourceId
+	return resource, nil
+}
+
+func (u *facilityUsecase) FindManyResource(ctx context.Context, facilityName string) ([]facility.Resource, error) {
+	return u.facilityRepository.FindManyResource(ctx, facilityName)
+}
+
+func (u *facilityUsecase) UpdateResource(ctx context.Context, facilityName, resourceId string, req *facility.ResourceRequest) (*facility.Resource, error) {
+	resource, err := u.facilityRepository.FindOneResource(ctx, facilityName, resourceId)
+	if err != nil {
+		return nil, err
+	}
+
+	resource.Name = req.Name
+	resource.Kind = req.Kind
+	resource.Number = req.Number
+	resource.Capacity = max(req.Capacity, 1)
+	resource.Status = req.Status
+	resource.UpdatedAt = utils.LocalTime()
+
+	if err := u.facilityRepository.UpdateResource(ctx, resource); err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// DeleteResource deletes a resource and its slots, refusing if any slot is
+// booked. Slots go first, each only while unbooked, so no booking can land on
+// a slot of a deleted resource; a refused delete keeps the booked slots and
+// the resource.
+func (u *facilityUsecase) DeleteResource(ctx context.Context, facilityName, resourceId string) error {
+	resource, err := u.facilityRepository.FindOneResource(ctx, facilityName, resourceId)
+	if err != nil {
+		return err
+	}
+
+	if err := u.facilityRepository.DeleteSlotsByResource(ctx, facilityName, resource.Id); err != nil {
+		return err
 	}
 
-	return badCourt, nil
+	return u.facilityRepository.DeleteResource(ctx, facilityName, resourceId)
 }
 
-func (u *facilityUsecase) InsertBadmintonSlot(ctx context.Context, slot *facility.BadmintonSlot) (primitive.ObjectID, error) {
-	return u.facilityRepository.InsertBadmintonSlot(ctx, slot)
+// resourceSlotRanges returns the ranges of slots on the same resource that
+// overlap r; slots without a resource only collide with each other.
+func (u *facilityUsecase) resourceSlotRanges(ctx context.Context, facilityName string, resourceId primitive.ObjectID, r utils.TimeRange) ([]slotRange, error) {
+	slots, err := u.facilityRepository.FindOverlappingSlots(ctx, facilityName, resourceId, r)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]slotRange, 0, len(slots))
+	for _, s := range slots {
+		ranges = append(ranges, slotRange{Id: s.Id, Range: s.TimeRange, Weekdays: s.Weekdays})
+	}
+
+	return ranges, nil
 }
 
-func (u *facilityUsecase) FindBadmintonSlot(ctx context.Context) ([]facility.BadmintonSlot, error) {
-	slots, err := u.facilityRepository.FindBadmintonSlot(ctx)
+func (u *facilityUsecase) CreateMaintenance(ctx context.Context, facilityName, createdBy string, req *facility.CreateMaintenanceRequest) (*facility.MaintenanceTask, error) {
+	if !req.EndAt.After(req.StartAt) {
+		return nil, fmt.Errorf("%w: end must be after start", facility.ErrInvalidMaintenance)
+	}
+	if req.RecurUntil != nil && req.RecurUntil.Before(req.StartAt) {
+		return nil, fmt.Errorf("%w: recur_until is before start", facility.ErrInvalidMaintenance)
+	}
+
+	// A recurring window longer than its period would overlap itself
+	if req.Recurrence != facility.RecurrenceNone {
+		next := req.StartAt
+		switch req.Recurrence {
+		case facility.RecurrenceDaily:
+			next = next.AddDate(0, 0, 1)
+		case facility.RecurrenceWeekly:
+			next = next.AddDate(0, 0, 7)
+		case facility.RecurrenceMonthly:
+			next = next.AddDate(0, 1, 0)
+		}
+		if req.EndAt.After(next) {
+			return nil, fmt.Errorf("%w: window is longer than its recurrence", facility.ErrInvalidMaintenance)
+		}
+	}
+
+	task := &facility.MaintenanceTask{
+		FacilityName: facilityName,
+		Title:        req.Title,
+		Description:  req.Description,
+		StartAt:      req.StartAt,
+		EndAt:        req.EndAt,
+		Recurrence:   req.Recurrence,
+		RecurUntil:   req.RecurUntil,
+		Status:       facility.MaintenancePlanned,
+		CreatedBy:    createdBy,
+		CreatedAt:    utils.LocalTime(),
+		UpdatedAt:    utils.LocalTime(),
+	}
+
+	if req.ResourceId != "" {
+		resource, err := u.facilityRepository.FindOneResource(ctx, facilityName, req.ResourceId)
+		if err != nil {
+			return nil, err
+		}
+		task.ResourceId = resource.Id
+	}
+
+	taskId, err := u.facilityRepository.InsertMaintenance(ctx, task)
 	if err != nil {
 		return nil, err
 	}
 
-	// Ensure each slot has proper max_bookings value
-	for i := range slots {
-		if slots[i].MaxBookings == 0 {
-			slots[i].MaxBookings = 1 // Set default max bookings to 1
+	task.Id = taskId
+	return task, nil
+}
+
+func (u *facilityUsecase) FindManyMaintenance(ctx context.Context, facilityName string, includeDone bool) ([]facility.MaintenanceTask, error) {
+	return u.facilityRepository.FindManyMaintenance(ctx, facilityName, includeDone)
+}
+
+func (u *facilityUsecase) UpdateMaintenanceStatus(ctx context.Context, facilityName, taskId, status string) (*facility.MaintenanceTask, error) {
+	if err := u.facilityRepository.UpdateMaintenanceStatus(ctx, facilityName, taskId, status); err != nil {
+		return nil, err
+	}
+
+	return u.facilityRepository.FindOneMaintenance(ctx, facilityName, taskId)
+}
+
+func (u *facilityUsecase) DeleteMaintenance(ctx context.Context, facilityName, taskId string) error {
+	return u.facilityRepository.DeleteMaintenance(ctx, facilityName, taskId)
+}
+
+// IsSlotUnderMaintenance reports whether an unfinished maintenance task covers the slot on day
+func (u *facilityUsecase) IsSlotUnderMaintenance(ctx context.Context, facilityName string, slot *facility.Slot, day time.Time) (bool, error) {
+	tasks, err := u.facilityRepository.FindManyMaintenance(ctx, facilityName, false)
+	if err != nil {
+		return false, err
+	}
+
+	for i := range tasks {
+		if facility.MaintenanceBlocksSlot(&tasks[i], slot, day) {
+			return true, nil
 		}
 	}
 
-	return slots, nil
+	return false, nil
 }
 
-// UpdateSlot updates a slot's details
-func (u *facilityUsecase) UpdateSlot(ctx context.Context, facilityName string, req *facility.Slot) (*facility.Slot, error) {
-	return u.facilityRepository.UpdateSlot(ctx, facilityName, req)
+func (u *facilityUsecase) UpdateOpeningHours(ctx context.Context, facilityName string, req *facility.OpeningHoursRequest) (*facility.FacilityBson, error) {
+	if err := facility.ValidateOpeningHours(req.OpeningHours); err != nil {
+		return nil, err
+	}
+
+	hours := append([]facility.OpeningHour{}, req.OpeningHours...)
+	sort.Slice(hours, func(i, j int) bool {
+		return hours[i].Weekday < hours[j].Weekday
+	})
+
+	if err := u.facilityRepository.UpdateOpeningHours(ctx, facilityName, hours); err != nil {
+		return nil, err
+	}
+
+	return u.FindOneFacility(ctx, "", facilityName)
 }
 
-// DeleteSlot deletes a slot by its ID
-func (u *facilityUsecase) DeleteSlot(ctx context.Context, facilityName, slotId string) error {
-	return u.facilityRepository.DeleteSlot(ctx, facilityName, slotId)
+func (u *facilityUsecase) AddHoliday(ctx context.Context, req *facility.HolidayRequest) (*facility.CalendarDay, error) {
+	if _, err := facility.ParseDate(req.Date, utils.Location()); err != nil {
+		return nil, err
+	}
+
+	return u.facilityRepository.UpsertCalendarDay(ctx, &facility.CalendarDay{
+		Date:      req.Date,
+		Kind:      facility.CalendarHoliday,
+		Name:      req.Name,
+		Source:    facility.CalendarSourceManual,
+		CreatedAt: utils.LocalTime(),
+		UpdatedAt: utils.LocalTime(),
+	})
+}
+
+// ImportHolidays stores every day of every event in an iCal file as a public
+// holiday. Days already on the calendar are updated, not duplicated.
+func (u *facilityUsecase) ImportHolidays(ctx context.Context, r io.Reader) (*facility.HolidayImportResponse, error) {
+	days, err := facility.ParseICalHolidays(r, utils.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &facility.HolidayImportResponse{Holidays: make([]facility.CalendarDay, 0, len(days))}
+	for i := range days {
+		days[i].CreatedAt = utils.LocalTime()
+		days[i].UpdatedAt = utils.LocalTime()
+		day, err := u.facilityRepository.UpsertCalendarDay(ctx, &days[i])
+		if err != nil {
+			return nil, err
+		}
+		result.Holidays = append(result.Holidays, *day)
+	}
+	result.Imported = len(result.Holidays)
+
+	return result, nil
 }
 
-// UpdateBadmintonSlot updates a badminton slot's details
-func (u *facilityUsecase) UpdateBadmintonSlot(ctx context.Context, req *facility.BadmintonSlot) error {
-	return u.facilityRepository.UpdateBadmintonSlot(ctx, req)
+func (u *facilityUsecase) FindManyHoliday(ctx context.Context, from, to string) ([]facility.CalendarDay, error) {
+	return u.facilityRepository.FindManyCalendarDay(ctx, "", facility.CalendarHoliday, from, to)
 }
 
-// DeleteBadmintonCourt deletes a badminton court by its ID
-func (u *facilityUsecase) DeleteBadmintonCourt(ctx context.Context, courtId string) error {
-	return u.facilityRepository.DeleteBadmintonCourt(ctx, courtId)
+func (u *facilityUsecase) DeleteHoliday(ctx context.Context, date string) error {
+	return u.facilityRepository.DeleteCalendarDay(ctx, "", facility.CalendarHoliday, date)
 }
 
-// DeleteBadmintonSlot deletes a badminton slot by its ID
-func (u *facilityUsecase) DeleteBadmintonSlot(ctx context.Context, slotId string) error {
-	return u.facilityRepository.DeleteBadmintonSlot(ctx, slotId)
-}
\ No newline at end of file
+// AddSpecialDay opens a facility with its own hours on one date, overriding
+// its weekly hours and any public holiday
+func (u *facilityUsecase) AddSpecialDay(ctx context.Context, facilityName string, req *facility.SpecialDayRequest) (*facility.CalendarDay, error) {
+	date, err := facility.ParseDate(req.Date, utils.Location())
+	if err != nil {
+		return nil, err
+	}
+	if err := facility.ValidateOpeningHours([]facility.OpeningHour{{
+		Weekday:   int(date.Weekday()),
+		OpenTime:  req.OpenTime,
+		CloseTime: req.CloseTime,
+	}}); err != nil {
+		return nil, err
+	}
+
+	return u.facilityRepository.UpsertCalendarDay(ctx, &facility.CalendarDay{
+		FacilityName: facilityName,
+		Date:         req.Date,
+		Kind:         facility.CalendarSpecial,
+		Name:         req.Name,
+		OpenTime:     req.OpenTime,
+		CloseTime:    req.CloseTime,
+		Source:       facility.CalendarSourceManual,
+		CreatedAt:    utils.LocalTime(),
+		UpdatedAt:    utils.LocalTime(),
+	})
+}
+
+func (u *facilityUsecase) DeleteSpecialDay(ctx context.Context, facilityName, date string) error {
+	return u.facilityRepository.DeleteCalendarDay(ctx, facilityName, facility.CalendarSpecial, date)
+}
+
+// FindFacilityCalendar resolves each day from from to to inclusive, defaulting
+// to the next 30 days and capped at a year
+func (u *facilityUsecase) FindFacilityCalendar(ctx context.Context, facilityName, from, to string) ([]facility.DayHours, error) {
+	loc := utils.Location()
+
+	now := utils.LocalTime()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if from != "" {
+		var err error
+		if start, err = facility.ParseDate(from, loc); err != nil {
+			return nil, err
+		}
+	}
+	end := start.AddDate(0, 0, defaultCalendarDays)
+	if to != "" {
+		var err error
+		if end, err = facility.ParseDate(to, loc); err != nil {
+			return nil, err
+		}
+	}
+	if end.Before(start) || end.After(start.AddDate(0, 0, maxCalendarDays)) {
+		return nil, fmt.Errorf("%w: range must be between 0 and %d days", facility.ErrInvalidDate, maxCalendarDays)
+	}
+
+	result, err := u.facilityRepository.FindOneFacility(ctx, "", facilityName)
+	if err != nil {
+		return nil, err
+	}
+	days, err := u.facilityRepository.FindManyCalendarDay(ctx, facilityName, "", start.Format(facility.DateLayout), end.Format(facility.DateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := make([]facility.DayHours, 0)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		calendar = append(calendar, facility.ResolveDayHours(result.OpeningHours, days, day))
+	}
+	return calendar, nil
+}
+
+// IsSlotOpenOn checks the slot's time range against the facility's opening on day
+func (u *facilityUsecase) IsSlotOpenOn(ctx context.Context, facilityName string, slot *facility.Slot, day time.Time) (bool, error) {
+	result, err := u.facilityRepository.FindOneFacility(ctx, "", facilityName)
+	if err != nil {
+		return false, err
+	}
+	date := day.In(utils.Location()).Format(facility.DateLayout)
+	days, err := u.facilityRepository.FindManyCalendarDay(ctx, facilityName, "", date, date)
+	if err != nil {
+		return false, err
+	}
+
+	hours := facility.ResolveDayHours(result.OpeningHours, days, day.In(utils.Location()))
+	return hours.WithinHours(slot.TimeRange), nil
+}
+
+// checkOpeningHours rejects slot times outside the facility's weekly hours
+func (u *facilityUsecase) checkOpeningHours(ctx context.Context, facilityName string, r utils.TimeRange, weekdays []int) error {
+	result, err := u.facilityRepository.FindOneFacility(ctx, "", facilityName)
+	if err != nil {
+		return err
+	}
+	if !facility.SlotWithinOpeningHours(result.OpeningHours, r, weekdays) {
+		return facility.ErrOutsideOpeningHours
+	}
+	return nil
+}
+
+// applyFacilityHours fills a template without opening hours from the facility
+// and makes sure the template never opens outside them
+func (u *facilityUsecase) applyFacilityHours(ctx context.Context, template *facility.SlotTemplate) error {
+	result, err := u.facilityRepository.FindOneFacility(ctx, "", template.FacilityName)
+	if err != nil {
+		return err
+	}
+
+	if len(template.OpeningHours) == 0 {
+		if len(result.OpeningHours) == 0 {
+			return fmt.Errorf("%w: no opening hours set on the template or facility", facility.ErrInvalidTemplate)
+		}
+		template.OpeningHours = result.OpeningHours
+		return nil
+	}
+
+	for _, hours := range template.OpeningHours {
+		opening, err := utils.NewTimeRange(hours.OpenTime, hours.CloseTime)
+		if err != nil {
+			return err
+		}
+		if !facility.SlotWithinOpeningHours(result.OpeningHours, opening, []int{hours.Weekday}) {
+			return fmt.Errorf("%w: weekday %d", facility.ErrOutsideOpeningHours, hours.Weekday)
+		}
+	}
+	return nil
+}
+
+// UploadMedia checks the sniffed type and size, stores the original with a
+// JPEG thumbnail per size and appends the media to the end of the facility's list
+func (u *facilityUsecase) UploadMedia(ctx context.Context, facilityName, uploadedBy string, req *facility.UploadMediaRequest, data []byte) (*facility.FacilityImage, error) {
+	if len(data) > facility.MaxMediaSize {
+		return nil, facility.ErrMediaTooLarge
+	}
+	contentType, ext, err := imaging.SniffType(data)
+	if err != nil {
+		return nil, facility.ErrInvalidMediaType
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", facility.ErrInvalidMediaType, err)
+	}
+
+	if _, err := u.facilityRepository.FindOneFacility(ctx, "", facilityName); err != nil {
+		return nil, err
+	}
+	existing, err := u.facilityRepository.FindManyMedia(ctx, facilityName)
+	if err != nil {
+		return nil, err
+	}
+
+	media := &facility.FacilityMedia{
+		Id:           primitive.NewObjectID(),
+		FacilityName: facilityName,
+		Kind:         req.Kind,
+		Caption:      req.Caption,
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        img.Bounds().Dx(),
+		Height:       img.Bounds().Dy(),
+		Thumbnails:   make(map[string]string, len(facility.ThumbnailSizes)),
+		Position:     len(existing),
+		UploadedBy:   uploadedBy,
+		CreatedAt:    utils.LocalTime(),
+		UpdatedAt:    utils.LocalTime(),
+	}
+	prefix := fmt.Sprintf("%s%s/%s/", mediaKeyPrefix, facilityName, media.Id.Hex())
+	media.Key = prefix + "original." + ext
+
+	if err := u.blobStore.Put(ctx, media.Key, contentType, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	for name, side := range facility.ThumbnailSizes {
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, imaging.Thumbnail(img, side)); err != nil {
+			u.deleteMediaBlobs(ctx, media)
+			return nil, fmt.Errorf("error: generate thumbnail failed: %w", err)
+		}
+		key := prefix + name + ".jpg"
+		if err := u.blobStore.Put(ctx, key, "image/jpeg", &buf); err != nil {
+			u.deleteMediaBlobs(ctx, media)
+			return nil, err
+		}
+		media.Thumbnails[name] = key
+	}
+
+	if _, err := u.facilityRepository.InsertMedia(ctx, media); err != nil {
+		u.deleteMediaBlobs(ctx, media)
+		return nil, err
+	}
+
+	image := u.toFacilityImage(media)
+	return &image, nil
+}
+
+func (u *facilityUsecase) FindManyMedia(ctx context.Context, facilityName string) ([]facility.FacilityImage, error) {
+	media, err := u.facilityRepository.FindManyMedia(ctx, facilityName)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]facility.FacilityImage, 0, len(media))
+	for i := range media {
+		images = append(images, u.toFacilityImage(&media[i]))
+	}
+	return images, nil
+}
+
+// ReorderMedia takes the facility's complete media list in the new order
+func (u *facilityUsecase) ReorderMedia(ctx context.Context, facilityName string, req *facility.ReorderMediaRequest) ([]facility.FacilityImage, error) {
+	media, err := u.facilityRepository.FindManyMedia(ctx, facilityName)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.MediaIds) != len(media) {
+		return nil, facility.ErrInvalidMediaOrder
+	}
+
+	known := make(map[string]bool, len(media))
+	for _, m := range media {
+		known[m.Id.Hex()] = true
+	}
+	ids := make([]primitive.ObjectID, 0, len(req.MediaIds))
+	for _, id := range req.MediaIds {
+		if !known[id] {
+			return nil, facility.ErrInvalidMediaOrder
+		}
+		delete(known, id)
+		ids = append(ids, utils.ConvertToObjectId(id))
+	}
+
+	if err := u.facilityRepository.UpdateMediaPositions(ctx, facilityName, ids); err != nil {
+		return nil, err
+	}
+	return u.FindManyMedia(ctx, facilityName)
+}
+
+func (u *facilityUsecase) DeleteMedia(ctx context.Context, facilityName, mediaId string) error {
+	media, err := u.facilityRepository.FindOneMedia(ctx, facilityName, mediaId)
+	if err != nil {
+		return err
+	}
+	if err := u.facilityRepository.DeleteMedia(ctx, facilityName, mediaId); err != nil {
+		return err
+	}
+
+	u.deleteMediaBlobs(ctx, media)
+	return nil
+}
+
+// OpenMedia opens a stored facility image for the public media route
+func (u *facilityUsecase) OpenMedia(ctx context.Context, key string) (io.ReadCloser, error) {
+	cleaned, err := storage.CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(cleaned, mediaKeyPrefix) {
+		return nil, storage.ErrInvalidKey
+	}
+	return u.blobStore.Get(ctx, cleaned)
+}
+
+// deleteMediaBlobs removes stored files best effort; a leftover blob is only wasted space
+func (u *facilityUsecase) deleteMediaBlobs(ctx context.Context, media *facility.FacilityMedia) {
+	keys := []string{media.Key}
+	for _, key := range media.Thumbnails {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		if err := u.blobStore.Delete(ctx, key); err != nil {
+			log.Printf("Error: deleteMediaBlobs: %s", err.Error())
+		}
+	}
+}
+
+func (u *facilityUsecase) toFacilityImage(media *facility.FacilityMedia) facility.FacilityImage {
+	thumbnails := make(map[string]string, len(media.Thumbnails))
+	for name, key := range media.Thumbnails {
+		thumbnails[name] = u.blobStore.URL(key)
+	}
+	return facility.FacilityImage{
+		Id:         media.Id.Hex(),
+		Kind:       media.Kind,
+		Caption:    media.Caption,
+		Url:        u.blobStore.URL(media.Key),
+		Thumbnails: thumbnails,
+		Width:      media.Width,
+		Height:     media.Height,
+		Position:   media.Position,
+	}
+}
+
+func (u *facilityUsecase) SaveSlotTemplate(ctx context.Context, facilityName string, req *facility.SlotTemplateRequest) (*facility.SlotTemplate, error) {
+	template := &facility.SlotTemplate{
+		FacilityName:        facilityName,
+		OpeningHours:        req.OpeningHours,
+		SlotDurationMinutes: req.SlotDurationMinutes,
+		GapMinutes:          req.GapMinutes,
+		CapacityPerSlot:     req.CapacityPerSlot,
+		ResourceIds:         req.ResourceIds,
+		CreatedAt:           utils.LocalTime(),
+		UpdatedAt:           utils.LocalTime(),
+	}
+
+	if err := u.applyFacilityHours(ctx, template); err != nil {
+		return nil, err
+	}
+
+	// Reject templates that can't be expanded before storing them
+	if _, err := generateSlotPlans(template); err != nil {
+		return nil, err
+	}
+
+	return u.facilityRepository.UpsertSlotTemplate(ctx, template)
+}
+
+func (u *facilityUsecase) FindSlotTemplate(ctx context.Context, facilityName string) (*facility.SlotTemplate, error) {
+	return u.facilityRepository.FindSlotTemplate(ctx, facilityName)
+}
+
+func (u *facilityUsecase) PreviewSlotTemplate(ctx context.Context, facilityName string) (*facility.SlotTemplateDiff, error) {
+	template, err := u.facilityRepository.FindSlotTemplate(ctx, facilityName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Facilities split into resources get a slot set per resource by default
+	if len(template.ResourceIds) == 0 {
+		resources, err := u.facilityRepository.FindManyResource(ctx, facilityName)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			template.ResourceIds = append(template.ResourceIds, resource.Id.Hex())
+		}
+	}
+
+	// Opening hours may have been narrowed since the template was saved
+	if err := u.applyFacilityHours(ctx, template); err != nil {
+		return nil, err
+	}
+
+	plans, err := generateSlotPlans(template)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := u.existingSlotPlans(ctx, facilityName)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffSlotPlans(plans, existing), nil
+}
+
+// ApplySlotTemplate regenerates a facility's slots from its template. Slots
+// with bookings are never deleted or shrunk; they are returned as blocked.
+func (u *facilityUsecase) ApplySlotTemplate(ctx context.Context, facilityName string) (*facility.SlotTemplateDiff, error) {
+	diff, err := u.PreviewSlotTemplate(ctx, facilityName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, plan := range diff.Delete {
+		if err := u.DeleteSlot(ctx, facilityName, plan.ExistingId); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, plan := range diff.Update {
+		slot, err := u.facilityRepository.FindOneSlot(ctx, facilityName, plan.ExistingId)
+		if err != nil {
+			return nil, err
+		}
+		slot.MaxBookings = plan.MaxBookings
+		slot.Weekdays = plan.Weekdays
+		slot.UpdatedAt = time.Now()
+		if _, err := u.facilityRepository.UpdateSlot(ctx, facilityName, slot); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, plan := range diff.Create {
+		timeRange, err := utils.NewTimeRange(plan.StartTime, plan.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := u.facilityRepository.InsertSlot(ctx, facilityName, facility.Slot{
+			TimeRange:    timeRange,
+			Status:       1,
+			MaxBookings:  plan.MaxBookings,
+			FacilityType: facilityName,
+			Weekdays:     plan.Weekdays,
+			ResourceId:   utils.ConvertToObjectId(plan.ResourceId),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return diff, nil
+}
+
+func (u *facilityUsecase) existingSlotPlans(ctx context.Context, facilityName string) ([]facility.SlotPlan, error) {
+	plans := make([]facility.SlotPlan, 0)
+
+	slots, err := u.facilityRepository.FindManySlot(ctx, facilityName)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range slots {
+		plans = append(plans, facility.SlotPlan{
+			ExistingId:      s.Id.Hex(),
+			StartTime:       s.StartTime.String(),
+			EndTime:         s.EndTime.String(),
+			ResourceId:      resourceHex(s.ResourceId),
+			Weekdays:        s.Weekdays,
+			MaxBookings:     s.MaxBookings,
+			CurrentBookings: s.CurrentBookings,
+		})
+	}
+
+	return plans, nil
+}
+
+func resourceHex(id primitive.ObjectID) string {
+	if id.IsZero() {
+		return ""
+	}
+	return id.Hex()
+}
@@ -0,0 +1,185 @@
+package usecase
+
+import (
+	"fmt"
+	"main/modules/facility"
+	"sort"
+	"time"
+)
+
+// generateSlotPlans expands a template into one plan per distinct time range
+// and resource. Ranges that occur on every weekday are left without weekdays so
+// they match slots created before templates existed.
+func generateSlotPlans(t *facility.SlotTemplate) ([]facility.SlotPlan, error) {
+	if t.SlotDurationMinutes <= 0 || t.GapMinutes < 0 || t.CapacityPerSlot <= 0 {
+		return nil, fmt.Errorf("%w: duration and capacity must be positive", facility.ErrInvalidTemplate)
+	}
+
+	type timeKey struct{ start, end int }
+	weekdaysByRange := make(map[timeKey][]int)
+	seenWeekday := make(map[int]bool)
+
+	for _, hours := range t.OpeningHours {
+		if hours.Weekday < 0 || hours.Weekday > 6 || seenWeekday[hours.Weekday] {
+			return nil, fmt.Errorf("%w: weekday %d is invalid or repeated", facility.ErrInvalidTemplate, hours.Weekday)
+		}
+		seenWeekday[hours.Weekday] = true
+
+		open, err := parseSlotMinutes(hours.OpenTime)
+		if err != nil {
+			return nil, err
+		}
+		close, err := parseSlotMinutes(hours.CloseTime)
+		if err != nil {
+			return nil, err
+		}
+		if open >= close {
+			return nil, facility.ErrInvalidTimeRange
+		}
+
+		for start := open; start+t.SlotDurationMinutes <= close; start += t.SlotDurationMinutes + t.GapMinutes {
+			key := timeKey{start, start + t.SlotDurationMinutes}
+			weekdaysByRange[key] = append(weekdaysByRange[key], hours.Weekday)
+		}
+	}
+
+	keys := make([]timeKey, 0, len(weekdaysByRange))
+	for key := range weekdaysByRange {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].start != keys[j].start {
+			return keys[i].start < keys[j].start
+		}
+		return keys[i].end < keys[j].end
+	})
+
+	resources := t.ResourceIds
+	if len(resources) == 0 {
+		resources = []string{""}
+	}
+
+	plans := make([]facility.SlotPlan, 0, len(keys)*len(resources))
+	for _, resource := range resources {
+		for _, key := range keys {
+			weekdays := weekdaysByRange[key]
+			sort.Ints(weekdays)
+			if len(weekdays) == 7 {
+				weekdays = nil
+			}
+			plans = append(plans, facility.SlotPlan{
+				StartTime:   formatSlotMinutes(key.start),
+				EndTime:     formatSlotMinutes(key.end),
+				ResourceId:  resource,
+				Weekdays:    weekdays,
+				MaxBookings: t.CapacityPerSlot,
+			})
+		}
+	}
+
+	return plans, nil
+}
+
+// diffSlotPlans compares generated plans with the stored slots. Stored slots
+// the template drops are only deleted when nobody has booked them; otherwise
+// they are kept and any generated slot overlapping them is reported as a conflict.
+func diffSlotPlans(plans, existing []facility.SlotPlan) *facility.SlotTemplateDiff {
+	diff := &facility.SlotTemplateDiff{
+		Create:    []facility.SlotPlan{},
+		Update:    []facility.SlotPlan{},
+		Delete:    []facility.SlotPlan{},
+		Blocked:   []facility.SlotPlan{},
+		Conflicts: []facility.SlotPlan{},
+	}
+
+	planKey := func(p facility.SlotPlan) string {
+		return fmt.Sprintf("%s|%s|%s", p.ResourceId, p.StartTime, p.EndTime)
+	}
+
+	existingByKey := make(map[string]facility.SlotPlan, len(existing))
+	for _, e := range existing {
+		existingByKey[planKey(e)] = e
+	}
+
+	matched := make(map[string]bool)
+	var creates []facility.SlotPlan
+	for _, plan := range plans {
+		key := planKey(plan)
+		current, ok := existingByKey[key]
+		if !ok {
+			creates = append(creates, plan)
+			continue
+		}
+		matched[key] = true
+
+		if current.MaxBookings == plan.MaxBookings && sameWeekdays(current.Weekdays, plan.Weekdays) {
+			continue
+		}
+		plan.ExistingId = current.ExistingId
+		plan.CurrentBookings = current.CurrentBookings
+		if plan.MaxBookings < current.CurrentBookings {
+			diff.Blocked = append(diff.Blocked, current)
+			continue
+		}
+		diff.Update = append(diff.Update, plan)
+	}
+
+	for _, e := range existing {
+		if matched[planKey(e)] {
+			continue
+		}
+		if e.CurrentBookings > 0 {
+			diff.Blocked = append(diff.Blocked, e)
+		} else {
+			diff.Delete = append(diff.Delete, e)
+		}
+	}
+
+	for _, plan := range creates {
+		if overlapsAny(plan, diff.Blocked) {
+			diff.Conflicts = append(diff.Conflicts, plan)
+			continue
+		}
+		diff.Create = append(diff.Create, plan)
+	}
+
+	return diff
+}
+
+func overlapsAny(plan facility.SlotPlan, kept []facility.SlotPlan) bool {
+	for _, k := range kept {
+		if k.ResourceId != plan.ResourceId || !weekdaysIntersect(k.Weekdays, plan.Weekdays) {
+			continue
+		}
+		planStart, _ := parseSlotMinutes(plan.StartTime)
+		planEnd, _ := parseSlotMinutes(plan.EndTime)
+		keptStart, err := parseSlotMinutes(k.StartTime)
+		if err != nil {
+			continue
+		}
+		keptEnd, err := parseSlotMinutes(k.EndTime)
+		if err != nil {
+			continue
+		}
+		if planStart < keptEnd && keptStart < planEnd {
+			return true
+		}
+	}
+	return false
+}
+
+func sameWeekdays(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func formatSlotMinutes(minutes int) string {
+	return time.Date(0, 1, 1, minutes/60, minutes%60, 0, 0, time.UTC).Format("15:04")
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"main/modules/facility"
+	"main/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// slotRange is the part of a slot needed to detect overlaps on the same resource
+type slotRange struct {
+	Id       primitive.ObjectID
+	Range    utils.TimeRange
+	Weekdays []int
+}
+
+func parseSlotMinutes(value string) (int, error) {
+	t, err := utils.ParseClock(value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Minutes(), nil
+}
+
+// checkSlotTimes validates the range and that it doesn't overlap any existing
+// range, sharing a weekday, other than the one identified by selfId.
+func checkSlotTimes(r utils.TimeRange, weekdays []int, selfId primitive.ObjectID, existing []slotRange) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	for _, other := range existing {
+		if other.Id == selfId || !weekdaysIntersect(weekdays, other.Weekdays) {
+			continue
+		}
+		if r.Overlaps(other.Range) {
+			return facility.ErrSlotOverlap
+		}
+	}
+
+	return nil
+}
+
+// applySlotUpdate overwrites the fields set in req, parsing the new times
+func applySlotUpdate(r *utils.TimeRange, maxBookings *int, req *facility.UpdateSlotRequest) error {
+	if req.StartTime != "" {
+		start, err := utils.ParseClock(req.StartTime)
+		if err != nil {
+			return err
+		}
+		r.StartTime = start
+	}
+	if req.EndTime != "" {
+		end, err := utils.ParseClock(req.EndTime)
+		if err != nil {
+			return err
+		}
+		r.EndTime = end
+	}
+	if req.MaxBookings != 0 {
+		*maxBookings = req.MaxBookings
+	}
+	return nil
+}
+
+// weekdaysIntersect treats an empty weekday list as every day
+func weekdaysIntersect(a, b []int) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
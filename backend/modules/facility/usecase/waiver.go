@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"main/modules/facility"
+	"main/pkg/utils"
+)
+
+// PublishWaiver stores the next version of a facility's waiver. Acceptances
+// of earlier versions stop counting as soon as it is stored.
+func (u *facilityUsecase) PublishWaiver(ctx context.Context, facilityName, publishedBy string, req *facility.PublishWaiverRequest) (*facility.Waiver, error) {
+	if _, err := u.facilityRepository.FindOneFacility(ctx, "", facilityName); err != nil {
+		return nil, err
+	}
+
+	version := 1
+	latest, err := u.facilityRepository.FindLatestWaiver(ctx, facilityName)
+	switch {
+	case err == nil:
+		version = latest.Version + 1
+	case !errors.Is(err, facility.ErrWaiverNotFound):
+		return nil, err
+	}
+
+	waiver := &facility.Waiver{
+		FacilityName: facilityName,
+		Version:      version,
+		Title:        req.Title,
+		Body:         req.Body,
+		PublishedBy:  publishedBy,
+		CreatedAt:    utils.LocalTime(),
+	}
+	waiverId, err := u.facilityRepository.InsertWaiver(ctx, waiver)
+	if err != nil {
+		return nil, err
+	}
+
+	waiver.Id = waiverId
+	return waiver, nil
+}
+
+func (u *facilityUsecase) FindCurrentWaiver(ctx context.Context, facilityName string) (*facility.Waiver, error) {
+	return u.facilityRepository.FindLatestWaiver(ctx, facilityName)
+}
+
+func (u *facilityUsecase) FindManyWaiver(ctx context.Context, facilityName string) ([]facility.Waiver, error) {
+	return u.facilityRepository.FindManyWaiver(ctx, facilityName)
+}
+
+// FindWaiverStatus reports whether the user has accepted the current waiver.
+// Facilities without a waiver need no acceptance.
+func (u *facilityUsecase) FindWaiverStatus(ctx context.Context, facilityName, userId string) (*facility.WaiverStatus, error) {
+	waiver, err := u.facilityRepository.FindLatestWaiver(ctx, facilityName)
+	if errors.Is(err, facility.ErrWaiverNotFound) {
+		return &facility.WaiverStatus{Accepted: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	acceptances, err := u.facilityRepository.FindManyWaiverAcceptance(ctx, facilityName, userId, waiver.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &facility.WaiverStatus{Waiver: waiver, Accepted: len(acceptances) > 0}
+	if status.Accepted {
+		status.Acceptance = &acceptances[0]
+	}
+	return status, nil
+}
+
+// AcceptWaiver records the user's acceptance of the current version only
+func (u *facilityUsecase) AcceptWaiver(ctx context.Context, facilityName, userId, ipAddress string, req *facility.AcceptWaiverRequest) (*facility.WaiverAcceptance, error) {
+	waiver, err := u.facilityRepository.FindLatestWaiver(ctx, facilityName)
+	if err != nil {
+		return nil, err
+	}
+	if req.Version != waiver.Version {
+		return nil, facility.ErrWaiverOutdated
+	}
+
+	return u.facilityRepository.UpsertWaiverAcceptance(ctx, &facility.WaiverAcceptance{
+		FacilityName: facilityName,
+		WaiverId:     waiver.Id,
+		Version:      waiver.Version,
+		UserId:       userId,
+		IpAddress:    ipAddress,
+		AcceptedAt:   utils.LocalTime(),
+	})
+}
+
+// FindManyWaiverAcceptance lists who accepted which version; a zero version lists all
+func (u *facilityUsecase) FindManyWaiverAcceptance(ctx context.Context, facilityName string, version int) ([]facility.WaiverAcceptance, error) {
+	return u.facilityRepository.FindManyWaiverAcceptance(ctx, facilityName, "", version)
+}
@@ -0,0 +1,32 @@
+package facility
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type (
+	// Waiver is one published version of a facility's safety rules. Versions
+	// are never edited; publishing a new one requires every user to accept again.
+	Waiver struct {
+		Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		FacilityName string             `bson:"facility_name" json:"facility_name"`
+		Version      int                `bson:"version" json:"version"`
+		Title        string             `bson:"title" json:"title"`
+		Body         string             `bson:"body" json:"body"`
+		PublishedBy  string             `bson:"published_by" json:"published_by"`
+		CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	}
+
+	// WaiverAcceptance records that a user accepted one version of a facility's waiver
+	WaiverAcceptance struct {
+		Id           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		FacilityName string             `bson:"facility_name" json:"facility_name"`
+		WaiverId     primitive.ObjectID `bson:"waiver_id" json:"waiver_id"`
+		Version      int                `bson:"version" json:"version"`
+		UserId       string             `bson:"user_id" json:"user_id"`
+		IpAddress    string             `bson:"ip_address" json:"ip_address"`
+		AcceptedAt   time.Time          `bson:"accepted_at" json:"accepted_at"`
+	}
+)
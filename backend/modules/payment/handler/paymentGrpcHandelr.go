@@ -23,7 +23,7 @@ func NewPaymentGrpcHandler(paymentUsecase usecase.PaymentUsecaseService) payment
 }
 
 func (h *paymentGrpcHandler) CreatePayment(ctx context.Context, req *paymentPb.CreatePaymentRequest) (*paymentPb.PaymentResponse, error) {
-    result, err := h.paymentUsecase.CreatePayment(ctx, req.UserId, req.BookingId, req.PaymentMethod, req.FacilityName, req.Amount)
+    result, err := h.paymentUsecase.CreatePayment(ctx, req.UserId, req.BookingId, req.PaymentMethod)
     if err != nil {
         return nil, fmt.Errorf("failed to process payment: %v", err)
     }
@@ -34,6 +34,8 @@ func (h *paymentGrpcHandler) CreatePayment(ctx context.Context, req *paymentPb.C
 		status = "Pending"
 	case payment.Completed:
 		status = "Completed"
+	case payment.Expired:
+		status = "Expired"
 	default:
 		status = "Unknown"
 	}
@@ -51,6 +53,10 @@ func (h *paymentGrpcHandler) CreatePayment(ctx context.Context, req *paymentPb.C
 		CreatedAt:    result.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:    result.UpdatedAt.Format(time.RFC3339),
 		QrCodeUrl:    result.QRCodeURL,
+		ExpiresAt:    result.ExpiresAt.Format(time.RFC3339),
+		Provider:     result.Provider,
+		CheckoutUrl:  result.CheckoutUrl,
+		ProviderRef:  result.ProviderRef,
 	}, nil
 	
 }
@@ -68,6 +74,8 @@ func (h *paymentGrpcHandler) UpdatePaymentStatus(ctx context.Context, req *payme
 		status = "Pending"
 	case payment.Completed:
 		status = "Completed"
+	case payment.Expired:
+		status = "Expired"
 	default:
 		status = "Unknown"
 	}
@@ -85,5 +93,97 @@ func (h *paymentGrpcHandler) UpdatePaymentStatus(ctx context.Context, req *payme
 		CreatedAt:    result.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:    result.UpdatedAt.Format(time.RFC3339),
 		QrCodeUrl:    result.QRCodeURL,
+		ExpiresAt:    result.ExpiresAt.Format(time.RFC3339),
+		Provider:     result.Provider,
+		CheckoutUrl:  result.CheckoutUrl,
+		ProviderRef:  result.ProviderRef,
 	}, nil
-}
\ No newline at end of file
+}
+
+// RequestRefund is called by trusted services, so it isn't limited to the payer
+func (h *paymentGrpcHandler) RequestRefund(ctx context.Context, req *paymentPb.RequestRefundRequest) (*paymentPb.RefundResponse, error) {
+	result, err := h.paymentUsecase.RequestRefund(ctx, req.PaymentId, req.RequestedBy, true, &payment.RefundRequest{
+		Amount: req.Amount,
+		Method: req.Method,
+		Reason: req.Reason,
+		Note:   req.Note,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to request refund: %v", err)
+	}
+	return refundToPb(result), nil
+}
+
+func (h *paymentGrpcHandler) ApproveRefund(ctx context.Context, req *paymentPb.RefundDecisionRequest) (*paymentPb.RefundResponse, error) {
+	result, err := h.paymentUsecase.ApproveRefund(ctx, req.RefundId, req.AdminId, refundDecisionFromPb(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve refund: %v", err)
+	}
+	return refundToPb(result), nil
+}
+
+func (h *paymentGrpcHandler) RejectRefund(ctx context.Context, req *paymentPb.RefundDecisionRequest) (*paymentPb.RefundResponse, error) {
+	result, err := h.paymentUsecase.RejectRefund(ctx, req.RefundId, req.AdminId, refundDecisionFromPb(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject refund: %v", err)
+	}
+	return refundToPb(result), nil
+}
+
+func (h *paymentGrpcHandler) CompleteRefund(ctx context.Context, req *paymentPb.RefundDecisionRequest) (*paymentPb.RefundResponse, error) {
+	result, err := h.paymentUsecase.CompleteRefund(ctx, req.RefundId, req.AdminId, refundDecisionFromPb(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete refund: %v", err)
+	}
+	return refundToPb(result), nil
+}
+
+func (h *paymentGrpcHandler) ListRefunds(ctx context.Context, req *paymentPb.ListRefundsRequest) (*paymentPb.ListRefundsResponse, error) {
+	result, err := h.paymentUsecase.FindManyRefund(ctx, req.PaymentId, payment.RefundStatus(req.Status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refunds: %v", err)
+	}
+
+	refunds := make([]*paymentPb.RefundResponse, 0, len(result))
+	for i := range result {
+		refunds = append(refunds, refundToPb(&result[i]))
+	}
+	return &paymentPb.ListRefundsResponse{Refunds: refunds}, nil
+}
+
+func refundDecisionFromPb(req *paymentPb.RefundDecisionRequest) *payment.RefundDecisionRequest {
+	return &payment.RefundDecisionRequest{
+		Note:      req.Note,
+		Reference: req.Reference,
+	}
+}
+
+func refundToPb(r *payment.Refund) *paymentPb.RefundResponse {
+	timeline := make([]*paymentPb.RefundEvent, 0, len(r.Timeline))
+	for _, e := range r.Timeline {
+		timeline = append(timeline, &paymentPb.RefundEvent{
+			Status:  string(e.Status),
+			ActorId: e.ActorId,
+			Note:    e.Note,
+			At:      e.At.Format(time.RFC3339),
+		})
+	}
+
+	return &paymentPb.RefundResponse{
+		RefundId:    r.Id.Hex(),
+		PaymentId:   r.PaymentId.Hex(),
+		BookingId:   r.BookingId,
+		UserId:      r.UserId,
+		Amount:      r.Amount,
+		Currency:    r.Currency,
+		Method:      string(r.Method),
+		Reason:      string(r.Reason),
+		Note:        r.Note,
+		Reference:   r.Reference,
+		Status:      string(r.Status),
+		RequestedBy: r.RequestedBy,
+		Timeline:    timeline,
+		CreatedAt:   r.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   r.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -1,13 +1,26 @@
 package handler
 
 import (
+    "context"
+    "errors"
     "fmt"
-    client "main/client/payment"
+    "io"
     "main/config"
+    "main/modules/auth"
     "main/modules/payment"
+    "main/modules/payment/ledger"
+    "main/modules/payment/provider"
     "main/modules/payment/usecase"
+    "main/pkg/qrimage"
+    "main/pkg/rbac"
     "main/pkg/response"
+    "main/pkg/storage"
+    "main/pkg/utils"
+    "mime"
     "net/http"
+    "path"
+    "strconv"
+    "time"
 
     "github.com/labstack/echo/v4"
 )
@@ -16,24 +29,56 @@ type PaymentHttpHandlerService interface {
     CreatePayment(c echo.Context) error
     FindPayment(c echo.Context) error
     FindPaymentsByUser(c echo.Context) error
-    HandlePaymentSuccess(c echo.Context) error
-    SaveSlip(c echo.Context) error
-    UpdateSlipStatus(c echo.Context) error
+    FindPaymentQR(c echo.Context) error
+    SyncPaymentStatus(c echo.Context) error
+    UpdatePaymentStatus(c echo.Context) error
+    HandleWebhook(c echo.Context) error
+    UploadSlip(c echo.Context) error
+    FindSlipURL(c echo.Context) error
+    ServeSlipFile(c echo.Context) error
+    ApproveSlip(c echo.Context) error
+    RejectSlip(c echo.Context) error
     GetPendingSlips(c echo.Context) error
+
+    // Refunds
+    RequestRefund(c echo.Context) error
+    FindPaymentRefunds(c echo.Context) error
+    FindManyRefund(c echo.Context) error
+    ApproveRefund(c echo.Context) error
+    RejectRefund(c echo.Context) error
+    CompleteRefund(c echo.Context) error
+
+    // Receipts
+    FindPaymentReceipts(c echo.Context) error
+    DownloadReceipt(c echo.Context) error
+
+    // Ledger
+    PostJournalEntry(c echo.Context) error
+    ReverseJournalEntry(c echo.Context) error
+    FindJournalEntry(c echo.Context) error
+    FindManyJournalEntry(c echo.Context) error
+    FindAccountBalances(c echo.Context) error
+    CheckLedger(c echo.Context) error
+
+    // Merchant accounts
+    CreateMerchantAccount(c echo.Context) error
+    FindManyMerchantAccount(c echo.Context) error
+    FindMerchantAccount(c echo.Context) error
+    UpdateMerchantAccount(c echo.Context) error
+    DeleteMerchantAccount(c echo.Context) error
+    RotateMerchantAccount(c echo.Context) error
 }
 
 type paymentHttpHandler struct {
     cfg            *config.Config
     paymentUsecase usecase.PaymentUsecaseService
-    paymentClient  *client.PaymentClient
 }
 
 // NewPaymentHttpHandler creates a new PaymentHttpHandler
-func NewPaymentHttpHandler(cfg *config.Config, paymentUsecase usecase.PaymentUsecaseService, paymentClient *client.PaymentClient) PaymentHttpHandlerService {
+func NewPaymentHttpHandler(cfg *config.Config, paymentUsecase usecase.PaymentUsecaseService) PaymentHttpHandlerService {
     return &paymentHttpHandler{
         cfg:            cfg,
         paymentUsecase: paymentUsecase,
-        paymentClient:  paymentClient,
     }
 }
 
@@ -50,7 +95,19 @@ func (h *paymentHttpHandler) CreatePayment(c echo.Context) error {
     }
 
     // Call the usecase to create the payment
-    createdPayment, err := h.paymentUsecase.CreatePayment(c.Request().Context(), req.UserId, req.BookingId, req.PaymentMethod, req.FacilityName , req.Amount)
+    createdPayment, err := h.paymentUsecase.CreatePayment(c.Request().Context(), req.UserId, req.BookingId, req.PaymentMethod)
+    if errors.Is(err, provider.ErrUnknownProvider) {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+    if errors.Is(err, payment.ErrBookingForbidden) {
+        return response.ErrResponse(c, http.StatusForbidden, err.Error())
+    }
+    if errors.Is(err, payment.ErrBookingNotPayable) {
+        return response.ErrResponse(c, http.StatusConflict, err.Error())
+    }
+    if errors.Is(err, payment.ErrNoMerchantAccount) {
+        return response.ErrResponse(c, http.StatusUnprocessableEntity, err.Error())
+    }
     if err != nil {
         return response.ErrResponse(c, http.StatusInternalServerError, "Failed to create payment: "+err.Error())
     }
@@ -69,6 +126,48 @@ func (h *paymentHttpHandler) FindPayment(c echo.Context) error {
     return response.SuccessResponse(c, http.StatusOK, payment)
 }
 
+// SyncPaymentStatus returns a payment after asking its provider whether the
+// charge was paid
+func (h *paymentHttpHandler) SyncPaymentStatus(c echo.Context) error {
+    synced, err := h.paymentUsecase.SyncPaymentStatus(c.Request().Context(), c.Param("id"))
+    switch {
+    case errors.Is(err, payment.ErrPaymentNotFound):
+        return response.ErrResponse(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, provider.ErrChargeNotFound):
+        return response.ErrResponse(c, http.StatusBadGateway, err.Error())
+    case err != nil:
+        return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, payment.NewPaymentResponse(synced))
+}
+
+// UpdatePaymentStatus lets staff settle a pending payment by hand; completing
+// it publishes a payment.completed event that marks its booking paid
+func (h *paymentHttpHandler) UpdatePaymentStatus(c echo.Context) error {
+    var req payment.UpdatePaymentStatusRequest
+    if err := c.Bind(&req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    updated, err := h.paymentUsecase.UpdatePayment(c.Request().Context(), c.Param("id"), req.Status)
+    switch {
+    case errors.Is(err, payment.ErrInvalidPaymentStatus):
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    case errors.Is(err, payment.ErrPaymentNotFound):
+        return response.ErrResponse(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, payment.ErrPaymentNotPayable):
+        return response.ErrResponse(c, http.StatusConflict, err.Error())
+    case err != nil:
+        return response.ErrResponse(c, http.StatusInternalServerError, "Failed to update payment: "+err.Error())
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, updated)
+}
+
 func (h *paymentHttpHandler) FindPaymentsByUser(c echo.Context) error {
     userId := c.Param("userId") // Get the user ID from the URL parameter
 
@@ -85,50 +184,165 @@ func (h *paymentHttpHandler) FindPaymentsByUser(c echo.Context) error {
     return response.SuccessResponse(c, http.StatusOK, payments)
 }
 
+// FindPaymentQR renders a pending payment's QR code as ?format=png|svg at
+// ?size= pixels square
+func (h *paymentHttpHandler) FindPaymentQR(c echo.Context) error {
+    format, err := qrimage.ParseFormat(c.QueryParam("format"))
+    if err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+    size := qrimage.DefaultSize
+    if raw := c.QueryParam("size"); raw != "" {
+        if size, err = strconv.Atoi(raw); err != nil {
+            return response.ErrResponse(c, http.StatusBadRequest, qrimage.ErrInvalidSize.Error())
+        }
+    }
 
-// HandlePaymentSuccess handles payment success callback
-func (h *paymentHttpHandler) HandlePaymentSuccess(c echo.Context) error {
-    paymentID := c.Param("payment_id")
-    if paymentID == "" {
-        return response.ErrResponse(c, http.StatusBadRequest, "Payment ID is required")
+    image, err := h.paymentUsecase.RenderPaymentQR(c.Request().Context(), c.Param("id"), format, size)
+    switch {
+    case errors.Is(err, payment.ErrPaymentNotFound),
+        errors.Is(err, payment.ErrNoQRCode):
+        return response.ErrResponse(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, payment.ErrPaymentNotPayable):
+        return response.ErrResponse(c, http.StatusGone, err.Error())
+    case errors.Is(err, qrimage.ErrInvalidSize):
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    case err != nil:
+        return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
     }
 
-    // Update payment status to "COMPLETED"
-    err := h.paymentClient.UpdatePaymentStatus(paymentID, "COMPLETED")
+    // The code stops being valid once the payment is paid or expires
+    c.Response().Header().Set("Cache-Control", "private, max-age=60")
+    c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+    return c.Blob(http.StatusOK, format.ContentType(), image)
+}
+
+// maxWebhookSize bounds a webhook body; gateway events are a few KB
+const maxWebhookSize = 1 << 20
+
+// HandleWebhook receives a signed event from the provider in the path. The
+// body is read raw, since the signature covers it byte for byte.
+func (h *paymentHttpHandler) HandleWebhook(c echo.Context) error {
+    body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookSize+1))
     if err != nil {
-        return response.ErrResponse(c, http.StatusInternalServerError, "Failed to update payment status: "+err.Error())
+        return response.ErrResponse(c, http.StatusBadRequest, "Failed to read webhook body")
+    }
+    if len(body) > maxWebhookSize {
+        return response.ErrResponse(c, http.StatusRequestEntityTooLarge, "Webhook body is too large")
     }
 
-    return response.SuccessResponse(c, http.StatusOK, "Payment status updated to completed")
+    delivery, err := h.paymentUsecase.HandleWebhook(c.Request().Context(), c.Param("provider"), c.Request().Header, body)
+    switch {
+    case errors.Is(err, provider.ErrUnknownProvider), errors.Is(err, provider.ErrNotSupported):
+        return response.ErrResponse(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, provider.ErrSignatureInvalid), errors.Is(err, provider.ErrWebhookStale):
+        return response.ErrResponse(c, http.StatusUnauthorized, err.Error())
+    case errors.Is(err, payment.ErrInvalidWebhook):
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    case err != nil:
+        // Not applied; the provider retries and the stored delivery is picked up again
+        return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, delivery)
 }
 
-// SaveSlip handles saving a payment slip
-func (h *paymentHttpHandler) SaveSlip(c echo.Context) error {
-    var slip payment.PaymentSlip
-    if err := c.Bind(&slip); err != nil {
-        return response.ErrResponse(c, http.StatusBadRequest, "Invalid input format for slip")
+// UploadSlip takes a slip image as the multipart "file" part for the booking
+// in the booking_id field
+func (h *paymentHttpHandler) UploadSlip(c echo.Context) error {
+    var req payment.UploadSlipRequest
+    if err := c.Bind(&req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
     }
 
-    if err := h.paymentUsecase.SaveSlip(c.Request().Context(), slip); err != nil {
-        return response.ErrResponse(c, http.StatusInternalServerError, "Failed to save slip: "+err.Error())
+    file, err := c.FormFile("file")
+    if err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Missing slip file")
+    }
+    if file.Size > payment.MaxSlipSize {
+        return response.ErrResponse(c, http.StatusRequestEntityTooLarge, payment.ErrSlipTooLarge.Error())
+    }
+    src, err := file.Open()
+    if err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid slip file")
+    }
+    defer src.Close()
+
+    data, err := io.ReadAll(io.LimitReader(src, payment.MaxSlipSize+1))
+    if err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid slip file")
     }
 
-    return response.SuccessResponse(c, http.StatusCreated, "Slip saved successfully")
+    slip, err := h.paymentUsecase.UploadSlip(c.Request().Context(), c.Get("user_id").(string), &req, data)
+    if err != nil {
+        return slipErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusCreated, slip)
+}
+
+// FindSlipURL gives staff a short-lived link to a slip's image
+func (h *paymentHttpHandler) FindSlipURL(c echo.Context) error {
+    link, err := h.paymentUsecase.FindSlipURL(c.Request().Context(), c.Param("slip_id"))
+    if err != nil {
+        return slipErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, link)
+}
+
+// ServeSlipFile streams a slip image for a link signed by FindSlipURL
+func (h *paymentHttpHandler) ServeSlipFile(c echo.Context) error {
+    key := c.Param("*")
+    blob, err := h.paymentUsecase.OpenSlipFile(c.Request().Context(), key, c.QueryParam("expires"), c.QueryParam("signature"))
+    switch {
+    case errors.Is(err, storage.ErrSignatureInvalid), errors.Is(err, storage.ErrSignatureExpired):
+        return response.ErrResponse(c, http.StatusForbidden, err.Error())
+    case errors.Is(err, payment.ErrSlipNotFound), errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrInvalidKey):
+        return response.ErrResponse(c, http.StatusNotFound, payment.ErrSlipNotFound.Error())
+    case err != nil:
+        return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+    }
+    defer blob.Close()
+
+    contentType := mime.TypeByExtension(path.Ext(key))
+    if contentType == "" {
+        contentType = echo.MIMEOctetStream
+    }
+    // Slips are private; don't let shared caches keep a copy past the link's life
+    c.Response().Header().Set("Cache-Control", "private, no-store")
+    c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+    return c.Stream(http.StatusOK, contentType, blob)
+}
+
+// ApproveSlip confirms a slip as the admin in the JWT, completing its payment
+func (h *paymentHttpHandler) ApproveSlip(c echo.Context) error {
+    return h.decideSlip(c, h.paymentUsecase.ApproveSlip)
+}
+
+// RejectSlip turns a slip down as the admin in the JWT; a reason is required
+func (h *paymentHttpHandler) RejectSlip(c echo.Context) error {
+    return h.decideSlip(c, h.paymentUsecase.RejectSlip)
 }
 
-// UpdateSlipStatus handles updating the status of a payment slip
-func (h *paymentHttpHandler) UpdateSlipStatus(c echo.Context) error {
-    slipId := c.Param("slipId")
-    var req payment.UpdateSlipStatusRequest
+func (h *paymentHttpHandler) decideSlip(c echo.Context, decide func(ctx context.Context, slipId, adminId string, req *payment.SlipDecisionRequest) (*payment.PaymentSlip, error)) error {
+    var req payment.SlipDecisionRequest
     if err := c.Bind(&req); err != nil {
-        return response.ErrResponse(c, http.StatusBadRequest, "Invalid input format for status update")
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
     }
 
-    if err := h.paymentUsecase.UpdateSlipStatus(c.Request().Context(), slipId, req.Status); err != nil {
-        return response.ErrResponse(c, http.StatusInternalServerError, "Failed to update slip status: "+err.Error())
+    slip, err := decide(c.Request().Context(), c.Param("slip_id"), c.Get("user_id").(string), &req)
+    if err != nil {
+        return slipErrResponse(c, err)
     }
 
-    return response.SuccessResponse(c, http.StatusOK, "Slip status updated successfully")
+    return response.SuccessResponse(c, http.StatusOK, slip)
 }
 
 // GetPendingSlips handles retrieving all pending payment slips
@@ -148,3 +362,396 @@ func generateQRCodeURL(payment *payment.PaymentEntity) string {
         baseURL, payment.Amount, payment.Currency, payment.UserID, payment.BookingID)
 }
 
+// RequestRefund asks for a refund of a payment; users may only refund their
+// own payments, staff with manage:payments may refund any
+func (h *paymentHttpHandler) RequestRefund(c echo.Context) error {
+    var req payment.RefundRequest
+    if err := c.Bind(&req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    userId := c.Get("user_id").(string)
+    isAdmin := rbac.HasPermission(c.Get("role_code").(int), auth.PermissionManagePayments)
+
+    refund, err := h.paymentUsecase.RequestRefund(c.Request().Context(), c.Param("id"), userId, isAdmin, &req)
+    if err != nil {
+        return refundErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusCreated, refund)
+}
+
+func (h *paymentHttpHandler) FindPaymentRefunds(c echo.Context) error {
+    p, err := h.paymentUsecase.FindPayment(c.Request().Context(), c.Param("id"))
+    if err != nil {
+        return refundErrResponse(c, err)
+    }
+    if p.UserID != c.Get("user_id").(string) && !rbac.HasPermission(c.Get("role_code").(int), auth.PermissionManagePayments) {
+        return refundErrResponse(c, payment.ErrRefundForbidden)
+    }
+
+    refunds, err := h.paymentUsecase.FindManyRefund(c.Request().Context(), p.Id.Hex(), "")
+    if err != nil {
+        return refundErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, refunds)
+}
+
+// FindManyRefund lists refunds for staff, e.g. ?status=REQUESTED for the approval queue
+func (h *paymentHttpHandler) FindManyRefund(c echo.Context) error {
+    refunds, err := h.paymentUsecase.FindManyRefund(c.Request().Context(), c.QueryParam("payment_id"), payment.RefundStatus(c.QueryParam("status")))
+    if err != nil {
+        return refundErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, refunds)
+}
+
+func (h *paymentHttpHandler) ApproveRefund(c echo.Context) error {
+    return h.decideRefund(c, h.paymentUsecase.ApproveRefund)
+}
+
+func (h *paymentHttpHandler) RejectRefund(c echo.Context) error {
+    return h.decideRefund(c, h.paymentUsecase.RejectRefund)
+}
+
+func (h *paymentHttpHandler) CompleteRefund(c echo.Context) error {
+    return h.decideRefund(c, h.paymentUsecase.CompleteRefund)
+}
+
+// decideRefund runs an admin step on a refund as the admin in the JWT
+func (h *paymentHttpHandler) decideRefund(c echo.Context, decide func(ctx context.Context, refundId, adminId string, req *payment.RefundDecisionRequest) (*payment.Refund, error)) error {
+    var req payment.RefundDecisionRequest
+    if err := c.Bind(&req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    refund, err := decide(c.Request().Context(), c.Param("refund_id"), c.Get("user_id").(string), &req)
+    if err != nil {
+        return refundErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, refund)
+}
+
+// refundErrResponse maps refund errors to HTTP statuses
+func refundErrResponse(c echo.Context, err error) error {
+    switch {
+    case errors.Is(err, payment.ErrPaymentNotFound),
+        errors.Is(err, payment.ErrRefundNotFound):
+        return response.ErrResponse(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, payment.ErrRefundForbidden):
+        return response.ErrResponse(c, http.StatusForbidden, err.Error())
+    case errors.Is(err, payment.ErrInvalidRefund):
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    case errors.Is(err, payment.ErrPaymentNotRefundable),
+        errors.Is(err, payment.ErrRefundExceedsCaptured),
+        errors.Is(err, payment.ErrRefundStatus):
+        return response.ErrResponse(c, http.StatusConflict, err.Error())
+    default:
+        return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+    }
+}
+
+// FindPaymentReceipts lists a paid payment's receipt and credit notes; users
+// see their own payments, staff with manage:payments any
+func (h *paymentHttpHandler) FindPaymentReceipts(c echo.Context) error {
+    isAdmin := rbac.HasPermission(c.Get("role_code").(int), auth.PermissionManagePayments)
+
+    receipts, err := h.paymentUsecase.FindPaymentReceipts(c.Request().Context(), c.Param("id"), c.Get("user_id").(string), isAdmin)
+    if err != nil {
+        return receiptErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, receipts)
+}
+
+// DownloadReceipt sends a receipt or credit note as a PDF. Only the first
+// download is the original; later ones are watermarked as copies.
+func (h *paymentHttpHandler) DownloadReceipt(c echo.Context) error {
+    isAdmin := rbac.HasPermission(c.Get("role_code").(int), auth.PermissionManagePayments)
+
+    receipt, pdf, err := h.paymentUsecase.RenderReceipt(c.Request().Context(), c.Param("id"), c.Param("receipt_id"), c.Get("user_id").(string), isAdmin)
+    if err != nil {
+        return receiptErrResponse(c, err)
+    }
+
+    c.Response().Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": receipt.Number + ".pdf"}))
+    c.Response().Header().Set("Cache-Control", "private, no-store")
+    return c.Blob(http.StatusOK, "application/pdf", pdf)
+}
+
+func receiptErrResponse(c echo.Context, err error) error {
+    switch {
+    case errors.Is(err, payment.ErrPaymentNotFound),
+        errors.Is(err, payment.ErrReceiptNotFound):
+        return response.ErrResponse(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, payment.ErrReceiptForbidden):
+        return response.ErrResponse(c, http.StatusForbidden, err.Error())
+    case errors.Is(err, payment.ErrPaymentNotPaid):
+        return response.ErrResponse(c, http.StatusConflict, err.Error())
+    default:
+        return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+    }
+}
+
+// PostJournalEntry posts a manual entry as the admin in the JWT
+func (h *paymentHttpHandler) PostJournalEntry(c echo.Context) error {
+    var req payment.JournalEntryRequest
+    if err := c.Bind(&req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    entry, err := h.paymentUsecase.PostJournalEntry(c.Request().Context(), c.Get("user_id").(string), &req)
+    if err != nil {
+        return ledgerErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusCreated, entry)
+}
+
+func (h *paymentHttpHandler) ReverseJournalEntry(c echo.Context) error {
+    var req payment.ReverseJournalEntryRequest
+    if err := c.Bind(&req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    entry, err := h.paymentUsecase.ReverseJournalEntry(c.Request().Context(), c.Param("entry_id"), c.Get("user_id").(string), &req)
+    if err != nil {
+        return ledgerErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusCreated, entry)
+}
+
+func (h *paymentHttpHandler) FindJournalEntry(c echo.Context) error {
+    entry, err := h.paymentUsecase.FindJournalEntry(c.Request().Context(), c.Param("entry_id"))
+    if err != nil {
+        return ledgerErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, entry)
+}
+
+// FindManyJournalEntry lists entries, e.g. ?account=revenue&from=2026-10-01&to=2026-10-31
+func (h *paymentHttpHandler) FindManyJournalEntry(c echo.Context) error {
+    from, err := parseLedgerTime(c.QueryParam("from"), false)
+    if err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+    to, err := parseLedgerTime(c.QueryParam("to"), true)
+    if err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    entries, err := h.paymentUsecase.FindManyJournalEntry(c.Request().Context(), c.QueryParam("account"), from, to)
+    if err != nil {
+        return ledgerErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, entries)
+}
+
+// FindAccountBalances reports balances as at ?at=, now when it is left out
+func (h *paymentHttpHandler) FindAccountBalances(c echo.Context) error {
+    at, err := parseLedgerAt(c.QueryParam("at"))
+    if err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    balances, err := h.paymentUsecase.FindAccountBalances(c.Request().Context(), c.QueryParam("account"), at)
+    if err != nil {
+        return ledgerErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, balances)
+}
+
+// CheckLedger proves debits equal credits as at ?at=, now when it is left out
+func (h *paymentHttpHandler) CheckLedger(c echo.Context) error {
+    at, err := parseLedgerAt(c.QueryParam("at"))
+    if err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    check, err := h.paymentUsecase.CheckLedger(c.Request().Context(), at)
+    if err != nil {
+        return ledgerErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, check)
+}
+
+// parseLedgerAt reads an "as at" time, defaulting to now
+func parseLedgerAt(raw string) (time.Time, error) {
+    if raw == "" {
+        return time.Now(), nil
+    }
+    return parseLedgerTime(raw, true)
+}
+
+// parseLedgerTime reads an RFC 3339 time or a date in the facilities' time
+// zone. A date means its start, or its end when endOfDay is set, so a to or at
+// date includes the whole day. Empty is the zero time.
+func parseLedgerTime(raw string, endOfDay bool) (time.Time, error) {
+    if raw == "" {
+        return time.Time{}, nil
+    }
+    if t, err := time.Parse(time.RFC3339, raw); err == nil {
+        return t, nil
+    }
+    day, err := time.ParseInLocation("2006-01-02", raw, utils.Location())
+    if err != nil {
+        return time.Time{}, fmt.Errorf("error: invalid time %q, expected YYYY-MM-DD or RFC 3339", raw)
+    }
+    if endOfDay {
+        return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+    }
+    return day, nil
+}
+
+func ledgerErrResponse(c echo.Context, err error) error {
+    switch {
+    case errors.Is(err, ledger.ErrEntryNotFound):
+        return response.ErrResponse(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, ledger.ErrInvalidEntry),
+        errors.Is(err, ledger.ErrUnbalanced):
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    case errors.Is(err, ledger.ErrDuplicateEntry),
+        errors.Is(err, ledger.ErrReversed):
+        return response.ErrResponse(c, http.StatusConflict, err.Error())
+    default:
+        return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+    }
+}
+
+// CreateMerchantAccount registers a PromptPay account payments are received into
+func (h *paymentHttpHandler) CreateMerchantAccount(c echo.Context) error {
+    var req payment.MerchantAccountRequest
+    if err := c.Bind(&req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    account, err := h.paymentUsecase.CreateMerchantAccount(c.Request().Context(), c.Get("user_id").(string), &req)
+    if err != nil {
+        return merchantErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusCreated, account)
+}
+
+// FindManyMerchantAccount lists accounts, filtered by ?stage= and ?facility=
+func (h *paymentHttpHandler) FindManyMerchantAccount(c echo.Context) error {
+    accounts, err := h.paymentUsecase.FindManyMerchantAccount(c.Request().Context(), c.QueryParam("stage"), c.QueryParam("facility"))
+    if err != nil {
+        return merchantErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, accounts)
+}
+
+func (h *paymentHttpHandler) FindMerchantAccount(c echo.Context) error {
+    account, err := h.paymentUsecase.FindMerchantAccount(c.Request().Context(), c.Param("account_id"))
+    if err != nil {
+        return merchantErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, account)
+}
+
+func (h *paymentHttpHandler) UpdateMerchantAccount(c echo.Context) error {
+    var req payment.UpdateMerchantAccountRequest
+    if err := c.Bind(&req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    account, err := h.paymentUsecase.UpdateMerchantAccount(c.Request().Context(), c.Param("account_id"), &req)
+    if err != nil {
+        return merchantErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, account)
+}
+
+func (h *paymentHttpHandler) DeleteMerchantAccount(c echo.Context) error {
+    if err := h.paymentUsecase.DeleteMerchantAccount(c.Request().Context(), c.Param("account_id")); err != nil {
+        return merchantErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusOK, &response.MsgResponse{Message: "Merchant account deleted"})
+}
+
+// RotateMerchantAccount replaces an account's PromptPay ID with a new account
+func (h *paymentHttpHandler) RotateMerchantAccount(c echo.Context) error {
+    var req payment.RotateMerchantAccountRequest
+    if err := c.Bind(&req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, "Invalid request format")
+    }
+    if err := c.Validate(req); err != nil {
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    }
+
+    account, err := h.paymentUsecase.RotateMerchantAccount(c.Request().Context(), c.Param("account_id"), c.Get("user_id").(string), &req)
+    if err != nil {
+        return merchantErrResponse(c, err)
+    }
+
+    return response.SuccessResponse(c, http.StatusCreated, account)
+}
+
+func merchantErrResponse(c echo.Context, err error) error {
+    switch {
+    case errors.Is(err, payment.ErrMerchantNotFound):
+        return response.ErrResponse(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, payment.ErrInvalidMerchant):
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    case errors.Is(err, payment.ErrMerchantScopeTaken),
+        errors.Is(err, payment.ErrMerchantInUse),
+        errors.Is(err, payment.ErrMerchantRetired):
+        return response.ErrResponse(c, http.StatusConflict, err.Error())
+    default:
+        return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+    }
+}
+
+func slipErrResponse(c echo.Context, err error) error {
+    switch {
+    case errors.Is(err, payment.ErrSlipNotFound),
+        errors.Is(err, payment.ErrPaymentNotFound):
+        return response.ErrResponse(c, http.StatusNotFound, err.Error())
+    case errors.Is(err, payment.ErrSlipForbidden):
+        return response.ErrResponse(c, http.StatusForbidden, err.Error())
+    case errors.Is(err, payment.ErrSlipTooLarge):
+        return response.ErrResponse(c, http.StatusRequestEntityTooLarge, err.Error())
+    case errors.Is(err, payment.ErrInvalidSlipType):
+        return response.ErrResponse(c, http.StatusUnsupportedMediaType, err.Error())
+    case errors.Is(err, payment.ErrInvalidSlipDecision):
+        return response.ErrResponse(c, http.StatusBadRequest, err.Error())
+    case errors.Is(err, payment.ErrSlipReused),
+        errors.Is(err, payment.ErrSlipRefReused),
+        errors.Is(err, payment.ErrSlipStatus),
+        errors.Is(err, payment.ErrPaymentNotPayable):
+        return response.ErrResponse(c, http.StatusConflict, err.Error())
+    default:
+        return response.ErrResponse(c, http.StatusInternalServerError, err.Error())
+    }
+}
@@ -0,0 +1,261 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"main/modules/payment"
+	"main/modules/payment/provider"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kind is the money movement an entry records
+type Kind string
+
+const (
+	KindCharge      Kind = "CHARGE"       // A payment was captured
+	KindRefund      Kind = "REFUND"       // A refund was paid out
+	KindWalletTopUp Kind = "WALLET_TOPUP" // Money paid into a user's wallet
+	KindDiscount    Kind = "DISCOUNT"     // Price given up on a booking
+	KindFee         Kind = "FEE"          // Charged to us by a provider, e.g. card fees
+	KindAdjustment  Kind = "ADJUSTMENT"   // Any other correction finance posts
+	KindReversal    Kind = "REVERSAL"     // Cancels an earlier entry; entries are never edited
+)
+
+// Account roots. Accounts are named root:group:detail, e.g.
+// assets:clearing:promptpay or revenue:bookings:swimming.
+const (
+	Assets      = "assets"
+	Liabilities = "liabilities"
+	Equity      = "equity"
+	Revenue     = "revenue"
+	Expenses    = "expenses"
+)
+
+// VatOutput is VAT collected on sales and owed to the Revenue Department
+const VatOutput = Liabilities + ":vat-output"
+
+// Discounts is price given up, kept apart from revenue so gross sales show
+const Discounts = Expenses + ":discounts"
+
+var (
+	ErrInvalidEntry   = errors.New("error: invalid journal entry")
+	ErrUnbalanced     = errors.New("error: journal entry debits do not equal credits")
+	ErrEntryNotFound  = errors.New("error: journal entry not found")
+	ErrDuplicateEntry = errors.New("error: journal entry was already posted")
+	ErrReversed       = errors.New("error: journal entry was already reversed")
+)
+
+// Line is one side of an entry. Amounts are in satang and exactly one of
+// Debit and Credit is set.
+type Line struct {
+	Account string `bson:"account" json:"account"`
+	Debit   int64  `bson:"debit" json:"debit"`
+	Credit  int64  `bson:"credit" json:"credit"`
+}
+
+// Entry is a balanced journal entry. The ledger is append-only: entries are
+// never changed or deleted, and a mistake is undone by posting its reversal.
+type Entry struct {
+	Id          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Kind        Kind                `bson:"kind" json:"kind"`
+	SourceId    string              `bson:"source_id,omitempty" json:"source_id,omitempty"` // What was posted, e.g. the payment; unique per kind
+	Description string              `bson:"description" json:"description"`
+	Currency    string              `bson:"currency" json:"currency"`
+	Lines       []Line              `bson:"lines" json:"lines"`
+	Reverses    *primitive.ObjectID `bson:"reverses,omitempty" json:"reverses,omitempty"`
+	EffectiveAt time.Time           `bson:"effective_at" json:"effective_at"` // When the money moved; balances are as at this time
+	PostedBy    string              `bson:"posted_by" json:"posted_by"`
+	PostedAt    time.Time           `bson:"posted_at" json:"posted_at"`
+}
+
+// Balance is an account's totals as at a time, in satang. Balance is debits
+// less credits, so revenue and liabilities are usually negative.
+type Balance struct {
+	Account string `bson:"_id" json:"account"`
+	Debit   int64  `bson:"debit" json:"debit"`
+	Credit  int64  `bson:"credit" json:"credit"`
+	Balance int64  `bson:"-" json:"balance"`
+}
+
+// Check is the result of checking the ledger adds up as at a time
+type Check struct {
+	At         time.Time            `json:"at"`
+	Entries    int64                `json:"entries"`
+	Debit      int64                `json:"debit"`
+	Credit     int64                `json:"credit"`
+	Balanced   bool                 `json:"balanced"`             // Total debits equal total credits and every entry balances
+	Unbalanced []primitive.ObjectID `json:"unbalanced,omitempty"` // Entries whose own lines don't balance
+}
+
+// Validate checks e can be posted: known kind, named accounts under a known
+// root, one positive side per line and debits equal to credits
+func (e *Entry) Validate() error {
+	switch e.Kind {
+	case KindCharge, KindRefund, KindWalletTopUp, KindDiscount, KindFee, KindAdjustment, KindReversal:
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidEntry, e.Kind)
+	}
+	if len(e.Lines) < 2 {
+		return fmt.Errorf("%w: an entry needs at least two lines", ErrInvalidEntry)
+	}
+	if e.EffectiveAt.IsZero() {
+		return fmt.Errorf("%w: effective time is required", ErrInvalidEntry)
+	}
+
+	var debit, credit int64
+	for _, line := range e.Lines {
+		if err := validAccount(line.Account); err != nil {
+			return err
+		}
+		if line.Debit < 0 || line.Credit < 0 || (line.Debit == 0) == (line.Credit == 0) {
+			return fmt.Errorf("%w: line %s must have either a debit or a credit", ErrInvalidEntry, line.Account)
+		}
+		debit += line.Debit
+		credit += line.Credit
+	}
+	if debit != credit {
+		return fmt.Errorf("%w: %d debit, %d credit", ErrUnbalanced, debit, credit)
+	}
+	return nil
+}
+
+func validAccount(account string) error {
+	parts := strings.Split(account, ":")
+	switch parts[0] {
+	case Assets, Liabilities, Equity, Revenue, Expenses:
+	default:
+		return fmt.Errorf("%w: account %q must start with assets, liabilities, equity, revenue or expenses", ErrInvalidEntry, account)
+	}
+	if len(parts) < 2 {
+		return fmt.Errorf("%w: account %q needs a name under its root", ErrInvalidEntry, account)
+	}
+	for _, part := range parts[1:] {
+		if strings.TrimSpace(part) == "" {
+			return fmt.Errorf("%w: account %q has an empty part", ErrInvalidEntry, account)
+		}
+	}
+	return nil
+}
+
+// Clearing holds money a provider has taken for us until it is settled,
+// e.g. assets:clearing:card
+func Clearing(providerName string) string {
+	if providerName == "" {
+		providerName = provider.PromptPay
+	}
+	return Assets + ":clearing:" + providerName
+}
+
+// BookingRevenue is a facility's booking revenue, before VAT
+func BookingRevenue(facilityName string) string {
+	if facilityName == "" {
+		facilityName = "unassigned"
+	}
+	return Revenue + ":bookings:" + facilityName
+}
+
+// Wallet is what we owe a user as wallet credit
+func Wallet(userId string) string {
+	return Liabilities + ":wallet:" + userId
+}
+
+// PaymentFees are a provider's charges to us
+func PaymentFees(providerName string) string {
+	return Expenses + ":payment-fees:" + providerName
+}
+
+// Satang converts a baht amount to satang
+func Satang(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// SplitVat splits a VAT inclusive amount in satang into its value before VAT
+// and the VAT
+func SplitVat(total int64) (int64, int64) {
+	net := int64(math.Round(float64(total) / (1 + payment.VatRate)))
+	return net, total - net
+}
+
+// ChargeEntry posts a captured payment: the money is in the provider's
+// clearing account, and is the facility's revenue plus VAT owed
+func ChargeEntry(p *payment.PaymentEntity, at time.Time) *Entry {
+	total := Satang(p.Amount)
+	net, vat := SplitVat(total)
+	return &Entry{
+		Kind:        KindCharge,
+		SourceId:    p.Id.Hex(),
+		Description: "Payment " + p.Id.Hex() + " for booking " + p.BookingID,
+		Currency:    p.Currency,
+		Lines: nonZero(
+			Line{Account: Clearing(p.Provider), Debit: total},
+			Line{Account: BookingRevenue(p.FacilityName), Credit: net},
+			Line{Account: VatOutput, Credit: vat},
+		),
+		EffectiveAt: at,
+	}
+}
+
+// RefundEntry posts a refund paid out of the account its method pays from.
+// The revenue and VAT of the refunded amount are given back.
+func RefundEntry(p *payment.PaymentEntity, refund *payment.Refund, at time.Time) *Entry {
+	total := Satang(refund.Amount)
+	net, vat := SplitVat(total)
+
+	var paidFrom string
+	switch refund.Method {
+	case payment.RefundWallet:
+		paidFrom = Wallet(refund.UserId)
+	case payment.RefundCash:
+		paidFrom = Clearing(provider.Cash)
+	case payment.RefundOriginal:
+		paidFrom = Clearing(p.Provider)
+	default:
+		paidFrom = Clearing(provider.PromptPay)
+	}
+
+	return &Entry{
+		Kind:        KindRefund,
+		SourceId:    refund.Id.Hex(),
+		Description: "Refund " + refund.Id.Hex() + " of payment " + p.Id.Hex() + " by " + string(refund.Method),
+		Currency:    refund.Currency,
+		Lines: nonZero(
+			Line{Account: BookingRevenue(p.FacilityName), Debit: net},
+			Line{Account: VatOutput, Debit: vat},
+			Line{Account: paidFrom, Credit: total},
+		),
+		EffectiveAt: at,
+	}
+}
+
+// ReversalEntry cancels e by posting each line on the other side
+func ReversalEntry(e *Entry, postedBy, reason string, at time.Time) *Entry {
+	lines := make([]Line, len(e.Lines))
+	for i, line := range e.Lines {
+		lines[i] = Line{Account: line.Account, Debit: line.Credit, Credit: line.Debit}
+	}
+	return &Entry{
+		Kind:        KindReversal,
+		SourceId:    e.Id.Hex(),
+		Description: "Reversal of " + e.Id.Hex() + ": " + reason,
+		Currency:    e.Currency,
+		Lines:       lines,
+		Reverses:    &e.Id,
+		EffectiveAt: at,
+		PostedBy:    postedBy,
+	}
+}
+
+// nonZero drops lines with nothing on them, e.g. the VAT of a one satang charge
+func nonZero(lines ...Line) []Line {
+	result := make([]Line, 0, len(lines))
+	for _, line := range lines {
+		if line.Debit != 0 || line.Credit != 0 {
+			result = append(result, line)
+		}
+	}
+	return result
+}
@@ -14,6 +14,10 @@ const (
 	Completed PaymentStatus = "COMPLETED"
 	Failed    PaymentStatus = "FAILED"
 	Canceled  PaymentStatus = "CANCELED"
+	Expired   PaymentStatus = "EXPIRED" // Pending past ExpiresAt; the booking hold is released
+
+	PartiallyRefunded PaymentStatus = "PARTIALLY_REFUNDED"
+	Refunded          PaymentStatus = "REFUNDED"
 )
 
 // PaymentEntity เป็นโครงสร้างข้อมูลสำหรับการจัดเก็บ transaction การชำระเงิน
@@ -24,9 +28,18 @@ type PaymentEntity struct {
 	Amount        float64            `bson:"amount" json:"amount"`                 // Amount to be paid
 	Currency      string             `bson:"currency" json:"currency"`             // Currency used, e.g., THB, USD
 	PaymentMethod string             `bson:"payment_method" json:"payment_method"` // Payment method, e.g., PromptPay, CreditCard
+	Provider      string             `bson:"provider" json:"provider"`                         // Provider that took the payment: promptpay, card, cash or fake
+	ProviderRef   string             `bson:"provider_ref,omitempty" json:"provider_ref,omitempty"` // The provider's id for the charge; the counter code for cash
+	CheckoutUrl   string             `bson:"checkout_url,omitempty" json:"checkout_url,omitempty"` // Hosted page card payments are made on
 	FacilityName  string             `json:"facility_name"`
 	QRCodeURL     string             `bson:"qr_code_url" json:"qr_code_url"` // URL of the QR Code for payment
+	QRPayload     string             `bson:"qr_payload" json:"-"`            // PromptPay payload the QR code encodes
+	MerchantAccountId primitive.ObjectID `bson:"merchant_account_id,omitempty" json:"merchant_account_id,omitempty"` // Account the payment is received into
+	CostCentre    string             `bson:"cost_centre,omitempty" json:"cost_centre,omitempty"` // Copied from the merchant account for finance reports
 	Status        PaymentStatus      `bson:"status" json:"status"`           // Payment status (Pending, Completed, Failed)
+	RefundedAmount      float64      `bson:"refunded_amount" json:"refunded_amount"`             // Sum of completed refunds
+	RefundPendingAmount float64      `bson:"refund_pending_amount" json:"refund_pending_amount"` // Sum of refunds requested or approved but not yet paid out
+	ExpiresAt     time.Time          `bson:"expires_at" json:"expires_at"`   // Deadline to pay the QR code; pending payments expire after it
 	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`   // Time when the payment record was created
 	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`   // Time when the record was last updated
 }
@@ -35,7 +48,216 @@ type PaymentSlip struct {
 	Id            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID        string             `bson:"user_id"`
 	BookingID     string             `bson:"booking_id"`
-	ImagePath     string             `bson:"image_path"`
-	Status        string             `bson:"status"` // e.g., Pending, Approved, Rejected
+	PaymentID     primitive.ObjectID `bson:"payment_id,omitempty" json:"payment_id,omitempty"`
+	ImagePath     string             `bson:"image_path"` // Deprecated: client supplied path of slips saved before uploads
+	BlobKey       string             `bson:"blob_key,omitempty" json:"-"`                       // Where the uploaded image is stored; view it through a signed URL
+	ContentType   string             `bson:"content_type,omitempty" json:"content_type,omitempty"`
+	Size          int64              `bson:"size,omitempty" json:"size,omitempty"`
+	ContentHash   string             `bson:"content_hash,omitempty" json:"content_hash,omitempty"` // SHA-256 of the uploaded bytes, unique across slips
+	Status        string             `bson:"status"` // pending, verified, approved or rejected
 	SubmittedDate time.Time          `bson:"submitted_date"`
+	NeedsReview   bool               `bson:"needs_review" json:"needs_review"`                     // Can't be approved automatically
+	ReviewReason  string             `bson:"review_reason,omitempty" json:"review_reason,omitempty"` // Why NeedsReview is set
+	QRPayload     string             `bson:"qr_payload,omitempty" json:"-"`                            // Raw text of the bank's mini QR on the slip
+	TransRef      string             `bson:"trans_ref,omitempty" json:"trans_ref,omitempty"`           // Bank transaction reference, unique across slips
+	SendingBank   string             `bson:"sending_bank,omitempty" json:"sending_bank,omitempty"`     // Bank of Thailand code of the payer's bank
+	TransDate     *time.Time         `bson:"trans_date,omitempty" json:"trans_date,omitempty"`         // Transfer time as reported by the bank, or the date in the reference
+	VerifiedAmount float64           `bson:"verified_amount,omitempty" json:"verified_amount,omitempty"` // Amount the bank confirmed was transferred
+	RejectReason  string             `bson:"reject_reason,omitempty" json:"reject_reason,omitempty"`     // Shown to the user so they can send a better slip
+	Timeline      []SlipEvent        `bson:"timeline" json:"timeline"`                                   // Every submission and decision, oldest first
+}
+
+const (
+	SlipPending  = "pending"  // Waiting for an admin
+	SlipVerified = "verified" // Matched to its payment from the bank's record; no admin needed
+	SlipApproved = "approved" // An admin confirmed the transfer
+	SlipRejected = "rejected" // An admin turned it down; the user may send another
+)
+
+// SlipVerifierActor is the actor of decisions made from the bank's record
+const SlipVerifierActor = "slip-verifier"
+
+// SlipEvent is one step in a slip's audit trail
+type SlipEvent struct {
+	Status  string    `bson:"status" json:"status"`
+	ActorId string    `bson:"actor_id" json:"actor_id"`
+	Note    string    `bson:"note,omitempty" json:"note,omitempty"`
+	BlobKey string    `bson:"blob_key,omitempty" json:"-"` // Image the user sent, on submissions
+	At      time.Time `bson:"at" json:"at"`
+}
+
+// MaxSlipSize is the largest accepted slip upload, below the server body limit
+const MaxSlipSize = 5 << 20
+
+// Reasons a slip is left for an admin instead of being verified automatically
+const (
+	ReviewLateSubmission    = "SUBMITTED_AFTER_EXPIRY"
+	ReviewQRNotFound        = "QR_NOT_FOUND"               // No readable QR on the image
+	ReviewQRInvalid         = "QR_INVALID"                 // The QR is not a bank slip QR
+	ReviewBankNotVerified   = "BANK_NOT_VERIFIED"          // The bank couldn't be asked or doesn't know the reference
+	ReviewNoMatch           = "NO_MATCHING_PAYMENT"        // No pending payment has the amount and time of the transfer
+	ReviewMultipleMatches   = "MULTIPLE_MATCHING_PAYMENTS" // More than one of the user's pending payments fits
+	ReviewOtherBooking      = "MATCHES_OTHER_BOOKING"      // The transfer fits another of the user's bookings
+	ReviewReceiverMismatch  = "RECEIVER_MISMATCH"          // The money went to another PromptPay id
+	ReviewReceiverUnknown   = "RECEIVER_UNKNOWN"           // The bank didn't say who received the money
+	ReviewCompleteFailed    = "PAYMENT_UPDATE_FAILED"      // Matched, but the payment couldn't be marked paid
+)
+
+// RefundStatus is where a refund is in its approval workflow
+type RefundStatus string
+
+const (
+	RefundRequested RefundStatus = "REQUESTED"
+	RefundApproved  RefundStatus = "APPROVED"
+	RefundPaying    RefundStatus = "PAYING" // ORIGINAL refunds while the provider pays them out
+	RefundRejected  RefundStatus = "REJECTED"
+	RefundCompleted RefundStatus = "COMPLETED"
+)
+
+// RefundMethod is how the money goes back to the user
+type RefundMethod string
+
+const (
+	RefundPromptPay RefundMethod = "PROMPTPAY" // Transfer back to the payer's PromptPay
+	RefundWallet    RefundMethod = "WALLET"    // Credit to the user's wallet
+	RefundCash      RefundMethod = "CASH"      // Paid out at the counter
+	RefundOriginal  RefundMethod = "ORIGINAL"  // Back through the provider that took the payment, e.g. to the card
+)
+
+// RefundReason is why a refund was asked for
+type RefundReason string
+
+const (
+	ReasonCustomerRequest RefundReason = "CUSTOMER_REQUEST"
+	ReasonFacilityClosed  RefundReason = "FACILITY_CLOSED"
+	ReasonBookingCanceled RefundReason = "BOOKING_CANCELED"
+	ReasonDuplicate       RefundReason = "DUPLICATE_PAYMENT"
+	ReasonOther           RefundReason = "OTHER"
+)
+
+// RefundEvent is one step in a refund's status timeline
+type RefundEvent struct {
+	Status  RefundStatus `bson:"status" json:"status"`
+	ActorId string       `bson:"actor_id" json:"actor_id"`
+	Note    string       `bson:"note,omitempty" json:"note,omitempty"`
+	At      time.Time    `bson:"at" json:"at"`
+}
+
+// Refund returns part or all of a completed payment
+type Refund struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PaymentId   primitive.ObjectID `bson:"payment_id" json:"payment_id"`
+	BookingId   string             `bson:"booking_id" json:"booking_id"`
+	UserId      string             `bson:"user_id" json:"user_id"`
+	Amount      float64            `bson:"amount" json:"amount"`
+	Currency    string             `bson:"currency" json:"currency"`
+	Method      RefundMethod       `bson:"method" json:"method"`
+	Reason      RefundReason       `bson:"reason" json:"reason"`
+	Note        string             `bson:"note,omitempty" json:"note,omitempty"`
+	Reference   string             `bson:"reference,omitempty" json:"reference,omitempty"` // Transfer ref, wallet transaction or receipt number once paid out
+	Status      RefundStatus       `bson:"status" json:"status"`
+	RequestedBy string             `bson:"requested_by" json:"requested_by"`
+	Timeline    []RefundEvent      `bson:"timeline" json:"timeline"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// MerchantAccount is a PromptPay account payments are received into. An
+// account without a facility is the default for its stage.
+type MerchantAccount struct {
+	Id           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Stage        string              `bson:"stage" json:"stage"` // App stage the account serves, e.g. dev or prod
+	FacilityName string              `bson:"facility_name" json:"facility_name"`
+	CostCentre   string              `bson:"cost_centre" json:"cost_centre"`
+	PromptPayId  string              `bson:"promptpay_id" json:"promptpay_id"`
+	TaxId        string              `bson:"tax_id" json:"tax_id"`
+	DisplayName  string              `bson:"display_name" json:"display_name"`
+	Enabled      bool                `bson:"enabled" json:"enabled"`
+	ReplacedBy   *primitive.ObjectID `bson:"replaced_by,omitempty" json:"replaced_by,omitempty"` // Set when the account was rotated out
+	RetiredAt    *time.Time          `bson:"retired_at,omitempty" json:"retired_at,omitempty"`
+	CreatedBy    string              `bson:"created_by" json:"created_by"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// WebhookDelivery is a verified webhook from a provider. The raw body is kept
+// as sent, for disputes, and the provider and event id are unique so a
+// redelivered event is only applied once.
+type WebhookDelivery struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Provider    string             `bson:"provider" json:"provider"`
+	EventId     string             `bson:"event_id" json:"event_id"`
+	Reference   string             `bson:"reference" json:"reference"`                       // Charge the event is about
+	Status      PaymentStatus      `bson:"status" json:"status"`                             // Status the provider reported
+	Amount      float64            `bson:"amount" json:"amount"`
+	PaymentId   primitive.ObjectID `bson:"payment_id,omitempty" json:"payment_id,omitempty"` // Set once the charge is matched to a payment
+	Payload     string             `bson:"payload" json:"payload"`                           // Raw body, byte for byte
+	Signature   string             `bson:"signature" json:"-"`
+	OccurredAt  time.Time          `bson:"occurred_at" json:"occurred_at"`                   // When the provider says the event happened
+	ReceivedAt  time.Time          `bson:"received_at" json:"received_at"`
+	ProcessedAt *time.Time         `bson:"processed_at,omitempty" json:"processed_at,omitempty"` // Unset until applied; a failed attempt is retried on redelivery
+	Outcome     string             `bson:"outcome,omitempty" json:"outcome,omitempty"`           // What applying it did, e.g. why it was ignored
+}
+
+// ReceiptKind is what a receipt document records
+type ReceiptKind string
+
+const (
+	ReceiptKindReceipt    ReceiptKind = "RECEIPT"     // Receipt and abbreviated tax invoice for a completed payment
+	ReceiptKindCreditNote ReceiptKind = "CREDIT_NOTE" // Reduces a receipt by a completed refund
+)
+
+// VatRate is Thai VAT. Facility prices are quoted with it included.
+const VatRate = 0.07
+
+// Receipt is an issued receipt or credit note. Numbers run without gaps per
+// kind and fiscal year, e.g. RC2570-000001, and a document never changes once
+// issued; a re-issue prints the same document marked as a copy.
+type Receipt struct {
+	Id            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Kind          ReceiptKind         `bson:"kind" json:"kind"`
+	Number        string              `bson:"number" json:"number"`
+	FiscalYear    int                 `bson:"fiscal_year" json:"fiscal_year"` // Buddhist era year the fiscal year ends in; it starts 1 October
+	Sequence      int64               `bson:"sequence" json:"sequence"`
+	PaymentId     primitive.ObjectID  `bson:"payment_id" json:"payment_id"`
+	RefundId      *primitive.ObjectID `bson:"refund_id,omitempty" json:"refund_id,omitempty"`           // Credit notes: the refund they record
+	ReceiptNumber string              `bson:"receipt_number,omitempty" json:"receipt_number,omitempty"` // Credit notes: the receipt they amend
+	BookingId     string              `bson:"booking_id" json:"booking_id"`
+	UserId        string              `bson:"user_id" json:"user_id"`
+	FacilityName  string              `bson:"facility_name" json:"facility_name"`
+	Slot          *ReceiptSlot        `bson:"slot,omitempty" json:"slot,omitempty"` // Unset when the booking could no longer be found
+	Amount        float64             `bson:"amount" json:"amount"`                 // VAT included
+	NetAmount     float64             `bson:"net_amount" json:"net_amount"`
+	VatRate       float64             `bson:"vat_rate" json:"vat_rate"`
+	VatAmount     float64             `bson:"vat_amount" json:"vat_amount"`
+	Currency      string              `bson:"currency" json:"currency"`
+	PaymentMethod string              `bson:"payment_method" json:"payment_method"`
+	Note          string              `bson:"note,omitempty" json:"note,omitempty"` // Credit notes: why the money was refunded
+	SellerName    string              `bson:"seller_name" json:"seller_name"`
+	SellerTaxId   string              `bson:"seller_tax_id" json:"seller_tax_id"`
+	SellerAddress string              `bson:"seller_address" json:"seller_address"`
+	IssuedAt      time.Time           `bson:"issued_at" json:"issued_at"`
+	DeliveredAt   *time.Time          `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"` // First download, which is the original
+	Reissues      []ReceiptReissue    `bson:"reissues" json:"reissues"`                               // Every later download, printed as a copy
+}
+
+// BookingCharge is what a booking owes, read from the booking service's data
+type BookingCharge struct {
+	UserId   string  `bson:"user_id"`
+	Facility string  `bson:"facility"`
+	Status   string  `bson:"status"`
+	Amount   float64 `bson:"amount"`
+}
+
+// ReceiptSlot is the booked slot a receipt is for
+type ReceiptSlot struct {
+	Date      time.Time `bson:"date" json:"date"`
+	StartTime string    `bson:"start_time" json:"start_time"`
+	EndTime   string    `bson:"end_time" json:"end_time"`
+}
+
+// ReceiptReissue is one copy of a receipt handed out after the original
+type ReceiptReissue struct {
+	ActorId string    `bson:"actor_id" json:"actor_id"`
+	At      time.Time `bson:"at" json:"at"`
 }
@@ -0,0 +1,39 @@
+package payment
+
+import "errors"
+
+var (
+	ErrPaymentNotFound       = errors.New("error: payment not found")
+	ErrInvalidPaymentStatus  = errors.New("error: invalid payment status")
+	ErrPaymentNotRefundable  = errors.New("error: only completed payments can be refunded")
+	ErrRefundExceedsCaptured = errors.New("error: refunds would exceed the captured amount")
+	ErrRefundNotFound        = errors.New("error: refund not found")
+	ErrInvalidRefund         = errors.New("error: invalid refund")
+	ErrRefundStatus          = errors.New("error: refund is not in a state that allows this")
+	ErrRefundForbidden       = errors.New("error: refund belongs to another user")
+	ErrPaymentNotPayable     = errors.New("error: payment is no longer awaiting payment")
+	ErrNoQRCode              = errors.New("error: payment is not paid by QR code")
+	ErrDuplicateWebhook      = errors.New("error: webhook event was already received")
+	ErrInvalidWebhook        = errors.New("error: invalid webhook")
+	ErrPaymentNotPaid        = errors.New("error: payment has not been paid")
+	ErrReceiptNotFound       = errors.New("error: receipt not found")
+	ErrReceiptForbidden      = errors.New("error: receipt belongs to another user")
+	ErrBookingNotPayable     = errors.New("error: booking is not awaiting payment")
+	ErrBookingForbidden      = errors.New("error: booking belongs to another user")
+
+	ErrMerchantNotFound   = errors.New("error: merchant account not found")
+	ErrNoMerchantAccount  = errors.New("error: no enabled merchant account for this facility")
+	ErrInvalidMerchant    = errors.New("error: invalid merchant account")
+	ErrMerchantScopeTaken = errors.New("error: an enabled merchant account already exists for this facility")
+	ErrMerchantInUse      = errors.New("error: merchant account has received payments; disable it instead")
+	ErrMerchantRetired    = errors.New("error: merchant account was rotated out")
+
+	ErrSlipNotFound    = errors.New("error: slip not found")
+	ErrSlipTooLarge    = errors.New("error: slip image is too large")
+	ErrInvalidSlipType = errors.New("error: slip must be a JPEG or PNG image")
+	ErrSlipReused      = errors.New("error: this slip image was already submitted for another booking")
+	ErrSlipForbidden   = errors.New("error: booking belongs to another user")
+	ErrSlipRefReused   = errors.New("error: this bank transfer was already submitted for another booking")
+	ErrSlipStatus      = errors.New("error: slip is not awaiting a decision")
+	ErrInvalidSlipDecision = errors.New("error: invalid slip decision")
+)
@@ -1,14 +1,17 @@
 package payment
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
 
 type CreatePaymentRequest struct {
 	UserId        string  `json:"user_id" validate:"required"`        // ID of the user making the payment
 	BookingId     string  `json:"booking_id" validate:"required"`     // ID of the booking related to the payment
-	Amount        float64 `json:"amount" validate:"required,gt=0"`    // Amount to be paid
+	Amount        float64 `json:"amount,omitempty"`                   // Deprecated: ignored; the booking's amount is charged
 	Currency      string  `json:"currency" validate:"required"`       // Currency of the payment, e.g., THB, USD
 	PaymentMethod string  `json:"payment_method" validate:"required"` // Method of payment, e.g., PromptPay
-    FacilityName   string  `json:"facility_name"`
+    FacilityName   string  `json:"facility_name"`                    // Deprecated: ignored; the booking's facility is used
 }
 
 // PaymentRequestModel ใช้สำหรับรับข้อมูลการสร้าง payment จาก API
@@ -31,22 +34,32 @@ type PaymentResponse struct {
 	Amount        float64       `json:"amount"`                 // จำนวนเงินที่ชำระ
 	Currency      string        `json:"currency"`               // สกุลเงินที่ใช้
 	PaymentMethod string        `json:"payment_method"`         // วิธีการชำระเงิน
+	Provider      string        `json:"provider"`
+	ProviderRef   string        `json:"provider_ref,omitempty"` // Counter code to show when paying cash
+	CheckoutUrl   string        `json:"checkout_url,omitempty"` // Where to send the user to pay by card
 	QRCodeURL     string        `json:"qr_code_url"`
 	FacilityName  string        `json:"facility_name"` // URL ของ QR Code สำหรับการชำระเงิน
 	Status        PaymentStatus `json:"status"`        // สถานะการชำระเงิน
+	ExpiresAt     time.Time     `json:"expires_at"`    // เวลาที่ QR หมดอายุ
 	CreatedAt     time.Time     `json:"created_at"`    // เวลาที่สร้าง
 	UpdatedAt     time.Time     `json:"updated_at"`    // เวลาที่อัปเดตล่าสุด
 }
 
+// UploadSlipRequest is the form sent with a slip image in the "file" part
 type UploadSlipRequest struct {
-	UserID    string `json:"user_id" binding:"required"`
-	BookingID string `json:"booking_id" binding:"required"`
-	ImagePath string `json:"image_path" binding:"required"`
+	BookingID string `form:"booking_id" validate:"required"`
+}
+
+// SlipURLResponse is a short-lived link to a slip image
+type SlipURLResponse struct {
+	Url       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
-type UpdateSlipStatusRequest struct {
-	Status  string `json:"status" binding:"required"` // Approved or Rejected
-	AdminID string `json:"admin_id" binding:"required"`
+// SlipDecisionRequest approves or rejects a slip; the admin comes from the JWT.
+// Rejections need a reason, which the user is shown.
+type SlipDecisionRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
 }
 
 type PaymentSlipResponse struct {
@@ -68,9 +81,164 @@ func NewPaymentResponse(payment *PaymentEntity) *PaymentResponse {
 		Amount:        payment.Amount,
 		Currency:      payment.Currency,
 		PaymentMethod: payment.PaymentMethod,
+		Provider:      payment.Provider,
+		ProviderRef:   payment.ProviderRef,
+		CheckoutUrl:   payment.CheckoutUrl,
 		QRCodeURL:     payment.QRCodeURL,
+		FacilityName:  payment.FacilityName,
 		Status:        payment.Status,
+		ExpiresAt:     payment.ExpiresAt,
 		CreatedAt:     payment.CreatedAt,
 		UpdatedAt:     payment.UpdatedAt,
 	}
 }
+
+// TopicPaymentCompleted carries a PaymentCompletedEvent for every captured payment
+const TopicPaymentCompleted = "payment.completed"
+
+// PaymentCompletedEvent tells the booking service a payment was captured. The
+// event id is derived from the payment, so publishing it again is harmless.
+type PaymentCompletedEvent struct {
+	EventId      string    `json:"event_id" validate:"required"`
+	PaymentId    string    `json:"payment_id" validate:"required"`
+	BookingId    string    `json:"booking_id" validate:"required"`
+	UserId       string    `json:"user_id"`
+	Amount       float64   `json:"amount"`
+	Currency     string    `json:"currency"`
+	FacilityName string    `json:"facility_name"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
+
+func NewPaymentCompletedEvent(p *PaymentEntity) *PaymentCompletedEvent {
+	return &PaymentCompletedEvent{
+		EventId:      TopicPaymentCompleted + ":" + p.Id.Hex(),
+		PaymentId:    p.Id.Hex(),
+		BookingId:    p.BookingID,
+		UserId:       p.UserID,
+		Amount:       p.Amount,
+		Currency:     p.Currency,
+		FacilityName: p.FacilityName,
+		CompletedAt:  p.UpdatedAt,
+	}
+}
+
+// TopicPaymentExpired carries a PaymentExpiredEvent for every payment that
+// was not paid before its deadline
+const TopicPaymentExpired = "payment.expired"
+
+// PaymentExpiredEvent tells the booking service to release the booking's hold
+type PaymentExpiredEvent struct {
+	EventId   string    `json:"event_id" validate:"required"`
+	PaymentId string    `json:"payment_id" validate:"required"`
+	BookingId string    `json:"booking_id" validate:"required"`
+	UserId    string    `json:"user_id"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+func NewPaymentExpiredEvent(p *PaymentEntity) *PaymentExpiredEvent {
+	return &PaymentExpiredEvent{
+		EventId:   TopicPaymentExpired + ":" + p.Id.Hex(),
+		PaymentId: p.Id.Hex(),
+		BookingId: p.BookingID,
+		UserId:    p.UserID,
+		ExpiredAt: p.ExpiresAt,
+	}
+}
+
+type UpdatePaymentStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=COMPLETED FAILED CANCELED"` // Pending payments only
+}
+
+type RefundRequest struct {
+	Amount float64 `json:"amount" validate:"gte=0"` // 0 refunds everything not yet refunded
+	Method string  `json:"method" validate:"required,oneof=PROMPTPAY WALLET CASH ORIGINAL"`
+	Reason string  `json:"reason" validate:"required,oneof=CUSTOMER_REQUEST FACILITY_CLOSED BOOKING_CANCELED DUPLICATE_PAYMENT OTHER"`
+	Note   string  `json:"note" validate:"max=500"`
+}
+
+// RefundDecisionRequest carries an admin's note on approval or rejection, and
+// the payout reference when a refund is completed
+type RefundDecisionRequest struct {
+	Note      string `json:"note" validate:"max=500"`
+	Reference string `json:"reference" validate:"max=128"`
+}
+
+// JournalEntryRequest posts a money movement that has no automatic posting,
+// e.g. card fees from a gateway statement. Amounts are in satang.
+type JournalEntryRequest struct {
+	Kind        string               `json:"kind" validate:"required,oneof=WALLET_TOPUP DISCOUNT FEE ADJUSTMENT"`
+	SourceId    string               `json:"source_id" validate:"max=128"` // Optional, e.g. the statement line; posting it again is refused
+	Description string               `json:"description" validate:"required,max=500"`
+	EffectiveAt *time.Time           `json:"effective_at"` // Defaults to now; can't be in the future
+	Lines       []JournalLineRequest `json:"lines" validate:"required,min=2,dive"`
+}
+
+type JournalLineRequest struct {
+	Account string `json:"account" validate:"required,max=128"`
+	Debit   int64  `json:"debit" validate:"gte=0"`
+	Credit  int64  `json:"credit" validate:"gte=0"`
+}
+
+// ReverseJournalEntryRequest cancels a posted entry; the reason is kept on the reversal
+type ReverseJournalEntryRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// MerchantAccountRequest registers a receiving account; FacilityName empty
+// makes it the stage's default and Stage defaults to the running stage
+type MerchantAccountRequest struct {
+	Stage        string `json:"stage" validate:"max=32"`
+	FacilityName string `json:"facility_name" validate:"max=64"`
+	CostCentre   string `json:"cost_centre" validate:"max=64"`
+	PromptPayId  string `json:"promptpay_id" validate:"required"`
+	TaxId        string `json:"tax_id" validate:"required"`
+	DisplayName  string `json:"display_name" validate:"required,max=128"`
+	Enabled      *bool  `json:"enabled"` // Defaults to true
+}
+
+// UpdateMerchantAccountRequest changes an account's details. The PromptPay ID
+// can't change here; rotate the account instead.
+type UpdateMerchantAccountRequest struct {
+	CostCentre  *string `json:"cost_centre" validate:"omitempty,max=64"`
+	TaxId       *string `json:"tax_id"`
+	DisplayName *string `json:"display_name" validate:"omitempty,min=1,max=128"`
+	Enabled     *bool   `json:"enabled"`
+}
+
+// RotateMerchantAccountRequest replaces an account's PromptPay ID with a new
+// account for the same facility; details left empty are copied over
+type RotateMerchantAccountRequest struct {
+	PromptPayId string `json:"promptpay_id" validate:"required"`
+	TaxId       string `json:"tax_id"`
+	DisplayName string `json:"display_name" validate:"max=128"`
+	CostCentre  string `json:"cost_centre" validate:"max=64"`
+}
+
+// TopicSlipUpdated carries a SlipUpdatedEvent for every admin decision on a
+// slip, so the user can be told
+const TopicSlipUpdated = "slip.updated"
+
+// SlipUpdatedEvent tells the user their slip was approved or rejected
+type SlipUpdatedEvent struct {
+	EventId   string    `json:"event_id" validate:"required"`
+	SlipId    string    `json:"slip_id" validate:"required"`
+	BookingId string    `json:"booking_id"`
+	PaymentId string    `json:"payment_id"`
+	UserId    string    `json:"user_id"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+func NewSlipUpdatedEvent(slip *PaymentSlip, event SlipEvent) *SlipUpdatedEvent {
+	return &SlipUpdatedEvent{
+		EventId:   TopicSlipUpdated + ":" + slip.Id.Hex() + ":" + strconv.Itoa(len(slip.Timeline)),
+		SlipId:    slip.Id.Hex(),
+		BookingId: slip.BookingID,
+		PaymentId: slip.PaymentID.Hex(),
+		UserId:    slip.UserID,
+		Status:    event.Status,
+		Reason:    slip.RejectReason,
+		DecidedAt: event.At,
+	}
+}
@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"main/config"
+	"main/modules/payment"
+	"main/pkg/circuitbreaker"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// cardGateway charges cards through a hosted checkout page. The gateway calls
+// back with signed webhooks, and charges can also be queried.
+type cardGateway struct {
+	baseUrl       string
+	secretKey     string
+	webhookSecret string
+	returnUrl     string
+	client        *http.Client
+	breaker       *gobreaker.CircuitBreaker
+}
+
+func NewCardGateway(cfg *config.Payment) Provider {
+	return &cardGateway{
+		baseUrl:       strings.TrimRight(cfg.CardGatewayUrl, "/"),
+		secretKey:     cfg.CardSecretKey,
+		webhookSecret: cfg.CardWebhookSecret,
+		returnUrl:     cfg.CardReturnUrl,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		breaker:       circuitbreaker.NewCircuitBreaker("card-gateway"),
+	}
+}
+
+func (g *cardGateway) Name() string {
+	return Card
+}
+
+type cardCharge struct {
+	Id           string `json:"id"`
+	Status       string `json:"status"`
+	Amount       int64  `json:"amount"`
+	AuthorizeUri string `json:"authorize_uri"`
+}
+
+type cardWebhook struct {
+	Id        string     `json:"id"`
+	Data      cardCharge `json:"data"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (g *cardGateway) CreateCharge(ctx context.Context, req *ChargeRequest) (*Charge, error) {
+	charge := new(cardCharge)
+	err := g.call(ctx, http.MethodPost, "/charges", "", map[string]any{
+		"amount":      satang(req.Amount),
+		"currency":    strings.ToLower(req.Currency),
+		"reference":   req.PaymentId,
+		"description": req.Description,
+		"return_uri":  g.returnUrl,
+		"expires_at":  req.ExpiresAt,
+	}, charge)
+	if err != nil {
+		return nil, err
+	}
+	return &Charge{Reference: charge.Id, Status: cardStatus(charge.Status), CheckoutUrl: charge.AuthorizeUri}, nil
+}
+
+func (g *cardGateway) QueryStatus(ctx context.Context, reference string) (payment.PaymentStatus, error) {
+	charge := new(cardCharge)
+	if err := g.call(ctx, http.MethodGet, "/charges/"+url.PathEscape(reference), "", nil, charge); err != nil {
+		return "", err
+	}
+	return cardStatus(charge.Status), nil
+}
+
+func (g *cardGateway) Refund(ctx context.Context, reference string, amount float64, idempotencyKey string) (*RefundResult, error) {
+	var refund struct {
+		Id string `json:"id"`
+	}
+	err := g.call(ctx, http.MethodPost, "/charges/"+url.PathEscape(reference)+"/refunds", idempotencyKey, map[string]any{"amount": satang(amount)}, &refund)
+	if err != nil {
+		return nil, err
+	}
+	return &RefundResult{Reference: refund.Id}, nil
+}
+
+// VerifyWebhook checks the body was signed with CARD_WEBHOOK_SECRET
+func (g *cardGateway) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if err := verifySignature(g.webhookSecret, header, body, time.Now()); err != nil {
+		return nil, err
+	}
+
+	event := new(cardWebhook)
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, fmt.Errorf("error: decode card webhook: %w", err)
+	}
+	return &WebhookEvent{
+		EventId:   event.Id,
+		Reference: event.Data.Id,
+		Status:    cardStatus(event.Data.Status),
+		Amount:    float64(event.Data.Amount) / 100,
+		At:        event.CreatedAt,
+	}, nil
+}
+
+// call sends a JSON request to the gateway through the circuit breaker.
+// Answers the gateway gives on purpose, like an unknown charge, don't trip it.
+// A request sent again with the same idempotencyKey is only acted on once.
+func (g *cardGateway) call(ctx context.Context, method, path, idempotencyKey string, in, out any) error {
+	var notFound bool
+	_, err := g.breaker.Execute(func() (interface{}, error) {
+		var body io.Reader
+		if in != nil {
+			b, err := json.Marshal(in)
+			if err != nil {
+				return nil, err
+			}
+			body = bytes.NewReader(b)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, g.baseUrl+path, body)
+		if err != nil {
+			return nil, fmt.Errorf("error: build card gateway request: %w", err)
+		}
+		req.SetBasicAuth(g.secretKey, "")
+		req.Header.Set("Content-Type", "application/json")
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+
+		resp, err := g.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error: card gateway request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			notFound = true
+			return nil, nil
+		case resp.StatusCode >= 300:
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return nil, fmt.Errorf("error: card gateway returned %s: %s", resp.Status, msg)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("error: decode card gateway response: %w", err)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	if notFound {
+		return ErrChargeNotFound
+	}
+	return nil
+}
+
+func cardStatus(status string) payment.PaymentStatus {
+	switch strings.ToLower(status) {
+	case "successful", "succeeded", "paid":
+		return payment.Completed
+	case "failed", "declined":
+		return payment.Failed
+	case "expired":
+		return payment.Expired
+	default:
+		return payment.Pending
+	}
+}
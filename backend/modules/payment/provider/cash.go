@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"main/modules/payment"
+	"net/http"
+)
+
+// counterAlphabet leaves out characters staff could misread, like 0/O and 1/I
+const counterAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// cashProvider is paid at the facility counter. The user shows the counter
+// code and staff mark the payment completed once they have the cash.
+type cashProvider struct{}
+
+func NewCash() Provider {
+	return cashProvider{}
+}
+
+func (cashProvider) Name() string {
+	return Cash
+}
+
+func (cashProvider) CreateCharge(context.Context, *ChargeRequest) (*Charge, error) {
+	code, err := counterCode(8)
+	if err != nil {
+		return nil, err
+	}
+	return &Charge{Reference: code, Status: payment.Pending}, nil
+}
+
+func (cashProvider) QueryStatus(context.Context, string) (payment.PaymentStatus, error) {
+	return "", ErrNotSupported
+}
+
+// Refund is not supported; cash is handed back at the counter and recorded
+// through the refund workflow
+func (cashProvider) Refund(context.Context, string, float64, string) (*RefundResult, error) {
+	return nil, ErrNotSupported
+}
+
+func (cashProvider) VerifyWebhook(http.Header, []byte) (*WebhookEvent, error) {
+	return nil, ErrNotSupported
+}
+
+func counterCode(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error: generate counter code: %w", err)
+	}
+	for i := range b {
+		b[i] = counterAlphabet[int(b[i])%len(counterAlphabet)]
+	}
+	return string(b), nil
+}
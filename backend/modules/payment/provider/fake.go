@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"main/modules/payment"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FakeGateway is an in-memory gateway for tests. Charges stay pending until
+// Settle is called, and refunds always succeed. Clients can't pick it as a
+// payment method; its webhooks are signed with PAYMENT_FAKE_WEBHOOK_SECRET.
+type FakeGateway struct {
+	webhookSecret string
+	mu            sync.Mutex
+	charges       map[string]*fakeCharge
+	refunds       map[string]*RefundResult // By idempotency key
+	next          int
+}
+
+type fakeCharge struct {
+	status   payment.PaymentStatus
+	amount   float64
+	refunded float64
+}
+
+// FakeWebhook is the body the fake gateway's webhooks carry
+type FakeWebhook struct {
+	EventId   string                `json:"event_id"`
+	Reference string                `json:"reference"`
+	Status    payment.PaymentStatus `json:"status"`
+	Amount    float64               `json:"amount"`
+	At        time.Time             `json:"at"`
+}
+
+func NewFakeGateway(webhookSecret string) *FakeGateway {
+	return &FakeGateway{webhookSecret: webhookSecret, charges: make(map[string]*fakeCharge), refunds: make(map[string]*RefundResult)}
+}
+
+func (f *FakeGateway) Name() string {
+	return Fake
+}
+
+func (f *FakeGateway) CreateCharge(_ context.Context, req *ChargeRequest) (*Charge, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.next++
+	reference := fmt.Sprintf("fake_%s_%d", req.PaymentId, f.next)
+	f.charges[reference] = &fakeCharge{status: payment.Pending, amount: req.Amount}
+	return &Charge{Reference: reference, Status: payment.Pending, CheckoutUrl: "/fake/checkout/" + reference}, nil
+}
+
+func (f *FakeGateway) QueryStatus(_ context.Context, reference string) (payment.PaymentStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	charge, ok := f.charges[reference]
+	if !ok {
+		return "", ErrChargeNotFound
+	}
+	return charge.status, nil
+}
+
+func (f *FakeGateway) Refund(_ context.Context, reference string, amount float64, idempotencyKey string) (*RefundResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if result, ok := f.refunds[idempotencyKey]; ok && idempotencyKey != "" {
+		return result, nil
+	}
+
+	charge, ok := f.charges[reference]
+	if !ok {
+		return nil, ErrChargeNotFound
+	}
+	if charge.status != payment.Completed || charge.refunded+amount > charge.amount {
+		return nil, fmt.Errorf("error: fake gateway can't refund %.2f of %s", amount, reference)
+	}
+	charge.refunded += amount
+	f.next++
+	result := &RefundResult{Reference: fmt.Sprintf("%s_refund_%d", reference, f.next)}
+	if idempotencyKey != "" {
+		f.refunds[idempotencyKey] = result
+	}
+	return result, nil
+}
+
+// VerifyWebhook checks the body was signed with the configured webhook secret
+func (f *FakeGateway) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	if err := verifySignature(f.webhookSecret, header, body, time.Now()); err != nil {
+		return nil, err
+	}
+	event := new(FakeWebhook)
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, fmt.Errorf("error: decode fake webhook: %w", err)
+	}
+	return &WebhookEvent{EventId: event.EventId, Reference: event.Reference, Status: event.Status, Amount: event.Amount, At: event.At}, nil
+}
+
+// Settle moves a charge to status, as the user paying or abandoning it would
+func (f *FakeGateway) Settle(reference string, status payment.PaymentStatus) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	charge, ok := f.charges[reference]
+	if !ok {
+		return ErrChargeNotFound
+	}
+	charge.status = status
+	return nil
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"main/modules/payment"
+	"net/http"
+
+	"github.com/Frontware/promptpay"
+)
+
+// promptPayProvider is paid by scanning a one-time PromptPay QR. Banks give no
+// callback for it, so payments are settled from the slips users upload.
+type promptPayProvider struct{}
+
+func NewPromptPay() Provider {
+	return promptPayProvider{}
+}
+
+func (promptPayProvider) Name() string {
+	return PromptPay
+}
+
+func (promptPayProvider) CreateCharge(ctx context.Context, req *ChargeRequest) (*Charge, error) {
+	payload, err := PromptPayPayload(req.PromptPayId, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return &Charge{Status: payment.Pending, QRPayload: payload}, nil
+}
+
+func (promptPayProvider) QueryStatus(context.Context, string) (payment.PaymentStatus, error) {
+	return "", ErrNotSupported
+}
+
+// Refund is not supported; PromptPay refunds are transferred by finance and
+// recorded through the refund workflow
+func (promptPayProvider) Refund(context.Context, string, float64, string) (*RefundResult, error) {
+	return nil, ErrNotSupported
+}
+
+func (promptPayProvider) VerifyWebhook(http.Header, []byte) (*WebhookEvent, error) {
+	return nil, ErrNotSupported
+}
+
+// PromptPayPayload builds the one-time PromptPay payload paying amount to promptPayId
+func PromptPayPayload(promptPayId string, amount float64) (string, error) {
+	payload, err := (&promptpay.PromptPay{
+		PromptPayID: promptPayId,
+		Amount:      amount,
+		OneTime:     true,
+	}).Gen()
+	if err != nil {
+		return "", fmt.Errorf("error generating QR code: %w", err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"main/config"
+	"main/modules/payment"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the providers, as stored on PaymentEntity.Provider
+const (
+	PromptPay = "promptpay"
+	Card      = "card"
+	Cash      = "cash"
+	Fake      = "fake"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("error: unknown payment provider")
+	ErrNotSupported     = errors.New("error: payment provider does not support this")
+	ErrChargeNotFound   = errors.New("error: provider has no such charge")
+	ErrSignatureInvalid = errors.New("error: webhook signature is invalid")
+	ErrWebhookStale     = errors.New("error: webhook timestamp is outside the tolerance")
+)
+
+// WebhookTolerance is how far a webhook's timestamp may be from our clock.
+// Older deliveries are refused so a captured request can't be replayed later.
+const WebhookTolerance = 5 * time.Minute
+
+// ChargeRequest is what a provider needs to start taking a payment
+type ChargeRequest struct {
+	PaymentId   string
+	Amount      float64
+	Currency    string
+	PromptPayId string // Receiving account of the payment's merchant account
+	Description string
+	ExpiresAt   time.Time
+}
+
+// Charge is a provider's answer to CreateCharge. Only the fields that apply
+// to the provider are set.
+type Charge struct {
+	Reference   string // The provider's id for the charge, or the counter code for cash
+	Status      payment.PaymentStatus
+	QRPayload   string // Payload of the QR code the user scans
+	CheckoutUrl string // Hosted page the user pays on
+}
+
+// RefundResult is a refund the provider paid out
+type RefundResult struct {
+	Reference string
+}
+
+// WebhookEvent is a verified notification about a charge
+type WebhookEvent struct {
+	EventId   string
+	Reference string // Charge the event is about
+	Status    payment.PaymentStatus
+	Amount    float64
+	At        time.Time
+}
+
+// Provider takes payments one way. Operations a provider has no way to do,
+// such as querying a cash payment, return ErrNotSupported; those payments are
+// settled by slips or by staff instead.
+type Provider interface {
+	Name() string
+	CreateCharge(ctx context.Context, req *ChargeRequest) (*Charge, error)
+	QueryStatus(ctx context.Context, reference string) (payment.PaymentStatus, error)
+	Refund(ctx context.Context, reference string, amount float64, idempotencyKey string) (*RefundResult, error) // A key already used returns the first refund
+	VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+}
+
+// Registry holds the providers enabled by config
+type Registry struct {
+	providers map[string]Provider
+}
+
+// FakeGatewayStages are the app stages the fake gateway may run in
+var FakeGatewayStages = []string{"test", "local"}
+
+// NewRegistry enables PromptPay and cash always, the card gateway when
+// CARD_GATEWAY_URL is set and the fake gateway when PAYMENT_FAKE_GATEWAY is on
+func NewRegistry(cfg *config.Payment) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	r.register(NewPromptPay())
+	r.register(NewCash())
+	if cfg.CardGatewayUrl != "" {
+		r.register(NewCardGateway(cfg))
+	}
+	if cfg.FakeGateway {
+		r.register(NewFakeGateway(cfg.FakeWebhookSecret))
+	}
+	return r
+}
+
+func (r *Registry) register(p Provider) {
+	r.providers[p.Name()] = p
+}
+
+// Get returns the provider called name. Payments made before providers were
+// recorded have no name; they were all PromptPay.
+func (r *Registry) Get(name string) (Provider, error) {
+	if name == "" {
+		name = PromptPay
+	}
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// ForMethod returns the provider taking payments by a payment method as
+// clients send it, e.g. PromptPay, CreditCard or Cash. The fake gateway is
+// never one; only code holding the registry can charge through it.
+func (r *Registry) ForMethod(method string) (Provider, error) {
+	key := strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(method))
+	switch key {
+	case "promptpay", "qr":
+		return r.Get(PromptPay)
+	case "card", "creditcard", "debitcard":
+		return r.Get(Card)
+	case "cash", "counter", "cashatcounter":
+		return r.Get(Cash)
+	default:
+		return nil, fmt.Errorf("%w: payment method %q", ErrUnknownProvider, method)
+	}
+}
+
+// Sign is the hex HMAC-SHA256 under secret of "<timestamp>.<body>", sent in
+// the X-Signature header next to the unix timestamp in X-Timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignature checks a webhook's X-Signature and that its X-Timestamp is
+// within WebhookTolerance of now
+func verifySignature(secret string, header http.Header, body []byte, now time.Time) error {
+	timestamp, err := strconv.ParseInt(header.Get("X-Timestamp"), 10, 64)
+	if err != nil || secret == "" {
+		return ErrSignatureInvalid
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(strings.ToLower(header.Get("X-Signature")))) {
+		return ErrSignatureInvalid
+	}
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > WebhookTolerance || skew < -WebhookTolerance {
+		return ErrWebhookStale
+	}
+	return nil
+}
+
+// satang converts baht to the smallest unit gateways charge in
+func satang(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -5,23 +5,81 @@ import (
     "errors"
     "fmt"
     "log"
+    "main/modules/facility"
     "main/modules/payment"
+    "main/modules/payment/ledger"
+    "main/pkg/utils"
+    "regexp"
+    "strings"
     "time"
 
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
     "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type PaymentRepositoryService interface {
     InsertPayment(ctx context.Context, payment *payment.PaymentEntity) (*payment.PaymentEntity, error)
-    UpdatePayment(ctx context.Context, payment *payment.PaymentEntity) (*payment.PaymentEntity, error)
+    UpdatePaymentStatus(ctx context.Context, paymentId primitive.ObjectID, from, to payment.PaymentStatus) (*payment.PaymentEntity, error)
     FindPayment(ctx context.Context, paymentId string) (*payment.PaymentEntity, error)
     FindPaymentsByUser(ctx context.Context, userId string) ([]payment.PaymentEntity, error)
+    FindPaymentByBooking(ctx context.Context, bookingId string) (*payment.PaymentEntity, error)
+    FindPaymentByProviderRef(ctx context.Context, provider, reference string) (*payment.PaymentEntity, error)
+    FindManyPendingPayment(ctx context.Context, userId string) ([]payment.PaymentEntity, error)
+    FindExpiredPayments(ctx context.Context, now, legacyCutoff time.Time) ([]payment.PaymentEntity, error)
+    ExpirePayment(ctx context.Context, paymentId primitive.ObjectID) (bool, error)
+    CompletePendingPayment(ctx context.Context, paymentId primitive.ObjectID) (bool, error)
     FindSlipByUserId(ctx context.Context, userId string) ([]payment.PaymentSlip, error)
-    SaveSlip(ctx context.Context, slip payment.PaymentSlip) error
-    UpdateSlipStatus(ctx context.Context, slipId string, newStatus string) error
+    SaveSlip(ctx context.Context, slip *payment.PaymentSlip) (*payment.PaymentSlip, error)
+    FindSlip(ctx context.Context, slipId string) (*payment.PaymentSlip, error)
+    FindSlipByHash(ctx context.Context, contentHash string) (*payment.PaymentSlip, error)
+    FindSlipByTransRef(ctx context.Context, transRef string) (*payment.PaymentSlip, error)
+    FlagSlipForReview(ctx context.Context, slipId primitive.ObjectID, event payment.SlipEvent) error
+    UpdateSlipStatus(ctx context.Context, slipId primitive.ObjectID, from string, event payment.SlipEvent, rejectReason string) (*payment.PaymentSlip, error)
+    ReplaceSlip(ctx context.Context, slip *payment.PaymentSlip) (*payment.PaymentSlip, error)
     GetPendingSlips (ctx context.Context) ([]payment.PaymentSlip, error) 
+
+    // Refunds
+    InsertRefund(ctx context.Context, refund *payment.Refund) (*payment.Refund, error)
+    FindRefund(ctx context.Context, refundId string) (*payment.Refund, error)
+    FindManyRefund(ctx context.Context, paymentId string, status payment.RefundStatus) ([]payment.Refund, error)
+    UpdateRefundStatus(ctx context.Context, refundId primitive.ObjectID, from payment.RefundStatus, event payment.RefundEvent, reference string) (*payment.Refund, error)
+    ReserveRefund(ctx context.Context, paymentId primitive.ObjectID, amount float64) error
+    ReleaseRefund(ctx context.Context, paymentId primitive.ObjectID, amount float64) error
+    SettleRefund(ctx context.Context, paymentId primitive.ObjectID, amount float64) error
+
+    // Merchant accounts
+    InsertMerchantAccount(ctx context.Context, account *payment.MerchantAccount) (*payment.MerchantAccount, error)
+    FindMerchantAccount(ctx context.Context, accountId string) (*payment.MerchantAccount, error)
+    FindManyMerchantAccount(ctx context.Context, stage, facilityName string) ([]payment.MerchantAccount, error)
+    ResolveMerchantAccount(ctx context.Context, stage, facilityName string) (*payment.MerchantAccount, error)
+    ReplaceMerchantAccount(ctx context.Context, account *payment.MerchantAccount) (*payment.MerchantAccount, error)
+    RetireMerchantAccount(ctx context.Context, accountId, replacedBy primitive.ObjectID) error
+    DeleteMerchantAccount(ctx context.Context, accountId primitive.ObjectID) error
+    CountMerchantPayments(ctx context.Context, accountId primitive.ObjectID) (int64, error)
+
+    // Webhooks
+    InsertWebhookDelivery(ctx context.Context, delivery *payment.WebhookDelivery) (*payment.WebhookDelivery, error)
+    FindWebhookDelivery(ctx context.Context, provider, eventId string) (*payment.WebhookDelivery, error)
+    MarkWebhookProcessed(ctx context.Context, deliveryId, paymentId primitive.ObjectID, outcome string) error
+
+    // Receipts
+    InsertReceipt(ctx context.Context, receipt *payment.Receipt) (*payment.Receipt, error)
+    FindReceipt(ctx context.Context, receiptId string) (*payment.Receipt, error)
+    FindManyReceipt(ctx context.Context, paymentId primitive.ObjectID) ([]payment.Receipt, error)
+    FindReceiptByPayment(ctx context.Context, paymentId primitive.ObjectID) (*payment.Receipt, error)
+    FindCreditNoteByRefund(ctx context.Context, refundId primitive.ObjectID) (*payment.Receipt, error)
+    MarkReceiptPrinted(ctx context.Context, receiptId primitive.ObjectID, actorId string, at time.Time) (bool, error)
+    FindBookingSlot(ctx context.Context, bookingId string) (*payment.ReceiptSlot, error)
+    FindBookingCharge(ctx context.Context, bookingId string) (*payment.BookingCharge, error)
+
+    // Ledger
+    InsertJournalEntry(ctx context.Context, entry *ledger.Entry) (*ledger.Entry, error)
+    FindJournalEntry(ctx context.Context, entryId string) (*ledger.Entry, error)
+    FindManyJournalEntry(ctx context.Context, account string, from, to time.Time) ([]ledger.Entry, error)
+    FindAccountBalances(ctx context.Context, account string, at time.Time) ([]ledger.Balance, error)
+    CheckLedger(ctx context.Context, at time.Time) (*ledger.Check, error)
 }
 
 type paymentRepository struct {
@@ -70,12 +128,15 @@ func (r *paymentRepository) FindPayment(ctx context.Context, paymentId string) (
     objectId, err := primitive.ObjectIDFromHex(paymentId)
     if err != nil {
         log.Printf("Error: Invalid ObjectID: %s", err.Error())
-        return nil, fmt.Errorf("error: invalid payment ID format")
+        return nil, fmt.Errorf("%w: invalid payment ID format", payment.ErrPaymentNotFound)
     }
 
     // Find the payment by ObjectID
     result := new(payment.PaymentEntity)
     err = col.FindOne(ctx, bson.M{"_id": objectId}).Decode(result)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, fmt.Errorf("%w: %s", payment.ErrPaymentNotFound, paymentId)
+    }
     if err != nil {
         log.Printf("Error: FindPayment failed: %s", err.Error())
         return nil, fmt.Errorf("error: FindPayment failed")
@@ -84,17 +145,29 @@ func (r *paymentRepository) FindPayment(ctx context.Context, paymentId string) (
     return result, nil
 }
 
-func (r *paymentRepository) UpdatePayment(ctx context.Context, payment *payment.PaymentEntity) (*payment.PaymentEntity, error) {
-    db := r.db.Database("payment_db")
-    col := db.Collection("payments")
+// UpdatePaymentStatus moves a payment from one status to another. Only the
+// status is written, so refund totals reserved at the same time are kept. It
+// reports ErrPaymentNotPayable when the payment had already left from.
+func (r *paymentRepository) UpdatePaymentStatus(ctx context.Context, paymentId primitive.ObjectID, from, to payment.PaymentStatus) (*payment.PaymentEntity, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
 
-    _, err := col.UpdateOne(ctx, bson.M{"_id": payment.Id}, bson.M{"$set": payment})
+    result := new(payment.PaymentEntity)
+    err := r.paymentDbConn(ctx).Collection("payments").FindOneAndUpdate(
+        ctx,
+        bson.M{"_id": paymentId, "status": from},
+        bson.M{"$set": bson.M{"status": to, "updated_at": time.Now()}},
+        options.FindOneAndUpdate().SetReturnDocument(options.After),
+    ).Decode(result)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, fmt.Errorf("%w: payment %s is no longer %s", payment.ErrPaymentNotPayable, paymentId.Hex(), from)
+    }
     if err != nil {
-        log.Printf("Error: UpdatePayment failed: %s", err.Error())
-        return nil, fmt.Errorf("error: UpdatePayment failed")
+        log.Printf("Error: UpdatePaymentStatus: %s", err.Error())
+        return nil, fmt.Errorf("error: update payment status failed: %w", err)
     }
 
-    return payment, nil
+    return result, nil
 }
 
 // FindPaymentsByUser retrieves all payments made by a specific user
@@ -121,17 +194,237 @@ func (r *paymentRepository) FindPaymentsByUser(ctx context.Context, userId strin
     return result, nil
 }
 
-func (r *paymentRepository)  SaveSlip(ctx context.Context, slip payment.PaymentSlip) error {
+// FindPaymentByBooking returns the booking's latest payment
+func (r *paymentRepository) FindPaymentByBooking(ctx context.Context, bookingId string) (*payment.PaymentEntity, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result := new(payment.PaymentEntity)
+    err := r.paymentDbConn(ctx).Collection("payments").FindOne(
+        ctx,
+        bson.M{"booking_id": bookingId},
+        options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+    ).Decode(result)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, fmt.Errorf("%w: booking %s", payment.ErrPaymentNotFound, bookingId)
+    }
+    if err != nil {
+        log.Printf("Error: FindPaymentByBooking: %s", err.Error())
+        return nil, fmt.Errorf("error: find payment by booking failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// FindPaymentByProviderRef returns the payment a provider's charge belongs to
+func (r *paymentRepository) FindPaymentByProviderRef(ctx context.Context, provider, reference string) (*payment.PaymentEntity, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result := new(payment.PaymentEntity)
+    err := r.paymentDbConn(ctx).Collection("payments").FindOne(ctx, bson.M{"provider": provider, "provider_ref": reference}).Decode(result)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, fmt.Errorf("%w: %s charge %s", payment.ErrPaymentNotFound, provider, reference)
+    }
+    if err != nil {
+        log.Printf("Error: FindPaymentByProviderRef: %s", err.Error())
+        return nil, fmt.Errorf("error: find payment by provider reference failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// FindManyPendingPayment lists the user's payments still awaiting payment
+func (r *paymentRepository) FindManyPendingPayment(ctx context.Context, userId string) ([]payment.PaymentEntity, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    cursor, err := r.paymentDbConn(ctx).Collection("payments").Find(ctx, bson.M{"user_id": userId, "status": payment.Pending})
+    if err != nil {
+        log.Printf("Error: FindManyPendingPayment: %s", err.Error())
+        return nil, fmt.Errorf("error: find pending payments failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    result := make([]payment.PaymentEntity, 0)
+    if err := cursor.All(ctx, &result); err != nil {
+        log.Printf("Error: FindManyPendingPayment: %s", err.Error())
+        return nil, fmt.Errorf("error: find pending payments failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// FindExpiredPayments lists pending payments past their deadline. Payments made
+// before deadlines existed have none, so they expire once created before legacyCutoff.
+func (r *paymentRepository) FindExpiredPayments(ctx context.Context, now, legacyCutoff time.Time) ([]payment.PaymentEntity, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    filter := bson.M{
+        "status": payment.Pending,
+        "$or": bson.A{
+            bson.M{"expires_at": bson.M{"$gt": time.Time{}, "$lte": now}},
+            bson.M{"expires_at": bson.M{"$in": bson.A{nil, time.Time{}}}, "created_at": bson.M{"$lte": legacyCutoff}},
+        },
+    }
+
+    cursor, err := r.paymentDbConn(ctx).Collection("payments").Find(ctx, filter)
+    if err != nil {
+        log.Printf("Error: FindExpiredPayments: %s", err.Error())
+        return nil, fmt.Errorf("error: find expired payments failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    result := make([]payment.PaymentEntity, 0)
+    if err := cursor.All(ctx, &result); err != nil {
+        log.Printf("Error: FindExpiredPayments: %s", err.Error())
+        return nil, fmt.Errorf("error: find expired payments failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// ExpirePayment moves a pending payment to EXPIRED. It reports false when the
+// payment was no longer pending, e.g. paid just before the sweep.
+func (r *paymentRepository) ExpirePayment(ctx context.Context, paymentId primitive.ObjectID) (bool, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result, err := r.paymentDbConn(ctx).Collection("payments").UpdateOne(
+        ctx,
+        bson.M{"_id": paymentId, "status": payment.Pending},
+        bson.M{"$set": bson.M{"status": payment.Expired, "updated_at": time.Now()}},
+    )
+    if err != nil {
+        log.Printf("Error: ExpirePayment: %s", err.Error())
+        return false, fmt.Errorf("error: expire payment failed: %w", err)
+    }
+
+    return result.ModifiedCount > 0, nil
+}
+
+// CompletePendingPayment moves a pending payment to COMPLETED. It reports false
+// when the payment was no longer pending, e.g. expired by the sweep.
+func (r *paymentRepository) CompletePendingPayment(ctx context.Context, paymentId primitive.ObjectID) (bool, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result, err := r.paymentDbConn(ctx).Collection("payments").UpdateOne(
+        ctx,
+        bson.M{"_id": paymentId, "status": payment.Pending},
+        bson.M{"$set": bson.M{"status": payment.Completed, "updated_at": time.Now()}},
+    )
+    if err != nil {
+        log.Printf("Error: CompletePendingPayment: %s", err.Error())
+        return false, fmt.Errorf("error: complete payment failed: %w", err)
+    }
+
+    return result.ModifiedCount > 0, nil
+}
+
+// SaveSlip stores a slip. Content hashes and transaction references are
+// unique, so neither the same image nor the same transfer can be stored twice
+// even by concurrent uploads.
+func (r *paymentRepository) SaveSlip(ctx context.Context, slip *payment.PaymentSlip) (*payment.PaymentSlip, error) {
     ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
     defer cancel()
 
     db := r.slipDbConn(ctx)
     col := db.Collection("slips")
 
-    _, err := col.InsertOne(ctx, slip)
-    if err != nil {
+    if slip.Id.IsZero() {
+        slip.Id = primitive.NewObjectID()
+    }
+    if _, err := col.InsertOne(ctx, slip); err != nil {
+        if mongo.IsDuplicateKeyError(err) {
+            if strings.Contains(err.Error(), "trans_ref") {
+                return nil, payment.ErrSlipRefReused
+            }
+            return nil, payment.ErrSlipReused
+        }
         log.Printf("Error: SaveSlip failed: %s", err.Error())
-        return errors.New("error: SaveSlip failed")
+        return nil, errors.New("error: SaveSlip failed")
+    }
+
+    return slip, nil
+}
+
+func (r *paymentRepository) FindSlip(ctx context.Context, slipId string) (*payment.PaymentSlip, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    id, err := primitive.ObjectIDFromHex(slipId)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %s", payment.ErrSlipNotFound, slipId)
+    }
+
+    result := new(payment.PaymentSlip)
+    err = r.slipDbConn(ctx).Collection("slips").FindOne(ctx, bson.M{"_id": id}).Decode(result)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, fmt.Errorf("%w: %s", payment.ErrSlipNotFound, slipId)
+    }
+    if err != nil {
+        log.Printf("Error: FindSlip: %s", err.Error())
+        return nil, fmt.Errorf("error: find slip failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// FindSlipByHash returns the slip stored with the image hash, if any
+func (r *paymentRepository) FindSlipByHash(ctx context.Context, contentHash string) (*payment.PaymentSlip, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result := new(payment.PaymentSlip)
+    err := r.slipDbConn(ctx).Collection("slips").FindOne(ctx, bson.M{"content_hash": contentHash}).Decode(result)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, payment.ErrSlipNotFound
+    }
+    if err != nil {
+        log.Printf("Error: FindSlipByHash: %s", err.Error())
+        return nil, fmt.Errorf("error: find slip failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// FindSlipByTransRef returns the slip stored for a bank transaction, if any
+func (r *paymentRepository) FindSlipByTransRef(ctx context.Context, transRef string) (*payment.PaymentSlip, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result := new(payment.PaymentSlip)
+    err := r.slipDbConn(ctx).Collection("slips").FindOne(ctx, bson.M{"trans_ref": transRef}).Decode(result)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, payment.ErrSlipNotFound
+    }
+    if err != nil {
+        log.Printf("Error: FindSlipByTransRef: %s", err.Error())
+        return nil, fmt.Errorf("error: find slip failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// FlagSlipForReview puts a slip back in the manual queue, the event's note
+// being the reason
+func (r *paymentRepository) FlagSlipForReview(ctx context.Context, slipId primitive.ObjectID, event payment.SlipEvent) error {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    _, err := r.slipDbConn(ctx).Collection("slips").UpdateOne(
+        ctx,
+        bson.M{"_id": slipId},
+        bson.M{
+            "$set":  bson.M{"status": payment.SlipPending, "needs_review": true, "review_reason": event.Note},
+            "$push": bson.M{"timeline": event},
+        },
+    )
+    if err != nil {
+        log.Printf("Error: FlagSlipForReview: %s", err.Error())
+        return fmt.Errorf("error: flag slip for review failed: %w", err)
     }
 
     return nil
@@ -163,38 +456,57 @@ func (r *paymentRepository) FindSlipByUserId(ctx context.Context, userId string)
     return result, nil
 }
 
-func (r *paymentRepository) UpdateSlipStatus(ctx context.Context, slipId string, newStatus string) error {
+// UpdateSlipStatus records a decision on a slip still in status from. It
+// fails with ErrSlipStatus when another decision got there first.
+func (r *paymentRepository) UpdateSlipStatus(ctx context.Context, slipId primitive.ObjectID, from string, event payment.SlipEvent, rejectReason string) (*payment.PaymentSlip, error) {
     ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
     defer cancel()
 
-    // Update status in payment_db's slips collection
-    paymentDb := r.slipDbConn(ctx)
-    paymentCol := paymentDb.Collection("slips")
+    set := bson.M{"status": event.Status}
+    if rejectReason != "" {
+        set["reject_reason"] = rejectReason
+    }
 
-    _, err := paymentCol.UpdateOne(ctx, bson.M{"_id": slipId}, bson.M{"$set": bson.M{"status": newStatus}})
+    slip := new(payment.PaymentSlip)
+    err := r.slipDbConn(ctx).Collection("slips").FindOneAndUpdate(
+        ctx,
+        bson.M{"_id": slipId, "status": from},
+        bson.M{"$set": set, "$push": bson.M{"timeline": event}},
+        options.FindOneAndUpdate().SetReturnDocument(options.After),
+    ).Decode(slip)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, fmt.Errorf("%w: slip %s is no longer %s", payment.ErrSlipStatus, slipId.Hex(), from)
+    }
     if err != nil {
-        log.Printf("Error: UpdateSlipStatus failed to update slip in payment_db: %s", err.Error())
-        return errors.New("error: failed to update slip status in payment database")
+        log.Printf("Error: UpdateSlipStatus failed: %s", err.Error())
+        return nil, fmt.Errorf("error: update slip status failed: %w", err)
     }
 
-    // Fetch the booking ID associated with this slip
-    var slip payment.PaymentSlip
-    if err := paymentCol.FindOne(ctx, bson.M{"_id": slipId}).Decode(&slip); err != nil {
-        log.Printf("Error: UpdateSlipStatus failed to fetch slip: %s", err.Error())
-        return errors.New("error: failed to fetch slip details")
-    }
+    return slip, nil
+}
 
-    // Update status in booking_db's booking_transaction collection
-    bookingDb := r.bookingDbConn(ctx) // Connect to booking database
-    bookingCol := bookingDb.Collection("booking_transaction")
+// ReplaceSlip stores a resubmission over a rejected slip, keeping its id and
+// audit trail. It fails with ErrSlipStatus when the slip is no longer rejected.
+func (r *paymentRepository) ReplaceSlip(ctx context.Context, slip *payment.PaymentSlip) (*payment.PaymentSlip, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
 
-    _, err = bookingCol.UpdateOne(ctx, bson.M{"booking_id": slip.BookingID}, bson.M{"$set": bson.M{"status": newStatus}})
+    result, err := r.slipDbConn(ctx).Collection("slips").ReplaceOne(ctx, bson.M{"_id": slip.Id, "status": payment.SlipRejected}, slip)
     if err != nil {
-        log.Printf("Error: UpdateSlipStatus failed to update booking transaction in booking_db: %s", err.Error())
-        return errors.New("error: failed to update booking transaction status in booking database")
+        if mongo.IsDuplicateKeyError(err) {
+            if strings.Contains(err.Error(), "trans_ref") {
+                return nil, payment.ErrSlipRefReused
+            }
+            return nil, payment.ErrSlipReused
+        }
+        log.Printf("Error: ReplaceSlip failed: %s", err.Error())
+        return nil, fmt.Errorf("error: replace slip failed: %w", err)
+    }
+    if result.MatchedCount == 0 {
+        return nil, fmt.Errorf("%w: slip %s is no longer rejected", payment.ErrSlipStatus, slip.Id.Hex())
     }
 
-    return nil
+    return slip, nil
 }
 
 func (r *paymentRepository) GetPendingSlips(ctx context.Context) ([]payment.PaymentSlip, error) {
@@ -205,7 +517,7 @@ func (r *paymentRepository) GetPendingSlips(ctx context.Context) ([]payment.Paym
     db := r.slipDbConn(ctx)
     col := db.Collection("slips")
 
-    cursor, err := col.Find(ctx, bson.M{"status": "pending"})
+    cursor, err := col.Find(ctx, bson.M{"status": payment.SlipPending})
     if err != nil {
         log.Printf("Error: GetPendingSlips failed to execute query: %s", err.Error())
         return nil, errors.New("error: failed to retrieve pending slips")
@@ -221,3 +533,831 @@ func (r *paymentRepository) GetPendingSlips(ctx context.Context) ([]payment.Paym
 
     return pendingSlips, nil
 }
+
+// refundTolerance absorbs float rounding when summing refunds in satang
+const refundTolerance = 0.005
+
+func (r *paymentRepository) InsertRefund(ctx context.Context, refund *payment.Refund) (*payment.Refund, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result, err := r.paymentDbConn(ctx).Collection("refunds").InsertOne(ctx, refund)
+    if err != nil {
+        log.Printf("Error: InsertRefund failed: %s", err.Error())
+        return nil, fmt.Errorf("error: insert refund failed: %w", err)
+    }
+
+    id, ok := result.InsertedID.(primitive.ObjectID)
+    if !ok {
+        return nil, errors.New("error: insert refund failed")
+    }
+    refund.Id = id
+    return refund, nil
+}
+
+func (r *paymentRepository) FindRefund(ctx context.Context, refundId string) (*payment.Refund, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    id, err := primitive.ObjectIDFromHex(refundId)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %s", payment.ErrRefundNotFound, refundId)
+    }
+
+    refund := new(payment.Refund)
+    if err := r.paymentDbConn(ctx).Collection("refunds").FindOne(ctx, bson.M{"_id": id}).Decode(refund); err != nil {
+        if errors.Is(err, mongo.ErrNoDocuments) {
+            return nil, fmt.Errorf("%w: %s", payment.ErrRefundNotFound, refundId)
+        }
+        log.Printf("Error: FindRefund failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find refund failed: %w", err)
+    }
+
+    return refund, nil
+}
+
+// FindManyRefund lists refunds oldest first; an empty payment id or status
+// matches every payment or status
+func (r *paymentRepository) FindManyRefund(ctx context.Context, paymentId string, status payment.RefundStatus) ([]payment.Refund, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    filter := bson.M{}
+    if paymentId != "" {
+        id, err := primitive.ObjectIDFromHex(paymentId)
+        if err != nil {
+            return nil, fmt.Errorf("error: invalid payment ID format")
+        }
+        filter["payment_id"] = id
+    }
+    if status != "" {
+        filter["status"] = status
+    }
+
+    cursor, err := r.paymentDbConn(ctx).Collection("refunds").Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": 1}))
+    if err != nil {
+        log.Printf("Error: FindManyRefund failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find refunds failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    result := make([]payment.Refund, 0)
+    if err := cursor.All(ctx, &result); err != nil {
+        log.Printf("Error: FindManyRefund failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find refunds failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// UpdateRefundStatus moves a refund on from status from, appending the event to
+// its timeline. It fails with ErrRefundStatus if the refund has already moved on.
+func (r *paymentRepository) UpdateRefundStatus(ctx context.Context, refundId primitive.ObjectID, from payment.RefundStatus, event payment.RefundEvent, reference string) (*payment.Refund, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    set := bson.M{"status": event.Status, "updated_at": event.At}
+    if reference != "" {
+        set["reference"] = reference
+    }
+
+    refund := new(payment.Refund)
+    err := r.paymentDbConn(ctx).Collection("refunds").FindOneAndUpdate(
+        ctx,
+        bson.M{"_id": refundId, "status": from},
+        bson.M{"$set": set, "$push": bson.M{"timeline": event}},
+        options.FindOneAndUpdate().SetReturnDocument(options.After),
+    ).Decode(refund)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, fmt.Errorf("%w: refund %s is no longer %s", payment.ErrRefundStatus, refundId.Hex(), from)
+    }
+    if err != nil {
+        log.Printf("Error: UpdateRefundStatus failed: %s", err.Error())
+        return nil, fmt.Errorf("error: update refund status failed: %w", err)
+    }
+
+    return refund, nil
+}
+
+// ReserveRefund holds amount against a completed payment. The check and the
+// increment are one update, so concurrent requests can't together refund more
+// than was captured.
+func (r *paymentRepository) ReserveRefund(ctx context.Context, paymentId primitive.ObjectID, amount float64) error {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    col := r.paymentDbConn(ctx).Collection("payments")
+    filter := bson.M{
+        "_id":    paymentId,
+        "status": bson.M{"$in": bson.A{payment.Completed, payment.PartiallyRefunded}},
+        "$expr": bson.M{"$lte": bson.A{
+            bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$refunded_amount", 0}}, bson.M{"$ifNull": bson.A{"$refund_pending_amount", 0}}, amount}},
+            bson.M{"$add": bson.A{"$amount", refundTolerance}},
+        }},
+    }
+
+    result, err := col.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"refund_pending_amount": amount}})
+    if err != nil {
+        log.Printf("Error: ReserveRefund failed: %s", err.Error())
+        return fmt.Errorf("error: reserve refund failed: %w", err)
+    }
+    if result.MatchedCount == 0 {
+        return payment.ErrRefundExceedsCaptured
+    }
+
+    return nil
+}
+
+// ReleaseRefund gives back the amount held by a rejected refund
+func (r *paymentRepository) ReleaseRefund(ctx context.Context, paymentId primitive.ObjectID, amount float64) error {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    _, err := r.paymentDbConn(ctx).Collection("payments").UpdateOne(ctx,
+        bson.M{"_id": paymentId},
+        bson.M{"$inc": bson.M{"refund_pending_amount": -amount}, "$set": bson.M{"updated_at": time.Now()}},
+    )
+    if err != nil {
+        log.Printf("Error: ReleaseRefund failed: %s", err.Error())
+        return fmt.Errorf("error: release refund failed: %w", err)
+    }
+
+    return nil
+}
+
+// SettleRefund moves a paid-out refund from pending to refunded and marks the
+// payment partially or fully refunded
+func (r *paymentRepository) SettleRefund(ctx context.Context, paymentId primitive.ObjectID, amount float64) error {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    pipeline := mongo.Pipeline{
+        {{Key: "$set", Value: bson.M{
+            "refund_pending_amount": bson.M{"$subtract": bson.A{bson.M{"$ifNull": bson.A{"$refund_pending_amount", 0}}, amount}},
+            "refunded_amount":       bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$refunded_amount", 0}}, amount}},
+            "updated_at":            time.Now(),
+        }}},
+        {{Key: "$set", Value: bson.M{
+            "status": bson.M{"$cond": bson.A{
+                bson.M{"$gte": bson.A{bson.M{"$add": bson.A{"$refunded_amount", refundTolerance}}, "$amount"}},
+                payment.Refunded,
+                payment.PartiallyRefunded,
+            }},
+        }}},
+    }
+
+    if _, err := r.paymentDbConn(ctx).Collection("payments").UpdateOne(ctx, bson.M{"_id": paymentId}, pipeline); err != nil {
+        log.Printf("Error: SettleRefund failed: %s", err.Error())
+        return fmt.Errorf("error: settle refund failed: %w", err)
+    }
+
+    return nil
+}
+
+func (r *paymentRepository) merchantAccountCol(ctx context.Context) *mongo.Collection {
+    return r.paymentDbConn(ctx).Collection("merchant_accounts")
+}
+
+// InsertMerchantAccount stores a new account. A unique index allows only one
+// enabled account per stage and facility.
+func (r *paymentRepository) InsertMerchantAccount(ctx context.Context, account *payment.MerchantAccount) (*payment.MerchantAccount, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    if account.Id.IsZero() {
+        account.Id = primitive.NewObjectID()
+    }
+    if _, err := r.merchantAccountCol(ctx).InsertOne(ctx, account); err != nil {
+        if mongo.IsDuplicateKeyError(err) {
+            return nil, payment.ErrMerchantScopeTaken
+        }
+        log.Printf("Error: InsertMerchantAccount: %s", err.Error())
+        return nil, fmt.Errorf("error: insert merchant account failed: %w", err)
+    }
+
+    return account, nil
+}
+
+func (r *paymentRepository) FindMerchantAccount(ctx context.Context, accountId string) (*payment.MerchantAccount, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    id, err := primitive.ObjectIDFromHex(accountId)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %s", payment.ErrMerchantNotFound, accountId)
+    }
+
+    result := new(payment.MerchantAccount)
+    err = r.merchantAccountCol(ctx).FindOne(ctx, bson.M{"_id": id}).Decode(result)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, fmt.Errorf("%w: %s", payment.ErrMerchantNotFound, accountId)
+    }
+    if err != nil {
+        log.Printf("Error: FindMerchantAccount: %s", err.Error())
+        return nil, fmt.Errorf("error: find merchant account failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// FindManyMerchantAccount lists accounts, optionally only one stage's or one
+// facility's, newest first
+func (r *paymentRepository) FindManyMerchantAccount(ctx context.Context, stage, facilityName string) ([]payment.MerchantAccount, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    filter := bson.M{}
+    if stage != "" {
+        filter["stage"] = stage
+    }
+    if facilityName != "" {
+        filter["facility_name"] = facilityName
+    }
+
+    cursor, err := r.merchantAccountCol(ctx).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+    if err != nil {
+        log.Printf("Error: FindManyMerchantAccount: %s", err.Error())
+        return nil, fmt.Errorf("error: find merchant accounts failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    result := make([]payment.MerchantAccount, 0)
+    if err := cursor.All(ctx, &result); err != nil {
+        log.Printf("Error: FindManyMerchantAccount: %s", err.Error())
+        return nil, fmt.Errorf("error: find merchant accounts failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// ResolveMerchantAccount returns the enabled account of the facility, falling
+// back to the stage's default account
+func (r *paymentRepository) ResolveMerchantAccount(ctx context.Context, stage, facilityName string) (*payment.MerchantAccount, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    scopes := []string{""}
+    if facilityName != "" {
+        scopes = []string{facilityName, ""}
+    }
+
+    for _, scope := range scopes {
+        result := new(payment.MerchantAccount)
+        err := r.merchantAccountCol(ctx).FindOne(ctx, bson.M{"stage": stage, "facility_name": scope, "enabled": true}).Decode(result)
+        if errors.Is(err, mongo.ErrNoDocuments) {
+            continue
+        }
+        if err != nil {
+            log.Printf("Error: ResolveMerchantAccount: %s", err.Error())
+            return nil, fmt.Errorf("error: resolve merchant account failed: %w", err)
+        }
+        return result, nil
+    }
+
+    return nil, fmt.Errorf("%w: %s", payment.ErrNoMerchantAccount, facilityName)
+}
+
+func (r *paymentRepository) ReplaceMerchantAccount(ctx context.Context, account *payment.MerchantAccount) (*payment.MerchantAccount, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result, err := r.merchantAccountCol(ctx).ReplaceOne(ctx, bson.M{"_id": account.Id}, account)
+    if err != nil {
+        if mongo.IsDuplicateKeyError(err) {
+            return nil, payment.ErrMerchantScopeTaken
+        }
+        log.Printf("Error: ReplaceMerchantAccount: %s", err.Error())
+        return nil, fmt.Errorf("error: update merchant account failed: %w", err)
+    }
+    if result.MatchedCount == 0 {
+        return nil, fmt.Errorf("%w: %s", payment.ErrMerchantNotFound, account.Id.Hex())
+    }
+
+    return account, nil
+}
+
+// RetireMerchantAccount disables an account and records its replacement. An
+// account can only be retired once, so two rotations can't both win.
+func (r *paymentRepository) RetireMerchantAccount(ctx context.Context, accountId, replacedBy primitive.ObjectID) error {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    now := time.Now()
+    result, err := r.merchantAccountCol(ctx).UpdateOne(
+        ctx,
+        bson.M{"_id": accountId, "replaced_by": bson.M{"$exists": false}},
+        bson.M{"$set": bson.M{"enabled": false, "replaced_by": replacedBy, "retired_at": now, "updated_at": now}},
+    )
+    if err != nil {
+        log.Printf("Error: RetireMerchantAccount: %s", err.Error())
+        return fmt.Errorf("error: retire merchant account failed: %w", err)
+    }
+    if result.MatchedCount == 0 {
+        return payment.ErrMerchantRetired
+    }
+
+    return nil
+}
+
+func (r *paymentRepository) DeleteMerchantAccount(ctx context.Context, accountId primitive.ObjectID) error {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result, err := r.merchantAccountCol(ctx).DeleteOne(ctx, bson.M{"_id": accountId})
+    if err != nil {
+        log.Printf("Error: DeleteMerchantAccount: %s", err.Error())
+        return fmt.Errorf("error: delete merchant account failed: %w", err)
+    }
+    if result.DeletedCount == 0 {
+        return fmt.Errorf("%w: %s", payment.ErrMerchantNotFound, accountId.Hex())
+    }
+
+    return nil
+}
+
+func (r *paymentRepository) CountMerchantPayments(ctx context.Context, accountId primitive.ObjectID) (int64, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    count, err := r.paymentDbConn(ctx).Collection("payments").CountDocuments(ctx, bson.M{"merchant_account_id": accountId})
+    if err != nil {
+        log.Printf("Error: CountMerchantPayments: %s", err.Error())
+        return 0, fmt.Errorf("error: count merchant payments failed: %w", err)
+    }
+
+    return count, nil
+}
+
+// InsertWebhookDelivery stores a verified webhook. A provider's event ids are
+// unique, so a redelivery fails with ErrDuplicateWebhook.
+func (r *paymentRepository) InsertWebhookDelivery(ctx context.Context, delivery *payment.WebhookDelivery) (*payment.WebhookDelivery, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result, err := r.paymentDbConn(ctx).Collection("webhook_events").InsertOne(ctx, delivery)
+    if mongo.IsDuplicateKeyError(err) {
+        return nil, payment.ErrDuplicateWebhook
+    }
+    if err != nil {
+        log.Printf("Error: InsertWebhookDelivery: %s", err.Error())
+        return nil, fmt.Errorf("error: insert webhook delivery failed: %w", err)
+    }
+
+    id, ok := result.InsertedID.(primitive.ObjectID)
+    if !ok {
+        return nil, errors.New("error: insert webhook event failed")
+    }
+    delivery.Id = id
+    return delivery, nil
+}
+
+func (r *paymentRepository) FindWebhookDelivery(ctx context.Context, provider, eventId string) (*payment.WebhookDelivery, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result := new(payment.WebhookDelivery)
+    err := r.paymentDbConn(ctx).Collection("webhook_events").FindOne(ctx, bson.M{"provider": provider, "event_id": eventId}).Decode(result)
+    if err != nil {
+        log.Printf("Error: FindWebhookDelivery: %s", err.Error())
+        return nil, fmt.Errorf("error: find webhook delivery failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// MarkWebhookProcessed records that a delivery was applied and what it did
+func (r *paymentRepository) MarkWebhookProcessed(ctx context.Context, deliveryId, paymentId primitive.ObjectID, outcome string) error {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    set := bson.M{"processed_at": time.Now(), "outcome": outcome}
+    if !paymentId.IsZero() {
+        set["payment_id"] = paymentId
+    }
+    if _, err := r.paymentDbConn(ctx).Collection("webhook_events").UpdateOne(ctx, bson.M{"_id": deliveryId}, bson.M{"$set": set}); err != nil {
+        log.Printf("Error: MarkWebhookProcessed: %s", err.Error())
+        return fmt.Errorf("error: mark webhook processed failed: %w", err)
+    }
+
+    return nil
+}
+
+// receiptPrefixes start receipt numbers of each kind
+var receiptPrefixes = map[payment.ReceiptKind]string{
+    payment.ReceiptKindReceipt:    "RC",
+    payment.ReceiptKindCreditNote: "CN",
+}
+
+// maxReceiptNumberAttempts bounds retries when another receipt took the next number
+const maxReceiptNumberAttempts = 5
+
+// InsertReceipt numbers and stores receipt. The number is one past the last
+// receipt of the kind in its fiscal year and the unique index on it decides
+// races, so numbers are only used by stored receipts and never skipped. A
+// payment has one receipt and a refund one credit note; if it was issued
+// meanwhile, that one is returned instead.
+func (r *paymentRepository) InsertReceipt(ctx context.Context, receipt *payment.Receipt) (*payment.Receipt, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    col := r.paymentDbConn(ctx).Collection("receipts")
+    for attempt := 0; attempt < maxReceiptNumberAttempts; attempt++ {
+        last := new(payment.Receipt)
+        err := col.FindOne(ctx,
+            bson.M{"kind": receipt.Kind, "fiscal_year": receipt.FiscalYear},
+            options.FindOne().SetSort(bson.M{"sequence": -1}).SetProjection(bson.M{"sequence": 1}),
+        ).Decode(last)
+        if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+            log.Printf("Error: InsertReceipt: %s", err.Error())
+            return nil, fmt.Errorf("error: insert receipt failed: %w", err)
+        }
+
+        receipt.Id = primitive.NewObjectID()
+        receipt.Sequence = last.Sequence + 1
+        receipt.Number = fmt.Sprintf("%s%d-%06d", receiptPrefixes[receipt.Kind], receipt.FiscalYear, receipt.Sequence)
+        _, err = col.InsertOne(ctx, receipt)
+        if err == nil {
+            return receipt, nil
+        }
+        if !mongo.IsDuplicateKeyError(err) {
+            log.Printf("Error: InsertReceipt: %s", err.Error())
+            return nil, fmt.Errorf("error: insert receipt failed: %w", err)
+        }
+
+        // Either the document was issued by someone else or the number was taken
+        existing, findErr := r.findReceiptFor(ctx, receipt)
+        if findErr == nil {
+            return existing, nil
+        }
+        if !errors.Is(findErr, payment.ErrReceiptNotFound) {
+            return nil, findErr
+        }
+    }
+
+    return nil, fmt.Errorf("error: insert receipt failed: no free %s number after %d attempts", receipt.Kind, maxReceiptNumberAttempts)
+}
+
+// findReceiptFor finds the stored document for what receipt records
+func (r *paymentRepository) findReceiptFor(ctx context.Context, receipt *payment.Receipt) (*payment.Receipt, error) {
+    if receipt.Kind == payment.ReceiptKindCreditNote {
+        return r.FindCreditNoteByRefund(ctx, *receipt.RefundId)
+    }
+    return r.FindReceiptByPayment(ctx, receipt.PaymentId)
+}
+
+func (r *paymentRepository) FindReceipt(ctx context.Context, receiptId string) (*payment.Receipt, error) {
+    id, err := primitive.ObjectIDFromHex(receiptId)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %s", payment.ErrReceiptNotFound, receiptId)
+    }
+    return r.findOneReceipt(ctx, bson.M{"_id": id}, receiptId)
+}
+
+// FindReceiptByPayment finds the receipt of a payment, not its credit notes
+func (r *paymentRepository) FindReceiptByPayment(ctx context.Context, paymentId primitive.ObjectID) (*payment.Receipt, error) {
+    return r.findOneReceipt(ctx, bson.M{"payment_id": paymentId, "kind": payment.ReceiptKindReceipt}, "payment "+paymentId.Hex())
+}
+
+func (r *paymentRepository) FindCreditNoteByRefund(ctx context.Context, refundId primitive.ObjectID) (*payment.Receipt, error) {
+    return r.findOneReceipt(ctx, bson.M{"refund_id": refundId, "kind": payment.ReceiptKindCreditNote}, "refund "+refundId.Hex())
+}
+
+func (r *paymentRepository) findOneReceipt(ctx context.Context, filter bson.M, what string) (*payment.Receipt, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    receipt := new(payment.Receipt)
+    if err := r.paymentDbConn(ctx).Collection("receipts").FindOne(ctx, filter).Decode(receipt); err != nil {
+        if errors.Is(err, mongo.ErrNoDocuments) {
+            return nil, fmt.Errorf("%w: %s", payment.ErrReceiptNotFound, what)
+        }
+        log.Printf("Error: FindReceipt failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find receipt failed: %w", err)
+    }
+
+    return receipt, nil
+}
+
+// FindManyReceipt lists a payment's receipt and credit notes in issue order
+func (r *paymentRepository) FindManyReceipt(ctx context.Context, paymentId primitive.ObjectID) ([]payment.Receipt, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    cursor, err := r.paymentDbConn(ctx).Collection("receipts").Find(ctx, bson.M{"payment_id": paymentId}, options.Find().SetSort(bson.M{"issued_at": 1}))
+    if err != nil {
+        log.Printf("Error: FindManyReceipt failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find receipts failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    result := make([]payment.Receipt, 0)
+    if err := cursor.All(ctx, &result); err != nil {
+        log.Printf("Error: FindManyReceipt failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find receipts failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// MarkReceiptPrinted records a download of a receipt. The first one is the
+// original; it reports true for every later one, which is recorded as a
+// re-issue and must be printed as a copy.
+func (r *paymentRepository) MarkReceiptPrinted(ctx context.Context, receiptId primitive.ObjectID, actorId string, at time.Time) (bool, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    col := r.paymentDbConn(ctx).Collection("receipts")
+    result, err := col.UpdateOne(ctx,
+        bson.M{"_id": receiptId, "delivered_at": bson.M{"$exists": false}},
+        bson.M{"$set": bson.M{"delivered_at": at}},
+    )
+    if err != nil {
+        log.Printf("Error: MarkReceiptPrinted: %s", err.Error())
+        return false, fmt.Errorf("error: mark receipt printed failed: %w", err)
+    }
+    if result.ModifiedCount == 1 {
+        return false, nil
+    }
+
+    result, err = col.UpdateOne(ctx,
+        bson.M{"_id": receiptId},
+        bson.M{"$push": bson.M{"reissues": payment.ReceiptReissue{ActorId: actorId, At: at}}},
+    )
+    if err != nil {
+        log.Printf("Error: MarkReceiptPrinted: %s", err.Error())
+        return false, fmt.Errorf("error: mark receipt printed failed: %w", err)
+    }
+    if result.MatchedCount == 0 {
+        return false, fmt.Errorf("%w: %s", payment.ErrReceiptNotFound, receiptId.Hex())
+    }
+
+    return true, nil
+}
+
+// FindBookingCharge reads what a booking made today owes. Only today's
+// bookings hold a slot, so older ones are reported as not found.
+func (r *paymentRepository) FindBookingCharge(ctx context.Context, bookingId string) (*payment.BookingCharge, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    id, err := primitive.ObjectIDFromHex(bookingId)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %s", payment.ErrBookingNotPayable, bookingId)
+    }
+
+    charge := new(payment.BookingCharge)
+    err = r.bookingDbConn(ctx).Collection("booking_transaction").FindOne(
+        ctx,
+        bson.M{"_id": id},
+        options.FindOne().SetProjection(bson.M{"user_id": 1, "facility": 1, "status": 1, "amount": 1}),
+    ).Decode(charge)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+        return nil, fmt.Errorf("%w: booking %s not found", payment.ErrBookingNotPayable, bookingId)
+    }
+    if err != nil {
+        log.Printf("Error: FindBookingCharge: %s", err.Error())
+        return nil, fmt.Errorf("error: find booking failed: %w", err)
+    }
+
+    return charge, nil
+}
+
+// FindBookingSlot reads the slot a booking is for from the booking and
+// facility databases. Bookings are for the day they were made, and move to
+// the history collection after it.
+func (r *paymentRepository) FindBookingSlot(ctx context.Context, bookingId string) (*payment.ReceiptSlot, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    id, err := primitive.ObjectIDFromHex(bookingId)
+    if err != nil {
+        return nil, fmt.Errorf("error: invalid booking ID format")
+    }
+
+    var booking struct {
+        SlotId          string    `bson:"slot_id"`
+        BadmintonSlotId string    `bson:"badminton_slot_id"`
+        CreatedAt       time.Time `bson:"created_at"`
+    }
+    for _, name := range []string{"booking_transaction", "histories_transaction"} {
+        err = r.bookingDbConn(ctx).Collection(name).FindOne(ctx, bson.M{"_id": id}).Decode(&booking)
+        if !errors.Is(err, mongo.ErrNoDocuments) {
+            break
+        }
+    }
+    if err != nil {
+        log.Printf("Error: FindBookingSlot: booking %s: %s", bookingId, err.Error())
+        return nil, fmt.Errorf("error: find booking failed: %w", err)
+    }
+
+    slotHex := booking.SlotId
+    if slotHex == "" {
+        slotHex = booking.BadmintonSlotId
+    }
+    slotId, err := primitive.ObjectIDFromHex(slotHex)
+    if err != nil {
+        return nil, fmt.Errorf("error: booking %s has no slot", bookingId)
+    }
+
+    var slot struct {
+        StartTime string `bson:"start_time"`
+        EndTime   string `bson:"end_time"`
+    }
+    if err := r.db.Database(facility.RegistryDb).Collection("slots").FindOne(ctx, bson.M{"_id": slotId}).Decode(&slot); err != nil {
+        log.Printf("Error: FindBookingSlot: slot %s: %s", slotHex, err.Error())
+        return nil, fmt.Errorf("error: find slot failed: %w", err)
+    }
+
+    day := booking.CreatedAt.In(utils.Location())
+    return &payment.ReceiptSlot{
+        Date:      time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, utils.Location()),
+        StartTime: slot.StartTime,
+        EndTime:   slot.EndTime,
+    }, nil
+}
+
+// InsertJournalEntry appends entry to the ledger. There is deliberately no
+// way to change or delete an entry. Posting the same source twice fails with
+// ErrDuplicateEntry, so a retried posting is harmless.
+func (r *paymentRepository) InsertJournalEntry(ctx context.Context, entry *ledger.Entry) (*ledger.Entry, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    result, err := r.paymentDbConn(ctx).Collection("journal_entries").InsertOne(ctx, entry)
+    if mongo.IsDuplicateKeyError(err) {
+        return nil, fmt.Errorf("%w: %s %s", ledger.ErrDuplicateEntry, entry.Kind, entry.SourceId)
+    }
+    if err != nil {
+        log.Printf("Error: InsertJournalEntry: %s", err.Error())
+        return nil, fmt.Errorf("error: insert journal entry failed: %w", err)
+    }
+
+    id, ok := result.InsertedID.(primitive.ObjectID)
+    if !ok {
+        return nil, errors.New("error: insert journal entry failed")
+    }
+    entry.Id = id
+    return entry, nil
+}
+
+func (r *paymentRepository) FindJournalEntry(ctx context.Context, entryId string) (*ledger.Entry, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    id, err := primitive.ObjectIDFromHex(entryId)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %s", ledger.ErrEntryNotFound, entryId)
+    }
+
+    entry := new(ledger.Entry)
+    if err := r.paymentDbConn(ctx).Collection("journal_entries").FindOne(ctx, bson.M{"_id": id}).Decode(entry); err != nil {
+        if errors.Is(err, mongo.ErrNoDocuments) {
+            return nil, fmt.Errorf("%w: %s", ledger.ErrEntryNotFound, entryId)
+        }
+        log.Printf("Error: FindJournalEntry failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find journal entry failed: %w", err)
+    }
+
+    return entry, nil
+}
+
+// FindManyJournalEntry lists entries effective in [from, to] in the order
+// they took effect. An account matches itself and the accounts under it, and
+// an empty one matches every entry; a zero from or to leaves that end open.
+func (r *paymentRepository) FindManyJournalEntry(ctx context.Context, account string, from, to time.Time) ([]ledger.Entry, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    filter := bson.M{}
+    if account != "" {
+        filter["lines.account"] = accountPattern(account)
+    }
+    effective := bson.M{}
+    if !from.IsZero() {
+        effective["$gte"] = from
+    }
+    if !to.IsZero() {
+        effective["$lte"] = to
+    }
+    if len(effective) > 0 {
+        filter["effective_at"] = effective
+    }
+
+    cursor, err := r.paymentDbConn(ctx).Collection("journal_entries").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "effective_at", Value: 1}, {Key: "_id", Value: 1}}))
+    if err != nil {
+        log.Printf("Error: FindManyJournalEntry failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find journal entries failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    result := make([]ledger.Entry, 0)
+    if err := cursor.All(ctx, &result); err != nil {
+        log.Printf("Error: FindManyJournalEntry failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find journal entries failed: %w", err)
+    }
+
+    return result, nil
+}
+
+// FindAccountBalances totals every account, or account and those under it,
+// over the entries effective up to at
+func (r *paymentRepository) FindAccountBalances(ctx context.Context, account string, at time.Time) ([]ledger.Balance, error) {
+    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    defer cancel()
+
+    pipeline := []bson.M{
+        {"$match": bson.M{"effective_at": bson.M{"$lte": at}}},
+        {"$unwind": "$lines"},
+    }
+    if account != "" {
+        pipeline = append(pipeline, bson.M{"$match": bson.M{"lines.account": accountPattern(account)}})
+    }
+    pipeline = append(pipeline,
+        bson.M{"$group": bson.M{
+            "_id":    "$lines.account",
+            "debit":  bson.M{"$sum": "$lines.debit"},
+            "credit": bson.M{"$sum": "$lines.credit"},
+        }},
+        bson.M{"$sort": bson.M{"_id": 1}},
+    )
+
+    cursor, err := r.paymentDbConn(ctx).Collection("journal_entries").Aggregate(ctx, pipeline)
+    if err != nil {
+        log.Printf("Error: FindAccountBalances failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find account balances failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    result := make([]ledger.Balance, 0)
+    if err := cursor.All(ctx, &result); err != nil {
+        log.Printf("Error: FindAccountBalances failed: %s", err.Error())
+        return nil, fmt.Errorf("error: find account balances failed: %w", err)
+    }
+    for i := range result {
+        result[i].Balance = result[i].Debit - result[i].Credit
+    }
+
+    return result, nil
+}
+
+// CheckLedger adds up every entry effective up to at, in the database, and
+// reports the totals and any entry whose own lines don't balance
+func (r *paymentRepository) CheckLedger(ctx context.Context, at time.Time) (*ledger.Check, error) {
+    ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+    defer cancel()
+
+    pipeline := []bson.M{
+        {"$match": bson.M{"effective_at": bson.M{"$lte": at}}},
+        {"$project": bson.M{
+            "debit":  bson.M{"$sum": "$lines.debit"},
+            "credit": bson.M{"$sum": "$lines.credit"},
+        }},
+        {"$group": bson.M{
+            "_id":     nil,
+            "entries": bson.M{"$sum": 1},
+            "debit":   bson.M{"$sum": "$debit"},
+            "credit":  bson.M{"$sum": "$credit"},
+            "unbalanced": bson.M{"$addToSet": bson.M{
+                "$cond": bson.A{bson.M{"$ne": bson.A{"$debit", "$credit"}}, "$_id", nil},
+            }},
+        }},
+    }
+
+    cursor, err := r.paymentDbConn(ctx).Collection("journal_entries").Aggregate(ctx, pipeline)
+    if err != nil {
+        log.Printf("Error: CheckLedger failed: %s", err.Error())
+        return nil, fmt.Errorf("error: check ledger failed: %w", err)
+    }
+    defer cursor.Close(ctx)
+
+    var totals struct {
+        Entries    int64                 `bson:"entries"`
+        Debit      int64                 `bson:"debit"`
+        Credit     int64                 `bson:"credit"`
+        Unbalanced []*primitive.ObjectID `bson:"unbalanced"`
+    }
+    if cursor.Next(ctx) {
+        if err := cursor.Decode(&totals); err != nil {
+            log.Printf("Error: CheckLedger failed: %s", err.Error())
+            return nil, fmt.Errorf("error: check ledger failed: %w", err)
+        }
+    }
+    if err := cursor.Err(); err != nil {
+        log.Printf("Error: CheckLedger failed: %s", err.Error())
+        return nil, fmt.Errorf("error: check ledger failed: %w", err)
+    }
+
+    check := &ledger.Check{At: at, Entries: totals.Entries, Debit: totals.Debit, Credit: totals.Credit}
+    for _, id := range totals.Unbalanced {
+        if id != nil {
+            check.Unbalanced = append(check.Unbalanced, *id)
+        }
+    }
+    check.Balanced = check.Debit == check.Credit && len(check.Unbalanced) == 0
+    return check, nil
+}
+
+// accountPattern matches account and the accounts under it
+func accountPattern(account string) primitive.Regex {
+    return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(account) + "(:|$)"}
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"main/modules/payment"
+	"time"
+)
+
+// expirySweepInterval is how often pending payments are checked against their deadline
+const expirySweepInterval = time.Minute
+
+func (u *paymentUsecase) paymentTtl() time.Duration {
+	return time.Duration(u.cfg.Payment.TtlSeconds) * time.Second
+}
+
+// deadline is when p stops being payable. Payments created before deadlines
+// were stored get one from their creation time.
+func (u *paymentUsecase) deadline(p *payment.PaymentEntity) time.Time {
+	if p.ExpiresAt.IsZero() {
+		return p.CreatedAt.Add(u.paymentTtl())
+	}
+	return p.ExpiresAt
+}
+
+// isExpired reports whether p could no longer be paid at t
+func (u *paymentUsecase) isExpired(p *payment.PaymentEntity, t time.Time) bool {
+	if p.Status == payment.Expired {
+		return true
+	}
+	return p.Status == payment.Pending && t.After(u.deadline(p))
+}
+
+// ExpirePayments moves pending payments past their deadline to EXPIRED and
+// publishes payment.expired for each, so the booking gives its slot back
+func (u *paymentUsecase) ExpirePayments(ctx context.Context) (int, error) {
+	now := time.Now()
+	overdue, err := u.paymentRepository.FindExpiredPayments(ctx, now, now.Add(-u.paymentTtl()))
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range overdue {
+		p := &overdue[i]
+		changed, err := u.paymentRepository.ExpirePayment(ctx, p.Id)
+		if err != nil {
+			log.Printf("Error: ExpirePayments: payment %s: %s", p.Id.Hex(), err.Error())
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		p.Status = payment.Expired
+		p.ExpiresAt = u.deadline(p)
+		u.publishEvent(payment.TopicPaymentExpired, p.BookingID, payment.NewPaymentExpiredEvent(p))
+		expired++
+	}
+
+	return expired, nil
+}
+
+// SchedulePaymentExpiry runs ExpirePayments every expirySweepInterval
+func (u *paymentUsecase) SchedulePaymentExpiry() {
+	ticker := time.NewTicker(expirySweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := u.ExpirePayments(context.Background())
+		if err != nil {
+			log.Printf("Error expiring payments: %s", err.Error())
+			continue
+		}
+		if expired > 0 {
+			log.Printf("Expired %d unpaid payments", expired)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"main/modules/payment"
+	"main/modules/payment/ledger"
+	"time"
+)
+
+// ledgerSystemActor posts the entries the payment service makes itself
+const ledgerSystemActor = "payment-service"
+
+// PostJournalEntry posts an entry finance makes by hand, for movements the
+// service doesn't post itself such as provider fees or wallet top-ups
+func (u *paymentUsecase) PostJournalEntry(ctx context.Context, adminId string, req *payment.JournalEntryRequest) (*ledger.Entry, error) {
+	now := time.Now()
+	entry := &ledger.Entry{
+		Kind:        ledger.Kind(req.Kind),
+		SourceId:    req.SourceId,
+		Description: req.Description,
+		Currency:    "THB",
+		Lines:       make([]ledger.Line, 0, len(req.Lines)),
+		EffectiveAt: now,
+		PostedBy:    adminId,
+	}
+	if req.EffectiveAt != nil {
+		if req.EffectiveAt.After(now) {
+			return nil, fmt.Errorf("%w: effective time is in the future", ledger.ErrInvalidEntry)
+		}
+		entry.EffectiveAt = *req.EffectiveAt
+	}
+	for _, line := range req.Lines {
+		entry.Lines = append(entry.Lines, ledger.Line{Account: line.Account, Debit: line.Debit, Credit: line.Credit})
+	}
+
+	return u.postEntry(ctx, entry)
+}
+
+// ReverseJournalEntry cancels an entry by posting its mirror image now. An
+// entry can be reversed once, and a reversal can't itself be reversed; post
+// the movement again instead.
+func (u *paymentUsecase) ReverseJournalEntry(ctx context.Context, entryId, adminId string, req *payment.ReverseJournalEntryRequest) (*ledger.Entry, error) {
+	entry, err := u.paymentRepository.FindJournalEntry(ctx, entryId)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Kind == ledger.KindReversal {
+		return nil, fmt.Errorf("%w: %s is a reversal", ledger.ErrInvalidEntry, entryId)
+	}
+
+	reversal, err := u.postEntry(ctx, ledger.ReversalEntry(entry, adminId, req.Reason, time.Now()))
+	if errors.Is(err, ledger.ErrDuplicateEntry) {
+		return nil, fmt.Errorf("%w: %s", ledger.ErrReversed, entryId)
+	}
+	return reversal, err
+}
+
+func (u *paymentUsecase) FindJournalEntry(ctx context.Context, entryId string) (*ledger.Entry, error) {
+	return u.paymentRepository.FindJournalEntry(ctx, entryId)
+}
+
+func (u *paymentUsecase) FindManyJournalEntry(ctx context.Context, account string, from, to time.Time) ([]ledger.Entry, error) {
+	return u.paymentRepository.FindManyJournalEntry(ctx, account, from, to)
+}
+
+// FindAccountBalances reports account, and the accounts under it, as they
+// stood at the time at; an empty account reports the whole ledger
+func (u *paymentUsecase) FindAccountBalances(ctx context.Context, account string, at time.Time) ([]ledger.Balance, error) {
+	return u.paymentRepository.FindAccountBalances(ctx, account, at)
+}
+
+// CheckLedger proves the ledger balances as at the time at: total debits
+// equal total credits and no single entry is out of balance
+func (u *paymentUsecase) CheckLedger(ctx context.Context, at time.Time) (*ledger.Check, error) {
+	return u.paymentRepository.CheckLedger(ctx, at)
+}
+
+// postCharge posts a payment that was just captured. A failure is only logged;
+// the ledger check then shows the charge missing from the clearing account.
+func (u *paymentUsecase) postCharge(ctx context.Context, p *payment.PaymentEntity) {
+	entry := ledger.ChargeEntry(p, time.Now())
+	entry.PostedBy = ledgerSystemActor
+	if _, err := u.postEntry(ctx, entry); err != nil && !errors.Is(err, ledger.ErrDuplicateEntry) {
+		log.Printf("Error: postCharge: payment %s: %s", p.Id.Hex(), err.Error())
+	}
+}
+
+// postRefund posts a refund that was just paid out
+func (u *paymentUsecase) postRefund(ctx context.Context, refund *payment.Refund) {
+	p, err := u.paymentRepository.FindPayment(ctx, refund.PaymentId.Hex())
+	if err != nil {
+		log.Printf("Error: postRefund: refund %s: %s", refund.Id.Hex(), err.Error())
+		return
+	}
+
+	entry := ledger.RefundEntry(p, refund, time.Now())
+	entry.PostedBy = ledgerSystemActor
+	if _, err := u.postEntry(ctx, entry); err != nil && !errors.Is(err, ledger.ErrDuplicateEntry) {
+		log.Printf("Error: postRefund: refund %s: %s", refund.Id.Hex(), err.Error())
+	}
+}
+
+// postEntry checks entry balances and appends it to the ledger
+func (u *paymentUsecase) postEntry(ctx context.Context, entry *ledger.Entry) (*ledger.Entry, error) {
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+	entry.PostedAt = time.Now()
+	return u.paymentRepository.InsertJournalEntry(ctx, entry)
+}
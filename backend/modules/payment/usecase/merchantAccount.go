@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"main/modules/payment"
+	"strings"
+	"time"
+
+	"github.com/Frontware/promptpay"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateMerchantAccount registers a receiving account. Only one account per
+// stage and facility may be enabled at a time.
+func (u *paymentUsecase) CreateMerchantAccount(ctx context.Context, createdBy string, req *payment.MerchantAccountRequest) (*payment.MerchantAccount, error) {
+	promptPayId, err := normalisePromptPayId(req.PromptPayId)
+	if err != nil {
+		return nil, err
+	}
+	taxId, err := normaliseTaxId(req.TaxId)
+	if err != nil {
+		return nil, err
+	}
+
+	stage := req.Stage
+	if stage == "" {
+		stage = u.cfg.App.Stage
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	now := time.Now()
+	return u.paymentRepository.InsertMerchantAccount(ctx, &payment.MerchantAccount{
+		Stage:        stage,
+		FacilityName: strings.TrimSpace(req.FacilityName),
+		CostCentre:   strings.TrimSpace(req.CostCentre),
+		PromptPayId:  promptPayId,
+		TaxId:        taxId,
+		DisplayName:  strings.TrimSpace(req.DisplayName),
+		Enabled:      enabled,
+		CreatedBy:    createdBy,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+}
+
+func (u *paymentUsecase) FindMerchantAccount(ctx context.Context, accountId string) (*payment.MerchantAccount, error) {
+	return u.paymentRepository.FindMerchantAccount(ctx, accountId)
+}
+
+func (u *paymentUsecase) FindManyMerchantAccount(ctx context.Context, stage, facilityName string) ([]payment.MerchantAccount, error) {
+	return u.paymentRepository.FindManyMerchantAccount(ctx, stage, facilityName)
+}
+
+func (u *paymentUsecase) UpdateMerchantAccount(ctx context.Context, accountId string, req *payment.UpdateMerchantAccountRequest) (*payment.MerchantAccount, error) {
+	account, err := u.paymentRepository.FindMerchantAccount(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if account.ReplacedBy != nil {
+		return nil, payment.ErrMerchantRetired
+	}
+
+	if req.CostCentre != nil {
+		account.CostCentre = strings.TrimSpace(*req.CostCentre)
+	}
+	if req.TaxId != nil {
+		if account.TaxId, err = normaliseTaxId(*req.TaxId); err != nil {
+			return nil, err
+		}
+	}
+	if req.DisplayName != nil {
+		account.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Enabled != nil {
+		account.Enabled = *req.Enabled
+	}
+	account.UpdatedAt = time.Now()
+
+	return u.paymentRepository.ReplaceMerchantAccount(ctx, account)
+}
+
+// DeleteMerchantAccount removes an account that never received a payment.
+// Used accounts stay for the payments that point at them; disable them instead.
+func (u *paymentUsecase) DeleteMerchantAccount(ctx context.Context, accountId string) error {
+	account, err := u.paymentRepository.FindMerchantAccount(ctx, accountId)
+	if err != nil {
+		return err
+	}
+
+	count, err := u.paymentRepository.CountMerchantPayments(ctx, account.Id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return payment.ErrMerchantInUse
+	}
+
+	return u.paymentRepository.DeleteMerchantAccount(ctx, account.Id)
+}
+
+// RotateMerchantAccount moves a facility onto a new PromptPay account. The old
+// account is retired before the new one is stored, so payment creation never
+// sees two enabled accounts, and restored if storing the new one fails.
+// Pending payments keep their stored QR payload and stay payable to the old account.
+func (u *paymentUsecase) RotateMerchantAccount(ctx context.Context, accountId, rotatedBy string, req *payment.RotateMerchantAccountRequest) (*payment.MerchantAccount, error) {
+	old, err := u.paymentRepository.FindMerchantAccount(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if old.ReplacedBy != nil {
+		return nil, payment.ErrMerchantRetired
+	}
+
+	promptPayId, err := normalisePromptPayId(req.PromptPayId)
+	if err != nil {
+		return nil, err
+	}
+	if promptPayId == old.PromptPayId {
+		return nil, fmt.Errorf("%w: the new PromptPay ID is the current one", payment.ErrInvalidMerchant)
+	}
+	taxId := old.TaxId
+	if req.TaxId != "" {
+		if taxId, err = normaliseTaxId(req.TaxId); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	next := &payment.MerchantAccount{
+		Id:           primitive.NewObjectID(),
+		Stage:        old.Stage,
+		FacilityName: old.FacilityName,
+		CostCentre:   firstNonEmpty(strings.TrimSpace(req.CostCentre), old.CostCentre),
+		PromptPayId:  promptPayId,
+		TaxId:        taxId,
+		DisplayName:  firstNonEmpty(strings.TrimSpace(req.DisplayName), old.DisplayName),
+		Enabled:      old.Enabled,
+		CreatedBy:    rotatedBy,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := u.paymentRepository.RetireMerchantAccount(ctx, old.Id, next.Id); err != nil {
+		return nil, err
+	}
+	saved, err := u.paymentRepository.InsertMerchantAccount(ctx, next)
+	if err != nil {
+		if _, restoreErr := u.paymentRepository.ReplaceMerchantAccount(ctx, old); restoreErr != nil {
+			return nil, fmt.Errorf("%w; restoring the old account also failed: %v", err, restoreErr)
+		}
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// resolveMerchantAccount picks the account a new payment for facilityName is
+// received into
+func (u *paymentUsecase) resolveMerchantAccount(ctx context.Context, facilityName string) (*payment.MerchantAccount, error) {
+	account, err := u.paymentRepository.ResolveMerchantAccount(ctx, u.cfg.App.Stage, facilityName)
+	if errors.Is(err, payment.ErrNoMerchantAccount) {
+		return nil, fmt.Errorf("%w (stage %s)", err, u.cfg.App.Stage)
+	}
+	return account, err
+}
+
+// normalisePromptPayId strips separators and accepts a mobile number or a
+// 13 digit national or tax ID, the kinds the payload generator supports
+func normalisePromptPayId(raw string) (string, error) {
+	id := stripSeparators(raw)
+	if !isDigits(id) {
+		return "", fmt.Errorf("%w: PromptPay ID must be digits", payment.ErrInvalidMerchant)
+	}
+	if len(id) == 13 {
+		if !validThaiId(id) {
+			return "", fmt.Errorf("%w: PromptPay ID %s fails its checksum", payment.ErrInvalidMerchant, id)
+		}
+		return id, nil
+	}
+	if len(id) == 10 && (&promptpay.PromptPay{PromptPayID: id}).GetPromptPayType() == promptpay.PHONE {
+		return id, nil
+	}
+	return "", fmt.Errorf("%w: PromptPay ID must be a 10 digit mobile number or a 13 digit ID", payment.ErrInvalidMerchant)
+}
+
+func normaliseTaxId(raw string) (string, error) {
+	id := stripSeparators(raw)
+	if len(id) != 13 || !isDigits(id) || !validThaiId(id) {
+		return "", fmt.Errorf("%w: tax ID must be 13 digits with a valid checksum", payment.ErrInvalidMerchant)
+	}
+	return id, nil
+}
+
+// validThaiId checks the check digit of a 13 digit Thai national or tax ID
+func validThaiId(id string) bool {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		sum += int(id[i]-'0') * (13 - i)
+	}
+	return (11-sum%11)%10 == int(id[12]-'0')
+}
+
+func stripSeparators(s string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s))
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
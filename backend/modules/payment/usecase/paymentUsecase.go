@@ -2,44 +2,132 @@ package usecase
 
 import (
     "context"
+    "encoding/json"
     "fmt"
+    "io"
     "log"
+    "net/http"
     "main/config"
     "main/modules/payment"
+    "main/modules/payment/ledger"
+    "main/modules/payment/provider"
     "main/modules/payment/repository"
+    "main/pkg/qrimage"
+    "main/pkg/queue"
+    "main/pkg/receiptpdf"
+    "main/pkg/slipqr"
+    "main/pkg/storage"
 
-    "net/url"
+    "strings"
     "time"
 
-    "github.com/Frontware/promptpay"
     "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type PaymentUsecaseService interface {
-    CreatePayment(ctx context.Context, userId, bookingId, paymentMethod, facilityName string, amount float64) (*payment.PaymentResponse, error)
+    CreatePayment(ctx context.Context, userId, bookingId, paymentMethod string) (*payment.PaymentResponse, error)
     UpdatePayment(ctx context.Context, paymentId, status string) (*payment.PaymentEntity, error)
     FindPayment(ctx context.Context, paymentId string) (*payment.PaymentEntity, error)
     FindPaymentsByUser(ctx context.Context, userId string) ([]payment.PaymentEntity, error)
-    SaveSlip(ctx context.Context, slip payment.PaymentSlip) error
+    SyncPaymentStatus(ctx context.Context, paymentId string) (*payment.PaymentEntity, error)
+    HandleWebhook(ctx context.Context, providerName string, header http.Header, body []byte) (*payment.WebhookDelivery, error)
+    RenderPaymentQR(ctx context.Context, paymentId string, format qrimage.Format, size int) ([]byte, error)
+    UploadSlip(ctx context.Context, userId string, req *payment.UploadSlipRequest, data []byte) (*payment.PaymentSlip, error)
+    FindSlipURL(ctx context.Context, slipId string) (*payment.SlipURLResponse, error)
+    OpenSlipFile(ctx context.Context, key, expires, signature string) (io.ReadCloser, error)
     FindSlipByUserId(ctx context.Context, userId string) ([]payment.PaymentSlip, error)
-    UpdateSlipStatus(ctx context.Context, slipId string, newStatus string) error
+    ApproveSlip(ctx context.Context, slipId, adminId string, req *payment.SlipDecisionRequest) (*payment.PaymentSlip, error)
+    RejectSlip(ctx context.Context, slipId, adminId string, req *payment.SlipDecisionRequest) (*payment.PaymentSlip, error)
     GetPendingSlips(ctx context.Context) ([]payment.PaymentSlip, error)
+
+    // Receipts
+    FindPaymentReceipts(ctx context.Context, paymentId, userId string, isAdmin bool) ([]payment.Receipt, error)
+    RenderReceipt(ctx context.Context, paymentId, receiptId, userId string, isAdmin bool) (*payment.Receipt, []byte, error)
+
+    // Ledger
+    PostJournalEntry(ctx context.Context, adminId string, req *payment.JournalEntryRequest) (*ledger.Entry, error)
+    ReverseJournalEntry(ctx context.Context, entryId, adminId string, req *payment.ReverseJournalEntryRequest) (*ledger.Entry, error)
+    FindJournalEntry(ctx context.Context, entryId string) (*ledger.Entry, error)
+    FindManyJournalEntry(ctx context.Context, account string, from, to time.Time) ([]ledger.Entry, error)
+    FindAccountBalances(ctx context.Context, account string, at time.Time) ([]ledger.Balance, error)
+    CheckLedger(ctx context.Context, at time.Time) (*ledger.Check, error)
+
+    // Expiry
+    ExpirePayments(ctx context.Context) (int, error)
+    SchedulePaymentExpiry()
+
+    // Refunds
+    RequestRefund(ctx context.Context, paymentId, requestedBy string, isAdmin bool, req *payment.RefundRequest) (*payment.Refund, error)
+    ApproveRefund(ctx context.Context, refundId, adminId string, req *payment.RefundDecisionRequest) (*payment.Refund, error)
+    RejectRefund(ctx context.Context, refundId, adminId string, req *payment.RefundDecisionRequest) (*payment.Refund, error)
+    CompleteRefund(ctx context.Context, refundId, adminId string, req *payment.RefundDecisionRequest) (*payment.Refund, error)
+    FindRefund(ctx context.Context, refundId string) (*payment.Refund, error)
+    FindManyRefund(ctx context.Context, paymentId string, status payment.RefundStatus) ([]payment.Refund, error)
+
+    // Merchant accounts
+    CreateMerchantAccount(ctx context.Context, createdBy string, req *payment.MerchantAccountRequest) (*payment.MerchantAccount, error)
+    FindMerchantAccount(ctx context.Context, accountId string) (*payment.MerchantAccount, error)
+    FindManyMerchantAccount(ctx context.Context, stage, facilityName string) ([]payment.MerchantAccount, error)
+    UpdateMerchantAccount(ctx context.Context, accountId string, req *payment.UpdateMerchantAccountRequest) (*payment.MerchantAccount, error)
+    DeleteMerchantAccount(ctx context.Context, accountId string) error
+    RotateMerchantAccount(ctx context.Context, accountId, rotatedBy string, req *payment.RotateMerchantAccountRequest) (*payment.MerchantAccount, error)
 }
 
 type paymentUsecase struct {
     cfg               *config.Config
     paymentRepository repository.PaymentRepositoryService
+    blobStore         storage.BlobStore
+    slipSigner        *storage.Signer
+    slipVerifier      slipqr.Verifier
+    providers         *provider.Registry
+    receiptRenderer   *receiptpdf.Renderer
 }
 
 // NewPaymentUsecase creates and returns a new payment usecase instance.
-func NewPaymentUsecase(cfg *config.Config, paymentRepository repository.PaymentRepositoryService) PaymentUsecaseService {
+func NewPaymentUsecase(cfg *config.Config, paymentRepository repository.PaymentRepositoryService, blobStore storage.BlobStore) PaymentUsecaseService {
+    renderer, err := receiptpdf.NewRenderer(cfg.Payment.ReceiptFontPath)
+    if err != nil {
+        log.Printf("Error: %s; receipts use the core fonts", err.Error())
+        renderer, _ = receiptpdf.NewRenderer("")
+    }
+
     return &paymentUsecase{
         cfg:               cfg,
         paymentRepository: paymentRepository,
+        blobStore:         blobStore,
+        slipSigner:        storage.NewSigner(cfg.Storage.SigningKey, strings.TrimRight(cfg.Payment.PublicUrl, "/")+"/slips/files"),
+        slipVerifier:      slipqr.NewVerifier(&cfg.Payment),
+        providers:         provider.NewRegistry(&cfg.Payment),
+        receiptRenderer:   renderer,
     }
 }
 
-func (u *paymentUsecase) CreatePayment(ctx context.Context, userId, bookingId, paymentMethod, facilityName string, amount float64) (*payment.PaymentResponse, error) {
+// CreatePayment starts a charge with the provider for paymentMethod. What the
+// user needs to pay depends on it: a QR code, a checkout page or a counter code.
+// CreatePayment charges a pending booking its stored amount. The amount and
+// facility come from the booking, never the caller, so a payment always
+// covers what the booking service will check it against.
+func (u *paymentUsecase) CreatePayment(ctx context.Context, userId, bookingId, paymentMethod string) (*payment.PaymentResponse, error) {
+    chargeProvider, err := u.providers.ForMethod(paymentMethod)
+    if err != nil {
+        return nil, err
+    }
+
+    booking, err := u.paymentRepository.FindBookingCharge(ctx, bookingId)
+    if err != nil {
+        return nil, err
+    }
+    if booking.UserId != userId {
+        return nil, fmt.Errorf("%w: %s", payment.ErrBookingForbidden, bookingId)
+    }
+    // "pending" is the booking service's status for a booking holding its slot
+    if booking.Status != "pending" || booking.Amount <= 0 {
+        return nil, fmt.Errorf("%w: booking %s is %s with amount %.2f", payment.ErrBookingNotPayable, bookingId, booking.Status, booking.Amount)
+    }
+    amount := roundSatang(booking.Amount)
+    facilityName := booking.Facility
+
+    now := time.Now()
     paymentDoc := &payment.PaymentEntity{
         Id:            primitive.NewObjectID(),
         UserID:        userId,
@@ -47,27 +135,40 @@ func (u *paymentUsecase) CreatePayment(ctx context.Context, userId, bookingId, p
         Amount:        amount,
         Currency:      "THB",
         PaymentMethod: paymentMethod,
+        Provider:      chargeProvider.Name(),
         FacilityName:  facilityName,
         Status:        payment.Pending,
-        CreatedAt:     time.Now(),
-        UpdatedAt:     time.Now(),
+        ExpiresAt:     now.Add(u.paymentTtl()),
+        CreatedAt:     now,
+        UpdatedAt:     now,
     }
 
-    // Generate QR code (PromptPay logic)
-    promptPay := &promptpay.PromptPay{
-        PromptPayID: "1579901028845", // Replace with actual PromptPay ID
-        Amount:      amount,
-        OneTime:     true,
+    account, err := u.resolveMerchantAccount(ctx, facilityName)
+    if err != nil {
+        return nil, err
     }
+    paymentDoc.MerchantAccountId = account.Id
+    paymentDoc.CostCentre = account.CostCentre
 
-    qrCodeData, err := promptPay.Gen()
+    charge, err := chargeProvider.CreateCharge(ctx, &provider.ChargeRequest{
+        PaymentId:   paymentDoc.Id.Hex(),
+        Amount:      amount,
+        Currency:    paymentDoc.Currency,
+        PromptPayId: account.PromptPayId,
+        Description: "Booking " + bookingId,
+        ExpiresAt:   paymentDoc.ExpiresAt,
+    })
     if err != nil {
-        return nil, fmt.Errorf("error generating QR code: %w", err)
+        return nil, err
     }
+    paymentDoc.ProviderRef = charge.Reference
+    paymentDoc.CheckoutUrl = charge.CheckoutUrl
 
-    // Create the QR code URL
-    qrCodeURL := fmt.Sprintf("https://api.qrserver.com/v1/create-qr-code/?data=%s&size=300x300", url.QueryEscape(qrCodeData))
-    paymentDoc.QRCodeURL = qrCodeURL
+    // The QR image is rendered by this service from the stored payload
+    if charge.QRPayload != "" {
+        paymentDoc.QRPayload = charge.QRPayload
+        paymentDoc.QRCodeURL = u.qrCodeURL(paymentDoc.Id)
+    }
 
     // Save payment in the repository
     paymentResult, err := u.paymentRepository.InsertPayment(ctx, paymentDoc)
@@ -75,43 +176,67 @@ func (u *paymentUsecase) CreatePayment(ctx context.Context, userId, bookingId, p
         return nil, fmt.Errorf("error creating payment: %w", err)
     }
 
-    // Convert the entity to response
-    response := &payment.PaymentResponse{
-        Id:           paymentResult.Id.Hex(),
-        PaymentID:    paymentResult.Id.Hex(),
-        UserId:       paymentResult.UserID,
-        BookingId:    paymentResult.BookingID,
-        Amount:       paymentResult.Amount,
-        Currency:     paymentResult.Currency,
-        PaymentMethod: paymentResult.PaymentMethod,
-        Status:       paymentResult.Status,
-        FacilityName: paymentResult.FacilityName,
-        CreatedAt:    paymentResult.CreatedAt,
-        UpdatedAt:    paymentResult.UpdatedAt,
-        QRCodeURL:    paymentResult.QRCodeURL,
-    }
-
-    return response, nil
+    return payment.NewPaymentResponse(paymentResult), nil
 }
 
+// UpdatePayment settles a pending payment by hand as COMPLETED, FAILED or
+// CANCELED. Nothing else may change by hand: expiry and refunds have their own
+// flows, and a settled payment is never reopened.
 func (u *paymentUsecase) UpdatePayment(ctx context.Context, paymentId, status string) (*payment.PaymentEntity, error) {
+    newStatus := payment.PaymentStatus(strings.ToUpper(status))
+    switch newStatus {
+    case payment.Completed, payment.Failed, payment.Canceled:
+    default:
+        return nil, fmt.Errorf("%w: %s", payment.ErrInvalidPaymentStatus, status)
+    }
+
     paymentEntity, err := u.paymentRepository.FindPayment(ctx, paymentId)
     if err != nil {
         return nil, fmt.Errorf("failed to find payment: %w", err)
     }
+    if paymentEntity.Status != payment.Pending {
+        return nil, fmt.Errorf("%w: payment %s is %s", payment.ErrPaymentNotPayable, paymentId, paymentEntity.Status)
+    }
 
-    paymentEntity.Status = payment.PaymentStatus(status)
-    paymentEntity.UpdatedAt = time.Now()
-
-    // Update the payment status
-    updatedPayment, err := u.paymentRepository.UpdatePayment(ctx, paymentEntity)
+    // Only the pending payment moves, so a concurrent expiry or capture wins cleanly
+    updatedPayment, err := u.paymentRepository.UpdatePaymentStatus(ctx, paymentEntity.Id, payment.Pending, newStatus)
     if err != nil {
         return nil, fmt.Errorf("failed to update payment: %w", err)
     }
 
+    if newStatus == payment.Completed {
+        u.paymentCompleted(ctx, updatedPayment)
+    }
+
     return updatedPayment, nil
 }
 
+// publishPaymentCompleted tells the booking service the payment was captured.
+// A failed publish is only logged; the booking reconciler picks the payment up.
+func (u *paymentUsecase) publishPaymentCompleted(p *payment.PaymentEntity) {
+    u.publishEvent(payment.TopicPaymentCompleted, p.BookingID, payment.NewPaymentCompletedEvent(p))
+}
+
+// publishEvent pushes event to topic keyed by key, logging any failure
+func (u *paymentUsecase) publishEvent(topic, key string, event any) {
+    message, err := json.Marshal(event)
+    if err != nil {
+        log.Printf("Error: publishEvent: %s: %s", topic, err.Error())
+        return
+    }
+
+    if err := queue.PushMessageWithKeyToQueue(
+        []string{u.cfg.Kafka.Url},
+        u.cfg.Kafka.ApiKey,
+        u.cfg.Kafka.Secret,
+        topic,
+        key,
+        message,
+    ); err != nil {
+        log.Printf("Error: publishEvent: %s key %s: %s", topic, key, err.Error())
+    }
+}
+
 func (u *paymentUsecase) FindPayment(ctx context.Context, paymentId string) (*payment.PaymentEntity, error) {
     return u.paymentRepository.FindPayment(ctx, paymentId)
 }
@@ -128,14 +253,6 @@ func (u *paymentUsecase) FindPaymentsByUser(ctx context.Context, userId string)
 }
 
 
-func (u *paymentUsecase) SaveSlip(ctx context.Context, slip payment.PaymentSlip) error {
-    err := u.paymentRepository.SaveSlip(ctx, slip)
-    if err != nil {
-        return fmt.Errorf("error saving slip: %w", err)
-    }
-    return nil
-}
-
 func (u *paymentUsecase) FindSlipByUserId(ctx context.Context, userId string) ([]payment.PaymentSlip, error) {
     slips, err := u.paymentRepository.FindSlipByUserId(ctx, userId)
     if err != nil {
@@ -144,14 +261,6 @@ func (u *paymentUsecase) FindSlipByUserId(ctx context.Context, userId string) ([
     return slips, nil
 }
 
-func (u *paymentUsecase) UpdateSlipStatus(ctx context.Context, slipId string, newStatus string) error {
-    err := u.paymentRepository.UpdateSlipStatus(ctx, slipId, newStatus)
-    if err != nil {
-        return fmt.Errorf("error updating slip status: %w", err)
-    }
-    return nil
-}
-
 func (u *paymentUsecase) GetPendingSlips(ctx context.Context) ([]payment.PaymentSlip, error) {
     slips, err := u.paymentRepository.GetPendingSlips(ctx)
     if err != nil {
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"main/modules/payment"
+	"main/modules/payment/provider"
+	"time"
+)
+
+// SyncPaymentStatus asks the payment's provider how its charge stands and
+// applies the answer. Providers that can't be asked, like PromptPay and cash,
+// leave the payment as it is.
+func (u *paymentUsecase) SyncPaymentStatus(ctx context.Context, paymentId string) (*payment.PaymentEntity, error) {
+	p, err := u.paymentRepository.FindPayment(ctx, paymentId)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != payment.Pending || p.ProviderRef == "" {
+		return p, nil
+	}
+
+	chargeProvider, err := u.providers.Get(p.Provider)
+	if err != nil {
+		return nil, err
+	}
+	status, err := chargeProvider.QueryStatus(ctx, p.ProviderRef)
+	if errors.Is(err, provider.ErrNotSupported) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.applyProviderStatus(ctx, p, status); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// applyProviderStatus moves a pending payment to what its provider reports.
+// A failed charge is expired like an unpaid one, so the booking gives its slot
+// back and the user can book again.
+func (u *paymentUsecase) applyProviderStatus(ctx context.Context, p *payment.PaymentEntity, status payment.PaymentStatus) error {
+	switch status {
+	case payment.Completed:
+		changed, err := u.paymentRepository.CompletePendingPayment(ctx, p.Id)
+		if err != nil || !changed {
+			return err
+		}
+		p.Status = payment.Completed
+		p.UpdatedAt = time.Now()
+		u.paymentCompleted(ctx, p)
+
+	case payment.Failed, payment.Expired:
+		changed, err := u.paymentRepository.ExpirePayment(ctx, p.Id)
+		if err != nil || !changed {
+			return err
+		}
+		p.Status = payment.Expired
+		p.UpdatedAt = time.Now()
+		u.publishEvent(payment.TopicPaymentExpired, p.BookingID, payment.NewPaymentExpiredEvent(p))
+	}
+	return nil
+}
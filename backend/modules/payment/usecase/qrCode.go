@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"main/modules/payment"
+	"main/modules/payment/provider"
+	"main/pkg/qrimage"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// legacyPromptPayId received every payment made before merchant accounts
+const legacyPromptPayId = "1579901028845"
+
+// qrCodeURL is where the payment's QR image is served
+func (u *paymentUsecase) qrCodeURL(paymentId primitive.ObjectID) string {
+	return strings.TrimRight(u.cfg.Payment.PublicUrl, "/") + "/payments/" + paymentId.Hex() + "/qr"
+}
+
+// RenderPaymentQR draws the QR code of a payment still awaiting payment.
+// Payments made before payloads were stored get theirs rebuilt for the account
+// that received payments then.
+func (u *paymentUsecase) RenderPaymentQR(ctx context.Context, paymentId string, format qrimage.Format, size int) ([]byte, error) {
+	p, err := u.paymentRepository.FindPayment(ctx, paymentId)
+	if err != nil {
+		return nil, err
+	}
+	if p.Provider != "" && p.Provider != provider.PromptPay {
+		return nil, payment.ErrNoQRCode
+	}
+	if p.Status != payment.Pending || u.isExpired(p, time.Now()) {
+		return nil, payment.ErrPaymentNotPayable
+	}
+
+	qrPayload := p.QRPayload
+	if qrPayload == "" {
+		if qrPayload, err = provider.PromptPayPayload(legacyPromptPayId, p.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	return qrimage.Render(qrPayload, format, size)
+}
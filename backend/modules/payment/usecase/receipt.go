@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"main/modules/payment"
+	"main/modules/payment/ledger"
+	"main/pkg/receiptpdf"
+	"main/pkg/utils"
+	"time"
+)
+
+// fiscalYearStart is the month the university's fiscal year starts in, as
+// the government's does; receipt numbers start again from 1 each year
+const fiscalYearStart = time.October
+
+// buddhistEra is added to Gregorian years to name Thai fiscal years
+const buddhistEra = 543
+
+// FindPaymentReceipts lists a paid payment's receipt and credit notes. Any
+// that failed to be issued when the payment or a refund completed are issued
+// now, so the list is whole.
+func (u *paymentUsecase) FindPaymentReceipts(ctx context.Context, paymentId, userId string, isAdmin bool) ([]payment.Receipt, error) {
+	p, err := u.paymentRepository.FindPayment(ctx, paymentId)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && p.UserID != userId {
+		return nil, payment.ErrReceiptForbidden
+	}
+
+	if _, err := u.issueReceipt(ctx, p); err != nil {
+		return nil, err
+	}
+	refunds, err := u.paymentRepository.FindManyRefund(ctx, p.Id.Hex(), payment.RefundCompleted)
+	if err != nil {
+		return nil, err
+	}
+	for i := range refunds {
+		if _, err := u.issueCreditNote(ctx, &refunds[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return u.paymentRepository.FindManyReceipt(ctx, p.Id)
+}
+
+// RenderReceipt prints one of a payment's receipts or credit notes as a PDF.
+// The first download is the original; every later one, by the user or by
+// staff, is recorded as a re-issue and watermarked as a copy.
+func (u *paymentUsecase) RenderReceipt(ctx context.Context, paymentId, receiptId, userId string, isAdmin bool) (*payment.Receipt, []byte, error) {
+	receipt, err := u.paymentRepository.FindReceipt(ctx, receiptId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if receipt.PaymentId.Hex() != paymentId {
+		return nil, nil, fmt.Errorf("%w: %s", payment.ErrReceiptNotFound, receiptId)
+	}
+	if !isAdmin && receipt.UserId != userId {
+		return nil, nil, payment.ErrReceiptForbidden
+	}
+
+	copied, err := u.paymentRepository.MarkReceiptPrinted(ctx, receipt.Id, userId, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pdf, err := u.receiptRenderer.Render(receiptDocument(receipt, copied))
+	if err != nil {
+		return nil, nil, err
+	}
+	return receipt, pdf, nil
+}
+
+// paymentCompleted follows up a payment that was just captured: the booking
+// service is told, the charge posted to the ledger and the receipt issued. A
+// receipt that fails here is issued when the user first asks for it.
+func (u *paymentUsecase) paymentCompleted(ctx context.Context, p *payment.PaymentEntity) {
+	u.publishPaymentCompleted(p)
+	u.postCharge(ctx, p)
+	if _, err := u.issueReceipt(ctx, p); err != nil {
+		log.Printf("Error: paymentCompleted: receipt for payment %s: %s", p.Id.Hex(), err.Error())
+	}
+}
+
+// issueReceipt issues the receipt of a paid payment, or returns the one
+// already issued
+func (u *paymentUsecase) issueReceipt(ctx context.Context, p *payment.PaymentEntity) (*payment.Receipt, error) {
+	existing, err := u.paymentRepository.FindReceiptByPayment(ctx, p.Id)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, payment.ErrReceiptNotFound) {
+		return nil, err
+	}
+
+	switch p.Status {
+	case payment.Completed, payment.PartiallyRefunded, payment.Refunded:
+	default:
+		return nil, fmt.Errorf("%w: payment %s is %s", payment.ErrPaymentNotPaid, p.Id.Hex(), p.Status)
+	}
+
+	receipt := u.newReceipt(ctx, p, payment.ReceiptKindReceipt, p.Amount)
+	receipt.PaymentMethod = p.PaymentMethod
+	return u.paymentRepository.InsertReceipt(ctx, receipt)
+}
+
+// issueCreditNote issues the credit note of a completed refund against its
+// payment's receipt, or returns the one already issued
+func (u *paymentUsecase) issueCreditNote(ctx context.Context, refund *payment.Refund) (*payment.Receipt, error) {
+	existing, err := u.paymentRepository.FindCreditNoteByRefund(ctx, refund.Id)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, payment.ErrReceiptNotFound) {
+		return nil, err
+	}
+	if refund.Status != payment.RefundCompleted {
+		return nil, fmt.Errorf("%w: refund %s is %s", payment.ErrRefundStatus, refund.Id.Hex(), refund.Status)
+	}
+
+	p, err := u.paymentRepository.FindPayment(ctx, refund.PaymentId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	original, err := u.issueReceipt(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	note := u.newReceipt(ctx, p, payment.ReceiptKindCreditNote, refund.Amount)
+	note.RefundId = &refund.Id
+	note.ReceiptNumber = original.Number
+	note.PaymentMethod = string(refund.Method)
+	note.Note = "Refund reason: " + string(refund.Reason)
+	if refund.Note != "" {
+		note.Note += " - " + refund.Note
+	}
+	return u.paymentRepository.InsertReceipt(ctx, note)
+}
+
+// newReceipt fills in what every document of p shows for amount. The slot is
+// left out if the booking can't be read; the receipt is still valid without it.
+func (u *paymentUsecase) newReceipt(ctx context.Context, p *payment.PaymentEntity, kind payment.ReceiptKind, amount float64) *payment.Receipt {
+	now := time.Now()
+	net, vat := splitVat(amount)
+	receipt := &payment.Receipt{
+		Kind:          kind,
+		FiscalYear:    fiscalYear(now),
+		PaymentId:     p.Id,
+		BookingId:     p.BookingID,
+		UserId:        p.UserID,
+		FacilityName:  p.FacilityName,
+		Amount:        roundSatang(amount),
+		NetAmount:     net,
+		VatRate:       payment.VatRate,
+		VatAmount:     vat,
+		Currency:      p.Currency,
+		SellerName:    u.cfg.Payment.ReceiptSellerName,
+		SellerTaxId:   u.cfg.Payment.ReceiptSellerTaxId,
+		SellerAddress: u.cfg.Payment.ReceiptSellerAddress,
+		IssuedAt:      now,
+		Reissues:      []payment.ReceiptReissue{},
+	}
+
+	// Without a configured seller, the account the money went into stands in
+	if (receipt.SellerName == "" || receipt.SellerTaxId == "") && !p.MerchantAccountId.IsZero() {
+		account, err := u.paymentRepository.FindMerchantAccount(ctx, p.MerchantAccountId.Hex())
+		if err != nil {
+			log.Printf("Error: newReceipt: merchant account of payment %s: %s", p.Id.Hex(), err.Error())
+		} else {
+			if receipt.SellerName == "" {
+				receipt.SellerName = account.DisplayName
+			}
+			if receipt.SellerTaxId == "" {
+				receipt.SellerTaxId = account.TaxId
+			}
+		}
+	}
+
+	slot, err := u.paymentRepository.FindBookingSlot(ctx, p.BookingID)
+	if err != nil {
+		log.Printf("Error: newReceipt: slot of booking %s: %s", p.BookingID, err.Error())
+	}
+	receipt.Slot = slot
+	return receipt
+}
+
+// receiptDocument lays out receipt for printing
+func receiptDocument(receipt *payment.Receipt, copied bool) *receiptpdf.Document {
+	detail := "Booking " + receipt.BookingId
+	if receipt.Slot != nil {
+		detail = fmt.Sprintf("%s, %s %s-%s", detail, receipt.Slot.Date.In(utils.Location()).Format("02/01/2006"), receipt.Slot.StartTime, receipt.Slot.EndTime)
+	}
+
+	doc := &receiptpdf.Document{
+		Title:         "RECEIPT / TAX INVOICE (ABB.)",
+		Number:        receipt.Number,
+		IssuedAt:      receipt.IssuedAt.In(utils.Location()),
+		SellerName:    receipt.SellerName,
+		SellerTaxId:   receipt.SellerTaxId,
+		SellerAddress: receipt.SellerAddress,
+		Lines: []receiptpdf.Line{{
+			Description: "Facility booking: " + receipt.FacilityName,
+			Detail:      detail,
+			Amount:      receipt.Amount,
+		}},
+		NetAmount:  receipt.NetAmount,
+		VatRate:    receipt.VatRate,
+		VatAmount:  receipt.VatAmount,
+		Total:      receipt.Amount,
+		Currency:   receipt.Currency,
+		Settlement: "Paid by " + receipt.PaymentMethod,
+		Note:       receipt.Note,
+		Copy:       copied,
+	}
+	if receipt.Kind == payment.ReceiptKindCreditNote {
+		doc.Title = "CREDIT NOTE"
+		doc.Reference = receipt.ReceiptNumber
+		doc.Lines[0].Description = "Refund of facility booking: " + receipt.FacilityName
+		doc.Settlement = "Refunded by " + receipt.PaymentMethod
+	}
+	return doc
+}
+
+// fiscalYear is the Buddhist era fiscal year t falls in, named after the
+// year it ends in: 1 October 2026 starts fiscal year 2570
+func fiscalYear(t time.Time) int {
+	local := t.In(utils.Location())
+	year := local.Year()
+	if local.Month() >= fiscalYearStart {
+		year++
+	}
+	return year + buddhistEra
+}
+
+// splitVat splits a VAT inclusive amount into its value before VAT and the
+// VAT the same way the ledger does, so receipts agree with it
+func splitVat(amount float64) (float64, float64) {
+	net, vat := ledger.SplitVat(ledger.Satang(amount))
+	return float64(net) / 100, float64(vat) / 100
+}
@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"main/modules/payment"
+	"main/modules/payment/provider"
+	"math"
+	"time"
+)
+
+// RequestRefund asks for part or all of a completed payment back. The amount
+// is held against the payment straight away, so requests waiting for approval
+// count towards the captured amount too.
+func (u *paymentUsecase) RequestRefund(ctx context.Context, paymentId, requestedBy string, isAdmin bool, req *payment.RefundRequest) (*payment.Refund, error) {
+	p, err := u.paymentRepository.FindPayment(ctx, paymentId)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && p.UserID != requestedBy {
+		return nil, payment.ErrRefundForbidden
+	}
+	if p.Status != payment.Completed && p.Status != payment.PartiallyRefunded {
+		return nil, payment.ErrPaymentNotRefundable
+	}
+	switch payment.RefundMethod(req.Method) {
+	case payment.RefundPromptPay, payment.RefundWallet, payment.RefundCash, payment.RefundOriginal:
+	default:
+		return nil, fmt.Errorf("%w: unknown method %q", payment.ErrInvalidRefund, req.Method)
+	}
+	switch payment.RefundReason(req.Reason) {
+	case payment.ReasonCustomerRequest, payment.ReasonFacilityClosed, payment.ReasonBookingCanceled, payment.ReasonDuplicate, payment.ReasonOther:
+	default:
+		return nil, fmt.Errorf("%w: unknown reason %q", payment.ErrInvalidRefund, req.Reason)
+	}
+	if req.Amount < 0 {
+		return nil, fmt.Errorf("%w: amount must not be negative", payment.ErrInvalidRefund)
+	}
+	if payment.RefundReason(req.Reason) == payment.ReasonOther && req.Note == "" {
+		return nil, fmt.Errorf("%w: a note is required when the reason is OTHER", payment.ErrInvalidRefund)
+	}
+
+	amount := roundSatang(req.Amount)
+	if amount == 0 {
+		amount = roundSatang(p.Amount - p.RefundedAmount - p.RefundPendingAmount)
+	}
+	if amount <= 0 {
+		return nil, payment.ErrRefundExceedsCaptured
+	}
+
+	if err := u.paymentRepository.ReserveRefund(ctx, p.Id, amount); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refund := &payment.Refund{
+		PaymentId:   p.Id,
+		BookingId:   p.BookingID,
+		UserId:      p.UserID,
+		Amount:      amount,
+		Currency:    p.Currency,
+		Method:      payment.RefundMethod(req.Method),
+		Reason:      payment.RefundReason(req.Reason),
+		Note:        req.Note,
+		Status:      payment.RefundRequested,
+		RequestedBy: requestedBy,
+		Timeline:    []payment.RefundEvent{{Status: payment.RefundRequested, ActorId: requestedBy, Note: req.Note, At: now}},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	saved, err := u.paymentRepository.InsertRefund(ctx, refund)
+	if err != nil {
+		// Don't leave the amount held by a refund that was never stored
+		if releaseErr := u.paymentRepository.ReleaseRefund(ctx, p.Id, amount); releaseErr != nil {
+			return nil, fmt.Errorf("%w; releasing the held amount also failed: %v", err, releaseErr)
+		}
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// ApproveRefund lets finance pay the refund out
+func (u *paymentUsecase) ApproveRefund(ctx context.Context, refundId, adminId string, req *payment.RefundDecisionRequest) (*payment.Refund, error) {
+	refund, err := u.paymentRepository.FindRefund(ctx, refundId)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.paymentRepository.UpdateRefundStatus(ctx, refund.Id, payment.RefundRequested, payment.RefundEvent{
+		Status:  payment.RefundApproved,
+		ActorId: adminId,
+		Note:    req.Note,
+		At:      time.Now(),
+	}, "")
+}
+
+// RejectRefund turns a request down and releases the amount it held
+func (u *paymentUsecase) RejectRefund(ctx context.Context, refundId, adminId string, req *payment.RefundDecisionRequest) (*payment.Refund, error) {
+	if req.Note == "" {
+		return nil, fmt.Errorf("%w: a note is required to reject a refund", payment.ErrInvalidRefund)
+	}
+
+	refund, err := u.paymentRepository.FindRefund(ctx, refundId)
+	if err != nil {
+		return nil, err
+	}
+
+	rejected, err := u.paymentRepository.UpdateRefundStatus(ctx, refund.Id, payment.RefundRequested, payment.RefundEvent{
+		Status:  payment.RefundRejected,
+		ActorId: adminId,
+		Note:    req.Note,
+		At:      time.Now(),
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.paymentRepository.ReleaseRefund(ctx, refund.PaymentId, refund.Amount); err != nil {
+		return nil, err
+	}
+	return rejected, nil
+}
+
+// CompleteRefund records that an approved refund was paid out, by PromptPay
+// transfer, wallet credit or cash, and counts it as refunded on the payment.
+// ORIGINAL refunds are paid out here through the payment's provider.
+func (u *paymentUsecase) CompleteRefund(ctx context.Context, refundId, adminId string, req *payment.RefundDecisionRequest) (*payment.Refund, error) {
+	refund, err := u.paymentRepository.FindRefund(ctx, refundId)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Method == payment.RefundPromptPay && req.Reference == "" {
+		return nil, fmt.Errorf("%w: a transfer reference is required for PromptPay refunds", payment.ErrInvalidRefund)
+	}
+	from := payment.RefundApproved
+	if refund.Method == payment.RefundOriginal {
+		if req.Reference, err = u.payOutThroughProvider(ctx, refund, adminId); err != nil {
+			return nil, err
+		}
+		from = payment.RefundPaying
+	}
+
+	completed, err := u.paymentRepository.UpdateRefundStatus(ctx, refund.Id, from, payment.RefundEvent{
+		Status:  payment.RefundCompleted,
+		ActorId: adminId,
+		Note:    req.Note,
+		At:      time.Now(),
+	}, req.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	// Completing first keeps a refund from being settled twice. If settling then
+	// fails the refund goes back to APPROVED so completing it again settles it;
+	// an ORIGINAL refund's provider payout is idempotent on the refund id.
+	if err := u.paymentRepository.SettleRefund(ctx, refund.PaymentId, refund.Amount); err != nil {
+		if _, backErr := u.paymentRepository.UpdateRefundStatus(ctx, refund.Id, payment.RefundCompleted, payment.RefundEvent{
+			Status:  payment.RefundApproved,
+			ActorId: adminId,
+			Note:    "Settling the payment failed: " + err.Error(),
+			At:      time.Now(),
+		}, ""); backErr != nil {
+			log.Printf("Error: CompleteRefund: refund %s is COMPLETED but not settled: %s", refundId, backErr.Error())
+		}
+		return nil, err
+	}
+
+	u.postRefund(ctx, completed)
+
+	// A credit note that fails here is issued when the payment's receipts are next listed
+	if _, err := u.issueCreditNote(ctx, completed); err != nil {
+		log.Printf("Error: CompleteRefund: credit note for refund %s: %s", refundId, err.Error())
+	}
+	return completed, nil
+}
+
+// payOutThroughProvider claims an approved refund as PAYING before the
+// provider pays it, so a second complete of the same refund is refused
+// instead of paying out twice. If the provider fails the refund goes back to
+// APPROVED to be tried again; the refund id is the provider's idempotency key,
+// so a retry after a refund that did go through isn't paid again.
+func (u *paymentUsecase) payOutThroughProvider(ctx context.Context, refund *payment.Refund, adminId string) (string, error) {
+	if _, err := u.paymentRepository.UpdateRefundStatus(ctx, refund.Id, payment.RefundApproved, payment.RefundEvent{
+		Status:  payment.RefundPaying,
+		ActorId: adminId,
+		At:      time.Now(),
+	}, ""); err != nil {
+		return "", err
+	}
+
+	reference, err := u.refundThroughProvider(ctx, refund)
+	if err != nil {
+		if _, backErr := u.paymentRepository.UpdateRefundStatus(ctx, refund.Id, payment.RefundPaying, payment.RefundEvent{
+			Status:  payment.RefundApproved,
+			ActorId: adminId,
+			Note:    "Provider refund failed: " + err.Error(),
+			At:      time.Now(),
+		}, ""); backErr != nil {
+			log.Printf("Error: payOutThroughProvider: refund %s stays PAYING: %s", refund.Id.Hex(), backErr.Error())
+		}
+		return "", err
+	}
+	return reference, nil
+}
+
+// refundThroughProvider pays a refund back through the provider that took the
+// payment and returns the provider's reference for it
+func (u *paymentUsecase) refundThroughProvider(ctx context.Context, refund *payment.Refund) (string, error) {
+	p, err := u.paymentRepository.FindPayment(ctx, refund.PaymentId.Hex())
+	if err != nil {
+		return "", err
+	}
+	chargeProvider, err := u.providers.Get(p.Provider)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := chargeProvider.Refund(ctx, p.ProviderRef, refund.Amount, refund.Id.Hex())
+	if errors.Is(err, provider.ErrNotSupported) {
+		return "", fmt.Errorf("%w: %s payments can't be refunded through the provider; reject it and request another method", payment.ErrInvalidRefund, chargeProvider.Name())
+	}
+	if err != nil {
+		return "", err
+	}
+	return result.Reference, nil
+}
+
+func (u *paymentUsecase) FindRefund(ctx context.Context, refundId string) (*payment.Refund, error) {
+	return u.paymentRepository.FindRefund(ctx, refundId)
+}
+
+// FindManyRefund lists a payment's refunds, or every refund in a status when
+// paymentId is empty
+func (u *paymentUsecase) FindManyRefund(ctx context.Context, paymentId string, status payment.RefundStatus) ([]payment.Refund, error) {
+	return u.paymentRepository.FindManyRefund(ctx, paymentId, status)
+}
+
+func roundSatang(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,406 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"main/modules/payment"
+	"main/pkg/imaging"
+	"main/pkg/slipqr"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// slipURLTtl is how long a signed link to a slip image works
+const slipURLTtl = 5 * time.Minute
+
+// slipKeyPrefix keeps slips apart from other blobs so signed URLs can't reach them
+const slipKeyPrefix = "slips/"
+
+// slipClockSkew allows a transfer stamped slightly before its payment was
+// created, since the bank's clock and ours differ
+const slipClockSkew = 2 * time.Minute
+
+// slipAmountTolerance absorbs float rounding when comparing amounts in satang
+const slipAmountTolerance = 0.005
+
+// UploadSlip stores a slip image for the user's booking. The image must be a
+// JPEG or PNG; it is re-encoded so EXIF data such as location never reaches
+// the store. The same image or bank transfer can't be used for two bookings,
+// and sending it again for the same booking returns the slip already stored,
+// unless an admin rejected that slip; the upload then replaces it.
+//
+// The bank's QR on the slip is checked with the bank, and a transfer that
+// fits exactly one of the user's pending payments, this booking's, completes
+// it. Anything else stays pending for an admin with the reason.
+func (u *paymentUsecase) UploadSlip(ctx context.Context, userId string, req *payment.UploadSlipRequest, data []byte) (*payment.PaymentSlip, error) {
+	if len(data) > payment.MaxSlipSize {
+		return nil, payment.ErrSlipTooLarge
+	}
+	contentType, _, err := imaging.SniffType(data)
+	if err != nil || (contentType != "image/jpeg" && contentType != "image/png") {
+		return nil, payment.ErrInvalidSlipType
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", payment.ErrInvalidSlipType, err)
+	}
+
+	// Ownership comes first: a duplicate check would hand back the stored slip
+	p, err := u.paymentRepository.FindPaymentByBooking(ctx, req.BookingID)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != userId {
+		return nil, payment.ErrSlipForbidden
+	}
+
+	sum := sha256.Sum256(data)
+	contentHash := hex.EncodeToString(sum[:])
+	existing, err := u.paymentRepository.FindSlipByHash(ctx, contentHash)
+	if err != nil && !errors.Is(err, payment.ErrSlipNotFound) {
+		return nil, err
+	}
+	rejected, stored, err := earlierSlip(existing, req.BookingID, payment.ErrSlipReused)
+	if err != nil || stored != nil {
+		return stored, err
+	}
+
+	clean, cleanType, ext, err := imaging.Reencode(img, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("error: re-encode slip failed: %w", err)
+	}
+
+	now := time.Now()
+	slip := &payment.PaymentSlip{
+		UserID:        userId,
+		BookingID:     req.BookingID,
+		PaymentID:     p.Id,
+		ContentType:   cleanType,
+		Size:          int64(len(clean)),
+		ContentHash:   contentHash,
+		Status:        payment.SlipPending,
+		SubmittedDate: now,
+	}
+
+	qr, reason := readSlipQR(img)
+	if qr != nil {
+		slip.QRPayload = qr.Raw
+		slip.TransRef = qr.TransRef
+		slip.SendingBank = qr.SendingBank
+		slip.TransDate = qr.TransDate
+
+		existing, err := u.paymentRepository.FindSlipByTransRef(ctx, qr.TransRef)
+		if err != nil && !errors.Is(err, payment.ErrSlipNotFound) {
+			return nil, err
+		}
+		rejectedByRef, stored, err := earlierSlip(existing, req.BookingID, payment.ErrSlipRefReused)
+		if err != nil || stored != nil {
+			return stored, err
+		}
+		if rejected == nil {
+			rejected = rejectedByRef
+		}
+	}
+
+	switch {
+	case u.isExpired(p, now):
+		reason = payment.ReviewLateSubmission
+	case qr != nil:
+		if reason, err = u.matchSlip(ctx, slip, qr, p); err != nil {
+			return nil, err
+		}
+	}
+
+	// The id is known before the insert so the blob can be named after it. A
+	// resubmission keeps the slip's id and trail, and its rejected image too.
+	slip.Id = primitive.NewObjectID()
+	blobName := slip.Id
+	slip.Timeline = make([]payment.SlipEvent, 0, 2)
+	if rejected != nil {
+		slip.Id = rejected.Id
+		slip.Timeline = append(slip.Timeline, rejected.Timeline...)
+	}
+	slip.BlobKey = fmt.Sprintf("%s%s/%s.%s", slipKeyPrefix, now.Format("2006/01"), blobName.Hex(), ext)
+
+	slip.Timeline = append(slip.Timeline, payment.SlipEvent{Status: payment.SlipPending, ActorId: userId, Note: reason, BlobKey: slip.BlobKey, At: now})
+	if reason == "" {
+		slip.Status = payment.SlipVerified
+		slip.Timeline = append(slip.Timeline, payment.SlipEvent{Status: payment.SlipVerified, ActorId: payment.SlipVerifierActor, At: now})
+	} else {
+		slip.NeedsReview = true
+		slip.ReviewReason = reason
+	}
+
+	if err := u.blobStore.Put(ctx, slip.BlobKey, cleanType, bytes.NewReader(clean)); err != nil {
+		return nil, err
+	}
+
+	var saved *payment.PaymentSlip
+	if rejected != nil {
+		saved, err = u.paymentRepository.ReplaceSlip(ctx, slip)
+	} else {
+		saved, err = u.paymentRepository.SaveSlip(ctx, slip)
+	}
+	if err != nil {
+		if deleteErr := u.blobStore.Delete(ctx, slip.BlobKey); deleteErr != nil {
+			log.Printf("Error: UploadSlip: %s", deleteErr.Error())
+		}
+		return nil, err
+	}
+
+	if saved.Status == payment.SlipVerified {
+		u.completeVerifiedSlip(ctx, saved, p)
+	}
+	return saved, nil
+}
+
+// earlierSlip checks a slip already stored with the same image or transfer.
+// Another booking's is refused with reused. A rejected one of the same booking
+// is returned to be resubmitted over, and any other is stored again.
+func earlierSlip(existing *payment.PaymentSlip, bookingId string, reused error) (rejected, stored *payment.PaymentSlip, err error) {
+	switch {
+	case existing == nil:
+		return nil, nil, nil
+	case existing.BookingID != bookingId:
+		return nil, nil, reused
+	case existing.Status == payment.SlipRejected:
+		return existing, nil, nil
+	default:
+		return nil, existing, nil
+	}
+}
+
+// readSlipQR reads the bank's QR on a slip. Some banking apps crop it off, so
+// a slip without one is kept for review instead of refused.
+func readSlipQR(img image.Image) (*slipqr.Payload, string) {
+	raw, err := slipqr.Decode(img)
+	if err != nil {
+		return nil, payment.ReviewQRNotFound
+	}
+	qr, err := slipqr.Parse(raw)
+	if err != nil {
+		return nil, payment.ReviewQRInvalid
+	}
+	return qr, ""
+}
+
+// matchSlip asks the bank about the slip's transfer and checks it paid p. It
+// returns the reason an admin has to look at the slip, or "" when it did.
+func (u *paymentUsecase) matchSlip(ctx context.Context, slip *payment.PaymentSlip, qr *slipqr.Payload, p *payment.PaymentEntity) (string, error) {
+	tx, err := u.slipVerifier.Verify(ctx, qr)
+	if err != nil {
+		if !errors.Is(err, slipqr.ErrVerifierDisabled) {
+			log.Printf("Error: matchSlip: %s", err.Error())
+		}
+		return payment.ReviewBankNotVerified, nil
+	}
+	slip.VerifiedAmount = tx.Amount
+	if !tx.TransactedAt.IsZero() {
+		slip.TransDate = &tx.TransactedAt
+	}
+
+	// Matching against all of the user's pending payments catches a slip
+	// sent for the wrong booking
+	pending, err := u.paymentRepository.FindManyPendingPayment(ctx, p.UserID)
+	if err != nil {
+		return "", err
+	}
+	matches, matchesThis := 0, false
+	for i := range pending {
+		if u.transferFits(&pending[i], tx) {
+			matches++
+			matchesThis = matchesThis || pending[i].Id == p.Id
+		}
+	}
+	switch {
+	case matches == 0:
+		return payment.ReviewNoMatch, nil
+	case matches > 1:
+		return payment.ReviewMultipleMatches, nil
+	case !matchesThis:
+		return payment.ReviewOtherBooking, nil
+	}
+
+	promptPayId := legacyPromptPayId
+	if !p.MerchantAccountId.IsZero() {
+		account, err := u.paymentRepository.FindMerchantAccount(ctx, p.MerchantAccountId.Hex())
+		if err != nil {
+			return "", err
+		}
+		promptPayId = account.PromptPayId
+	}
+	return receiverReason(tx.ReceiverProxy, promptPayId), nil
+}
+
+// transferFits reports whether tx paid p's amount while p could be paid
+func (u *paymentUsecase) transferFits(p *payment.PaymentEntity, tx *slipqr.Transaction) bool {
+	if math.Abs(p.Amount-tx.Amount) >= slipAmountTolerance {
+		return false
+	}
+	return !tx.TransactedAt.Before(p.CreatedAt.Add(-slipClockSkew)) && !tx.TransactedAt.After(u.deadline(p))
+}
+
+// receiverReason compares the receiver the bank reports, masked like
+// xxx-xxx-4567, with the PromptPay id the payment was made out to. Masked
+// digits match anything; the visible ones line up from the right.
+func receiverReason(proxy, promptPayId string) string {
+	masked := strings.ToLower(stripSeparators(proxy))
+	visible := len(masked) - strings.Count(masked, "x")
+	if visible < 3 {
+		return payment.ReviewReceiverUnknown
+	}
+	if len(masked) > len(promptPayId) {
+		return payment.ReviewReceiverMismatch
+	}
+
+	offset := len(promptPayId) - len(masked)
+	for i := 0; i < len(masked); i++ {
+		if masked[i] != 'x' && masked[i] != promptPayId[offset+i] {
+			return payment.ReviewReceiverMismatch
+		}
+	}
+	return ""
+}
+
+// completeVerifiedSlip marks the slip's payment paid. A payment that expired
+// or changed in the meantime sends the slip back to the manual queue.
+func (u *paymentUsecase) completeVerifiedSlip(ctx context.Context, slip *payment.PaymentSlip, p *payment.PaymentEntity) {
+	completed, err := u.paymentRepository.CompletePendingPayment(ctx, p.Id)
+	if err == nil && completed {
+		p.Status = payment.Completed
+		p.UpdatedAt = time.Now()
+		u.paymentCompleted(ctx, p)
+		return
+	}
+
+	reason := payment.ReviewNoMatch
+	if err != nil {
+		log.Printf("Error: completeVerifiedSlip: %s", err.Error())
+		reason = payment.ReviewCompleteFailed
+	}
+	event := payment.SlipEvent{Status: payment.SlipPending, ActorId: payment.SlipVerifierActor, Note: reason, At: time.Now()}
+	if err := u.paymentRepository.FlagSlipForReview(ctx, slip.Id, event); err != nil {
+		log.Printf("Error: completeVerifiedSlip: %s", err.Error())
+		return
+	}
+	slip.Status = payment.SlipPending
+	slip.NeedsReview = true
+	slip.ReviewReason = reason
+	slip.Timeline = append(slip.Timeline, event)
+}
+
+// ApproveSlip confirms a slip an admin checked against the bank statement. It
+// completes the slip's payment, which tells the booking it is paid. Payments
+// that expired or were paid another way can't be completed; the slip has to be
+// rejected and the money refunded instead.
+func (u *paymentUsecase) ApproveSlip(ctx context.Context, slipId, adminId string, req *payment.SlipDecisionRequest) (*payment.PaymentSlip, error) {
+	slip, err := u.paymentRepository.FindSlip(ctx, slipId)
+	if err != nil {
+		return nil, err
+	}
+	if slip.Status != payment.SlipPending {
+		return nil, fmt.Errorf("%w: slip %s is %s", payment.ErrSlipStatus, slipId, slip.Status)
+	}
+
+	// Slips saved before uploads only know their booking
+	var p *payment.PaymentEntity
+	if slip.PaymentID.IsZero() {
+		p, err = u.paymentRepository.FindPaymentByBooking(ctx, slip.BookingID)
+	} else {
+		p, err = u.paymentRepository.FindPayment(ctx, slip.PaymentID.Hex())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != payment.Pending {
+		return nil, fmt.Errorf("%w: payment %s is %s", payment.ErrPaymentNotPayable, p.Id.Hex(), p.Status)
+	}
+
+	// Claim the slip first so two admins can't both act on it
+	event := payment.SlipEvent{Status: payment.SlipApproved, ActorId: adminId, Note: req.Reason, At: time.Now()}
+	approved, err := u.paymentRepository.UpdateSlipStatus(ctx, slip.Id, payment.SlipPending, event, "")
+	if err != nil {
+		return nil, err
+	}
+
+	completed, err := u.paymentRepository.CompletePendingPayment(ctx, p.Id)
+	if err != nil || !completed {
+		// Put the slip back in the queue; the admin sees why on the next try
+		if flagErr := u.paymentRepository.FlagSlipForReview(ctx, slip.Id, payment.SlipEvent{
+			Status:  payment.SlipPending,
+			ActorId: adminId,
+			Note:    payment.ReviewCompleteFailed,
+			At:      time.Now(),
+		}); flagErr != nil {
+			log.Printf("Error: ApproveSlip: %s", flagErr.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: payment %s changed while approving", payment.ErrPaymentNotPayable, p.Id.Hex())
+	}
+
+	p.Status = payment.Completed
+	p.UpdatedAt = event.At
+	u.paymentCompleted(ctx, p)
+	u.publishEvent(payment.TopicSlipUpdated, approved.BookingID, payment.NewSlipUpdatedEvent(approved, event))
+	return approved, nil
+}
+
+// RejectSlip turns a slip down with a reason the user is told. The payment
+// stays pending, so the user can send another slip until it expires.
+func (u *paymentUsecase) RejectSlip(ctx context.Context, slipId, adminId string, req *payment.SlipDecisionRequest) (*payment.PaymentSlip, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a reason is required to reject a slip", payment.ErrInvalidSlipDecision)
+	}
+
+	slip, err := u.paymentRepository.FindSlip(ctx, slipId)
+	if err != nil {
+		return nil, err
+	}
+
+	event := payment.SlipEvent{Status: payment.SlipRejected, ActorId: adminId, Note: reason, At: time.Now()}
+	rejected, err := u.paymentRepository.UpdateSlipStatus(ctx, slip.Id, payment.SlipPending, event, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	u.publishEvent(payment.TopicSlipUpdated, rejected.BookingID, payment.NewSlipUpdatedEvent(rejected, event))
+	return rejected, nil
+}
+
+// FindSlipURL signs a short-lived link to a slip's image
+func (u *paymentUsecase) FindSlipURL(ctx context.Context, slipId string) (*payment.SlipURLResponse, error) {
+	slip, err := u.paymentRepository.FindSlip(ctx, slipId)
+	if err != nil {
+		return nil, err
+	}
+	if slip.BlobKey == "" {
+		return nil, fmt.Errorf("%w: slip %s has no uploaded image", payment.ErrSlipNotFound, slipId)
+	}
+
+	url, expiresAt := u.slipSigner.SignedURL(slip.BlobKey, slipURLTtl)
+	return &payment.SlipURLResponse{Url: url, ExpiresAt: expiresAt}, nil
+}
+
+// OpenSlipFile opens a slip image for a signed link
+func (u *paymentUsecase) OpenSlipFile(ctx context.Context, key, expires, signature string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(key, slipKeyPrefix) {
+		return nil, payment.ErrSlipNotFound
+	}
+	if err := u.slipSigner.Verify(key, expires, signature); err != nil {
+		return nil, err
+	}
+	return u.blobStore.Get(ctx, key)
+}
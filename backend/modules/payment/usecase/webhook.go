@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"main/modules/payment"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Outcomes recorded on a webhook delivery; otherwise it records the payment's
+// status after applying it
+const (
+	webhookUnknownCharge  = "IGNORED_UNKNOWN_CHARGE"
+	webhookAmountMismatch = "IGNORED_AMOUNT_MISMATCH"
+	webhookPaidTooLate    = "PAID_NOT_PENDING" // Captured after the payment expired or was paid; needs a refund
+)
+
+// HandleWebhook verifies a provider's webhook, stores it and applies it to
+// the payment its charge belongs to; the booking follows through the payment
+// events. A redelivered event that was already applied returns the stored
+// delivery and changes nothing. Events that can never apply, like an unknown
+// charge, are recorded and acknowledged so the provider stops retrying.
+func (u *paymentUsecase) HandleWebhook(ctx context.Context, providerName string, header http.Header, body []byte) (*payment.WebhookDelivery, error) {
+	webhookProvider, err := u.providers.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+	event, err := webhookProvider.VerifyWebhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+	if event.EventId == "" || event.Reference == "" {
+		return nil, fmt.Errorf("%w: event id and charge reference are required", payment.ErrInvalidWebhook)
+	}
+
+	delivery, err := u.paymentRepository.InsertWebhookDelivery(ctx, &payment.WebhookDelivery{
+		Provider:   webhookProvider.Name(),
+		EventId:    event.EventId,
+		Reference:  event.Reference,
+		Status:     event.Status,
+		Amount:     event.Amount,
+		Payload:    string(body),
+		Signature:  header.Get("X-Signature"),
+		OccurredAt: event.At,
+		ReceivedAt: time.Now(),
+	})
+	if errors.Is(err, payment.ErrDuplicateWebhook) {
+		if delivery, err = u.paymentRepository.FindWebhookDelivery(ctx, webhookProvider.Name(), event.EventId); err != nil {
+			return nil, err
+		}
+		if delivery.ProcessedAt != nil {
+			return delivery, nil
+		}
+		// An earlier attempt failed part way; apply it again
+	} else if err != nil {
+		return nil, err
+	}
+
+	p, err := u.paymentRepository.FindPaymentByProviderRef(ctx, delivery.Provider, delivery.Reference)
+	if errors.Is(err, payment.ErrPaymentNotFound) {
+		log.Printf("Warning: %s webhook %s for unknown charge %s", delivery.Provider, delivery.EventId, delivery.Reference)
+		return u.finishWebhook(ctx, delivery, p, webhookUnknownCharge)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if delivery.Status == payment.Completed && math.Abs(delivery.Amount-p.Amount) >= slipAmountTolerance {
+		log.Printf("Warning: %s webhook %s paid %.2f for payment %s of %.2f", delivery.Provider, delivery.EventId, delivery.Amount, p.Id.Hex(), p.Amount)
+		return u.finishWebhook(ctx, delivery, p, webhookAmountMismatch)
+	}
+
+	if err := u.applyProviderStatus(ctx, p, delivery.Status); err != nil {
+		return nil, err
+	}
+	if delivery.Status == payment.Completed && p.Status != payment.Completed {
+		log.Printf("Warning: %s webhook %s captured payment %s that is %s", delivery.Provider, delivery.EventId, p.Id.Hex(), p.Status)
+		return u.finishWebhook(ctx, delivery, p, webhookPaidTooLate)
+	}
+	return u.finishWebhook(ctx, delivery, p, string(p.Status))
+}
+
+func (u *paymentUsecase) finishWebhook(ctx context.Context, delivery *payment.WebhookDelivery, p *payment.PaymentEntity, outcome string) (*payment.WebhookDelivery, error) {
+	if p != nil {
+		delivery.PaymentId = p.Id
+	}
+	if err := u.paymentRepository.MarkWebhookProcessed(ctx, delivery.Id, delivery.PaymentId, outcome); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery.ProcessedAt = &now
+	delivery.Outcome = outcome
+	return delivery, nil
+}
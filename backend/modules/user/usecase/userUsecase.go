@@ -131,7 +131,7 @@ func (u * userUsecase) FindOneUserProfile (pctx context.Context, userId string)
         return nil, err
     }
 
-    loc, _ := time.LoadLocation("Asia/Bangkok")
+    loc := utils.Location()
 
     return &user.UserProfile{
         Id:        result.Id.Hex(),
@@ -158,7 +158,7 @@ func (u *userUsecase) FindOneUserCredential(pctx context.Context, password, emai
         roleCode = v.RoleCode
     }
 
-    loc, _ := time.LoadLocation("Asia/Bangkok")
+    loc := utils.Location()
 
     return &userPb.UserProfile{
         Id:        result.Id.Hex(),
@@ -181,7 +181,7 @@ func (u *userUsecase) FindOneUserProfileToRefresh(pctx context.Context, userId s
         roleCode = v.RoleCode
     }
 
-    loc, _ := time.LoadLocation("Asia/Bangkok")
+    loc := utils.Location()
 
     return &userPb.UserProfile{
         Id:        result.Id.Hex(),
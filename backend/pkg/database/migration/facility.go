@@ -6,7 +6,7 @@ import (
 	"log"
 	"main/config"
 	"main/modules/facility"
-	"strings"
+	"main/pkg/utils"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -26,41 +26,29 @@ func CollectionExists(ctx context.Context, client *mongo.Client, db *mongo.Datab
 	return false, nil
 }
 
-// ensureNoDuplicateFacility ensures that database names are not duplicated
-func ensureNoDuplicateFacility(pctx context.Context, client *mongo.Client, originalName string) (*mongo.Database, error) {
-	// Create the sanitized name by removing any unwanted suffixes like '_facility_facility'
-	normalizedName := strings.Replace(originalName, "_facility_facility", "_facility", -1)
+// defaultFacilities are seeded into an empty registry on first start
+var defaultFacilities = []string{"fitness", "swimming", "football", "badminton"}
 
-	// Check if the database already exists
-	dbNames, err := client.ListDatabaseNames(pctx, bson.M{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to list database names: %v", err)
-	}
+// SetupFacilities seeds the facility registry once per default facility
+func SetupFacilities(pctx context.Context, cfg *config.Config, client *mongo.Client) error {
+	db := client.Database(facility.RegistryDb)
 
-	// If the database already exists, return it
-	for _, dbName := range dbNames {
-		if dbName == normalizedName {
-			log.Printf("Database '%s' already exists, skipping creation.", normalizedName)
-			return client.Database(normalizedName), nil
-		}
+	if err := createRegistryIndexes(pctx, db); err != nil {
+		return fmt.Errorf("failed to create facility registry indexes: %v", err)
 	}
 
-	// If it doesn't exist, create the database
-	log.Printf("Creating database: %s", normalizedName)
-	db := client.Database(normalizedName)
-	return db, nil
-}
-
-// SetupFacilities runs migration only if not already completed
-func SetupFacilities(pctx context.Context, cfg *config.Config, client *mongo.Client) error {
-	facilityNames := []string{"fitness_facility", "swimming_facility", "football_facility", "badminton_facility"}
-
-	for _, facilityName := range facilityNames {
-		db, err := ensureNoDuplicateFacility(pctx, client, facilityName)
-		if err != nil {
-			return err
-		}
+	// Deployments still on per-facility databases are moved by the migration
+	// script; seeding now would create duplicates next to the legacy data
+	legacyDbs, err := legacyFacilityDbs(pctx, client)
+	if err != nil {
+		return err
+	}
+	if len(legacyDbs) > 0 && !hasMigrationStep(pctx, db, registryMigrationStep) {
+		log.Printf("Found legacy facility databases %v, run the facility migration script to move them into %s", legacyDbs, facility.RegistryDb)
+		return nil
+	}
 
+	for _, facilityName := range defaultFacilities {
 		// Check migration flag to avoid duplicate migration
 		shouldMigrate, err := checkAndSetMigrationFlag(pctx, db, facilityName)
 		if err != nil {
@@ -77,13 +65,14 @@ func SetupFacilities(pctx context.Context, cfg *config.Config, client *mongo.Cli
 		}
 
 		// Create slots collection based on facility type
-		if facilityName == "badminton_facility" {
-			if err := createBadmintonSlots(pctx, db); err != nil {
-				log.Fatalf("Failed to create badminton slots for %s: %v", facilityName, err)
-			}
-			if err := createBadmintonCourts(pctx, db); err != nil {
+		if facilityName == "badminton" {
+			courts, err := createBadmintonCourts(pctx, db)
+			if err != nil {
 				log.Fatalf("Failed to create badminton courts for %s: %v", facilityName, err)
 			}
+			if err := createBadmintonSlots(pctx, db, courts); err != nil {
+				log.Fatalf("Failed to create badminton slots for %s: %v", facilityName, err)
+			}
 		} else {
 			if err := createNormalSlots(pctx, db, facilityName); err != nil {
 				log.Fatalf("Failed to create normal slots for %s: %v", facilityName, err)
@@ -123,8 +112,7 @@ func checkAndSetMigrationFlag(pctx context.Context, db *mongo.Database, facility
 func createFacilityCollection(pctx context.Context, db *mongo.Database, facilityName string) error {
 	facilitiesCollection := db.Collection("facilities")
 
-	// Sanitize the facility name by removing the "_facility" suffix
-	normalizedName := strings.Replace(facilityName, "_facility", "", -1)
+	normalizedName := facilityName
 
 	// Check if facility already exists in the database using the sanitized name
 	var existingFacility facility.Facilitiy
@@ -179,15 +167,15 @@ func createNormalSlots(pctx context.Context, db *mongo.Database, facilityName st
 	slots := []facility.Slot{}
 
 	switch facilityName {
-	case "fitness_facility":
+	case "fitness":
 		// Slots from 9:00 to 19:00 with 15 mins cleanup time
 		startTime := time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC)
 		endTime := time.Date(0, 1, 1, 19, 0, 0, 0, time.UTC)
 		for start := startTime; start.Before(endTime); start = start.Add(2*time.Hour + 15*time.Minute) {
 			slot := facility.Slot{
 				Id:             primitive.NewObjectID(),
-				StartTime:      start.Format("15:04"),
-				EndTime:        start.Add(2 * time.Hour).Format("15:04"),
+				FacilityName:   facilityName,
+				TimeRange:      utils.TimeRange{StartTime: utils.CustomTime(start), EndTime: utils.CustomTime(start.Add(2 * time.Hour))},
 				Status:         0,
 				MaxBookings:    10,
 				CurrentBookings: 0,
@@ -197,20 +185,20 @@ func createNormalSlots(pctx context.Context, db *mongo.Database, facilityName st
 			}
 			slots = append(slots, slot)
 		}
-	case "swimming_facility", "football_facility":
+	case "swimming", "football":
 		// Slots from 9:00 to 19:00 without cleanup time
 		startTime := time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC)
 		endTime := time.Date(0, 1, 1, 19, 0, 0, 0, time.UTC)
 		maxBookings := 1
-		if facilityName == "swimming_facility" {
+		if facilityName == "swimming" {
 
 			maxBookings = 10
 		}
 		for start := startTime; start.Before(endTime); start = start.Add(2 * time.Hour) {
 			slot := facility.Slot{
 				Id:             primitive.NewObjectID(),
-				StartTime:      start.Format("15:04"),
-				EndTime:        start.Add(2 * time.Hour).Format("15:04"),
+				FacilityName:   facilityName,
+				TimeRange:      utils.TimeRange{StartTime: utils.CustomTime(start), EndTime: utils.CustomTime(start.Add(2 * time.Hour))},
 				Status:         0,
 				MaxBookings:    maxBookings,
 				CurrentBookings: 0,
@@ -227,48 +215,50 @@ func createNormalSlots(pctx context.Context, db *mongo.Database, facilityName st
 	return err
 }
 
-// Convert badminton slots to []interface{}
-func convertBadmintonToInterface(slots []facility.BadmintonSlot) []interface{} {
-	interfaceSlots := make([]interface{}, len(slots))
-	for i, slot := range slots {
-		interfaceSlots[i] = slot
+// Create courts for badminton facility as bookable resources
+func createBadmintonCourts(pctx context.Context, db *mongo.Database) ([]facility.Resource, error) {
+	resourceCollection := db.Collection("resources")
+	courts := []facility.Resource{}
+	for number := 1; number <= 4; number++ {
+		courts = append(courts, facility.Resource{
+			Id:           primitive.NewObjectID(),
+			FacilityName: "badminton",
+			Name:         fmt.Sprintf("Court %d", number),
+			Kind:         "court",
+			Number:       number,
+			Capacity:     1,
+			Status:       0,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		})
 	}
-	return interfaceSlots
-}
 
-// Create courts for badminton facility
-func createBadmintonCourts(pctx context.Context, db *mongo.Database) error {
-	courtCollection := db.Collection("court")
-	courts := []interface{}{
-		facility.BadmintonCourt{Id: primitive.NewObjectID(), CourtNumber: 1, Status: 0},
-		facility.BadmintonCourt{Id: primitive.NewObjectID(), CourtNumber: 2, Status: 0},
-		facility.BadmintonCourt{Id: primitive.NewObjectID(), CourtNumber: 3, Status: 0},
-		facility.BadmintonCourt{Id: primitive.NewObjectID(), CourtNumber: 4, Status: 0},
+	docs := make([]interface{}, len(courts))
+	for i, court := range courts {
+		docs[i] = court
 	}
 
-	_, err := courtCollection.InsertMany(pctx, courts)
-	return err
+	_, err := resourceCollection.InsertMany(pctx, docs)
+	return courts, err
 }
 
-// Create badminton slots from 10:00 to 21:00
-// Create badminton slots from 10:00 to 21:00 with maxBooking set to 1 and multiple courts per time slot
-func createBadmintonSlots(pctx context.Context, db *mongo.Database) error {
+// Create badminton slots from 10:00 to 21:00 with maxBooking set to 1 and one slot per court per hour
+func createBadmintonSlots(pctx context.Context, db *mongo.Database, courts []facility.Resource) error {
     slotCollection := db.Collection("slots")
-    courts := []int{1, 2, 3, 4} // Court numbers
-    slots := []facility.BadmintonSlot{}
+    slots := []facility.Slot{}
     startTime := time.Date(0, 1, 1, 10, 0, 0, 0, time.UTC)
     endTime := time.Date(0, 1, 1, 21, 0, 0, 0, time.UTC)
 
     for start := startTime; start.Before(endTime); start = start.Add(1 * time.Hour) { // 1-hour time slots
-        for _, courtNumber := range courts {
-            slot := facility.BadmintonSlot{
+        for _, court := range courts {
+            slot := facility.Slot{
                 Id:             primitive.NewObjectID(),
-                StartTime:      start.Format("15:04"),
-                EndTime:        start.Add(1 * time.Hour).Format("15:04"),
-                CourtId:        primitive.NewObjectID(), // Replace with actual court ID in a full setup
-                CourtNumber:    courtNumber,
+                FacilityName:   "badminton",
+                TimeRange:      utils.TimeRange{StartTime: utils.CustomTime(start), EndTime: utils.CustomTime(start.Add(1 * time.Hour))},
+                ResourceId:     court.Id,
                 MaxBookings:    1, // MaxBookings is set to 1 per court per time slot
                 Status:         0,
+                FacilityType:   "badminton",
                 CreatedAt:      time.Now(),
                 UpdatedAt:      time.Now(),
             }
@@ -277,6 +267,6 @@ func createBadmintonSlots(pctx context.Context, db *mongo.Database) error {
     }
 
     // Insert slots as []interface{}
-    _, err := slotCollection.InsertMany(pctx, convertBadmintonToInterface(slots))
+    _, err := slotCollection.InsertMany(pctx, convertToInterface(slots))
     return err
 }
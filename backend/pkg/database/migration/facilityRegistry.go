@@ -0,0 +1,324 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"main/config"
+	"main/modules/facility"
+	"main/pkg/database"
+	"main/pkg/utils"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	registryMigrationStep = "facility_registry"
+	slotTimeRangeStep     = "slot_time_ranges"
+)
+
+// FacilityMigrate moves every legacy <name>_facility database into the
+// facility registry. Documents keep their ids so bookings referencing slots
+// stay valid; the legacy databases are left in place to be dropped by hand.
+// Slot times are then normalised into validated time ranges.
+func FacilityMigrate(pctx context.Context, cfg *config.Config) {
+	client := database.DbConn(pctx, cfg)
+	defer client.Disconnect(pctx)
+
+	registry := client.Database(facility.RegistryDb)
+	if err := createRegistryIndexes(pctx, registry); err != nil {
+		log.Fatalf("Error: FacilityMigrate: %v", err)
+	}
+
+	if hasMigrationStep(pctx, registry, registryMigrationStep) {
+		log.Println("Facility registry migration already completed, skipping.")
+	} else {
+		migrateLegacyFacilityDbs(pctx, client, registry)
+	}
+
+	if err := normaliseSlotTimes(pctx, registry); err != nil {
+		log.Fatalf("Error: FacilityMigrate: slot times: %v", err)
+	}
+}
+
+func migrateLegacyFacilityDbs(pctx context.Context, client *mongo.Client, registry *mongo.Database) {
+	legacyDbs, err := legacyFacilityDbs(pctx, client)
+	if err != nil {
+		log.Fatalf("Error: FacilityMigrate: %v", err)
+	}
+
+	for _, dbName := range legacyDbs {
+		facilityName := strings.TrimSuffix(dbName, "_facility")
+		legacy := client.Database(dbName)
+
+		if facilityName == "badminton" {
+			if err := migrateCourtsToResources(pctx, legacy); err != nil {
+				log.Fatalf("Error: FacilityMigrate: badminton courts: %v", err)
+			}
+		}
+
+		if err := copyFacilityDb(pctx, legacy, registry, facilityName); err != nil {
+			log.Fatalf("Error: FacilityMigrate: %s: %v", facilityName, err)
+		}
+
+		// Seeding must not run again for a facility that came from a legacy database
+		if _, err := checkAndSetMigrationFlag(pctx, registry, facilityName); err != nil {
+			log.Fatalf("Error: FacilityMigrate: %v", err)
+		}
+		log.Printf("Migrated %s into %s", dbName, facility.RegistryDb)
+	}
+
+	if _, err := checkAndSetMigrationStep(pctx, registry, registryMigrationStep); err != nil {
+		log.Fatalf("Error: FacilityMigrate: %v", err)
+	}
+	log.Printf("Facility registry migration completed for %d facilities", len(legacyDbs))
+}
+
+// copyFacilityDb upserts the facility, its slots, resources and slot template
+// into the registry by _id, so running it twice is harmless
+func copyFacilityDb(pctx context.Context, legacy, registry *mongo.Database, facilityName string) error {
+	collections := map[string]bson.M{
+		"facilities":     {"name": facilityName},
+		"slots":          {"facility_name": facilityName},
+		"resources":      {"facility_name": facilityName},
+		"slot_templates": {"facility_name": facilityName},
+	}
+
+	for name, set := range collections {
+		cur, err := legacy.Collection(name).Find(pctx, bson.M{})
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+
+		var docs []bson.M
+		if err := cur.All(pctx, &docs); err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+
+		col := registry.Collection(name)
+		for _, doc := range docs {
+			for key, value := range set {
+				doc[key] = value
+			}
+			if _, err := col.ReplaceOne(pctx, bson.M{"_id": doc["_id"]}, doc, options.Replace().SetUpsert(true)); err != nil {
+				return fmt.Errorf("write %s %v: %w", name, doc["_id"], err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func createRegistryIndexes(pctx context.Context, db *mongo.Database) error {
+	if _, err := db.Collection("facilities").Indexes().CreateOne(pctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+
+	for _, name := range []string{"slots", "resources", "maintenance", "media"} {
+		if _, err := db.Collection(name).Indexes().CreateOne(pctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "facility_name", Value: 1}},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Collection("calendar_days").Indexes().CreateOne(pctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "facility_name", Value: 1}, {Key: "kind", Value: 1}, {Key: "date", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+
+	// Overlap checks look up a court's slots by time range
+	if _, err := db.Collection("slots").Indexes().CreateOne(pctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "facility_name", Value: 1},
+			{Key: "resource_id", Value: 1},
+			{Key: "start_time", Value: 1},
+			{Key: "end_time", Value: 1},
+		},
+	}); err != nil {
+		return err
+	}
+
+	_, err := db.Collection("slot_templates").Indexes().CreateOne(pctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "facility_name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// legacyFacilityDbs lists databases from before the registry existed
+func legacyFacilityDbs(pctx context.Context, client *mongo.Client) ([]string, error) {
+	dbNames, err := client.ListDatabaseNames(pctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list database names: %v", err)
+	}
+
+	var legacy []string
+	for _, dbName := range dbNames {
+		if strings.HasSuffix(dbName, "_facility") {
+			legacy = append(legacy, dbName)
+		}
+	}
+	return legacy, nil
+}
+
+func hasMigrationStep(pctx context.Context, db *mongo.Database, step string) bool {
+	return db.Collection("_migrations").FindOne(pctx, bson.M{"step": step}).Err() == nil
+}
+
+// checkAndSetMigrationStep records a named one-off migration step, returning
+// true the first time it is seen
+func checkAndSetMigrationStep(pctx context.Context, db *mongo.Database, step string) (bool, error) {
+	migrationCollection := db.Collection("_migrations")
+	filter := bson.M{"step": step}
+
+	if err := migrationCollection.FindOne(pctx, filter).Err(); err == nil {
+		return false, nil
+	} else if err != mongo.ErrNoDocuments {
+		return false, fmt.Errorf("error checking migration step %s: %v", step, err)
+	}
+
+	if _, err := migrationCollection.InsertOne(pctx, bson.M{"step": step, "created_at": time.Now()}); err != nil {
+		return false, fmt.Errorf("failed to set migration step %s: %v", step, err)
+	}
+	return true, nil
+}
+
+// normaliseSlotTimes rewrites slot times saved as free-form strings to "HH:mm".
+// Slots whose times can't be parsed or don't form a valid range are disabled
+// and logged for staff to fix by hand rather than deleted, as they may be booked.
+func normaliseSlotTimes(pctx context.Context, db *mongo.Database) error {
+	shouldMigrate, err := checkAndSetMigrationStep(pctx, db, slotTimeRangeStep)
+	if err != nil || !shouldMigrate {
+		return err
+	}
+
+	col := db.Collection("slots")
+	cur, err := col.Find(pctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(pctx)
+
+	normalised, disabled := 0, 0
+	for cur.Next(pctx) {
+		var slot bson.M
+		if err := cur.Decode(&slot); err != nil {
+			return err
+		}
+
+		start, _ := slot["start_time"].(string)
+		end, _ := slot["end_time"].(string)
+		timeRange, err := utils.ParseTimeRange(start, end)
+		if err != nil {
+			log.Printf("Disabling slot %v of %v: %q-%q: %v", slot["_id"], slot["facility_name"], start, end, err)
+			update := bson.M{"status": 0, "updated_at": time.Now()}
+			if errors.Is(err, utils.ErrInvalidTimeFormat) {
+				// Unreadable times would fail every decode of the slot, so
+				// keep them aside and clear the range
+				update["legacy_start_time"] = slot["start_time"]
+				update["legacy_end_time"] = slot["end_time"]
+				update["start_time"] = utils.CustomTime{}
+				update["end_time"] = utils.CustomTime{}
+			}
+			if _, err := col.UpdateByID(pctx, slot["_id"], bson.M{"$set": update}); err != nil {
+				return err
+			}
+			disabled++
+			continue
+		}
+
+		if timeRange.StartTime.String() == start && timeRange.EndTime.String() == end {
+			continue
+		}
+		if _, err := col.UpdateByID(pctx, slot["_id"], bson.M{"$set": bson.M{
+			"start_time": timeRange.StartTime,
+			"end_time":   timeRange.EndTime,
+			"updated_at": time.Now(),
+		}}); err != nil {
+			return err
+		}
+		normalised++
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	log.Printf("Slot time migration normalised %d slots and disabled %d", normalised, disabled)
+	return nil
+}
+
+// migrateCourtsToResources converts the legacy badminton "court" collection into
+// resources, keeping the court ids, and links each slot to its court's resource
+func migrateCourtsToResources(pctx context.Context, db *mongo.Database) error {
+	shouldMigrate, err := checkAndSetMigrationStep(pctx, db, "court_resources")
+	if err != nil || !shouldMigrate {
+		return err
+	}
+
+	cur, err := db.Collection("court").Find(pctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	var courts []struct {
+		Id          primitive.ObjectID `bson:"_id"`
+		CourtNumber int                `bson:"court_number"`
+		Status      int                `bson:"status"`
+	}
+	if err := cur.All(pctx, &courts); err != nil {
+		return err
+	}
+
+	resourceCollection := db.Collection("resources")
+	slotCollection := db.Collection("slots")
+	for _, court := range courts {
+		if _, err := resourceCollection.UpdateOne(
+			pctx,
+			bson.M{"_id": court.Id},
+			bson.M{"$setOnInsert": facility.Resource{
+				Id:           court.Id,
+				FacilityName: "badminton",
+				Name:         fmt.Sprintf("Court %d", court.CourtNumber),
+				Kind:         "court",
+				Number:       court.CourtNumber,
+				Capacity:     1,
+				Status:       court.Status,
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			}},
+			options.Update().SetUpsert(true),
+		); err != nil {
+			return err
+		}
+
+		if _, err := slotCollection.UpdateMany(
+			pctx,
+			bson.M{"court_number": court.CourtNumber},
+			bson.M{
+				"$set":   bson.M{"resource_id": court.Id, "facility_type": "badminton"},
+				"$unset": bson.M{"court_id": "", "court_number": ""},
+			},
+		); err != nil {
+			return err
+		}
+	}
+
+	// Legacy badminton slots treated a zero capacity as one booking
+	if _, err := slotCollection.UpdateMany(pctx, bson.M{"max_bookings": 0}, bson.M{"$set": bson.M{"max_bookings": 1}}); err != nil {
+		return err
+	}
+
+	log.Printf("Migrated %d badminton courts to resources", len(courts))
+	return nil
+}
@@ -0,0 +1,268 @@
+package migration
+
+import (
+	"context"
+	"log"
+	"main/config"
+	"main/modules/payment"
+	"main/modules/payment/ledger"
+	"main/modules/payment/provider"
+	"main/pkg/database"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func paymentDbConn(pctx context.Context, cfg *config.Config) *mongo.Database {
+	return database.DbConn(pctx, cfg).Database("payment_db")
+}
+
+func PaymentMigrate(pctx context.Context, cfg *config.Config) {
+	db := paymentDbConn(pctx, cfg)
+	defer db.Client().Disconnect(pctx)
+
+	// Payments; status + expires_at serves the expiry sweep, and a provider's
+	// charge reference belongs to one payment
+	indexs, err := db.Collection("payments").Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "booking_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "provider", Value: 1}, {Key: "provider_ref", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"provider_ref": bson.M{"$type": "string"}}),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, index := range indexs {
+		log.Printf("Index: %s", index)
+	}
+
+	// QR codes used to be rendered by api.qrserver.com; point them at our own endpoint
+	result, err := db.Collection("payments").UpdateMany(pctx,
+		bson.M{"qr_code_url": bson.M{"$regex": "^https://api\\.qrserver\\.com/"}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"qr_code_url": bson.M{"$concat": bson.A{strings.TrimRight(cfg.Payment.PublicUrl, "/"), "/payments/", bson.M{"$toString": "$_id"}, "/qr"}},
+		}}}},
+	)
+	if err != nil {
+		panic(err)
+	}
+	log.Printf("Rewrote %d QR code urls", result.ModifiedCount)
+
+	// Every payment before providers were recorded was PromptPay
+	result, err = db.Collection("payments").UpdateMany(pctx,
+		bson.M{"provider": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"provider": provider.PromptPay}},
+	)
+	if err != nil {
+		panic(err)
+	}
+	log.Printf("Set the provider of %d payments", result.ModifiedCount)
+
+	// Webhook deliveries; a provider's event is stored, and applied, once
+	indexs, err = db.Collection("webhook_events").Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "provider", Value: 1}, {Key: "event_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "reference", Value: 1}}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, index := range indexs {
+		log.Printf("Index: %s", index)
+	}
+
+	// Receipts; numbers are unique and each payment and refund gets one document
+	indexs, err = db.Collection("receipts").Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "number", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "kind", Value: 1}, {Key: "fiscal_year", Value: 1}, {Key: "sequence", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "payment_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"kind": payment.ReceiptKindReceipt}),
+		},
+		{
+			Keys:    bson.D{{Key: "refund_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"kind": payment.ReceiptKindCreditNote}),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, index := range indexs {
+		log.Printf("Index: %s", index)
+	}
+
+	// Refunds
+	indexs, err = db.Collection("refunds").Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "payment_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, index := range indexs {
+		log.Printf("Index: %s", index)
+	}
+
+	// Merchant accounts; at most one enabled account per stage and facility
+	indexs, err = db.Collection("merchant_accounts").Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "stage", Value: 1}, {Key: "facility_name", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"enabled": true}),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, index := range indexs {
+		log.Printf("Index: %s", index)
+	}
+
+	// Slips; one image hash, and one bank transfer, may back only one slip
+	slips := db.Client().Database("slip_db").Collection("slips")
+	indexs, err = slips.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "booking_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "content_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"content_hash": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys:    bson.D{{Key: "trans_ref", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"trans_ref": bson.M{"$type": "string"}}),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, index := range indexs {
+		log.Printf("Index: %s", index)
+	}
+
+	// Ledger; a source is posted once per kind and an entry is reversed once
+	indexs, err = db.Collection("journal_entries").Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "kind", Value: 1}, {Key: "source_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"source_id": bson.M{"$type": "string"}}),
+		},
+		{Keys: bson.D{{Key: "effective_at", Value: 1}}},
+		{Keys: bson.D{{Key: "lines.account", Value: 1}, {Key: "effective_at", Value: 1}}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for _, index := range indexs {
+		log.Printf("Index: %s", index)
+	}
+	backfillLedger(pctx, db)
+
+	// Payments used to go to one hardcoded account; keep it as the stage's default
+	seed := &payment.MerchantAccount{
+		Stage:       cfg.App.Stage,
+		PromptPayId: "1579901028845",
+		TaxId:       "1579901028845",
+		DisplayName: "Default",
+		Enabled:     true,
+		CreatedBy:   "migration",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	seeded, err := db.Collection("merchant_accounts").UpdateOne(pctx,
+		bson.M{"stage": cfg.App.Stage, "facility_name": ""},
+		bson.M{"$setOnInsert": seed},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		panic(err)
+	}
+	if seeded.UpsertedCount > 0 {
+		log.Printf("Seeded default merchant account for stage %s", cfg.App.Stage)
+	}
+}
+
+// backfillLedger posts the charges and refunds completed before the ledger
+// existed. Payments are paid within minutes of being created, so a charge
+// takes effect when its payment was created; a refund when it was completed.
+// Sources already posted are skipped, so running it again is harmless.
+func backfillLedger(pctx context.Context, db *mongo.Database) {
+	journal := db.Collection("journal_entries")
+	post := func(entry *ledger.Entry) bool {
+		entry.PostedBy = "migration"
+		entry.PostedAt = time.Now()
+		if err := entry.Validate(); err != nil {
+			log.Printf("Warning: ledger backfill skipped %s %s: %s", entry.Kind, entry.SourceId, err.Error())
+			return false
+		}
+		if _, err := journal.InsertOne(pctx, entry); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return false
+			}
+			panic(err)
+		}
+		return true
+	}
+
+	cursor, err := db.Collection("payments").Find(pctx, bson.M{"status": bson.M{"$in": bson.A{payment.Completed, payment.PartiallyRefunded, payment.Refunded}}})
+	if err != nil {
+		panic(err)
+	}
+	payments := make(map[primitive.ObjectID]*payment.PaymentEntity)
+	charges := 0
+	for cursor.Next(pctx) {
+		p := new(payment.PaymentEntity)
+		if err := cursor.Decode(p); err != nil {
+			panic(err)
+		}
+		payments[p.Id] = p
+		if post(ledger.ChargeEntry(p, p.CreatedAt)) {
+			charges++
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		panic(err)
+	}
+	cursor.Close(pctx)
+
+	cursor, err = db.Collection("refunds").Find(pctx, bson.M{"status": payment.RefundCompleted})
+	if err != nil {
+		panic(err)
+	}
+	defer cursor.Close(pctx)
+	refunds := 0
+	for cursor.Next(pctx) {
+		refund := new(payment.Refund)
+		if err := cursor.Decode(refund); err != nil {
+			panic(err)
+		}
+		p, ok := payments[refund.PaymentId]
+		if !ok {
+			log.Printf("Warning: ledger backfill skipped refund %s: payment %s is not paid", refund.Id.Hex(), refund.PaymentId.Hex())
+			continue
+		}
+		completedAt := refund.UpdatedAt
+		for _, event := range refund.Timeline {
+			if event.Status == payment.RefundCompleted {
+				completedAt = event.At
+			}
+		}
+		if post(ledger.RefundEntry(p, refund, completedAt)) {
+			refunds++
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		panic(err)
+	}
+
+	log.Printf("Posted %d charges and %d refunds to the ledger", charges, refunds)
+}
@@ -24,6 +24,10 @@ func main() {
 		migration.UserMigrate(ctx, &cfg)
 	case "auth" :
 		migration.AuthMigrate(ctx, &cfg)
+	case "facility":
+		migration.FacilityMigrate(ctx, &cfg)
+	case "payment":
+		migration.PaymentMigrate(ctx, &cfg)
 	// case "booking" :
 	// 	migration.BookingMigrate(ctx, &cfg)
 	//other migration db script
@@ -0,0 +1,131 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+// MaxPixels bounds decoded images so a small file can't expand into a huge bitmap
+const MaxPixels = 40_000_000
+
+var (
+	ErrUnsupportedType = errors.New("error: unsupported image type")
+	ErrTooManyPixels   = errors.New("error: image dimensions are too large")
+)
+
+// AllowedTypes are the sniffed content types that can be decoded
+var AllowedTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// SniffType detects the content type from the data itself, ignoring what the
+// client claimed, and returns it with its file extension
+func SniffType(data []byte) (contentType, ext string, err error) {
+	contentType = http.DetectContentType(data)
+	ext, ok := AllowedTypes[contentType]
+	if !ok {
+		return contentType, "", ErrUnsupportedType
+	}
+	return contentType, ext, nil
+}
+
+// Decode checks the dimensions before decoding the full image
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	return img, nil
+}
+
+// Thumbnail scales img down so its longest side is at most maxSide, averaging
+// the source pixels each target pixel covers. Smaller images are returned as is.
+func Thumbnail(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+
+	tw, th := maxSide, h*maxSide/w
+	if h > w {
+		tw, th = w*maxSide/h, maxSide
+	}
+	return Resize(img, max(tw, 1), max(th, 1))
+}
+
+// Resize scales img to exactly w by h. Each target pixel averages the source
+// pixels it covers, which is nearest neighbour when scaling up.
+func Resize(img image.Image, w, h int) image.Image {
+	bounds := img.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := bounds.Min.Y + y*sh/h
+		y1 := max(bounds.Min.Y+(y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := bounds.Min.X + x*sw/w
+			x1 := max(bounds.Min.X+(x+1)*sw/w, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG flattens transparency onto white and writes a JPEG. Re-encoding
+// also drops any metadata the original carried.
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: 85})
+}
+
+// Reencode writes img back out as PNG when it came in as PNG and as JPEG
+// otherwise, dropping EXIF and any other metadata of the original
+func Reencode(img image.Image, contentType string) (data []byte, outType, ext string, err error) {
+	var buf bytes.Buffer
+	if contentType == "image/png" {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/png", "png", nil
+	}
+	if err := EncodeJPEG(&buf, img); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "image/jpeg", "jpg", nil
+}
@@ -3,6 +3,7 @@ package jwt
 import (
 	"context"
 	"errors"
+	"main/pkg/utils"
 	"math"
 	"sync"
 	"time"
@@ -43,8 +44,7 @@ func (a *authConcrete) SignToken() string {
 }
 
 func now() time.Time {
-	loc, _ := time.LoadLocation("Asia/Bangkok")
-	return time.Now().In(loc)
+	return utils.LocalTime()
 }
 
 func jwtTimeDurationCal(t int64) *jwt.NumericDate {
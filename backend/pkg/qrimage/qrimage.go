@@ -0,0 +1,159 @@
+package qrimage
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"main/pkg/imaging"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Format is the image encoding a code is rendered in
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+// Sizes are the pixel width and height of the rendered code
+const (
+	MinSize     = 128
+	MaxSize     = 1024
+	DefaultSize = 300
+)
+
+// logoShare is the logo's largest side as a share of the code. Codes use the
+// highest error correction, which survives far more than the logo covers.
+const logoShare = 0.22
+
+var (
+	ErrUnsupportedFormat = errors.New("error: qr format must be png or svg")
+	ErrInvalidSize       = fmt.Errorf("error: qr size must be between %d and %d", MinSize, MaxSize)
+)
+
+//go:embed logo.png
+var logoPNG []byte
+
+var logo = func() image.Image {
+	img, err := png.Decode(bytes.NewReader(logoPNG))
+	if err != nil {
+		panic(fmt.Sprintf("qrimage: embedded logo: %v", err))
+	}
+	return img
+}()
+
+// ParseFormat accepts png or svg in any case, defaulting to png
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case "", PNG:
+		return PNG, nil
+	case SVG:
+		return SVG, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+func (f Format) ContentType() string {
+	if f == SVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Render encodes payload as a square code of size pixels with the logo in
+// the middle
+func Render(payload string, format Format, size int) ([]byte, error) {
+	if size < MinSize || size > MaxSize {
+		return nil, ErrInvalidSize
+	}
+
+	code, err := qrcode.New(payload, qrcode.Highest)
+	if err != nil {
+		return nil, fmt.Errorf("error: encode qr code failed: %w", err)
+	}
+
+	switch format {
+	case PNG:
+		return renderPNG(code, size)
+	case SVG:
+		return renderSVG(code, size)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// logoBox returns the logo's size scaled to fit within side, keeping its
+// aspect ratio
+func logoBox(side int) (w, h int) {
+	b := logo.Bounds()
+	if b.Dx() >= b.Dy() {
+		return side, max(b.Dy()*side/b.Dx(), 1)
+	}
+	return max(b.Dx()*side/b.Dy(), 1), side
+}
+
+func renderPNG(code *qrcode.QRCode, size int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), code.Image(size), image.Point{}, draw.Src)
+
+	// White backdrop with a small margin so the logo doesn't touch modules
+	w, h := logoBox(int(float64(size) * logoShare))
+	margin := max(size/100, 2)
+	centre := image.Pt(size/2, size/2)
+	backdrop := image.Rect(centre.X-w/2-margin, centre.Y-h/2-margin, centre.X+w/2+margin, centre.Y+h/2+margin)
+	draw.Draw(img, backdrop, &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+
+	scaled := imaging.Resize(logo, w, h)
+	draw.Draw(img, image.Rect(centre.X-w/2, centre.Y-h/2, centre.X-w/2+w, centre.Y-h/2+h), scaled, image.Point{}, draw.Over)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error: encode qr png failed: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG draws modules in a viewBox of one unit per module, merging each
+// row's dark runs into single rectangles to keep the document small
+func renderSVG(code *qrcode.QRCode, size int) ([]byte, error) {
+	bitmap := code.Bitmap()
+	n := len(bitmap)
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x := 0; x < n; {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < n && row[x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+
+	side := float64(n) * logoShare
+	w, h := logoBox(1000)
+	lw, lh := side*float64(w)/1000, side*float64(h)/1000
+	margin := float64(n) / 100 * 2
+	centre := float64(n) / 2
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/>`, n, n)
+	fmt.Fprintf(&buf, `<path d="%s" fill="#000"/>`, path.String())
+	fmt.Fprintf(&buf, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="#fff"/>`, centre-lw/2-margin, centre-lh/2-margin, lw+2*margin, lh+2*margin)
+	fmt.Fprintf(&buf, `<image x="%.2f" y="%.2f" width="%.2f" height="%.2f" href="data:image/png;base64,%s"/>`, centre-lw/2, centre-lh/2, lw, lh, base64.StdEncoding.EncodeToString(logoPNG))
+	buf.WriteString(`</svg>`)
+	return buf.Bytes(), nil
+}
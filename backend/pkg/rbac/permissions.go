@@ -14,6 +14,8 @@ var RolePermissions = map[int][]string{
         auth.PermissionDeleteUser,
         auth.PermissionAccessDashboard,
         auth.PermissionManageBookings,
+        auth.PermissionManageFacilities,
+        auth.PermissionManagePayments,
     },
 }
 
@@ -0,0 +1,191 @@
+package receiptpdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Document is what a receipt or credit note shows. Amounts are in the
+// document's currency and already rounded to satang.
+type Document struct {
+	Title         string // e.g. RECEIPT / TAX INVOICE
+	Number        string
+	Reference     string // Credit notes: the receipt they amend
+	IssuedAt      time.Time
+	SellerName    string
+	SellerTaxId   string
+	SellerAddress string
+	Lines         []Line
+	NetAmount     float64 // Value before VAT
+	VatRate       float64 // e.g. 0.07
+	VatAmount     float64
+	Total         float64
+	Currency      string
+	Settlement    string // How the money moved, e.g. Paid by PromptPay
+	Note          string // Printed under the totals, e.g. the refund reason
+	Copy          bool   // Watermark the document as a re-issued copy
+}
+
+// Line is one item on the document
+type Line struct {
+	Description string
+	Detail      string // Second, smaller line, e.g. the slot
+	Amount      float64
+}
+
+// fontFamily is the name an embedded TTF is registered under
+const fontFamily = "receipt"
+
+// Renderer draws documents as A4 PDFs. Without a font file it uses the PDF
+// core fonts, which only cover Latin-1; other characters print as dots, so
+// deployments with Thai facility names should set a Thai TTF such as Sarabun.
+type Renderer struct {
+	font []byte
+}
+
+// NewRenderer loads the TTF at fontPath, or uses the core fonts when it is empty
+func NewRenderer(fontPath string) (*Renderer, error) {
+	if fontPath == "" {
+		return &Renderer{}, nil
+	}
+	font, err := os.ReadFile(fontPath)
+	if err != nil {
+		return nil, fmt.Errorf("error: read receipt font: %w", err)
+	}
+	return &Renderer{font: font}, nil
+}
+
+// Render draws doc. The output only depends on doc, so a re-issue is the same
+// document as the original apart from the watermark.
+func (r *Renderer) Render(doc *Document) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetCreationDate(doc.IssuedAt)
+	pdf.SetModificationDate(doc.IssuedAt)
+	pdf.SetCatalogSort(true)
+	pdf.SetTitle(doc.Title+" "+doc.Number, true)
+	pdf.SetAutoPageBreak(true, 20)
+
+	family, tr := "Helvetica", pdf.UnicodeTranslatorFromDescriptor("")
+	if r.font != nil {
+		pdf.AddUTF8FontFromBytes(fontFamily, "", r.font)
+		pdf.AddUTF8FontFromBytes(fontFamily, "B", r.font)
+		family, tr = fontFamily, func(s string) string { return s }
+	}
+
+	pdf.AddPage()
+	if doc.Copy {
+		watermark(pdf, family)
+	}
+
+	// Seller on the left, document on the right
+	pdf.SetFont(family, "B", 14)
+	pdf.CellFormat(110, 7, tr(doc.SellerName), "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 7, tr(doc.Title), "", 1, "R", false, 0, "")
+
+	pdf.SetFont(family, "", 9)
+	label := "ORIGINAL"
+	if doc.Copy {
+		label = "COPY"
+	}
+	top := pdf.GetY()
+	pdf.MultiCell(110, 4.5, tr(doc.SellerAddress+"\nTax ID: "+doc.SellerTaxId), "", "L", false)
+	bottom := pdf.GetY()
+	pdf.SetXY(120, top)
+	right := []string{"No. " + doc.Number, "Date " + doc.IssuedAt.Format("02/01/2006")}
+	if doc.Reference != "" {
+		right = append(right, "Refers to "+doc.Reference)
+	}
+	right = append(right, label)
+	for _, text := range right {
+		pdf.SetX(120)
+		pdf.CellFormat(0, 4.5, tr(text), "", 1, "R", false, 0, "")
+	}
+	pdf.SetY(math.Max(bottom, pdf.GetY()) + 8)
+
+	// Items
+	pdf.SetFont(family, "B", 10)
+	pdf.SetFillColor(235, 235, 235)
+	pdf.CellFormat(140, 8, "Description", "TB", 0, "L", true, 0, "")
+	pdf.CellFormat(0, 8, "Amount ("+doc.Currency+")", "TB", 1, "R", true, 0, "")
+	for _, line := range doc.Lines {
+		pdf.SetFont(family, "", 10)
+		pdf.CellFormat(140, 7, tr(line.Description), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 7, formatAmount(line.Amount), "", 1, "R", false, 0, "")
+		if line.Detail != "" {
+			pdf.SetFont(family, "", 8)
+			pdf.CellFormat(140, 5, tr(line.Detail), "", 1, "L", false, 0, "")
+		}
+	}
+	pdf.Line(pdf.GetX(), pdf.GetY()+1, 200, pdf.GetY()+1)
+	pdf.Ln(4)
+
+	// VAT breakdown; prices include VAT
+	totals := []struct {
+		label  string
+		amount float64
+	}{
+		{"Value before VAT", doc.NetAmount},
+		{"VAT " + strconv.FormatFloat(math.Round(doc.VatRate*10000)/100, 'f', -1, 64) + "%", doc.VatAmount},
+		{"Total (VAT included)", doc.Total},
+	}
+	for i, row := range totals {
+		style := ""
+		if i == len(totals)-1 {
+			style = "B"
+		}
+		pdf.SetFont(family, style, 10)
+		pdf.CellFormat(140, 6, row.label, "", 0, "R", false, 0, "")
+		pdf.CellFormat(0, 6, formatAmount(row.amount), "", 1, "R", false, 0, "")
+	}
+
+	pdf.Ln(6)
+	pdf.SetFont(family, "", 9)
+	if doc.Settlement != "" {
+		pdf.CellFormat(0, 5, tr(doc.Settlement), "", 1, "L", false, 0, "")
+	}
+	if doc.Note != "" {
+		pdf.MultiCell(0, 5, tr(doc.Note), "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("error: render receipt %s: %w", doc.Number, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// watermark prints COPY diagonally across the page, under the content
+func watermark(pdf *fpdf.Fpdf, family string) {
+	pdf.SetFont(family, "B", 110)
+	pdf.SetTextColor(225, 225, 225)
+	pdf.TransformBegin()
+	pdf.TransformRotate(45, 105, 160)
+	pdf.Text(105-pdf.GetStringWidth("COPY")/2, 175, "COPY")
+	pdf.TransformEnd()
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// formatAmount writes amount with thousands separators, e.g. 1,234.50
+func formatAmount(amount float64) string {
+	s := fmt.Sprintf("%.2f", math.Abs(amount))
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+
+	var b strings.Builder
+	if amount < 0 {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String() + frac
+}
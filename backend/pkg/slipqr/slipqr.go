@@ -0,0 +1,135 @@
+package slipqr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+var (
+	ErrNoCode         = errors.New("error: no QR code found on the slip")
+	ErrInvalidPayload = errors.New("error: slip QR code is not a bank transfer slip")
+)
+
+// Top level tags of the slip verification payload, and the sub tags of tagApi
+const (
+	tagApi     = "00"
+	tagCountry = "51"
+	tagCRC     = "91"
+
+	subApiId    = "00"
+	subBank     = "01"
+	subTransRef = "02"
+)
+
+// Payload is what the mini QR printed on a Thai bank transfer slip says
+type Payload struct {
+	Raw         string
+	ApiId       string
+	SendingBank string // Bank of Thailand code of the payer's bank, e.g. 014
+	TransRef    string // Transaction reference, unique per transfer
+	Country     string
+	TransDate   *time.Time // Transfer date, when the bank puts it at the start of TransRef
+}
+
+// Decode finds and reads the QR code in a slip image
+func Decode(img image.Image) (string, error) {
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", ErrNoCode
+	}
+	hints := map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_TRY_HARDER: true}
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, hints)
+	if err != nil {
+		return "", ErrNoCode
+	}
+	return result.GetText(), nil
+}
+
+// Parse reads the tag-length-value fields of a slip payload and checks its CRC
+func Parse(raw string) (*Payload, error) {
+	raw = strings.TrimSpace(raw)
+	fields, err := readTLV(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	crc, ok := fields[tagCRC]
+	if !ok || !strings.HasSuffix(raw, tagCRC+"04"+crc) {
+		return nil, fmt.Errorf("%w: missing checksum", ErrInvalidPayload)
+	}
+	if !strings.EqualFold(crc, fmt.Sprintf("%04X", crc16(raw[:len(raw)-4]))) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidPayload)
+	}
+
+	api, err := readTLV(fields[tagApi])
+	if err != nil {
+		return nil, err
+	}
+	payload := &Payload{
+		Raw:         raw,
+		ApiId:       api[subApiId],
+		SendingBank: api[subBank],
+		TransRef:    api[subTransRef],
+		Country:     fields[tagCountry],
+	}
+	if payload.TransRef == "" || payload.SendingBank == "" {
+		return nil, fmt.Errorf("%w: missing bank or transaction reference", ErrInvalidPayload)
+	}
+	payload.TransDate = refDate(payload.TransRef)
+	return payload, nil
+}
+
+func readTLV(s string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for i := 0; i < len(s); {
+		if i+4 > len(s) {
+			return nil, fmt.Errorf("%w: truncated field", ErrInvalidPayload)
+		}
+		n, err := strconv.Atoi(s[i+2 : i+4])
+		if err != nil || i+4+n > len(s) {
+			return nil, fmt.Errorf("%w: bad field length", ErrInvalidPayload)
+		}
+		fields[s[i:i+2]] = s[i+4 : i+4+n]
+		i += 4 + n
+	}
+	return fields, nil
+}
+
+// refDate reads a yyyyMMdd date some banks start the reference with. Dates
+// more than a year away are taken as coincidence.
+func refDate(ref string) *time.Time {
+	if len(ref) < 8 {
+		return nil
+	}
+	day, err := time.ParseInLocation("20060102", ref[:8], time.Local)
+	if err != nil {
+		return nil
+	}
+	if diff := time.Since(day); diff < -24*time.Hour || diff > 366*24*time.Hour {
+		return nil
+	}
+	return &day
+}
+
+// crc16 is CRC-16/CCITT-FALSE, as used by EMV QR payloads
+func crc16(s string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for b := 0; b < 8; b++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,133 @@
+package slipqr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"main/config"
+	"main/pkg/circuitbreaker"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+var (
+	ErrVerifierDisabled = errors.New("error: slip verification is not configured")
+	ErrTransNotFound    = errors.New("error: bank does not know the transaction")
+)
+
+// Transaction is what the bank reports for a slip's transaction reference.
+// The slip QR itself does not carry the amount, so it has to come from here.
+type Transaction struct {
+	TransRef      string
+	Amount        float64
+	TransactedAt  time.Time
+	ReceiverProxy string // PromptPay id of the receiver, masked by the bank, e.g. xxx-xxx-4567
+	ReceiverName  string
+}
+
+// Verifier looks a slip payload up with the bank
+type Verifier interface {
+	Verify(ctx context.Context, payload *Payload) (*Transaction, error)
+}
+
+// NewVerifier builds the verifier for SLIP_VERIFY_URL, or one that always
+// reports ErrVerifierDisabled when it is not set
+func NewVerifier(cfg *config.Payment) Verifier {
+	if cfg.SlipVerifyUrl == "" {
+		return noopVerifier{}
+	}
+	return &httpVerifier{
+		url:     cfg.SlipVerifyUrl,
+		apiKey:  cfg.SlipVerifyApiKey,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		breaker: circuitbreaker.NewCircuitBreaker("slip-verify"),
+	}
+}
+
+type noopVerifier struct{}
+
+func (noopVerifier) Verify(context.Context, *Payload) (*Transaction, error) {
+	return nil, ErrVerifierDisabled
+}
+
+type httpVerifier struct {
+	url     string
+	apiKey  string
+	client  *http.Client
+	breaker *gobreaker.CircuitBreaker
+}
+
+type verifyResponse struct {
+	Data struct {
+		TransRef string    `json:"transRef"`
+		Date     time.Time `json:"date"`
+		Amount   struct {
+			Amount float64 `json:"amount"`
+		} `json:"amount"`
+		Receiver struct {
+			DisplayName string `json:"displayName"`
+			Account     struct {
+				Proxy struct {
+					Account string `json:"account"`
+				} `json:"proxy"`
+			} `json:"account"`
+		} `json:"receiver"`
+	} `json:"data"`
+}
+
+func (v *httpVerifier) Verify(ctx context.Context, payload *Payload) (*Transaction, error) {
+	result, err := v.breaker.Execute(func() (interface{}, error) {
+		return v.lookup(ctx, payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.(*Transaction) == nil {
+		return nil, ErrTransNotFound
+	}
+	return result.(*Transaction), nil
+}
+
+func (v *httpVerifier) lookup(ctx context.Context, payload *Payload) (*Transaction, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url+"?payload="+url.QueryEscape(payload.Raw), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error: build slip verify request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+v.apiKey)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error: slip verify request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		// The bank answered, so this is no reason to trip the breaker
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("error: slip verify returned %s: %s", resp.Status, body)
+	}
+
+	var body verifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("error: decode slip verify response: %w", err)
+	}
+	if body.Data.TransRef != payload.TransRef {
+		return nil, fmt.Errorf("error: slip verify answered for %q, asked for %q", body.Data.TransRef, payload.TransRef)
+	}
+
+	return &Transaction{
+		TransRef:      body.Data.TransRef,
+		Amount:        body.Data.Amount.Amount,
+		TransactedAt:  body.Data.Date,
+		ReceiverProxy: body.Data.Receiver.Account.Proxy.Account,
+		ReceiverName:  body.Data.Receiver.DisplayName,
+	}, nil
+}
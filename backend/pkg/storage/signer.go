@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSignatureInvalid = errors.New("error: invalid url signature")
+	ErrSignatureExpired = errors.New("error: signed url has expired")
+)
+
+// Signer issues URLs that grant access to one private blob until they expire
+type Signer struct {
+	secret  []byte
+	baseUrl string
+}
+
+// NewSigner signs URLs below baseUrl, which serves blobs by key
+func NewSigner(secret, baseUrl string) *Signer {
+	return &Signer{secret: []byte(secret), baseUrl: strings.TrimRight(baseUrl, "/")}
+}
+
+// SignedURL returns a URL to key valid for ttl, and when it stops working
+func (s *Signer) SignedURL(key string, ttl time.Duration) (string, time.Time) {
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(key, expires))
+	return s.baseUrl + "/" + key + "?" + query.Encode(), expiresAt
+}
+
+// Verify checks a key's signature and expiry as they came in a signed URL
+func (s *Signer) Verify(key, expires, signature string) error {
+	if !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+		return ErrSignatureInvalid
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	if time.Now().Unix() > unix {
+		return ErrSignatureExpired
+	}
+	return nil
+}
+
+func (s *Signer) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
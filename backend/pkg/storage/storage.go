@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"main/config"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("error: blob not found")
+	ErrInvalidKey = errors.New("error: invalid blob key")
+)
+
+// BlobStore keeps uploaded files under slash separated keys
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// NewBlobStore builds the store selected by STORAGE_DRIVER
+func NewBlobStore(cfg *config.Storage) BlobStore {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStore(cfg.LocalPath, cfg.PublicUrl)
+	default:
+		log.Fatalf("Error: unknown storage driver %q", cfg.Driver)
+		return nil
+	}
+}
+
+// CleanKey rejects keys that are absolute or climb out of the store
+func CleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != key || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -1,27 +1,80 @@
 package utils
 
 import (
+	"encoding/json"
 	"errors"
 	"log"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 )
 
+// CustomTime is a wall clock time of day, written as "HH:mm" in JSON and BSON
 type CustomTime time.Time
 
+// ClockLayout is how times of day are accepted and stored
+const ClockLayout = "15:04"
+
+var ErrInvalidTimeFormat = errors.New("error: invalid time format, expected HH:mm")
+
+// ParseClock reads a strict "HH:mm" time of day, rejecting values like "25:99" or "8:00"
+func ParseClock(value string) (CustomTime, error) {
+    t, err := time.Parse(ClockLayout, value)
+    if err != nil || len(value) != len(ClockLayout) {
+        return CustomTime{}, ErrInvalidTimeFormat
+    }
+    return CustomTime(t), nil
+}
+
+// parseStoredClock also reads the "H:mm" and "HH:mm:ss" forms found in
+// documents written before times were validated
+func parseStoredClock(value string) (CustomTime, error) {
+    for _, layout := range []string{ClockLayout, "15:04:05"} {
+        if t, err := time.Parse(layout, value); err == nil {
+            return CustomTime(t), nil
+        }
+    }
+    return CustomTime{}, ErrInvalidTimeFormat
+}
+
 // UnmarshalJSON parses time in HH:mm format
 func (ct *CustomTime) UnmarshalJSON(b []byte) error {
-    s := string(b[1 : len(b)-1]) // remove quotes
-    parsedTime, err := time.Parse("15:04", s)
+    var s string
+    if err := json.Unmarshal(b, &s); err != nil {
+        return ErrInvalidTimeFormat
+    }
+    parsed, err := ParseClock(s)
     if err != nil {
-        return errors.New("invalid time format, expected HH:mm")
+        return err
     }
-    *ct = CustomTime(parsedTime)
+    *ct = parsed
     return nil
 }
 
 // MarshalJSON serializes time to HH:mm format
 func (ct CustomTime) MarshalJSON() ([]byte, error) {
-    return []byte(`"` + time.Time(ct).Format("15:04") + `"`), nil
+    return []byte(`"` + ct.String() + `"`), nil
+}
+
+// MarshalBSONValue stores the time as an "HH:mm" string, which sorts and
+// compares correctly in queries
+func (ct CustomTime) MarshalBSONValue() (bsontype.Type, []byte, error) {
+    return bson.MarshalValue(ct.String())
+}
+
+// UnmarshalBSONValue reads a stored "HH:mm" string
+func (ct *CustomTime) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+    var s string
+    if err := bson.UnmarshalValue(t, data, &s); err != nil {
+        return ErrInvalidTimeFormat
+    }
+    parsed, err := parseStoredClock(s)
+    if err != nil {
+        return err
+    }
+    *ct = parsed
+    return nil
 }
 
 // ToTime returns the time.Time value of CustomTime
@@ -31,12 +84,43 @@ func (ct CustomTime) ToTime() time.Time {
 
 // String returns CustomTime in "HH:mm" format
 func (ct CustomTime) String() string {
-    return time.Time(ct).Format("15:04")
+    return time.Time(ct).Format(ClockLayout)
+}
+
+// Minutes returns the minutes since midnight
+func (ct CustomTime) Minutes() int {
+    return time.Time(ct).Hour()*60 + time.Time(ct).Minute()
+}
+
+// On places the time of day on day's calendar date in the facilities' time zone
+func (ct CustomTime) On(day time.Time) time.Time {
+    y, m, d := day.In(location).Date()
+    return time.Date(y, m, d, time.Time(ct).Hour(), time.Time(ct).Minute(), 0, 0, location)
+}
+
+// TimeZone is the zone facilities operate in; opening hours, slots and
+// booking days are all read in it
+const TimeZone = "Asia/Bangkok"
+
+var location = loadLocation()
+
+func loadLocation() *time.Location {
+	loc, err := time.LoadLocation(TimeZone)
+	if err != nil {
+		// Images without tzdata still get the right offset, Thailand has no DST
+		log.Printf("Error: load location %s: %s", TimeZone, err.Error())
+		return time.FixedZone(TimeZone, 7*60*60)
+	}
+	return loc
+}
+
+// Location returns the facilities' time zone
+func Location() *time.Location {
+	return location
 }
 
 func LocalTime() time.Time {
-	loc, _ := time.LoadLocation("Asia/Bangkok")
-	return time.Now().In(loc)
+	return time.Now().In(location)
 }
 
 func ConvertStringTimeToTime(t string) time.Time {
@@ -49,9 +133,9 @@ func ConvertStringTimeToTime(t string) time.Time {
 }
 
 func ParseTimeOnly(timeStr string) CustomTime {
-	parsedTime, err := time.Parse("15:04", timeStr)
+	parsedTime, err := ParseClock(timeStr)
 	if err != nil {
 		return CustomTime{}
 	}
-	return CustomTime(parsedTime)
+	return parsedTime
 }
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTimeRange = errors.New("error: start time must be before end time")
+
+// TimeRange is a validated start and end time of day within one day
+type TimeRange struct {
+	StartTime CustomTime `bson:"start_time" json:"start_time"`
+	EndTime   CustomTime `bson:"end_time" json:"end_time"`
+}
+
+// NewTimeRange parses two "HH:mm" times and checks start is before end
+func NewTimeRange(startTime, endTime string) (TimeRange, error) {
+	start, err := ParseClock(startTime)
+	if err != nil {
+		return TimeRange{}, err
+	}
+	end, err := ParseClock(endTime)
+	if err != nil {
+		return TimeRange{}, err
+	}
+
+	r := TimeRange{StartTime: start, EndTime: end}
+	return r, r.Validate()
+}
+
+// ParseTimeRange is the lenient form of NewTimeRange for stored values, also
+// accepting "H:mm", "HH:mm:ss" and surrounding spaces
+func ParseTimeRange(startTime, endTime string) (TimeRange, error) {
+	start, err := parseStoredClock(strings.TrimSpace(startTime))
+	if err != nil {
+		return TimeRange{}, err
+	}
+	end, err := parseStoredClock(strings.TrimSpace(endTime))
+	if err != nil {
+		return TimeRange{}, err
+	}
+
+	r := TimeRange{StartTime: start, EndTime: end}
+	return r, r.Validate()
+}
+
+func (r TimeRange) Validate() error {
+	if r.StartTime.Minutes() >= r.EndTime.Minutes() {
+		return ErrInvalidTimeRange
+	}
+	return nil
+}
+
+// Overlaps reports whether the ranges share any minute; touching ranges don't overlap
+func (r TimeRange) Overlaps(other TimeRange) bool {
+	return r.StartTime.Minutes() < other.EndTime.Minutes() && other.StartTime.Minutes() < r.EndTime.Minutes()
+}
+
+// Within reports whether r lies inside other
+func (r TimeRange) Within(other TimeRange) bool {
+	return r.StartTime.Minutes() >= other.StartTime.Minutes() && r.EndTime.Minutes() <= other.EndTime.Minutes()
+}
+
+// On places the range on day's calendar date in the facilities' time zone
+func (r TimeRange) On(day time.Time) (start, end time.Time) {
+	return r.StartTime.On(day), r.EndTime.On(day)
+}
+
+func (r TimeRange) String() string {
+	return r.StartTime.String() + "-" + r.EndTime.String()
+}
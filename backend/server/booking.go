@@ -1,12 +1,18 @@
 package server
 
 import (
+	"context"
 	"log"
 	client "main/client/payment"
+	"main/modules/auth"
 	"main/modules/booking/handler"
 	bookingPb "main/modules/booking/proto"
 	"main/modules/booking/repository"
+	"main/modules/booking/service"
 	"main/modules/booking/usecase"
+	equipmentHandler "main/modules/equipment/handler"
+	equipmentRepo "main/modules/equipment/repository"
+	equipmentUsecase "main/modules/equipment/usecase"
 	"main/pkg/grpc"
 )
 
@@ -14,21 +20,26 @@ import (
 func (s *server) bookingService() {
 	// Initialize repositories
 	bookingRepo := repository.NewBookingRepository(s.db)
+	eRepo := equipmentRepo.NewEquipmentRepository(s.db)
 
 	// Initialize usecases
 	bookingUsecase := usecase.NewBookingUsecase(bookingRepo)
+	eUsecase := equipmentUsecase.NewEquipmentUsecase(eRepo)
 
 	// Initialize handlers
 	paymentClient := client.NewPaymentClient("http://localhost:1327/payment_v1")
-	bookingHttpHandler := handler.NewBookingHttpHandler(s.cfg, bookingUsecase, paymentClient)
+	bookingHttpHandler := handler.NewBookingHttpHandler(s.cfg, bookingUsecase, eUsecase, paymentClient)
+	eHttpHandler := equipmentHandler.NewEquipmentHttpHandler(s.cfg, eUsecase)
 	bookingGrpcHandler := handler.NewBookingGrpcHandler(bookingUsecase)
 
-	// Initialize and start queue service
-	// queueService, err := service.NewBookingQueueService(s.cfg, bookingRepo)
-	// if err != nil {
-	// 	log.Fatalf("Failed to initialize queue service: %v", err)
-	// }
-	// go queueService.Start(context.Background())
+	// Consume payment.completed; the reconciler covers the queue being down
+	queueService, err := service.NewBookingQueueService(s.cfg, bookingRepo, bookingUsecase)
+	if err != nil {
+		log.Printf("Warning: payment events disabled, relying on reconciliation: %v", err)
+	} else {
+		go queueService.Start(context.Background())
+	}
+	go bookingUsecase.ScheduleReconciliation()
 
 	// Start gRPC server
 	go func() {
@@ -49,7 +60,20 @@ func (s *server) bookingService() {
 	booking.GET("/bookings/user/:user_id", bookingHttpHandler.FindOneUserBooking)
 	bookingCreate := booking.Group("/:facilityName")
 	bookingCreate.POST("/booking", bookingHttpHandler.CreateBooking, s.middleware.JwtAuthorizationMiddleware(s.cfg))
-	booking.POST("/bookings/:booking_id/pay", bookingHttpHandler.UpdateBookingStatusToPaid)
+	booking.POST("/bookings/:booking_id/pay", bookingHttpHandler.UpdateBookingStatusToPaid, s.middleware.JwtAuthorizationMiddleware(s.cfg), s.middleware.RequirePermission(auth.PermissionManageBookings)) // Staff override; payments mark bookings paid through payment.completed
+	booking.GET("/bookings/:booking_id/equipment", eHttpHandler.FindBookingRentals)
+
+	// Equipment rental
+	equipment := s.app.Group("/equipment_v1")
+	equipment.GET("/:facilityName/items", eHttpHandler.FindManyItem)
+	equipment.GET("/:facilityName/availability", eHttpHandler.FindAvailability)
+
+	adminEquipment := s.app.Group("/admin/equipment_v1", s.middleware.JwtAuthorizationMiddleware(s.cfg))
+	equipmentItems := adminEquipment.Group("/:facilityName/items", s.middleware.RequirePermission(auth.PermissionManageFacilities))
+	equipmentItems.POST("", eHttpHandler.InsertItem)
+	equipmentItems.PUT("/:item_id", eHttpHandler.UpdateItem)
+	equipmentItems.DELETE("/:item_id", eHttpHandler.DeleteItem)
+	adminEquipment.PATCH("/bookings/:booking_id/rentals/:rental_id/check_out", eHttpHandler.CheckOutRental, s.middleware.RequirePermission(auth.PermissionManageBookings))
 
 	log.Println("Booking service initialized")
-}
\ No newline at end of file
+}
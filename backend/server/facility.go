@@ -2,6 +2,7 @@ package server
 
 import (
 	"log"
+	"main/modules/auth"
 	analyticsHandler "main/modules/analytics/handler"
 	analyticsRepo "main/modules/analytics/repository"
 	analyticsUsecase "main/modules/analytics/usecase"
@@ -10,6 +11,7 @@ import (
 	facilityRepo "main/modules/facility/repository"
 	facilityUsecase "main/modules/facility/usecase"
 	"main/pkg/grpc"
+	"main/pkg/storage"
 
 	"github.com/labstack/echo/v4"
 )
@@ -27,7 +29,7 @@ func setTimeRange(timeRange string) echo.MiddlewareFunc {
 
 func (s *server) facilityService() {
 	repo := facilityRepo.NewFacilityRepository(s.db)
-	fUsecase := facilityUsecase.NewFacilityUsecase(repo)
+	fUsecase := facilityUsecase.NewFacilityUsecase(repo, storage.NewBlobStore(&s.cfg.Storage))
 	aRepo := analyticsRepo.NewAnalyticsRepository(s.db)
 	aUsecase := analyticsUsecase.NewAnalyticsUsecase(aRepo)
 	
@@ -51,18 +53,42 @@ func (s *server) facilityService() {
 	facility.GET("/facility/:facility_id", fHttpHandler.FindOneFacility)
 	facility.POST("/facility/facility", fHttpHandler.CreateFacility)
 
+	// Slot and resource mutations are restricted to facility managers
+	manageFacility := []echo.MiddlewareFunc{
+		s.middleware.JwtAuthorizationMiddleware(s.cfg),
+		s.middleware.RequirePermission(auth.PermissionManageFacilities),
+	}
+
 	// Slot Routes
 	facilitySlot := facility.Group("/:facilityName/slot_v1")
-	facilitySlot.POST("/slots", fHttpHandler.InsertSlot)
+	facilitySlot.POST("/slots", fHttpHandler.InsertSlot, manageFacility...)
 	facilitySlot.GET("/slots/:slot_id", fHttpHandler.FindOneSlot)
 	facilitySlot.GET("/slots", fHttpHandler.FindAllSlots)
+	facilitySlot.PUT("/slots/:slot_id", fHttpHandler.UpdateSlot, manageFacility...)
+	facilitySlot.PATCH("/slots/:slot_id/status", fHttpHandler.EnableOrDisableSlot, manageFacility...)
+	facilitySlot.DELETE("/slots/:slot_id", fHttpHandler.DeleteSlot, manageFacility...)
+
+	// Resource Routes
+	facilityResource := facility.Group("/:facilityName/resource_v1")
+	facilityResource.GET("/resources", fHttpHandler.FindManyResource)
+	facilityResource.POST("/resources", fHttpHandler.InsertResource, manageFacility...)
+	facilityResource.PUT("/resources/:resource_id", fHttpHandler.UpdateResource, manageFacility...)
+	facilityResource.DELETE("/resources/:resource_id", fHttpHandler.DeleteResource, manageFacility...)
+
+	// Media Routes
+	facility.GET("/media/*", fHttpHandler.ServeMedia)
+	facility.GET("/:facilityName/media", fHttpHandler.FindManyMedia)
 
-	// Badminton Routes
-	badminton := facility.Group("/badminton_v1")
-	badminton.POST("/court", fHttpHandler.InsertBadCourt)
-	badminton.POST("/slot", fHttpHandler.InsertBadmintonSlot)
-	badminton.GET("/slots", fHttpHandler.FindBadmintonSlot)
-	badminton.GET("/courts", fHttpHandler.FindCourt)
+	// Calendar Routes
+	facility.GET("/:facilityName/calendar", fHttpHandler.FindFacilityCalendar)
+
+	// Waiver Routes
+	facility.GET("/:facilityName/waiver", fHttpHandler.FindCurrentWaiver)
+	facility.GET("/:facilityName/waiver/status", fHttpHandler.FindWaiverStatus, s.middleware.JwtAuthorizationMiddleware(s.cfg))
+	facility.POST("/:facilityName/waiver/accept", fHttpHandler.AcceptWaiver, s.middleware.JwtAuthorizationMiddleware(s.cfg))
+
+	// Availability search across every facility
+	facility.GET("/search/availability", fHttpHandler.SearchAvailability)
 
 	// Admin routes with analytics
 	adminFacility := s.app.Group("/admin/facility_v1", s.middleware.JwtAuthorizationMiddleware(s.cfg))
@@ -89,4 +115,50 @@ func (s *server) facilityService() {
 	adminFacility.GET("/facilities", fHttpHandler.FindManyFacility)
 	adminFacility.GET("/facility/:facility_id", fHttpHandler.FindOneFacility)
 	adminFacility.POST("/facility", fHttpHandler.CreateFacility)
+
+	// Slot template routes
+	slotTemplate := adminFacility.Group("/:facilityName/template", s.middleware.RequirePermission(auth.PermissionManageFacilities))
+	slotTemplate.GET("", fHttpHandler.FindSlotTemplate)
+	slotTemplate.PUT("", fHttpHandler.SaveSlotTemplate)
+	slotTemplate.GET("/preview", fHttpHandler.PreviewSlotTemplate)
+	slotTemplate.POST("/apply", fHttpHandler.ApplySlotTemplate)
+
+	// Opening hours and calendar routes
+	facilityCalendar := adminFacility.Group("/:facilityName/calendar", s.middleware.RequirePermission(auth.PermissionManageFacilities))
+	facilityCalendar.PUT("/opening_hours", fHttpHandler.UpdateOpeningHours)
+	facilityCalendar.POST("/special_days", fHttpHandler.AddSpecialDay)
+	facilityCalendar.DELETE("/special_days/:date", fHttpHandler.DeleteSpecialDay)
+
+	// Dated capacity overrides and the overbooking report
+	capacity := adminFacility.Group("/:facilityName/capacity", s.middleware.RequirePermission(auth.PermissionManageFacilities))
+	capacity.GET("", fHttpHandler.FindManyCapacityOverride)
+	capacity.PUT("", fHttpHandler.SetCapacityOverride)
+	capacity.GET("/report", fHttpHandler.FindCapacityReport)
+	capacity.DELETE("/:override_id", fHttpHandler.DeleteCapacityOverride)
+
+	// Waiver versions and the acceptance export
+	waivers := adminFacility.Group("/:facilityName/waivers", s.middleware.RequirePermission(auth.PermissionManageFacilities))
+	waivers.GET("", fHttpHandler.FindManyWaiver)
+	waivers.POST("", fHttpHandler.PublishWaiver)
+	waivers.GET("/acceptances", fHttpHandler.ExportWaiverAcceptances)
+
+	// Public holidays close every facility
+	holidays := adminFacility.Group("/holidays", s.middleware.RequirePermission(auth.PermissionManageFacilities))
+	holidays.GET("", fHttpHandler.FindManyHoliday)
+	holidays.POST("", fHttpHandler.AddHoliday)
+	holidays.POST("/import", fHttpHandler.ImportHolidays)
+	holidays.DELETE("/:date", fHttpHandler.DeleteHoliday)
+
+	// Media routes
+	facilityMedia := adminFacility.Group("/:facilityName/media", s.middleware.RequirePermission(auth.PermissionManageFacilities))
+	facilityMedia.POST("", fHttpHandler.UploadMedia)
+	facilityMedia.PUT("/order", fHttpHandler.ReorderMedia)
+	facilityMedia.DELETE("/:media_id", fHttpHandler.DeleteMedia)
+
+	// Maintenance routes
+	maintenance := adminFacility.Group("/:facilityName/maintenance", s.middleware.RequirePermission(auth.PermissionManageFacilities))
+	maintenance.GET("", fHttpHandler.FindManyMaintenance)
+	maintenance.POST("", fHttpHandler.CreateMaintenance)
+	maintenance.PATCH("/:maintenance_id/status", fHttpHandler.UpdateMaintenanceStatus)
+	maintenance.DELETE("/:maintenance_id", fHttpHandler.DeleteMaintenance)
 }
@@ -2,14 +2,18 @@ package server
 
 import (
     "log"
+    "slices"
 
 
-    client "main/client/payment"
+    "main/modules/auth"
+    paymentModule "main/modules/payment"
     "main/modules/payment/handler"
+    "main/modules/payment/provider"
     "main/modules/payment/repository"
     "main/modules/payment/usecase"
     paymentPb "main/modules/payment/proto"  // Import your generated protobuf package
     "main/pkg/grpc"
+    "main/pkg/storage"
 
     "github.com/IBM/sarama"
 )
@@ -20,22 +24,76 @@ func (s *server) paymentService() {
     paymentRepo := repository.NewPaymentRepository(s.db)
 
     // Initialize usecases with config and repository
-    paymentUsecase := usecase.NewPaymentUsecase(s.cfg, paymentRepo)
-
-    // Initialize the payment client (if needed for external API calls)
-    paymentClient := client.NewPaymentClient("http://localhost:1327/payment_v1")
+    if s.cfg.Storage.SigningKey == "" {
+        log.Fatal("Error: STORAGE_SIGNING_KEY is required to sign slip links")
+    }
+    if s.cfg.Payment.FakeGateway {
+        if !slices.Contains(provider.FakeGatewayStages, s.cfg.App.Stage) {
+            log.Fatalf("Error: PAYMENT_FAKE_GATEWAY is only allowed in stages %v, not %q", provider.FakeGatewayStages, s.cfg.App.Stage)
+        }
+        if s.cfg.Payment.FakeWebhookSecret == "" {
+            log.Fatal("Error: PAYMENT_FAKE_WEBHOOK_SECRET is required with PAYMENT_FAKE_GATEWAY")
+        }
+    }
+    paymentUsecase := usecase.NewPaymentUsecase(s.cfg, paymentRepo, storage.NewBlobStore(&s.cfg.Storage))
 
     // Initialize HTTP handlers
-    paymentHttpHandler := handler.NewPaymentHttpHandler(s.cfg, paymentUsecase, paymentClient)
+    paymentHttpHandler := handler.NewPaymentHttpHandler(s.cfg, paymentUsecase)
 
     // Payment Routes (HTTP)
     payment := s.app.Group("/payment_v1")
     payment.POST("/payments", paymentHttpHandler.CreatePayment)            // Create a payment
     payment.GET("/payments/:id", paymentHttpHandler.FindPayment)          // Get payment by ID
+    payment.GET("/payments/:id/qr", paymentHttpHandler.FindPaymentQR)     // QR image, ?format=png|svg&size=
+    payment.GET("/payments/:id/status", paymentHttpHandler.SyncPaymentStatus) // Asks the provider, e.g. the card gateway, how the charge stands
+    payment.PUT("/payments/:id", paymentHttpHandler.UpdatePaymentStatus, s.middleware.JwtAuthorizationMiddleware(s.cfg), s.middleware.RequirePermission(auth.PermissionManagePayments)) // Staff settle a pending payment, publishes payment.completed
     payment.GET("/payments/user/:userId", paymentHttpHandler.FindPaymentsByUser)
-    payment.POST("/payments/slips", paymentHttpHandler.SaveSlip)          // Save payment slip
-    payment.PUT("/payments/slips/:slipId", paymentHttpHandler.UpdateSlipStatus) // Update payment slip status
-    payment.GET("/payments/slips/pending", paymentHttpHandler.GetPendingSlips)  // Get pending payment slips
+    payment.POST("/payments/slips", paymentHttpHandler.UploadSlip, s.middleware.JwtAuthorizationMiddleware(s.cfg)) // Multipart slip image upload
+    payment.GET("/slips/files/*", paymentHttpHandler.ServeSlipFile)       // Slip image behind a signed link
+    payment.POST("/webhooks/:provider", paymentHttpHandler.HandleWebhook) // Signed gateway callbacks, e.g. /webhooks/card
+
+    // Refunds: users request, staff approve or reject and then record the payout
+    refunds := payment.Group("/payments/:id/refunds", s.middleware.JwtAuthorizationMiddleware(s.cfg))
+    refunds.POST("", paymentHttpHandler.RequestRefund)
+    refunds.GET("", paymentHttpHandler.FindPaymentRefunds)
+
+    // Receipts: the first download is the original, later ones are copies
+    receipts := payment.Group("/payments/:id/receipts", s.middleware.JwtAuthorizationMiddleware(s.cfg))
+    receipts.GET("", paymentHttpHandler.FindPaymentReceipts)             // Receipt and credit notes, issuing any that are missing
+    receipts.GET("/:receipt_id/pdf", paymentHttpHandler.DownloadReceipt)
+
+    adminRefunds := s.app.Group("/admin/payment_v1/refunds", s.middleware.JwtAuthorizationMiddleware(s.cfg), s.middleware.RequirePermission(auth.PermissionManagePayments))
+    adminRefunds.GET("", paymentHttpHandler.FindManyRefund)
+    adminRefunds.PATCH("/:refund_id/approve", paymentHttpHandler.ApproveRefund)
+    adminRefunds.PATCH("/:refund_id/reject", paymentHttpHandler.RejectRefund)
+    adminRefunds.PATCH("/:refund_id/complete", paymentHttpHandler.CompleteRefund)
+
+    // Expire unpaid payments so their bookings give the slot back
+    go paymentUsecase.SchedulePaymentExpiry()
+
+    adminSlips := s.app.Group("/admin/payment_v1/slips", s.middleware.JwtAuthorizationMiddleware(s.cfg), s.middleware.RequirePermission(auth.PermissionManagePayments))
+    adminSlips.GET("/pending", paymentHttpHandler.GetPendingSlips) // Slips waiting for a decision
+    adminSlips.GET("/:slip_id/url", paymentHttpHandler.FindSlipURL)
+    adminSlips.PATCH("/:slip_id/approve", paymentHttpHandler.ApproveSlip) // Completes the payment and pays the booking
+    adminSlips.PATCH("/:slip_id/reject", paymentHttpHandler.RejectSlip)   // Needs a reason; the user may resubmit
+
+    // Ledger: charges and refunds post themselves, finance posts the rest
+    ledger := s.app.Group("/admin/payment_v1/ledger", s.middleware.JwtAuthorizationMiddleware(s.cfg), s.middleware.RequirePermission(auth.PermissionManagePayments))
+    ledger.GET("/entries", paymentHttpHandler.FindManyJournalEntry)
+    ledger.POST("/entries", paymentHttpHandler.PostJournalEntry)   // Fees, wallet top-ups, discounts and adjustments
+    ledger.GET("/entries/:entry_id", paymentHttpHandler.FindJournalEntry)
+    ledger.POST("/entries/:entry_id/reverse", paymentHttpHandler.ReverseJournalEntry) // Entries are never edited
+    ledger.GET("/balances", paymentHttpHandler.FindAccountBalances)  // ?at= for balances on a past date
+    ledger.GET("/check", paymentHttpHandler.CheckLedger)             // Debits equal credits
+
+    // Merchant accounts: where each facility's payments are received
+    merchants := s.app.Group("/admin/payment_v1/merchant_accounts", s.middleware.JwtAuthorizationMiddleware(s.cfg), s.middleware.RequirePermission(auth.PermissionManagePayments))
+    merchants.GET("", paymentHttpHandler.FindManyMerchantAccount)
+    merchants.POST("", paymentHttpHandler.CreateMerchantAccount)
+    merchants.GET("/:account_id", paymentHttpHandler.FindMerchantAccount)
+    merchants.PATCH("/:account_id", paymentHttpHandler.UpdateMerchantAccount)
+    merchants.DELETE("/:account_id", paymentHttpHandler.DeleteMerchantAccount)
+    merchants.POST("/:account_id/rotate", paymentHttpHandler.RotateMerchantAccount)
 
     // Initialize gRPC handler
     grpcHandler := handler.NewPaymentGrpcHandler(paymentUsecase)
@@ -60,7 +118,7 @@ func (s *server) paymentService() {
         defer admin.Close()
 
         // Create necessary Kafka topics
-        topics := []string{"payments", "payment.created", "slip.updated"}
+        topics := []string{"payments", "payment.created", paymentModule.TopicPaymentCompleted, paymentModule.TopicPaymentExpired, paymentModule.TopicSlipUpdated}
         for _, topic := range topics {
             topicDetail := &sarama.TopicDetail{
                 NumPartitions:     1,